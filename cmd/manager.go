@@ -6,8 +6,10 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"text/tabwriter"
 	"time"
 
 	"code.gitea.io/gitea/modules/log"
@@ -26,6 +28,7 @@ var (
 			subcmdShutdown,
 			subcmdRestart,
 			subcmdFlushQueues,
+			subcmdQueues,
 			subcmdLogging,
 		},
 	}
@@ -67,6 +70,107 @@ var (
 			},
 		},
 	}
+	subcmdQueues = cli.Command{
+		Name:  "queues",
+		Usage: "Inspect and manage the queues of the running process",
+		Subcommands: []cli.Command{
+			{
+				Name:  "list",
+				Usage: "List the queues",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runListQueues,
+			}, {
+				Name:      "pause",
+				Usage:     "Pause a queue - its workers will stop processing items until it is resumed",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runPauseQueue,
+			}, {
+				Name:      "resume",
+				Usage:     "Resume a paused queue",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runResumeQueue,
+			}, {
+				Name:      "flush",
+				Usage:     "Flush a queue",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.DurationFlag{
+						Name:  "timeout",
+						Value: 60 * time.Second,
+						Usage: "Timeout for the flushing process",
+					}, cli.BoolFlag{
+						Name:  "non-blocking",
+						Usage: "Set to true to not wait for flush to complete before returning",
+					},
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runFlushQueue,
+			}, {
+				Name:      "peek",
+				Usage:     "Show the first pending items of a queue without removing them",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.IntFlag{
+						Name:  "count, c",
+						Value: 10,
+						Usage: "Number of items to show, at most 1000",
+					},
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runPeekQueue,
+			}, {
+				Name:      "export",
+				Usage:     "Write the pending items of a paused queue to a file, one item per line",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "output, o",
+						Usage: "File to write the items to - will default to stdout",
+					},
+					cli.BoolFlag{
+						Name:  "remove",
+						Usage: "Remove the exported items from the queue once they have been written",
+					},
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runExportQueue,
+			}, {
+				Name:      "import",
+				Usage:     "Push the items of a previously exported file to a queue",
+				ArgsUsage: "[name] Name of the queue",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "input, i",
+						Usage: "File to read the items from - will default to stdin",
+					},
+					cli.BoolFlag{
+						Name: "debug",
+					},
+				},
+				Action: runImportQueue,
+			},
+		},
+	}
 	defaultLoggingFlags = []cli.Flag{
 		cli.StringFlag{
 			Name:  "group, g",
@@ -417,6 +521,142 @@ func runFlushQueues(c *cli.Context) error {
 	return nil
 }
 
+func runListQueues(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	infos, statusCode, msg := private.ListQueues()
+	if statusCode != http.StatusOK {
+		fail("InternalServerError", msg)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QID\tName\tType\tWorkers\tPending\tPaused")
+	for _, info := range infos {
+		workers, pending := "-", "-"
+		if info.NumberOfWorkers >= 0 {
+			workers = fmt.Sprint(info.NumberOfWorkers)
+		}
+		if info.NumberOfPendingItems >= 0 {
+			pending = fmt.Sprint(info.NumberOfPendingItems)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\n", info.QID, info.Name, info.Type, workers, pending, info.IsPaused)
+	}
+	return w.Flush()
+}
+
+func queueNameArg(c *cli.Context) string {
+	name := c.Args().First()
+	if len(name) == 0 {
+		fail("Missing queue name", "A queue name must be provided - use the list command to show the queues")
+	}
+	return name
+}
+
+func runPauseQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	statusCode, msg := private.PauseQueue(queueNameArg(c))
+	if statusCode != http.StatusOK {
+		fail("Unable to pause queue", msg)
+	}
+
+	fmt.Fprintln(os.Stdout, msg)
+	return nil
+}
+
+func runResumeQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	statusCode, msg := private.ResumeQueue(queueNameArg(c))
+	if statusCode != http.StatusOK {
+		fail("Unable to resume queue", msg)
+	}
+
+	fmt.Fprintln(os.Stdout, msg)
+	return nil
+}
+
+func runFlushQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	statusCode, msg := private.FlushQueue(queueNameArg(c), c.Duration("timeout"), c.Bool("non-blocking"))
+	switch statusCode {
+	case http.StatusOK, http.StatusAccepted:
+	default:
+		fail("Unable to flush queue", msg)
+	}
+
+	fmt.Fprintln(os.Stdout, msg)
+	return nil
+}
+
+func runPeekQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	items, statusCode, msg := private.PeekQueue(queueNameArg(c), c.Int("count"))
+	if statusCode != http.StatusOK {
+		fail("Unable to peek queue", msg)
+	}
+
+	for _, item := range items {
+		fmt.Fprintln(os.Stdout, item)
+	}
+	return nil
+}
+
+func runExportQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	name := queueNameArg(c)
+	out := os.Stdout
+	if c.IsSet("output") {
+		f, err := os.OpenFile(c.String("output"), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("Unable to create output file %s: %v", c.String("output"), err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	count, statusCode, msg := private.ExportQueue(name, out)
+	if statusCode != http.StatusOK {
+		fail("Unable to export queue", msg)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+
+	if !c.Bool("remove") {
+		return nil
+	}
+	if out != os.Stdout {
+		if err := out.Sync(); err != nil {
+			return fmt.Errorf("Unable to sync output file %s: %v", c.String("output"), err)
+		}
+	}
+	statusCode, msg = private.RemovePendingQueueItems(name, count)
+	if statusCode != http.StatusOK {
+		fail("Unable to remove exported items", msg)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+	return nil
+}
+
+func runImportQueue(c *cli.Context) error {
+	setup("manager", c.Bool("debug"))
+	name := queueNameArg(c)
+	var data []byte
+	var err error
+	if c.IsSet("input") {
+		data, err = ioutil.ReadFile(c.String("input"))
+	} else {
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read items: %v", err)
+	}
+
+	statusCode, msg := private.ImportQueue(name, data)
+	if statusCode != http.StatusOK {
+		fail("Unable to import queue", msg)
+	}
+
+	fmt.Fprintln(os.Stdout, msg)
+	return nil
+}
+
 func runPauseLogging(c *cli.Context) error {
 	setup("manager", c.Bool("debug"))
 	statusCode, msg := private.PauseLogging()
@@ -7,6 +7,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"code.gitea.io/gitea/models"
@@ -28,7 +29,7 @@ var CmdMigrateStorage = cli.Command{
 		cli.StringFlag{
 			Name:  "type, t",
 			Value: "",
-			Usage: "Kinds of files to migrate, currently only 'attachments' is supported",
+			Usage: "Kinds of files to migrate: 'attachments', 'lfs', 'avatars' or 'repo-avatars'",
 		},
 		cli.StringFlag{
 			Name:  "storage, s",
@@ -74,37 +75,79 @@ var CmdMigrateStorage = cli.Command{
 			Name:  "minio-use-ssl",
 			Usage: "Enable SSL for minio",
 		},
+		cli.BoolFlag{
+			Name:  "deduplicate",
+			Usage: "Store the files deduplicated by their content. Without a new storage type the current storage is deduplicated in place",
+		},
 	},
 }
 
-func migrateAttachments(dstStorage storage.ObjectStorage) error {
+func iterateAttachmentPaths(fn func(path string) error) error {
 	return models.IterateAttachment(func(attach *models.Attachment) error {
-		_, err := storage.Copy(dstStorage, attach.RelativePath(), storage.Attachments, attach.RelativePath())
-		return err
+		return fn(attach.RelativePath())
 	})
 }
 
-func migrateLFS(dstStorage storage.ObjectStorage) error {
+func iterateLFSPaths(fn func(path string) error) error {
 	return models.IterateLFS(func(mo *models.LFSMetaObject) error {
-		_, err := storage.Copy(dstStorage, mo.RelativePath(), storage.LFS, mo.RelativePath())
-		return err
+		return fn(mo.RelativePath())
 	})
 }
 
-func migrateAvatars(dstStorage storage.ObjectStorage) error {
+func iterateAvatarPaths(fn func(path string) error) error {
 	return models.IterateUser(func(user *models.User) error {
-		_, err := storage.Copy(dstStorage, user.CustomAvatarRelativePath(), storage.Avatars, user.CustomAvatarRelativePath())
-		return err
+		return fn(user.CustomAvatarRelativePath())
 	})
 }
 
-func migrateRepoAvatars(dstStorage storage.ObjectStorage) error {
+func iterateRepoAvatarPaths(fn func(path string) error) error {
 	return models.IterateRepository(func(repo *models.Repository) error {
-		_, err := storage.Copy(dstStorage, repo.CustomAvatarRelativePath(), storage.RepoAvatars, repo.CustomAvatarRelativePath())
+		return fn(repo.CustomAvatarRelativePath())
+	})
+}
+
+// migrateStorage copies the files of srcStorage listed by iterate to dstStorage
+func migrateStorage(iterate func(fn func(path string) error) error, dstStorage, srcStorage storage.ObjectStorage) error {
+	return iterate(func(path string) error {
+		_, err := storage.Copy(dstStorage, path, srcStorage, path)
 		return err
 	})
 }
 
+// deduplicateStorage deduplicates the files of dedupStorage listed by iterate in place
+func deduplicateStorage(iterate func(fn func(path string) error) error, dedupStorage *storage.DedupStorage) error {
+	var deduplicated, skipped, missing int
+	if err := iterate(func(path string) error {
+		if path == "" {
+			return nil
+		}
+		done, err := dedupStorage.Deduplicate(path)
+		if os.IsNotExist(err) {
+			log.Warn("File %s is missing from the %s storage", path, dedupStorage.Name())
+			missing++
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if done {
+			deduplicated++
+		} else {
+			skipped++
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	refs, blobs, err := models.CountStorageBlobRefs(dedupStorage.Name())
+	if err != nil {
+		return err
+	}
+	log.Info("Deduplicated %d files, %d files were already deduplicated, %d files are missing", deduplicated, skipped, missing)
+	log.Info("The %s storage holds %d distinct contents for %d files", dedupStorage.Name(), blobs, refs)
+	return nil
+}
+
 func runMigrateStorage(ctx *cli.Context) error {
 	if err := initDB(); err != nil {
 		return err
@@ -127,6 +170,30 @@ func runMigrateStorage(ctx *cli.Context) error {
 		return err
 	}
 
+	tp := strings.ToLower(ctx.String("type"))
+	var iterate func(fn func(path string) error) error
+	var srcStorage storage.ObjectStorage
+	switch tp {
+	case "attachments":
+		iterate, srcStorage = iterateAttachmentPaths, storage.Attachments
+	case "lfs":
+		iterate, srcStorage = iterateLFSPaths, storage.LFS
+	case "avatars":
+		iterate, srcStorage = iterateAvatarPaths, storage.Avatars
+	case "repo-avatars":
+		iterate, srcStorage = iterateRepoAvatarPaths, storage.RepoAvatars
+	default:
+		return fmt.Errorf("Unsupported storage: %s", ctx.String("type"))
+	}
+
+	if ctx.Bool("deduplicate") && ctx.String("storage") == "" && ctx.String("path") == "" {
+		dedupStorage, ok := srcStorage.(*storage.DedupStorage)
+		if !ok {
+			return fmt.Errorf("DEDUPLICATE must be enabled for the %s storage before it can be deduplicated in place", tp)
+		}
+		return deduplicateStorage(iterate, dedupStorage)
+	}
+
 	var dstStorage storage.ObjectStorage
 	var err error
 	switch strings.ToLower(ctx.String("storage")) {
@@ -162,29 +229,22 @@ func runMigrateStorage(ctx *cli.Context) error {
 		return err
 	}
 
-	tp := strings.ToLower(ctx.String("type"))
-	switch tp {
-	case "attachments":
-		if err := migrateAttachments(dstStorage); err != nil {
+	if ctx.Bool("deduplicate") {
+		dedupStorage, err := storage.NewDedupStorage(tp, dstStorage)
+		if err != nil {
 			return err
 		}
-	case "lfs":
-		if err := migrateLFS(dstStorage); err != nil {
-			return err
-		}
-	case "avatars":
-		if err := migrateAvatars(dstStorage); err != nil {
-			return err
-		}
-	case "repo-avatars":
-		if err := migrateRepoAvatars(dstStorage); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("Unsupported storage: %s", ctx.String("type"))
+		dstStorage = dedupStorage
+	}
+
+	if err := migrateStorage(iterate, dstStorage, srcStorage); err != nil {
+		return err
 	}
 
 	log.Warn("All files have been copied to the new placement but old files are still on the orignial placement.")
+	if ctx.Bool("deduplicate") {
+		log.Warn("The files have been deduplicated. DEDUPLICATE must be enabled for the new placement to serve them.")
+	}
 
 	return nil
 }
@@ -0,0 +1,90 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestAccessToken(t *testing.T, name string, token *models.AccessToken) string {
+	token.UID = 2
+	token.Name = name
+	assert.NoError(t, models.NewAccessToken(token))
+	return token.Token
+}
+
+func TestAccessTokenScopeLFS(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	readToken := createTestAccessToken(t, "repo-read", &models.AccessToken{Scope: "repo:read"})
+	oid := "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+	batch := func(operation string, expectedStatus int) {
+		req := NewRequestWithJSON(t, "POST", "/user2/repo1.git/info/lfs/objects/batch", map[string]interface{}{
+			"operation": operation,
+			"objects": []map[string]interface{}{
+				{"oid": oid, "size": 10},
+			},
+		})
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+		req.SetBasicAuth(readToken, "x-oauth-basic")
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	batch("download", http.StatusOK)
+	batch("upload", http.StatusUnauthorized)
+
+	req := NewRequestWithBody(t, "PUT", "/user2/repo1.git/info/lfs/objects/"+oid, strings.NewReader("0123456789"))
+	req.Header.Set("Accept", "application/vnd.git-lfs")
+	req.SetBasicAuth(readToken, "x-oauth-basic")
+	MakeRequest(t, req, http.StatusUnauthorized)
+	models.AssertNotExistsBean(t, &models.LFSMetaObject{Oid: oid, RepositoryID: 1})
+}
+
+func TestAccessTokenRestrictionWeb(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	// user2/repo2 is private, user2/repo1 is the only repository the token is restricted to
+	restrictedToken := createTestAccessToken(t, "restricted", &models.AccessToken{Scope: models.AccessTokenScopeAll, RepoID: 1})
+	fullToken := createTestAccessToken(t, "full", &models.AccessToken{Scope: models.AccessTokenScopeAll})
+	readToken := createTestAccessToken(t, "repo-read", &models.AccessToken{Scope: "repo:read"})
+
+	for token, expectedStatus := range map[string]int{
+		restrictedToken: http.StatusNotFound,
+		readToken:       http.StatusNotFound,
+		fullToken:       http.StatusOK,
+	} {
+		req := NewRequest(t, "GET", "/user2/repo2/raw/blob/6395b68e1feebb1e4c657b4f9f6ba2676a283c0b")
+		req.SetBasicAuth(token, "x-oauth-basic")
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	// the API enforces the restriction itself
+	req := NewRequest(t, "GET", "/api/v1/repos/user2/repo2")
+	req.SetBasicAuth(restrictedToken, "x-oauth-basic")
+	MakeRequest(t, req, http.StatusForbidden)
+	req = NewRequest(t, "GET", "/api/v1/repos/user2/repo1")
+	req.SetBasicAuth(restrictedToken, "x-oauth-basic")
+	MakeRequest(t, req, http.StatusOK)
+}
+
+func TestAccessTokenScopePackage(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	for scope, expectedStatus := range map[models.AccessTokenScope]int{
+		"repo:write":   http.StatusForbidden,
+		"package:read": http.StatusOK,
+	} {
+		token := createTestAccessToken(t, "releases-"+string(scope), &models.AccessToken{Scope: scope})
+		req := NewRequest(t, "GET", "/api/v1/repos/user2/repo1/releases?token="+token)
+		MakeRequest(t, req, expectedStatus)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"code.gitea.io/gitea/models"
+	api "code.gitea.io/gitea/modules/structs"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -80,3 +81,135 @@ func TestAdminDeleteUser(t *testing.T) {
 	assertUserDeleted(t, 8)
 	models.CheckConsistencyFor(t, &models.User{})
 }
+
+func TestAdminImpersonateUser(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	session := loginUser(t, "user1")
+	csrf := GetCSRF(t, session, "/admin/users/2")
+	req := NewRequestWithValues(t, "POST", "/admin/users/2/impersonate", map[string]string{
+		"_csrf": csrf,
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+	models.AssertExistsAndLoadBean(t, &models.Notice{Type: models.NoticeImpersonation})
+
+	// the site is shown as user2 and the administration is not accessible
+	req = NewRequest(t, "GET", "/user/settings")
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	htmlDoc := NewHTMLParser(t, resp.Body)
+	assert.EqualValues(t, "user2", htmlDoc.GetInputValueByName("name"))
+	htmlDoc.AssertElement(t, `form[action$="/user/impersonation/stop"]`, true)
+	req = NewRequest(t, "GET", "/admin/users")
+	session.MakeRequest(t, req, http.StatusForbidden)
+
+	// changes are blocked unless allowed
+	req = NewRequestWithValues(t, "POST", "/user/settings", map[string]string{
+		"_csrf":     htmlDoc.GetCSRF(),
+		"name":      "user2",
+		"full_name": "Changed by admin",
+		"email":     "user2@example.com",
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+	user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	assert.NotEqual(t, "Changed by admin", user.FullName)
+
+	req = NewRequestWithValues(t, "POST", "/user/impersonation/stop", map[string]string{
+		"_csrf": htmlDoc.GetCSRF(),
+	})
+	resp = session.MakeRequest(t, req, http.StatusFound)
+	assert.EqualValues(t, "/admin/users/2", resp.Header().Get("Location"))
+
+	req = NewRequest(t, "GET", "/admin/users")
+	session.MakeRequest(t, req, http.StatusOK)
+}
+
+func TestAdminImpersonateAdmin(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	session := loginUser(t, "user1")
+	csrf := GetCSRF(t, session, "/admin/users/1")
+	req := NewRequestWithValues(t, "POST", "/admin/users/1/impersonate", map[string]string{
+		"_csrf": csrf,
+	})
+	resp := session.MakeRequest(t, req, http.StatusFound)
+	assert.EqualValues(t, "/admin/users/1", resp.Header().Get("Location"))
+
+	req = NewRequest(t, "GET", "/admin/users")
+	session.MakeRequest(t, req, http.StatusOK)
+}
+
+func TestAdminImpersonateUserAPI(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	session := loginUser(t, "user1")
+	csrf := GetCSRF(t, session, "/admin/users/2")
+	req := NewRequestWithValues(t, "POST", "/admin/users/2/impersonate", map[string]string{
+		"_csrf": csrf,
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+
+	// the session of the administrator can read as the user through the API
+	req = NewRequest(t, "GET", "/api/v1/user")
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	var user api.User
+	DecodeJSON(t, resp, &user)
+	assert.EqualValues(t, "user2", user.UserName)
+
+	// but changes are blocked
+	csrf = GetCSRF(t, session, "/user/settings")
+	req = NewRequest(t, "DELETE", "/api/v1/repos/user2/repo1")
+	req.Header.Add("X-Csrf-Token", csrf)
+	session.MakeRequest(t, req, http.StatusForbidden)
+	models.AssertExistsAndLoadBean(t, &models.Repository{OwnerName: "user2", LowerName: "repo1"})
+}
+
+func TestAdminImpersonateUserOAuth(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	session := loginUser(t, "user1")
+	csrf := GetCSRF(t, session, "/admin/users/2")
+	req := NewRequestWithValues(t, "POST", "/admin/users/2/impersonate", map[string]string{
+		"_csrf":       csrf,
+		"allow_write": "true",
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+
+	// applications cannot be authorized on behalf of the user
+	req = NewRequest(t, "GET", defaultAuthorize)
+	resp := session.MakeRequest(t, req, http.StatusFound)
+	assert.EqualValues(t, "/", resp.Header().Get("Location"))
+	models.AssertNotExistsBean(t, &models.OAuth2Grant{UserID: 2, ApplicationID: 1})
+}
+
+func TestAdminImpersonateUserDeletedAdmin(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	admin := &models.User{
+		Name:     "impersonating-admin",
+		Email:    "impersonating-admin@example.com",
+		Passwd:   userPassword,
+		IsActive: true,
+		IsAdmin:  true,
+	}
+	assert.NoError(t, models.CreateUser(admin))
+
+	session := loginUser(t, admin.Name)
+	csrf := GetCSRF(t, session, "/admin/users/2")
+	req := NewRequestWithValues(t, "POST", "/admin/users/2/impersonate", map[string]string{
+		"_csrf": csrf,
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+	csrf = GetCSRF(t, session, "/user/settings")
+	assert.NoError(t, models.DeleteUser(admin))
+
+	// the session cannot be handed back and is signed out
+	req = NewRequestWithValues(t, "POST", "/user/impersonation/stop", map[string]string{
+		"_csrf": csrf,
+	})
+	resp := session.MakeRequest(t, req, http.StatusFound)
+	assert.EqualValues(t, "/user/login", resp.Header().Get("Location"))
+
+	req = NewRequest(t, "GET", "/user/settings")
+	resp = session.MakeRequest(t, req, http.StatusFound)
+	assert.Contains(t, resp.Header().Get("Location"), "/user/login")
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/scim"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func getSCIMTokenForLoggedInUser(t *testing.T, session *TestSession, scope string) string {
+	t.Helper()
+	tokenCounter++
+	req := NewRequest(t, "GET", "/user/settings/applications")
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	doc := NewHTMLParser(t, resp.Body)
+	req = NewRequestWithValues(t, "POST", "/user/settings/applications", map[string]string{
+		"_csrf": doc.GetCSRF(),
+		"name":  fmt.Sprintf("scim-testing-token-%d", tokenCounter),
+		"scope": scope,
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+	req = NewRequest(t, "GET", "/user/settings/applications")
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	return NewHTMLParser(t, resp.Body).doc.Find(".ui.info p").Text()
+}
+
+func newSCIMRequest(t *testing.T, method, urlStr, token string, v interface{}) *http.Request {
+	t.Helper()
+	var req *http.Request
+	if v != nil {
+		req = NewRequestWithJSON(t, method, urlStr, v)
+		req.Header.Set("Content-Type", scim.ContentType)
+	} else {
+		req = NewRequest(t, method, urlStr)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func newSCIMPatchRequest(t *testing.T, urlStr, token string, ops ...scim.PatchOperation) *http.Request {
+	t.Helper()
+	return newSCIMRequest(t, "PATCH", urlStr, token, &scim.PatchRequest{
+		Schemas:    []string{scim.SchemaPatchOp},
+		Operations: ops,
+	})
+}
+
+func TestAPISCIMAuthentication(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", "", nil), http.StatusUnauthorized)
+
+	session := loginUser(t, "user1")
+	token := getTokenForLoggedInUser(t, session)
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", token, nil), http.StatusForbidden)
+
+	token = getSCIMTokenForLoggedInUser(t, session, "scim:read")
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", token, nil), http.StatusOK)
+	MakeRequest(t, newSCIMRequest(t, "DELETE", "/api/scim/v2/Users/2", token, nil), http.StatusForbidden)
+
+	// tokens of users who may not sign in are rejected
+	user1 := models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+	user1.ProhibitLogin = true
+	assert.NoError(t, models.UpdateUserCols(user1, "prohibit_login"))
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", token, nil), http.StatusForbidden)
+	user1.ProhibitLogin = false
+	user1.IsActive = false
+	assert.NoError(t, models.UpdateUserCols(user1, "prohibit_login", "is_active"))
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", token, nil), http.StatusForbidden)
+
+	// user2 is not a site administrator
+	session = loginUser(t, "user2")
+	token = getSCIMTokenForLoggedInUser(t, session, "scim:write")
+	resp := MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users", token, nil), http.StatusForbidden)
+	assert.Contains(t, resp.Header().Get("Content-Type"), scim.ContentType)
+	var scimErr scim.Error
+	DecodeJSON(t, resp, &scimErr)
+	assert.Equal(t, []string{scim.SchemaError}, scimErr.Schemas)
+	assert.Equal(t, "403", scimErr.Status)
+}
+
+func TestAPISCIMServiceProviderConfig(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	token := getSCIMTokenForLoggedInUser(t, loginUser(t, "user1"), "scim:read")
+
+	resp := MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/ServiceProviderConfig", token, nil), http.StatusOK)
+	var config scim.ServiceProviderConfig
+	DecodeJSON(t, resp, &config)
+	assert.True(t, config.Patch.Supported)
+	assert.True(t, config.Filter.Supported)
+	assert.False(t, config.Bulk.Supported)
+
+	resp = MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/ResourceTypes", token, nil), http.StatusOK)
+	var list scim.ListResponse
+	DecodeJSON(t, resp, &list)
+	assert.EqualValues(t, 2, list.TotalResults)
+}
+
+func TestAPISCIMUsers(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	token := getSCIMTokenForLoggedInUser(t, loginUser(t, "user1"), "scim:write")
+
+	active := true
+	user := &scim.User{
+		Schemas:    []string{scim.SchemaUser},
+		ExternalID: "ext-scim-user",
+		UserName:   "scim-user@example.com",
+		Name:       &scim.Name{GivenName: "Scim", FamilyName: "User"},
+		Emails:     []scim.MultiValuedAttribute{{Value: "scim-user@example.com", Type: "work", Primary: true}},
+		Active:     &active,
+	}
+	resp := MakeRequest(t, newSCIMRequest(t, "POST", "/api/scim/v2/Users", token, user), http.StatusCreated)
+	var created scim.User
+	DecodeJSON(t, resp, &created)
+	assert.Equal(t, "scim-user", created.UserName)
+	assert.Equal(t, "ext-scim-user", created.ExternalID)
+	assert.Equal(t, "scim-user@example.com", created.PrimaryEmail())
+	assert.True(t, created.IsActive())
+	assert.Equal(t, created.Meta.Location, resp.Header().Get("Location"))
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{Name: "scim-user"}).(*models.User)
+	assert.Equal(t, "Scim User", u.FullName)
+	assert.False(t, u.ProhibitLogin)
+
+	// the user name and the external id are unique
+	resp = MakeRequest(t, newSCIMRequest(t, "POST", "/api/scim/v2/Users", token, user), http.StatusConflict)
+	var scimErr scim.Error
+	DecodeJSON(t, resp, &scimErr)
+	assert.Equal(t, scim.ErrorTypeUniqueness, scimErr.ScimType)
+
+	for _, filter := range []string{
+		`userName eq "scim-user@example.com"`,
+		`externalId eq "ext-scim-user"`,
+		`emails.value eq "scim-user@example.com" and active eq true`,
+	} {
+		resp = MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users?filter="+url.QueryEscape(filter), token, nil), http.StatusOK)
+		var list scim.ListResponse
+		DecodeJSON(t, resp, &list)
+		assert.EqualValues(t, 1, list.TotalResults, filter)
+	}
+	resp = MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users?filter="+url.QueryEscape(`userName eq "unknown"`), token, nil), http.StatusOK)
+	var list scim.ListResponse
+	DecodeJSON(t, resp, &list)
+	assert.EqualValues(t, 0, list.TotalResults)
+	MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users?filter="+url.QueryEscape(`title eq "boss"`), token, nil), http.StatusBadRequest)
+
+	resp = MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Users?startIndex=2&count=3", token, nil), http.StatusOK)
+	DecodeJSON(t, resp, &list)
+	assert.Equal(t, 2, list.StartIndex)
+	assert.Equal(t, 3, list.ItemsPerPage)
+	assert.EqualValues(t, models.CountUsers(), list.TotalResults)
+
+	// deactivate the user the way Azure AD does
+	req := newSCIMPatchRequest(t, created.Meta.Location, token, scim.PatchOperation{
+		Op:    "Replace",
+		Path:  "active",
+		Value: jsoniter.RawMessage(`"False"`),
+	})
+	resp = MakeRequest(t, req, http.StatusOK)
+	var patched scim.User
+	DecodeJSON(t, resp, &patched)
+	assert.False(t, patched.IsActive())
+	u = models.AssertExistsAndLoadBean(t, &models.User{ID: u.ID}).(*models.User)
+	assert.True(t, u.ProhibitLogin)
+
+	// rename the user
+	user.UserName = "scim-renamed"
+	resp = MakeRequest(t, newSCIMRequest(t, "PUT", created.Meta.Location, token, user), http.StatusOK)
+	DecodeJSON(t, resp, &patched)
+	assert.Equal(t, "scim-renamed", patched.UserName)
+	assert.True(t, patched.IsActive())
+
+	MakeRequest(t, newSCIMRequest(t, "DELETE", created.Meta.Location, token, nil), http.StatusNoContent)
+	MakeRequest(t, newSCIMRequest(t, "GET", created.Meta.Location, token, nil), http.StatusNotFound)
+	models.AssertNotExistsBean(t, &models.SCIMExternalID{ResourceType: models.SCIMResourceTypeUser, ResourceID: u.ID})
+}
+
+func TestAPISCIMGroups(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	token := getSCIMTokenForLoggedInUser(t, loginUser(t, "user1"), "scim:write")
+
+	// user3 is an organization
+	group := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ExternalID:  "ext-scim-team",
+		DisplayName: "user3:scim-team",
+		Members:     []scim.MultiValuedAttribute{{Value: "4"}, {Value: "5"}},
+	}
+	resp := MakeRequest(t, newSCIMRequest(t, "POST", "/api/scim/v2/Groups", token, group), http.StatusCreated)
+	var created scim.Group
+	DecodeJSON(t, resp, &created)
+	assert.Equal(t, "user3:scim-team", created.DisplayName)
+	assert.ElementsMatch(t, []string{"4", "5"}, created.MemberIDs())
+
+	team := models.AssertExistsAndLoadBean(t, &models.Team{OrgID: 3, LowerName: "scim-team"}).(*models.Team)
+	assert.Equal(t, models.AccessModeRead, team.Authorize)
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: team.ID, UID: 4})
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: team.ID, UID: 5})
+
+	MakeRequest(t, newSCIMRequest(t, "POST", "/api/scim/v2/Groups", token, group), http.StatusConflict)
+	group.DisplayName = "unknown-org:scim-team"
+	MakeRequest(t, newSCIMRequest(t, "POST", "/api/scim/v2/Groups", token, group), http.StatusBadRequest)
+
+	resp = MakeRequest(t, newSCIMRequest(t, "GET", "/api/scim/v2/Groups?excludedAttributes=members&filter="+url.QueryEscape(`displayName eq "user3:scim-team"`), token, nil), http.StatusOK)
+	var list scim.ListResponse
+	DecodeJSON(t, resp, &list)
+	assert.EqualValues(t, 1, list.TotalResults)
+
+	req := newSCIMPatchRequest(t, created.Meta.Location, token,
+		scim.PatchOperation{Op: "remove", Path: `members[value eq "4"]`},
+		scim.PatchOperation{Op: "add", Path: "members", Value: jsoniter.RawMessage(`[{"value":"2"}]`)},
+		scim.PatchOperation{Op: "replace", Path: "displayName", Value: jsoniter.RawMessage(`"user3:scim-renamed"`)},
+	)
+	resp = MakeRequest(t, req, http.StatusOK)
+	var patched scim.Group
+	DecodeJSON(t, resp, &patched)
+	assert.Equal(t, "user3:scim-renamed", patched.DisplayName)
+	assert.ElementsMatch(t, []string{"2", "5"}, patched.MemberIDs())
+	models.AssertNotExistsBean(t, &models.TeamUser{TeamID: team.ID, UID: 4})
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: team.ID, UID: 2})
+
+	// teams can not be moved to another organization
+	req = newSCIMPatchRequest(t, created.Meta.Location, token,
+		scim.PatchOperation{Op: "replace", Path: "displayName", Value: jsoniter.RawMessage(`"org25:scim-renamed"`)},
+	)
+	MakeRequest(t, req, http.StatusBadRequest)
+
+	MakeRequest(t, newSCIMRequest(t, "DELETE", created.Meta.Location, token, nil), http.StatusNoContent)
+	MakeRequest(t, newSCIMRequest(t, "GET", created.Meta.Location, token, nil), http.StatusNotFound)
+	models.AssertNotExistsBean(t, &models.SCIMExternalID{ResourceType: models.SCIMResourceTypeGroup, ResourceID: team.ID})
+
+	// the owners team of user3 can not be deleted
+	MakeRequest(t, newSCIMRequest(t, "DELETE", "/api/scim/v2/Groups/1", token, nil), http.StatusBadRequest)
+}
@@ -30,6 +30,7 @@ func TestAPICreateAndDeleteToken(t *testing.T) {
 		Name:  newAccessToken.Name,
 		Token: newAccessToken.Token,
 		UID:   user.ID,
+		Scope: models.AccessTokenScopeAll,
 	})
 
 	req = NewRequestf(t, "DELETE", "/api/v1/users/user1/tokens/%d", newAccessToken.ID)
@@ -61,3 +62,55 @@ func TestAPIDeleteMissingToken(t *testing.T) {
 	req = AddBasicAuthHeader(req, user.Name)
 	MakeRequest(t, req, http.StatusNotFound)
 }
+
+// TestAPITokenCredentialRoutes ensures that only tokens with full access can manage the credentials of the account
+func TestAPITokenCredentialRoutes(t *testing.T) {
+	defer prepareTestEnv(t)()
+	user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+
+	createToken := func(opts api.CreateAccessTokenOption) string {
+		req := NewRequestWithJSON(t, "POST", "/api/v1/users/user2/tokens", opts)
+		req = AddBasicAuthHeader(req, user.Name)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var token api.AccessToken
+		DecodeJSON(t, resp, &token)
+		return token.Token
+	}
+	scopedToken := createToken(api.CreateAccessTokenOption{Name: "scoped", Scopes: []string{"user:write"}})
+	restrictedToken := createToken(api.CreateAccessTokenOption{Name: "restricted", Repository: "user2/repo1"})
+	fullToken := createToken(api.CreateAccessTokenOption{Name: "full"})
+
+	for _, token := range []string{scopedToken, restrictedToken} {
+		req := NewRequest(t, "GET", "/api/v1/user?token="+token)
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/user/keys?token="+token, &api.CreateKeyOption{
+			Title: "key",
+			Key:   "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCbyWJgIsLoY0Rs4l/7WAIwMtBDlfqo9VbBMS0YzTr61xR8bHfJl1wsJjZVYF/zy0nU4dMvGVXX+Zw2Wnc/0DjSYZAJvbxK4lfrpUvlPKdFSJo/TGf6zU6fdX5Cn6LHHQv1bkpE7zRETZDZGWJcgMlyPOMnjbtWFcHUYZNg98lGMlR1xgHZlX2yLm6aaeVgBdR3tg8HSs+9N1GZ0L33t54eqdSrxuyFkrQdZuXBbw1EXh8QgATP3D9INPn3dxo1tf9QoJ8WaJMX88s+yAzzshZjjKOGyiH3Mxi2U0zVpQxsyzwj8MRaSb8uhFXjXfDFkb2gw+QaRMv+3f+8Vz6mLNdQjt",
+		})
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/user/gpg_keys?token="+token, &api.CreateGPGKeyOption{
+			ArmoredKey: "-----BEGIN PGP PUBLIC KEY BLOCK-----",
+		})
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/user/emails?token="+token, &api.CreateEmailOption{
+			Emails: []string{"credential@example.com"},
+		})
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/user/applications/oauth2?token="+token, &api.CreateOAuth2ApplicationOptions{
+			Name:         "app",
+			RedirectURIs: []string{"https://example.com/callback"},
+		})
+		MakeRequest(t, req, http.StatusForbidden)
+	}
+
+	req := NewRequestWithJSON(t, "POST", "/api/v1/user/applications/oauth2?token="+fullToken, &api.CreateOAuth2ApplicationOptions{
+		Name:         "app",
+		RedirectURIs: []string{"https://example.com/callback"},
+	})
+	MakeRequest(t, req, http.StatusCreated)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Application{UID: user.ID, Name: "app"})
+}
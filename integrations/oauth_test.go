@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/setting"
 
 	jsoniter "github.com/json-iterator/go"
@@ -261,3 +262,113 @@ func TestRefreshTokenInvalidation(t *testing.T) {
 	refreshReq.Body = ioutil.NopCloser(bytes.NewReader(bs))
 	MakeRequest(t, refreshReq, 400)
 }
+
+func TestDeviceAuthorizationGrant(t *testing.T) {
+	defer prepareTestEnv(t)()
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+
+	req := NewRequestWithValues(t, "POST", "/login/oauth/device_authorization", map[string]string{
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"scope":         "openid",
+	})
+	resp := MakeRequest(t, req, 200)
+	type deviceResponse struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int64  `json:"expires_in"`
+		Interval                int64  `json:"interval"`
+	}
+	device := new(deviceResponse)
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), device))
+	assert.NotEmpty(t, device.DeviceCode)
+	assert.Len(t, device.UserCode, 9)
+	assert.Equal(t, setting.AppURL+"login/device", device.VerificationURI)
+	assert.EqualValues(t, setting.OAuth2.DeviceCodePollingInterval, device.Interval)
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	poll := func(expectedStatus int) *errorResponse {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+			"grant_type":    "urn:ietf:params:oauth:grant-type:device_code",
+			"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+			"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+			"device_code":   device.DeviceCode,
+		})
+		resp := MakeRequest(t, req, expectedStatus)
+		parsed := new(errorResponse)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), parsed))
+		return parsed
+	}
+
+	assert.Equal(t, "authorization_pending", poll(400).Error)
+	assert.Equal(t, "slow_down", poll(400).Error)
+
+	session := loginUser(t, "user2")
+	req = NewRequest(t, "GET", "/login/device?user_code="+device.UserCode)
+	resp = session.MakeRequest(t, req, 200)
+	assert.Contains(t, resp.Body.String(), device.UserCode)
+
+	req = NewRequestWithValues(t, "POST", "/login/device", map[string]string{
+		"_csrf":     GetCSRF(t, session, "/login/device"),
+		"user_code": device.UserCode,
+		"granted":   "true",
+	})
+	session.MakeRequest(t, req, 200)
+
+	assert.Empty(t, poll(200).Error)
+	// device codes can only be exchanged once
+	assert.Equal(t, "invalid_grant", poll(400).Error)
+}
+
+func TestIntrospectOAuth(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"redirect_uri":  "a",
+		"code":          "authcode",
+		"code_verifier": "N1Zo9-8Rfwhkt68r1r29ty8YwIraXR8eh_1Qwxg7yQXsonBt",
+	})
+	resp := MakeRequest(t, req, 200)
+	type response struct {
+		AccessToken string `json:"access_token"`
+	}
+	parsed := new(response)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), parsed))
+
+	otherApp, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
+		Name:         "Other",
+		UserID:       2,
+		RedirectURIs: []string{"b"},
+	})
+	assert.NoError(t, err)
+	otherSecret, err := otherApp.GenerateClientSecret()
+	assert.NoError(t, err)
+
+	introspect := func(clientID, clientSecret string) map[string]interface{} {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/introspect", map[string]string{
+			"client_id":     clientID,
+			"client_secret": clientSecret,
+			"token":         parsed.AccessToken,
+		})
+		resp := MakeRequest(t, req, 200)
+		result := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+		return result
+	}
+
+	result := introspect("da7da3ba-9a13-4167-856f-3899de0b0138", "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=")
+	assert.Equal(t, true, result["active"])
+	assert.Equal(t, "user1", result["username"])
+
+	// tokens issued to another client are not disclosed
+	result = introspect(otherApp.ClientID, otherSecret)
+	assert.Equal(t, map[string]interface{}{"active": false}, result)
+}
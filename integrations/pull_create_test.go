@@ -160,3 +160,28 @@ func TestPullBranchDelete(t *testing.T) {
 		session.MakeRequest(t, req, http.StatusOK)
 	})
 }
+
+func TestPullCodeComment_InvalidDiffRange(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, u *url.URL) {
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited)\n")
+		resp := testPullCreate(t, session, "user1", "repo1", "master", "This is a pull title")
+		pullURL := resp.Header().Get("Location")
+
+		csrf := GetCSRF(t, session, pullURL+"/files")
+		for _, commitID := range []string{"--output=/tmp/gitea-code-comment", "0123456789012345678901234567890123456789"} {
+			req := NewRequestWithValues(t, "POST", pullURL+"/files/reviews/comments", map[string]string{
+				"_csrf":          csrf,
+				"origin":         "diff",
+				"content":        "code comment",
+				"side":           "previous",
+				"line":           "1",
+				"path":           "README.md",
+				"diff_start_cid": commitID,
+			})
+			resp = session.MakeRequest(t, req, http.StatusFound)
+			assert.EqualValues(t, pullURL+"/files", resp.Header().Get("Location"))
+		}
+	})
+}
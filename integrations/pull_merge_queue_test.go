@@ -0,0 +1,168 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/repofiles"
+	api "code.gitea.io/gitea/modules/structs"
+	pull_service "code.gitea.io/gitea/services/pull"
+	repo_service "code.gitea.io/gitea/services/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createMergeQueueTestPR(t *testing.T, actor *models.User, repo *models.Repository, branch, file string) *models.PullRequest {
+	_, err := repofiles.CreateOrUpdateRepoFile(repo, actor, &repofiles.UpdateRepoFileOptions{
+		TreePath:  file,
+		Message:   "Add " + file,
+		Content:   file,
+		IsNewFile: true,
+		OldBranch: "master",
+		NewBranch: branch,
+		Author: &repofiles.IdentityOptions{
+			Name:  actor.Name,
+			Email: actor.Email,
+		},
+		Committer: &repofiles.IdentityOptions{
+			Name:  actor.Name,
+			Email: actor.Email,
+		},
+		Dates: &repofiles.CommitDateOptions{
+			Author:    time.Now(),
+			Committer: time.Now(),
+		},
+	})
+	assert.NoError(t, err)
+
+	pullIssue := &models.Issue{
+		RepoID:   repo.ID,
+		Title:    "Merge queue " + branch,
+		PosterID: actor.ID,
+		Poster:   actor,
+		IsPull:   true,
+	}
+	pullRequest := &models.PullRequest{
+		HeadRepoID: repo.ID,
+		BaseRepoID: repo.ID,
+		HeadBranch: branch,
+		BaseBranch: "master",
+		HeadRepo:   repo,
+		BaseRepo:   repo,
+		Type:       models.PullRequestGitea,
+	}
+	assert.NoError(t, pull_service.NewPullRequest(repo, pullIssue, nil, nil, pullRequest, nil))
+	return pullRequest
+}
+
+// waitForMergeQueue waits until the speculative merges of all entries of the merge queue are built
+func waitForMergeQueue(t *testing.T, repo *models.Repository, length int) []*models.PullMergeQueueEntry {
+	var entries []*models.PullMergeQueueEntry
+	assert.Eventually(t, func() bool {
+		var err error
+		entries, err = models.GetMergeQueue(repo.ID, "master")
+		assert.NoError(t, err)
+		if len(entries) != length {
+			return false
+		}
+		for i, entry := range entries {
+			if !entry.IsTesting() || (i > 0 && entry.BaseCommitID != entries[i-1].SpeculativeCommitID) {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 100*time.Millisecond)
+	return entries
+}
+
+func TestPullMergeQueue(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+		repo, err := repo_service.CreateRepository(user, user, models.CreateRepoOptions{
+			Name:     "repo-merge-queue",
+			AutoInit: true,
+			Readme:   "Default",
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, models.UpdateProtectBranch(repo, &models.ProtectedBranch{
+			RepoID:              repo.ID,
+			BranchName:          "master",
+			EnableMergeQueue:    true,
+			EnableStatusCheck:   true,
+			StatusCheckContexts: []string{"ci"},
+		}, models.WhitelistOptions{}))
+
+		prA := createMergeQueueTestPR(t, user, repo, "branch-a", "File_A")
+		prB := createMergeQueueTestPR(t, user, repo, "branch-b", "File_B")
+		prC := createMergeQueueTestPR(t, user, repo, "branch-c", "File_C")
+		for _, pr := range []*models.PullRequest{prA, prB, prC} {
+			assert.NoError(t, pull_service.AddToMergeQueue(pr, user, models.MergeStyleMerge, "Merge "+pr.HeadBranch))
+		}
+
+		gitRepo, err := git.OpenRepository(repo.RepoPath())
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		masterCommitID, err := gitRepo.GetBranchCommitID("master")
+		assert.NoError(t, err)
+
+		// each pull request is tested on top of the ones ahead of it
+		entries := waitForMergeQueue(t, repo, 3)
+		assert.Equal(t, masterCommitID, entries[0].BaseCommitID)
+		for i, pr := range []*models.PullRequest{prA, prB, prC} {
+			commitID, err := gitRepo.GetRefCommitID(pull_service.MergeQueueRef(pr))
+			assert.NoError(t, err)
+			assert.Equal(t, entries[i].SpeculativeCommitID, commitID)
+		}
+		branches, _, err := gitRepo.GetBranches(0, 0)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"master", "branch-a", "branch-b", "branch-c"}, branches)
+
+		// failing status checks remove the first pull request and the others are tested without it
+		assert.NoError(t, repo_service.CreateCommitStatus(repo, user, entries[0].SpeculativeCommitID, &models.CommitStatus{
+			State:   api.CommitStatusFailure,
+			Context: "ci",
+		}))
+		entries = waitForMergeQueue(t, repo, 2)
+		assert.EqualValues(t, prB.ID, entries[0].PullID)
+		assert.Equal(t, masterCommitID, entries[0].BaseCommitID)
+		models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: prA.IssueID, Type: models.CommentTypeMergeQueueRemove})
+		_, err = gitRepo.GetRefCommitID(pull_service.MergeQueueRef(prA))
+		assert.Error(t, err)
+
+		// passing status checks merge the first pull request and the queue advances
+		assert.NoError(t, repo_service.CreateCommitStatus(repo, user, entries[0].SpeculativeCommitID, &models.CommitStatus{
+			State:   api.CommitStatusSuccess,
+			Context: "ci",
+		}))
+		assert.Eventually(t, func() bool {
+			pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: prB.ID}).(*models.PullRequest)
+			return pr.HasMerged
+		}, 10*time.Second, 100*time.Millisecond)
+		masterCommitID, err = gitRepo.GetBranchCommitID("master")
+		assert.NoError(t, err)
+		assert.Equal(t, entries[0].SpeculativeCommitID, masterCommitID)
+
+		entries = waitForMergeQueue(t, repo, 1)
+		assert.EqualValues(t, prC.ID, entries[0].PullID)
+		assert.Equal(t, masterCommitID, entries[0].BaseCommitID)
+
+		// a warning of a required status check does not pass and removes the pull request
+		assert.NoError(t, repo_service.CreateCommitStatus(repo, user, entries[0].SpeculativeCommitID, &models.CommitStatus{
+			State:   api.CommitStatusWarning,
+			Context: "ci",
+		}))
+		assert.Eventually(t, func() bool {
+			entries, err := models.GetMergeQueue(repo.ID, "master")
+			assert.NoError(t, err)
+			return len(entries) == 0
+		}, 10*time.Second, 100*time.Millisecond)
+		models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: prC.IssueID, Type: models.CommentTypeMergeQueueRemove})
+	})
+}
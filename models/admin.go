@@ -22,6 +22,8 @@ const (
 	NoticeRepository NoticeType = iota + 1
 	// NoticeTask type
 	NoticeTask
+	// NoticeImpersonation type
+	NoticeImpersonation
 )
 
 // Notice represents a system notice for admin.
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
 // ErrNotExist represents a non-exist error.
@@ -177,6 +178,40 @@ func (err ErrUserProhibitLogin) Error() string {
 	return fmt.Sprintf("user is not allowed login [uid: %d, name: %s]", err.UID, err.Name)
 }
 
+// ErrUserLockedOut represents a "ErrUserLockedOut" kind of error.
+type ErrUserLockedOut struct {
+	UID         int64
+	Name        string
+	LockedUntil timeutil.TimeStamp
+	// JustLocked is true if the failed sign-in attempt returning the error locked the account
+	JustLocked bool
+}
+
+// IsErrUserLockedOut checks if an error is a ErrUserLockedOut
+func IsErrUserLockedOut(err error) bool {
+	_, ok := err.(ErrUserLockedOut)
+	return ok
+}
+
+func (err ErrUserLockedOut) Error() string {
+	return fmt.Sprintf("user is locked because of too many failed sign-in attempts [uid: %d, name: %s, until: %d]", err.UID, err.Name, err.LockedUntil)
+}
+
+// ErrLoginRateLimited represents a "ErrLoginRateLimited" kind of error.
+type ErrLoginRateLimited struct {
+	RemoteAddr string
+}
+
+// IsErrLoginRateLimited checks if an error is a ErrLoginRateLimited
+func IsErrLoginRateLimited(err error) bool {
+	_, ok := err.(ErrLoginRateLimited)
+	return ok
+}
+
+func (err ErrLoginRateLimited) Error() string {
+	return fmt.Sprintf("too many failed sign-in attempts from address [addr: %s]", err.RemoteAddr)
+}
+
 // ErrUserInactive represents a "ErrUserInactive" kind of error.
 type ErrUserInactive struct {
 	UID  int64
@@ -616,6 +651,21 @@ func (err ErrAccessTokenEmpty) Error() string {
 	return "access token is empty"
 }
 
+// ErrAccessTokenScopeInvalid represents a "AccessTokenScopeInvalid" kind of error.
+type ErrAccessTokenScopeInvalid struct {
+	Scope string
+}
+
+// IsErrAccessTokenScopeInvalid checks if an error is a ErrAccessTokenScopeInvalid.
+func IsErrAccessTokenScopeInvalid(err error) bool {
+	_, ok := err.(ErrAccessTokenScopeInvalid)
+	return ok
+}
+
+func (err ErrAccessTokenScopeInvalid) Error() string {
+	return fmt.Sprintf("access token scope is invalid [scope: %s]", err.Scope)
+}
+
 // ________                            .__                __  .__
 // \_____  \_______  _________    ____ |__|____________ _/  |_|__| ____   ____
 //  /   |   \_  __ \/ ___\__  \  /    \|  \___   /\__  \\   __\  |/  _ \ /    \
@@ -1036,6 +1086,38 @@ func (err ErrFilePathProtected) Error() string {
 	return fmt.Sprintf("path is protected and can not be changed [path: %s]", err.Path)
 }
 
+// ErrSuggestionOutdated represents a "SuggestionOutdated" kind of error.
+type ErrSuggestionOutdated struct {
+	Path string
+	Line int64
+}
+
+// IsErrSuggestionOutdated checks if an error is an ErrSuggestionOutdated.
+func IsErrSuggestionOutdated(err error) bool {
+	_, ok := err.(ErrSuggestionOutdated)
+	return ok
+}
+
+func (err ErrSuggestionOutdated) Error() string {
+	return fmt.Sprintf("the suggested line has been changed [path: %s, line: %d]", err.Path, err.Line)
+}
+
+// ErrSuggestionConflict represents a "SuggestionConflict" kind of error.
+type ErrSuggestionConflict struct {
+	Path string
+	Line int64
+}
+
+// IsErrSuggestionConflict checks if an error is an ErrSuggestionConflict.
+func IsErrSuggestionConflict(err error) bool {
+	_, ok := err.(ErrSuggestionConflict)
+	return ok
+}
+
+func (err ErrSuggestionConflict) Error() string {
+	return fmt.Sprintf("several suggestions change the same line [path: %s, line: %d]", err.Path, err.Line)
+}
+
 // ErrUserDoesNotHaveAccessToRepo represets an error where the user doesn't has access to a given repo.
 type ErrUserDoesNotHaveAccessToRepo struct {
 	UserID   int64
@@ -1474,6 +1556,36 @@ func (err ErrPullRequestHeadRepoMissing) Error() string {
 		err.ID, err.HeadRepoID)
 }
 
+// ErrMergeQueueEntryNotExist represents a "MergeQueueEntryNotExist" kind of error.
+type ErrMergeQueueEntryNotExist struct {
+	PullID int64
+}
+
+// IsErrMergeQueueEntryNotExist checks if an error is a ErrMergeQueueEntryNotExist.
+func IsErrMergeQueueEntryNotExist(err error) bool {
+	_, ok := err.(ErrMergeQueueEntryNotExist)
+	return ok
+}
+
+func (err ErrMergeQueueEntryNotExist) Error() string {
+	return fmt.Sprintf("pull request is not in a merge queue [pull_id: %d]", err.PullID)
+}
+
+// ErrPullAlreadyInMergeQueue represents a "PullAlreadyInMergeQueue" kind of error.
+type ErrPullAlreadyInMergeQueue struct {
+	PullID int64
+}
+
+// IsErrPullAlreadyInMergeQueue checks if an error is a ErrPullAlreadyInMergeQueue.
+func IsErrPullAlreadyInMergeQueue(err error) bool {
+	_, ok := err.(ErrPullAlreadyInMergeQueue)
+	return ok
+}
+
+func (err ErrPullAlreadyInMergeQueue) Error() string {
+	return fmt.Sprintf("pull request is already in the merge queue [pull_id: %d]", err.PullID)
+}
+
 // ErrInvalidMergeStyle represents an error if merging with disabled merge strategy
 type ErrInvalidMergeStyle struct {
 	ID    int64
@@ -101,6 +101,10 @@ const (
 	CommentTypeProjectBoard
 	// Dismiss Review
 	CommentTypeDismissReview
+	// 33 Pull request added to the merge queue
+	CommentTypeMergeQueueAdd
+	// 34 Pull request removed from the merge queue
+	CommentTypeMergeQueueRemove
 )
 
 // CommentTag defines comment tag type
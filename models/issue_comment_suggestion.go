@@ -0,0 +1,55 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var suggestionPattern = regexp.MustCompile("(?ms)^[ \t]*```suggestion[ \t]*\r?\n(.*?)^[ \t]*```[ \t]*\r?$")
+
+// SuggestedLines returns the lines of the first suggestion block of a code comment
+// and whether the comment contains a suggestion. A suggestion replaces the
+// commented line of the proposed side of a diff, an empty suggestion removes it.
+func (c *Comment) SuggestedLines() ([]string, bool) {
+	if c.Type != CommentTypeCode || c.Line <= 0 {
+		return nil, false
+	}
+	m := suggestionPattern.FindStringSubmatch(c.Content)
+	if m == nil {
+		return nil, false
+	}
+	content := strings.ReplaceAll(m[1], "\r\n", "\n")
+	if len(content) == 0 {
+		return []string{}, true
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), true
+}
+
+// HasSuggestion returns true if the code comment contains a suggestion
+func (c *Comment) HasSuggestion() bool {
+	_, ok := c.SuggestedLines()
+	return ok
+}
+
+// Suggestion returns the suggested lines of a code comment
+func (c *Comment) Suggestion() []string {
+	lines, _ := c.SuggestedLines()
+	return lines
+}
+
+// CommentedLine returns the content of the line a code comment was made on,
+// which is the last line of the patch of the comment.
+func (c *Comment) CommentedLine() string {
+	patch := strings.TrimRight(c.Patch, "\n")
+	if idx := strings.LastIndexByte(patch, '\n'); idx >= 0 {
+		patch = patch[idx+1:]
+	}
+	if len(patch) == 0 || (patch[0] != '+' && patch[0] != ' ') {
+		return ""
+	}
+	return patch[1:]
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComment_SuggestedLines(t *testing.T) {
+	kases := []struct {
+		comment *Comment
+		lines   []string
+		ok      bool
+	}{
+		{
+			comment: &Comment{Type: CommentTypeCode, Line: 4, Content: "Rename it:\n```suggestion\nfoo := bar()\nbaz(foo)\n```\nthanks"},
+			lines:   []string{"foo := bar()", "baz(foo)"},
+			ok:      true,
+		},
+		{
+			comment: &Comment{Type: CommentTypeCode, Line: 4, Content: "```suggestion\r\n\tx++\r\n```\r\n"},
+			lines:   []string{"\tx++"},
+			ok:      true,
+		},
+		{
+			comment: &Comment{Type: CommentTypeCode, Line: 4, Content: "Not needed\n```suggestion\n```"},
+			lines:   []string{},
+			ok:      true,
+		},
+		{
+			comment: &Comment{Type: CommentTypeCode, Line: -4, Content: "```suggestion\nfoo\n```"},
+		},
+		{
+			comment: &Comment{Type: CommentTypeComment, Content: "```suggestion\nfoo\n```"},
+		},
+		{
+			comment: &Comment{Type: CommentTypeCode, Line: 4, Content: "```go\nfoo\n```"},
+		},
+	}
+	for _, kase := range kases {
+		lines, ok := kase.comment.SuggestedLines()
+		assert.Equal(t, kase.ok, ok, kase.comment.Content)
+		assert.Equal(t, kase.lines, lines, kase.comment.Content)
+	}
+}
+
+func TestComment_CommentedLine(t *testing.T) {
+	comment := &Comment{Patch: "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1,2 +1,2 @@\n-old\n+new\n"}
+	assert.Equal(t, "new", comment.CommentedLine())
+
+	comment.Patch = "@@ -1,2 +1,2 @@\n context"
+	assert.Equal(t, "context", comment.CommentedLine())
+
+	comment.Patch = "@@ -1,2 +1,1 @@\n-removed"
+	assert.Equal(t, "", comment.CommentedLine())
+}
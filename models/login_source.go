@@ -11,12 +11,14 @@ import (
 	"fmt"
 	"net/smtp"
 	"net/textproto"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"code.gitea.io/gitea/modules/auth/ldap"
 	"code.gitea.io/gitea/modules/auth/oauth2"
 	"code.gitea.io/gitea/modules/auth/pam"
+	"code.gitea.io/gitea/modules/auth/saml"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
@@ -40,6 +42,7 @@ const (
 	LoginDLDAP            // 5
 	LoginOAuth2           // 6
 	LoginSSPI             // 7
+	LoginSAML             // 8
 )
 
 // LoginNames contains the name of LoginType values.
@@ -50,6 +53,7 @@ var LoginNames = map[LoginType]string{
 	LoginPAM:    "PAM",
 	LoginOAuth2: "OAuth2",
 	LoginSSPI:   "SPNEGO with SSPI",
+	LoginSAML:   "SAML 2.0",
 }
 
 // SecurityProtocolNames contains the name of SecurityProtocol values.
@@ -66,6 +70,7 @@ var (
 	_ convert.Conversion = &PAMConfig{}
 	_ convert.Conversion = &OAuth2Config{}
 	_ convert.Conversion = &SSPIConfig{}
+	_ convert.Conversion = &SAMLConfig{}
 )
 
 // LDAPConfig holds configuration for LDAP login source.
@@ -138,6 +143,42 @@ type OAuth2Config struct {
 	OpenIDConnectAutoDiscoveryURL string
 	CustomURLMapping              *oauth2.CustomURLMapping
 	IconURL                       string
+	// GroupClaimName is the claim listing the groups of the user, the other group settings require it
+	GroupClaimName      string
+	RequiredGroup       string
+	AdminGroup          string
+	RestrictedGroup     string
+	GroupTeamMap        string
+	GroupTeamMapRemoval bool
+}
+
+// GroupTeamMapping parses the mapping of groups to organization teams, e.g.
+// {"developers": {"MyOrg": ["Developers", "Readers"]}}
+func (cfg *OAuth2Config) GroupTeamMapping() (map[string]map[string][]string, error) {
+	mapping := make(map[string]map[string][]string)
+	if strings.TrimSpace(cfg.GroupTeamMap) == "" {
+		return mapping, nil
+	}
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal([]byte(cfg.GroupTeamMap), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid group team map: %v", err)
+	}
+	for group, orgs := range mapping {
+		if strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("invalid group team map: empty group name")
+		}
+		for org, teams := range orgs {
+			if strings.TrimSpace(org) == "" {
+				return nil, fmt.Errorf("invalid group team map: empty organization name for group %s", group)
+			}
+			for _, team := range teams {
+				if strings.TrimSpace(team) == "" {
+					return nil, fmt.Errorf("invalid group team map: empty team name for organization %s", org)
+				}
+			}
+		}
+	}
+	return mapping, nil
 }
 
 // FromDB fills up an OAuth2Config from serialized format.
@@ -173,6 +214,23 @@ func (cfg *SSPIConfig) ToDB() ([]byte, error) {
 	return json.Marshal(cfg)
 }
 
+// SAMLConfig holds configuration for the SAML login source.
+type SAMLConfig struct {
+	*saml.Source
+}
+
+// FromDB fills up a SAMLConfig from serialized format.
+func (cfg *SAMLConfig) FromDB(bs []byte) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	return json.Unmarshal(bs, &cfg)
+}
+
+// ToDB exports a SAMLConfig to a serialized format.
+func (cfg *SAMLConfig) ToDB() ([]byte, error) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	return json.Marshal(cfg)
+}
+
 // LoginSource represents an external way for authorizing users.
 type LoginSource struct {
 	ID            int64 `xorm:"pk autoincr"`
@@ -213,6 +271,8 @@ func (source *LoginSource) BeforeSet(colName string, val xorm.Cell) {
 			source.Cfg = new(OAuth2Config)
 		case LoginSSPI:
 			source.Cfg = new(SSPIConfig)
+		case LoginSAML:
+			source.Cfg = new(SAMLConfig)
 		default:
 			panic(fmt.Sprintf("unrecognized login source type: %v", *val))
 		}
@@ -254,6 +314,11 @@ func (source *LoginSource) IsSSPI() bool {
 	return source.Type == LoginSSPI
 }
 
+// IsSAML returns true of this source is of the SAML type.
+func (source *LoginSource) IsSAML() bool {
+	return source.Type == LoginSAML
+}
+
 // HasTLS returns true of this source supports TLS.
 func (source *LoginSource) HasTLS() bool {
 	return ((source.IsLDAP() || source.IsDLDAP()) &&
@@ -311,6 +376,26 @@ func (source *LoginSource) SSPI() *SSPIConfig {
 	return source.Cfg.(*SSPIConfig)
 }
 
+// SAML returns SAMLConfig for this source, if of SAML type.
+func (source *LoginSource) SAML() *SAMLConfig {
+	return source.Cfg.(*SAMLConfig)
+}
+
+// SAMLServiceProviderURL returns the URL the SAML endpoints of this source are served under.
+func (source *LoginSource) SAMLServiceProviderURL() string {
+	return setting.AppURL + "user/saml/" + url.PathEscape(source.Name)
+}
+
+// SAMLEntityID returns the entity ID of the service provider, which is the URL of its metadata.
+func (source *LoginSource) SAMLEntityID() string {
+	return source.SAMLServiceProviderURL() + "/metadata"
+}
+
+// SAMLAssertionConsumerServiceURL returns the URL the identity provider posts its responses to.
+func (source *LoginSource) SAMLAssertionConsumerServiceURL() string {
+	return source.SAMLServiceProviderURL() + "/acs"
+}
+
 // CreateLoginSource inserts a LoginSource in the DB if not already
 // existing with the given name.
 func CreateLoginSource(source *LoginSource) error {
@@ -510,7 +595,14 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource) (*Use
 		}
 	}
 
+	var isGroupTeamMapSet = source.LDAP().GroupsEnabled && source.LDAP().HasGroupTeamMap()
+
 	if user != nil {
+		if isGroupTeamMapSet {
+			if err := synchronizeLdapGroupTeams(user, source, sr.Groups); err != nil {
+				log.Error("LoginViaLDAP[%s]: Error synchronizing teams of user %s: %v", source.Name, user.Name, err)
+			}
+		}
 		if isAttributeSSHPublicKeySet && synchronizeLdapSSHPublicKeys(user, source, sr.SSHPublicKey) {
 			return user, RewriteAllPublicKeys()
 		}
@@ -542,6 +634,12 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource) (*Use
 
 	err := CreateUser(user)
 
+	if err == nil && isGroupTeamMapSet {
+		if err := synchronizeLdapGroupTeams(user, source, sr.Groups); err != nil {
+			log.Error("LoginViaLDAP[%s]: Error synchronizing teams of user %s: %v", source.Name, user.Name, err)
+		}
+	}
+
 	if err == nil && isAttributeSSHPublicKeySet && addLdapSSHPublicKeys(user, source, sr.SSHPublicKey) {
 		err = RewriteAllPublicKeys()
 	}
@@ -714,6 +812,94 @@ func LoginViaPAM(user *User, login, password string, sourceID int64, cfg *PAMCon
 	return user, CreateUser(user)
 }
 
+//   _________   _____      _____  .____
+//  /   _____/  /  _  \    /     \ |    |
+//  \_____  \  /  /_\  \  /  \ /  \|    |
+//  /        \/    |    \/    Y    \    |___
+// /_______  /\____|__  /\____|__  /_______ \
+//         \/         \/         \/        \/
+
+// GetActiveSAMLLoginSourceByName returns an active SAML LoginSource based on the given name
+func GetActiveSAMLLoginSourceByName(name string) (*LoginSource, error) {
+	source := new(LoginSource)
+	has, err := x.Where("name = ? and type = ? and is_actived = ?", name, LoginSAML, true).Get(source)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrLoginSourceNotExist{0}
+	}
+	return source, nil
+}
+
+// LoginViaSAML returns the user of a validated SAML assertion and synchronizes its
+// admin and restricted flags, or creates a local user if enabled.
+func LoginViaSAML(source *LoginSource, assertion *saml.Assertion) (*User, error) {
+	if !source.IsActived {
+		return nil, ErrLoginSourceNotActived
+	}
+	cfg := source.SAML()
+
+	user := &User{
+		LoginType:   LoginSAML,
+		LoginSource: source.ID,
+		LoginName:   assertion.NameID,
+	}
+	has, err := x.Get(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if has {
+		if user.ProhibitLogin {
+			return nil, ErrUserProhibitLogin{user.ID, user.Name}
+		}
+
+		cols := make([]string, 0, 3)
+		if len(assertion.FullName) > 0 && user.FullName != assertion.FullName {
+			user.FullName = assertion.FullName
+			cols = append(cols, "full_name")
+		}
+		if len(cfg.AdminGroup) > 0 && user.IsAdmin != assertion.IsAdmin {
+			// Change existing admin flag only if an admin group is set
+			user.IsAdmin = assertion.IsAdmin
+			cols = append(cols, "is_admin")
+		}
+		if !user.IsAdmin && len(cfg.RestrictedGroup) > 0 && user.IsRestricted != assertion.IsRestricted {
+			// Change existing restricted flag only if a restricted group is set
+			user.IsRestricted = assertion.IsRestricted
+			cols = append(cols, "is_restricted")
+		}
+		if len(cols) > 0 {
+			if err := UpdateUserCols(user, cols...); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+
+	if !cfg.AutoCreateUsers {
+		return nil, ErrUserNotExist{0, assertion.Username, 0}
+	}
+
+	if len(assertion.Email) == 0 {
+		assertion.Email = fmt.Sprintf("%s@localhost", assertion.Username)
+	}
+
+	user = &User{
+		LowerName:    strings.ToLower(assertion.Username),
+		Name:         assertion.Username,
+		FullName:     assertion.FullName,
+		Email:        assertion.Email,
+		LoginType:    LoginSAML,
+		LoginSource:  source.ID,
+		LoginName:    assertion.NameID,
+		IsActive:     true,
+		IsAdmin:      assertion.IsAdmin,
+		IsRestricted: assertion.IsRestricted,
+	}
+	return user, CreateUser(user)
+}
+
 // ExternalUserLogin attempts a login using external source types.
 func ExternalUserLogin(user *User, login, password string, source *LoginSource) (*User, error) {
 	if !source.IsActived {
@@ -775,42 +961,27 @@ func UserSignIn(username, password string) (*User, error) {
 	}
 
 	if hasUser {
-		switch user.LoginType {
-		case LoginNoType, LoginPlain, LoginOAuth2:
-			if user.IsPasswordSet() && user.ValidatePassword(password) {
-
-				// Update password hash if server password hash algorithm have changed
-				if user.PasswdHashAlgo != setting.PasswordHashAlgo {
-					if err = user.SetPassword(password); err != nil {
-						return nil, err
-					}
-					if err = UpdateUserCols(user, "passwd", "passwd_hash_algo", "salt"); err != nil {
-						return nil, err
-					}
-				}
-
-				// WARN: DON'T check user.IsActive, that will be checked on reqSign so that
-				// user could be hint to resend confirm email.
-				if user.ProhibitLogin {
-					return nil, ErrUserProhibitLogin{user.ID, user.Name}
-				}
+		if user.IsLockedOut() {
+			return nil, ErrUserLockedOut{UID: user.ID, Name: user.Name, LockedUntil: user.LockedUntilUnix}
+		}
 
-				return user, nil
-			}
-
-			return nil, ErrUserNotExist{user.ID, user.Name, 0}
-
-		default:
-			var source LoginSource
-			hasSource, err := x.ID(user.LoginSource).Get(&source)
-			if err != nil {
-				return nil, err
-			} else if !hasSource {
-				return nil, ErrLoginSourceNotExist{user.LoginSource}
+		authUser, err := existingUserSignIn(user, password)
+		if IsErrUserNotExist(err) {
+			locked, lockErr := recordFailedLogin(user)
+			if lockErr != nil {
+				return nil, lockErr
+			} else if locked {
+				return nil, ErrUserLockedOut{UID: user.ID, Name: user.Name, LockedUntil: user.LockedUntilUnix, JustLocked: true}
 			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
 
-			return ExternalUserLogin(user, user.LoginName, password, &source)
+		if err := resetFailedLogins(x, user); err != nil {
+			return nil, err
 		}
+		return authUser, nil
 	}
 
 	sources := make([]*LoginSource, 0, 5)
@@ -819,8 +990,8 @@ func UserSignIn(username, password string) (*User, error) {
 	}
 
 	for _, source := range sources {
-		if source.IsOAuth2() || source.IsSSPI() {
-			// don't try to authenticate against OAuth2 and SSPI sources here
+		if source.IsOAuth2() || source.IsSSPI() || source.IsSAML() {
+			// don't try to authenticate against OAuth2, SSPI and SAML sources here
 			continue
 		}
 		authUser, err := ExternalUserLogin(nil, username, password, source)
@@ -833,3 +1004,43 @@ func UserSignIn(username, password string) (*User, error) {
 
 	return nil, ErrUserNotExist{user.ID, user.Name, 0}
 }
+
+// existingUserSignIn validates the password of an existing user against the login source of the user
+func existingUserSignIn(user *User, password string) (*User, error) {
+	switch user.LoginType {
+	case LoginNoType, LoginPlain, LoginOAuth2, LoginSAML:
+		if user.IsPasswordSet() && user.ValidatePassword(password) {
+
+			// Update password hash if server password hash algorithm have changed
+			if user.PasswdHashAlgo != setting.PasswordHashAlgo {
+				if err := user.SetPassword(password); err != nil {
+					return nil, err
+				}
+				if err := UpdateUserCols(user, "passwd", "passwd_hash_algo", "salt"); err != nil {
+					return nil, err
+				}
+			}
+
+			// WARN: DON'T check user.IsActive, that will be checked on reqSign so that
+			// user could be hint to resend confirm email.
+			if user.ProhibitLogin {
+				return nil, ErrUserProhibitLogin{user.ID, user.Name}
+			}
+
+			return user, nil
+		}
+
+		return nil, ErrUserNotExist{user.ID, user.Name, 0}
+
+	default:
+		var source LoginSource
+		hasSource, err := x.ID(user.LoginSource).Get(&source)
+		if err != nil {
+			return nil, err
+		} else if !hasSource {
+			return nil, ErrLoginSourceNotExist{user.LoginSource}
+		}
+
+		return ExternalUserLogin(user, user.LoginName, password, &source)
+	}
+}
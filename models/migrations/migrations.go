@@ -296,6 +296,24 @@ var migrations = []Migration{
 	NewMigration("Add time_id column to Comment", addTimeIDCommentColumn),
 	// v174 -> v175
 	NewMigration("create repo transfer table", addRepoTransfer),
+	// v175 -> v176
+	NewMigration("Add storage blob tables for deduplicated storage", addStorageBlobTables),
+	// v176 -> v177
+	NewMigration("Add pull viewed file table", addPullViewedFileTable),
+	// v177 -> v178
+	NewMigration("Add allow maintainer edit to pull request", addAllowMaintainerEditToPullRequest),
+	// v178 -> v179
+	NewMigration("Add merge queue", addMergeQueue),
+	// v179 -> v180
+	NewMigration("Add scope, restriction and expiry to access tokens", addScopeAndExpiryToAccessToken),
+	// v180 -> v181
+	NewMigration("Add SCIM external id table", addSCIMExternalIDTable),
+	// v181 -> v182
+	NewMigration("Add OAuth2 device code table", addOAuth2DeviceCodeTable),
+	// v182 -> v183
+	NewMigration("Add failed login counters to user", addFailedLoginCountersToUser),
+	// v183 -> v184
+	NewMigration("Add require two factor to organizations", addRequireTwoFactorToUser),
 }
 
 // GetCurrentDBVersion returns the current db version
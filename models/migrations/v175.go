@@ -0,0 +1,31 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addStorageBlobTables(x *xorm.Engine) error {
+	type StorageBlob struct {
+		ID          int64  `xorm:"pk autoincr"`
+		Storage     string `xorm:"UNIQUE(s) NOT NULL"`
+		Hash        string `xorm:"UNIQUE(s) NOT NULL"`
+		Size        int64  `xorm:"NOT NULL DEFAULT 0"`
+		RefCount    int64  `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix int64  `xorm:"created"`
+	}
+
+	type StorageBlobRef struct {
+		ID          int64  `xorm:"pk autoincr"`
+		Storage     string `xorm:"UNIQUE(s) NOT NULL"`
+		Path        string `xorm:"UNIQUE(s) NOT NULL"`
+		Hash        string `xorm:"INDEX NOT NULL"`
+		CreatedUnix int64  `xorm:"created"`
+		UpdatedUnix int64  `xorm:"updated"`
+	}
+
+	return x.Sync2(new(StorageBlob), new(StorageBlobRef))
+}
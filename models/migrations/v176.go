@@ -0,0 +1,22 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPullViewedFileTable(x *xorm.Engine) error {
+	type PullViewedFile struct {
+		ID          int64  `xorm:"pk autoincr"`
+		UserID      int64  `xorm:"INDEX(s) NOT NULL"`
+		PullID      int64  `xorm:"INDEX(s) NOT NULL"`
+		TreePath    string `xorm:"TEXT NOT NULL"`
+		BlobSHA     string `xorm:"VARCHAR(40)"`
+		UpdatedUnix int64  `xorm:"updated"`
+	}
+
+	return x.Sync2(new(PullViewedFile))
+}
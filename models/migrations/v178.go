@@ -0,0 +1,31 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addMergeQueue(x *xorm.Engine) error {
+	type ProtectedBranch struct {
+		EnableMergeQueue bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	type PullMergeQueueEntry struct {
+		ID                  int64  `xorm:"pk autoincr"`
+		RepoID              int64  `xorm:"INDEX(s) NOT NULL"`
+		BaseBranch          string `xorm:"INDEX(s) NOT NULL"`
+		PullID              int64  `xorm:"UNIQUE NOT NULL"`
+		DoerID              int64  `xorm:"NOT NULL"`
+		MergeStyle          string `xorm:"VARCHAR(50)"`
+		Message             string `xorm:"TEXT"`
+		BaseCommitID        string `xorm:"VARCHAR(40)"`
+		SpeculativeCommitID string `xorm:"VARCHAR(40)"`
+		CreatedUnix         int64  `xorm:"created"`
+		UpdatedUnix         int64  `xorm:"updated"`
+	}
+
+	return x.Sync2(new(ProtectedBranch), new(PullMergeQueueEntry))
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addScopeAndExpiryToAccessToken(x *xorm.Engine) error {
+	type AccessToken struct {
+		Scope        string `xorm:"TEXT"`
+		RepoID       int64  `xorm:"NOT NULL DEFAULT 0"`
+		OrgID        int64  `xorm:"NOT NULL DEFAULT 0"`
+		ExpiresUnix  int64  `xorm:"INDEX NOT NULL DEFAULT 0"`
+		LastUsedUnix int64  `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	if err := x.Sync2(new(AccessToken)); err != nil {
+		return err
+	}
+
+	// existing tokens keep full access to the account
+	if _, err := x.Exec("UPDATE access_token SET scope = ?", "all"); err != nil {
+		return err
+	}
+
+	// the updated time of a token used to be bumped whenever it was used
+	_, err := x.Exec("UPDATE access_token SET last_used_unix = updated_unix WHERE updated_unix > created_unix")
+	return err
+}
@@ -0,0 +1,20 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addSCIMExternalIDTable(x *xorm.Engine) error {
+	type SCIMExternalID struct {
+		ID           int64  `xorm:"pk autoincr"`
+		ResourceType string `xorm:"VARCHAR(10) UNIQUE(s) UNIQUE(e) NOT NULL"`
+		ResourceID   int64  `xorm:"UNIQUE(s) NOT NULL"`
+		ExternalID   string `xorm:"VARCHAR(255) UNIQUE(e) NOT NULL"`
+	}
+
+	return x.Sync2(new(SCIMExternalID))
+}
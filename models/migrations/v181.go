@@ -0,0 +1,39 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+// OAuth2DeviceCode here is a snapshot of models.OAuth2DeviceCode for this version of the database
+type OAuth2DeviceCode struct {
+	ID             int64  `xorm:"pk autoincr"`
+	ApplicationID  int64  `xorm:"INDEX"`
+	DeviceCode     string `xorm:"UNIQUE"`
+	UserCode       string `xorm:"UNIQUE"`
+	Scope          string `xorm:"TEXT"`
+	UserID         int64
+	Status         int
+	PollInterval   int64
+	LastPolledUnix int64
+	ValidUntil     int64 `xorm:"INDEX"`
+	CreatedUnix    int64 `xorm:"created"`
+}
+
+// TableName sets the database table name to be the correct one, as the
+// autogenerated table name for this struct is "o_auth2_device_code".
+func (code *OAuth2DeviceCode) TableName() string {
+	return "oauth2_device_code"
+}
+
+func addOAuth2DeviceCodeTable(x *xorm.Engine) error {
+	if err := x.Sync2(new(OAuth2DeviceCode)); err != nil {
+		return fmt.Errorf("Sync2: %v", err)
+	}
+	return nil
+}
@@ -125,6 +125,7 @@ func init() {
 		new(OAuth2Application),
 		new(OAuth2AuthorizationCode),
 		new(OAuth2Grant),
+		new(OAuth2DeviceCode),
 		new(Task),
 		new(LanguageStat),
 		new(EmailHash),
@@ -134,6 +135,11 @@ func init() {
 		new(ProjectIssue),
 		new(Session),
 		new(RepoTransfer),
+		new(StorageBlob),
+		new(StorageBlobRef),
+		new(PullViewedFile),
+		new(PullMergeQueueEntry),
+		new(SCIMExternalID),
 	)
 
 	gonicNames := []string{"SSL", "UID"}
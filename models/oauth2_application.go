@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"code.gitea.io/gitea/modules/auth/oauth2"
 	"code.gitea.io/gitea/modules/secret"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
@@ -255,6 +256,10 @@ func deleteOAuth2Application(sess *xorm.Session, id, userid int64) error {
 	if _, err := sess.Where("application_id = ?", id).Delete(new(OAuth2Grant)); err != nil {
 		return err
 	}
+
+	if _, err := sess.Where("application_id = ?", id).Delete(new(OAuth2DeviceCode)); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -538,11 +543,18 @@ type OAuth2Token struct {
 
 // ParseOAuth2Token parses a singed jwt string
 func ParseOAuth2Token(jwtToken string) (*OAuth2Token, error) {
+	if oauth2.DefaultSigningKey == nil {
+		return nil, fmt.Errorf("OAuth2 is not enabled")
+	}
 	parsedToken, err := jwt.ParseWithClaims(jwtToken, &OAuth2Token{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method == nil || token.Method.Alg() != oauth2.DefaultSigningKey.SigningMethod().Alg() {
+			// Tokens issued before the signing algorithm was configurable are signed with the JWT secret
+			if token.Method == jwt.SigningMethodHS512 && !oauth2.DefaultSigningKey.IsSymmetric() {
+				return setting.OAuth2.JWTSecretBytes, nil
+			}
 			return nil, fmt.Errorf("unexpected signing algo: %v", token.Header["alg"])
 		}
-		return setting.OAuth2.JWTSecretBytes, nil
+		return oauth2.DefaultSigningKey.VerifyKey(), nil
 	})
 	if err != nil {
 		return nil, err
@@ -555,22 +567,47 @@ func ParseOAuth2Token(jwtToken string) (*OAuth2Token, error) {
 	return token, nil
 }
 
-// SignToken signs the token with the JWT secret
+// SignToken signs the token with the JWT signing key
 func (token *OAuth2Token) SignToken() (string, error) {
+	if oauth2.DefaultSigningKey == nil {
+		return "", fmt.Errorf("OAuth2 is not enabled")
+	}
 	token.IssuedAt = time.Now().Unix()
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS512, token)
-	return jwtToken.SignedString(setting.OAuth2.JWTSecretBytes)
+	jwtToken := jwt.NewWithClaims(oauth2.DefaultSigningKey.SigningMethod(), token)
+	oauth2.DefaultSigningKey.PreProcessToken(jwtToken)
+	return jwtToken.SignedString(oauth2.DefaultSigningKey.SignKey())
+}
+
+// OIDCClaims represents the user claims of an OpenID Connect id_token or userinfo response
+type OIDCClaims struct {
+	// Scope profile
+	Name              string             `json:"name,omitempty"`
+	PreferredUsername string             `json:"preferred_username,omitempty"`
+	Profile           string             `json:"profile,omitempty"`
+	Picture           string             `json:"picture,omitempty"`
+	Website           string             `json:"website,omitempty"`
+	Locale            string             `json:"locale,omitempty"`
+	UpdatedAt         timeutil.TimeStamp `json:"updated_at,omitempty"`
+
+	// Scope email
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified,omitempty"`
+
+	// Scope groups
+	Groups []string `json:"groups,omitempty"`
 }
 
 // OIDCToken represents an OpenID Connect id_token
 type OIDCToken struct {
 	jwt.StandardClaims
 	Nonce string `json:"nonce,omitempty"`
+	OIDCClaims
 }
 
-// SignToken signs an id_token with the (symmetric) client secret key
-func (token *OIDCToken) SignToken(clientSecret string) (string, error) {
+// SignToken signs an id_token with the given signing key
+func (token *OIDCToken) SignToken(signingKey oauth2.JWTSigningKey) (string, error) {
 	token.IssuedAt = time.Now().Unix()
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, token)
-	return jwtToken.SignedString([]byte(clientSecret))
+	jwtToken := jwt.NewWithClaims(signingKey.SigningMethod(), token)
+	signingKey.PreProcessToken(jwtToken)
+	return jwtToken.SignedString(signingKey.SignKey())
 }
@@ -0,0 +1,231 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/secret"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// userCodeAlphabet contains the characters of user codes, vowels and easily confused
+// characters are left out as recommended by RFC 8628
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength is the number of characters of a user code
+const userCodeLength = 8
+
+// OAuth2DeviceCodeStatus represents the state of a device authorization request
+type OAuth2DeviceCodeStatus int
+
+const (
+	// OAuth2DeviceCodePending is the state until the user approves or denies the request
+	OAuth2DeviceCodePending OAuth2DeviceCodeStatus = iota
+	// OAuth2DeviceCodeApproved is the state of a request the user granted access to
+	OAuth2DeviceCodeApproved
+	// OAuth2DeviceCodeDenied is the state of a request the user denied access to
+	OAuth2DeviceCodeDenied
+)
+
+// OAuth2DeviceCode is a device authorization request (RFC 8628). The device polls for an access token
+// with the device code while the user enters the user code on the verification page.
+type OAuth2DeviceCode struct {
+	ID             int64  `xorm:"pk autoincr"`
+	ApplicationID  int64  `xorm:"INDEX"`
+	DeviceCode     string `xorm:"UNIQUE"`
+	UserCode       string `xorm:"UNIQUE"`
+	Scope          string `xorm:"TEXT"`
+	UserID         int64
+	Status         OAuth2DeviceCodeStatus
+	PollInterval   int64
+	LastPolledUnix timeutil.TimeStamp
+	ValidUntil     timeutil.TimeStamp `xorm:"INDEX"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+}
+
+// TableName sets the table name to `oauth2_device_code`
+func (code *OAuth2DeviceCode) TableName() string {
+	return "oauth2_device_code"
+}
+
+// FormattedUserCode returns the user code split into two groups for readability, e.g. `BCDF-GHJK`
+func (code *OAuth2DeviceCode) FormattedUserCode() string {
+	if len(code.UserCode) != userCodeLength {
+		return code.UserCode
+	}
+	return code.UserCode[:userCodeLength/2] + "-" + code.UserCode[userCodeLength/2:]
+}
+
+// IsExpired returns true if the device code can no longer be used
+func (code *OAuth2DeviceCode) IsExpired() bool {
+	return code.ValidUntil <= timeutil.TimeStampNow()
+}
+
+// normalizeUserCode removes separators and whitespace the user may have entered
+func normalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+}
+
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CreateOAuth2DeviceCode creates a pending device authorization request of the application
+func CreateOAuth2DeviceCode(app *OAuth2Application, scope string) (*OAuth2DeviceCode, error) {
+	deviceCode, err := secret.New()
+	if err != nil {
+		return nil, err
+	}
+
+	code := &OAuth2DeviceCode{
+		ApplicationID: app.ID,
+		DeviceCode:    deviceCode,
+		Scope:         scope,
+		Status:        OAuth2DeviceCodePending,
+		PollInterval:  setting.OAuth2.DeviceCodePollingInterval,
+		ValidUntil:    timeutil.TimeStampNow().Add(setting.OAuth2.DeviceCodeExpirationTime),
+	}
+	// user codes are short, retry in the unlikely case of a collision
+	for i := 0; i < 5; i++ {
+		if code.UserCode, err = generateUserCode(); err != nil {
+			return nil, err
+		}
+		has, err := x.Where("user_code = ?", code.UserCode).Exist(new(OAuth2DeviceCode))
+		if err != nil {
+			return nil, err
+		} else if has {
+			continue
+		}
+		if _, err = x.Insert(code); err != nil {
+			return nil, err
+		}
+		return code, nil
+	}
+	return nil, fmt.Errorf("cannot generate a unique user code")
+}
+
+// GetOAuth2DeviceCodeByUserCode returns the device authorization request with the given user code,
+// or nil if there is none
+func GetOAuth2DeviceCodeByUserCode(userCode string) (*OAuth2DeviceCode, error) {
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return nil, nil
+	}
+	code := new(OAuth2DeviceCode)
+	if has, err := x.Where("user_code = ?", userCode).Get(code); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return code, nil
+}
+
+// GetOAuth2DeviceCodeByDeviceCode returns the device authorization request with the given device code,
+// or nil if there is none
+func GetOAuth2DeviceCodeByDeviceCode(deviceCode string) (*OAuth2DeviceCode, error) {
+	if deviceCode == "" {
+		return nil, nil
+	}
+	code := new(OAuth2DeviceCode)
+	if has, err := x.Where("device_code = ?", deviceCode).Get(code); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return code, nil
+}
+
+// Approve records that the user granted access to the pending device authorization request
+func (code *OAuth2DeviceCode) Approve(userID int64) error {
+	return code.setStatus(OAuth2DeviceCodeApproved, userID)
+}
+
+// Deny records that the user denied access to the pending device authorization request
+func (code *OAuth2DeviceCode) Deny(userID int64) error {
+	return code.setStatus(OAuth2DeviceCodeDenied, userID)
+}
+
+func (code *OAuth2DeviceCode) setStatus(status OAuth2DeviceCodeStatus, userID int64) error {
+	affected, err := x.ID(code.ID).
+		Where("status = ?", OAuth2DeviceCodePending).
+		Cols("status", "user_id").
+		Update(&OAuth2DeviceCode{Status: status, UserID: userID})
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("device authorization request %d is no longer pending", code.ID)
+	}
+	code.Status = status
+	code.UserID = userID
+	return nil
+}
+
+// Poll records a token request of the device. It returns true if the device polls faster than
+// the interval, in which case the interval is increased by 5 seconds as required by RFC 8628.
+func (code *OAuth2DeviceCode) Poll() (slowDown bool, err error) {
+	now := timeutil.TimeStampNow()
+	cols := []string{"last_polled_unix"}
+	if code.LastPolledUnix > 0 && now < code.LastPolledUnix.Add(code.PollInterval) {
+		slowDown = true
+		code.PollInterval += 5
+		cols = append(cols, "poll_interval")
+	}
+	code.LastPolledUnix = now
+	_, err = x.ID(code.ID).Cols(cols...).Update(code)
+	return slowDown, err
+}
+
+// Invalidate deletes the device code from the database so it can not be used again. It fails if
+// the device code has already been deleted, e.g. by a concurrent token request.
+func (code *OAuth2DeviceCode) Invalidate() error {
+	deleted, err := x.Delete(&OAuth2DeviceCode{ID: code.ID})
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return fmt.Errorf("device code %d has already been used", code.ID)
+	}
+	return nil
+}
+
+// DeleteExpiredOAuth2DeviceCodes deletes the device authorization requests which have expired.
+func DeleteExpiredOAuth2DeviceCodes(ctx context.Context) error {
+	log.Trace("Doing: DeleteExpiredOAuth2DeviceCodes")
+
+	select {
+	case <-ctx.Done():
+		return ErrCancelledf("before deleting expired OAuth2 device codes")
+	default:
+	}
+
+	deletes, err := x.Where("valid_until <= ?", timeutil.TimeStampNow()).Delete(new(OAuth2DeviceCode))
+	if err != nil {
+		return err
+	}
+	log.Trace("Deleted %d expired OAuth2 device codes", deletes)
+
+	log.Trace("Finished: DeleteExpiredOAuth2DeviceCodes")
+	return nil
+}
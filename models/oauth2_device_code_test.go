@@ -0,0 +1,82 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth2DeviceCode_UserCode(t *testing.T) {
+	userCode, err := generateUserCode()
+	assert.NoError(t, err)
+	assert.Len(t, userCode, userCodeLength)
+	for _, r := range userCode {
+		assert.True(t, strings.ContainsRune(userCodeAlphabet, r))
+	}
+
+	code := &OAuth2DeviceCode{UserCode: "BCDFGHJK"}
+	assert.Equal(t, "BCDF-GHJK", code.FormattedUserCode())
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode(" bcdf-ghjk "))
+}
+
+func TestOAuth2DeviceCode(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
+
+	code, err := CreateOAuth2DeviceCode(app, "openid")
+	assert.NoError(t, err)
+	assert.False(t, code.IsExpired())
+	assert.Equal(t, OAuth2DeviceCodePending, code.Status)
+
+	loaded, err := GetOAuth2DeviceCodeByUserCode(strings.ToLower(code.FormattedUserCode()))
+	assert.NoError(t, err)
+	assert.Equal(t, code.ID, loaded.ID)
+	loaded, err = GetOAuth2DeviceCodeByDeviceCode(code.DeviceCode)
+	assert.NoError(t, err)
+	assert.Equal(t, code.ID, loaded.ID)
+	loaded, err = GetOAuth2DeviceCodeByDeviceCode("invalid")
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	// the first poll is fine, polling again right away must slow down
+	slowDown, err := code.Poll()
+	assert.NoError(t, err)
+	assert.False(t, slowDown)
+	interval := code.PollInterval
+	slowDown, err = code.Poll()
+	assert.NoError(t, err)
+	assert.True(t, slowDown)
+	assert.Equal(t, interval+5, code.PollInterval)
+
+	assert.NoError(t, code.Approve(2))
+	AssertExistsAndLoadBean(t, &OAuth2DeviceCode{ID: code.ID, UserID: 2, Status: OAuth2DeviceCodeApproved})
+	assert.Error(t, code.Deny(2))
+
+	assert.NoError(t, code.Invalidate())
+	assert.Error(t, code.Invalidate())
+	AssertNotExistsBean(t, &OAuth2DeviceCode{ID: code.ID})
+}
+
+func TestDeleteExpiredOAuth2DeviceCodes(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
+
+	code, err := CreateOAuth2DeviceCode(app, "")
+	assert.NoError(t, err)
+	expired, err := CreateOAuth2DeviceCode(app, "")
+	assert.NoError(t, err)
+	_, err = x.ID(expired.ID).Cols("valid_until").Update(&OAuth2DeviceCode{ValidUntil: timeutil.TimeStampNow() - 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, DeleteExpiredOAuth2DeviceCodes(context.Background()))
+	AssertExistsAndLoadBean(t, &OAuth2DeviceCode{ID: code.ID})
+	AssertNotExistsBean(t, &OAuth2DeviceCode{ID: expired.ID})
+}
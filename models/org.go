@@ -296,6 +296,7 @@ func deleteOrg(e *xorm.Session, u *User) error {
 		&OrgUser{OrgID: u.ID},
 		&TeamUser{OrgID: u.ID},
 		&TeamUnit{OrgID: u.ID},
+		&AccessToken{OrgID: u.ID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
@@ -720,6 +720,11 @@ func DeleteTeam(t *Team) error {
 		return err
 	}
 
+	// Delete external id of team.
+	if _, err := sess.Delete(&SCIMExternalID{ResourceType: SCIMResourceTypeGroup, ResourceID: t.ID}); err != nil {
+		return err
+	}
+
 	// Delete team.
 	if _, err := sess.ID(t.ID).Delete(new(Team)); err != nil {
 		return err
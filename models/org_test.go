@@ -261,11 +261,14 @@ func TestCountOrganizations(t *testing.T) {
 
 func TestDeleteOrganization(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
+	token := &AccessToken{UID: 2, Name: "restricted to org", Scope: AccessTokenScopeAll, OrgID: 6}
+	assert.NoError(t, NewAccessToken(token))
 	org := AssertExistsAndLoadBean(t, &User{ID: 6}).(*User)
 	assert.NoError(t, DeleteOrganization(org))
 	AssertNotExistsBean(t, &User{ID: 6})
 	AssertNotExistsBean(t, &OrgUser{OrgID: 6})
 	AssertNotExistsBean(t, &Team{OrgID: 6})
+	AssertNotExistsBean(t, &AccessToken{ID: token.ID})
 
 	org = AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
 	err := DeleteOrganization(org)
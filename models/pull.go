@@ -62,6 +62,8 @@ type PullRequest struct {
 	ProtectedBranch *ProtectedBranch `xorm:"-"`
 	MergeBase       string           `xorm:"VARCHAR(40)"`
 
+	AllowMaintainerEdit bool `xorm:"NOT NULL DEFAULT false"`
+
 	HasMerged      bool               `xorm:"INDEX"`
 	MergedCommitID string             `xorm:"VARCHAR(40)"`
 	MergerID       int64              `xorm:"INDEX"`
@@ -592,6 +594,13 @@ func (pr *PullRequest) UpdateColsIfNotMerged(cols ...string) error {
 	return err
 }
 
+// SetAllowMaintainerEdit sets whether users who can write to the base repository
+// may push to the head branch of the pull request
+func (pr *PullRequest) SetAllowMaintainerEdit(allow bool) error {
+	pr.AllowMaintainerEdit = allow
+	return pr.UpdateCols("allow_maintainer_edit")
+}
+
 // IsWorkInProgress determine if the Pull Request is a Work In Progress by its title
 func (pr *PullRequest) IsWorkInProgress() bool {
 	if err := pr.LoadIssue(); err != nil {
@@ -0,0 +1,182 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// PullMergeQueueEntry represents a pull request waiting in the merge queue of a protected branch.
+// The speculative commit merges the pull request and all pull requests before it in the queue
+// into the base commit; the base branch is fast-forwarded to it once its status checks passed.
+type PullMergeQueueEntry struct {
+	ID                  int64        `xorm:"pk autoincr"`
+	RepoID              int64        `xorm:"INDEX(s) NOT NULL"`
+	BaseBranch          string       `xorm:"INDEX(s) NOT NULL"`
+	PullID              int64        `xorm:"UNIQUE NOT NULL"`
+	PullRequest         *PullRequest `xorm:"-"`
+	DoerID              int64        `xorm:"NOT NULL"`
+	Doer                *User        `xorm:"-"`
+	MergeStyle          MergeStyle   `xorm:"VARCHAR(50)"`
+	Message             string       `xorm:"TEXT"`
+	BaseCommitID        string       `xorm:"VARCHAR(40)"`
+	SpeculativeCommitID string       `xorm:"VARCHAR(40)"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+// LoadAttributes loads the pull request and the user who added it to the queue
+func (entry *PullMergeQueueEntry) LoadAttributes() (err error) {
+	if entry.PullRequest == nil {
+		if entry.PullRequest, err = getPullRequestByID(x, entry.PullID); err != nil {
+			return err
+		}
+	}
+	if entry.Doer == nil {
+		if entry.Doer, err = getUserByID(x, entry.DoerID); err != nil {
+			if !IsErrUserNotExist(err) {
+				return err
+			}
+			entry.Doer = NewGhostUser()
+		}
+	}
+	return nil
+}
+
+// IsTesting returns if a speculative commit of the pull request is being tested
+func (entry *PullMergeQueueEntry) IsTesting() bool {
+	return entry.SpeculativeCommitID != ""
+}
+
+// SetSpeculativeCommit stores the base commit and the speculative merge commit built on top of it,
+// the update time is the time its status checks started
+func (entry *PullMergeQueueEntry) SetSpeculativeCommit(baseCommitID, speculativeCommitID string) error {
+	entry.BaseCommitID = baseCommitID
+	entry.SpeculativeCommitID = speculativeCommitID
+	_, err := x.ID(entry.ID).Cols("base_commit_id", "speculative_commit_id").Update(entry)
+	return err
+}
+
+// GetMergeQueue returns the entries of the merge queue of a branch in queue order
+func GetMergeQueue(repoID int64, branch string) ([]*PullMergeQueueEntry, error) {
+	entries := make([]*PullMergeQueueEntry, 0, 10)
+	return entries, x.
+		Where(builder.Eq{"repo_id": repoID, "base_branch": branch}).
+		Asc("id").
+		Find(&entries)
+}
+
+// GetMergeQueueBranches returns the repositories and base branches of all non empty merge queues
+func GetMergeQueueBranches() ([]*PullMergeQueueEntry, error) {
+	branches := make([]*PullMergeQueueEntry, 0, 10)
+	return branches, x.Distinct("repo_id", "base_branch").Find(&branches)
+}
+
+// GetMergeQueueEntriesBySpeculativeCommitID returns the merge queue entries whose speculative merge is the commit
+func GetMergeQueueEntriesBySpeculativeCommitID(repoID int64, commitID string) ([]*PullMergeQueueEntry, error) {
+	entries := make([]*PullMergeQueueEntry, 0, 1)
+	return entries, x.Where(builder.Eq{"repo_id": repoID, "speculative_commit_id": commitID}).Find(&entries)
+}
+
+// GetMergeQueueEntryByPullID returns the merge queue entry of a pull request
+func GetMergeQueueEntryByPullID(pullID int64) (*PullMergeQueueEntry, error) {
+	return getMergeQueueEntryByPullID(x, pullID)
+}
+
+func getMergeQueueEntryByPullID(e Engine, pullID int64) (*PullMergeQueueEntry, error) {
+	entry := new(PullMergeQueueEntry)
+	has, err := e.Where("pull_id = ?", pullID).Get(entry)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrMergeQueueEntryNotExist{PullID: pullID}
+	}
+	return entry, nil
+}
+
+// AddToMergeQueue appends a pull request to the merge queue of its base branch
+func AddToMergeQueue(pr *PullRequest, doer *User, mergeStyle MergeStyle, message string) (*PullMergeQueueEntry, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	if _, err := getMergeQueueEntryByPullID(sess, pr.ID); err == nil {
+		return nil, ErrPullAlreadyInMergeQueue{PullID: pr.ID}
+	} else if !IsErrMergeQueueEntryNotExist(err) {
+		return nil, err
+	}
+
+	entry := &PullMergeQueueEntry{
+		RepoID:      pr.BaseRepoID,
+		BaseBranch:  pr.BaseBranch,
+		PullID:      pr.ID,
+		PullRequest: pr,
+		DoerID:      doer.ID,
+		Doer:        doer,
+		MergeStyle:  mergeStyle,
+		Message:     message,
+	}
+	if _, err := sess.Insert(entry); err != nil {
+		return nil, err
+	}
+	if err := createMergeQueueComment(sess, CommentTypeMergeQueueAdd, pr, doer, ""); err != nil {
+		return nil, err
+	}
+	return entry, sess.Commit()
+}
+
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch and
+// adds a timeline comment with the reason. Nothing is done if the pull request is not queued.
+func RemoveFromMergeQueue(pr *PullRequest, doer *User, reason string) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	deleted, err := sess.Where("pull_id = ?", pr.ID).Delete(new(PullMergeQueueEntry))
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return nil
+	}
+	if err := createMergeQueueComment(sess, CommentTypeMergeQueueRemove, pr, doer, reason); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// DeleteMergeQueueEntry deletes the merge queue entry of a pull request without a timeline comment
+func DeleteMergeQueueEntry(pullID int64) error {
+	_, err := x.Where("pull_id = ?", pullID).Delete(new(PullMergeQueueEntry))
+	return err
+}
+
+func createMergeQueueComment(e *xorm.Session, commentType CommentType, pr *PullRequest, doer *User, reason string) error {
+	if err := pr.loadIssue(e); err != nil {
+		return err
+	}
+	if err := pr.Issue.loadRepo(e); err != nil {
+		return err
+	}
+	if _, err := createComment(e, &CreateCommentOptions{
+		Type:    commentType,
+		Doer:    doer,
+		Repo:    pr.Issue.Repo,
+		Issue:   pr.Issue,
+		Content: reason,
+	}); err != nil {
+		return fmt.Errorf("createComment: %v", err)
+	}
+	return nil
+}
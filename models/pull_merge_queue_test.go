@@ -0,0 +1,49 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeQueue(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	pr1 := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	pr2 := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	_, err := AddToMergeQueue(pr1, doer, MergeStyleMerge, "merge pr1")
+	assert.NoError(t, err)
+	_, err = AddToMergeQueue(pr2, doer, MergeStyleSquash, "merge pr2")
+	assert.NoError(t, err)
+	_, err = AddToMergeQueue(pr1, doer, MergeStyleMerge, "merge pr1")
+	assert.True(t, IsErrPullAlreadyInMergeQueue(err))
+	AssertExistsAndLoadBean(t, &Comment{IssueID: pr1.IssueID, Type: CommentTypeMergeQueueAdd})
+
+	queue, err := GetMergeQueue(1, "master")
+	assert.NoError(t, err)
+	if assert.Len(t, queue, 2) {
+		assert.EqualValues(t, 1, queue[0].PullID)
+		assert.EqualValues(t, 2, queue[1].PullID)
+		assert.False(t, queue[0].IsTesting())
+	}
+
+	assert.NoError(t, queue[0].SetSpeculativeCommit("1111111111111111111111111111111111111111", "2222222222222222222222222222222222222222"))
+	entries, err := GetMergeQueueEntriesBySpeculativeCommitID(1, "2222222222222222222222222222222222222222")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.NoError(t, RemoveFromMergeQueue(pr1, doer, "removed"))
+	AssertExistsAndLoadBean(t, &Comment{IssueID: pr1.IssueID, Type: CommentTypeMergeQueueRemove, Content: "removed"})
+	_, err = GetMergeQueueEntryByPullID(pr1.ID)
+	assert.True(t, IsErrMergeQueueEntryNotExist(err))
+
+	assert.NoError(t, DeleteMergeQueueEntry(pr2.ID))
+	queue, err = GetMergeQueue(1, "master")
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// PullViewedFile represents a file of a pull request a user has marked as viewed.
+// The mark only applies to the version of the file with the blob SHA, which is
+// empty for deleted files.
+type PullViewedFile struct {
+	ID          int64              `xorm:"pk autoincr"`
+	UserID      int64              `xorm:"INDEX(s) NOT NULL"`
+	PullID      int64              `xorm:"INDEX(s) NOT NULL"`
+	TreePath    string             `xorm:"TEXT NOT NULL"`
+	BlobSHA     string             `xorm:"VARCHAR(40)"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+// GetPullViewedFiles returns the blob SHAs of the files of a pull request a user has marked as viewed by path
+func GetPullViewedFiles(userID, pullID int64) (map[string]string, error) {
+	files := make([]*PullViewedFile, 0, 10)
+	if err := x.Where(builder.Eq{"user_id": userID, "pull_id": pullID}).Find(&files); err != nil {
+		return nil, err
+	}
+	viewed := make(map[string]string, len(files))
+	for _, file := range files {
+		viewed[file.TreePath] = file.BlobSHA
+	}
+	return viewed, nil
+}
+
+// SetPullFilesViewed marks files of a pull request as viewed, the files are given
+// by path with the blob SHAs of the viewed versions.
+func SetPullFilesViewed(userID, pullID int64, files map[string]string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	treePaths := make([]string, 0, len(files))
+	viewed := make([]*PullViewedFile, 0, len(files))
+	for treePath, blobSHA := range files {
+		treePaths = append(treePaths, treePath)
+		viewed = append(viewed, &PullViewedFile{
+			UserID:   userID,
+			PullID:   pullID,
+			TreePath: treePath,
+			BlobSHA:  blobSHA,
+		})
+	}
+	if err := deletePullViewedFiles(sess, userID, pullID, treePaths); err != nil {
+		return err
+	}
+	if _, err := sess.Insert(&viewed); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
+
+// UnsetPullFilesViewed removes the viewed marks of files of a pull request
+func UnsetPullFilesViewed(userID, pullID int64, treePaths []string) error {
+	if len(treePaths) == 0 {
+		return nil
+	}
+	return deletePullViewedFiles(x, userID, pullID, treePaths)
+}
+
+func deletePullViewedFiles(e Engine, userID, pullID int64, treePaths []string) error {
+	_, err := e.Where(builder.Eq{"user_id": userID, "pull_id": pullID}).
+		And(builder.In("tree_path", treePaths)).
+		Delete(new(PullViewedFile))
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullViewedFiles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	assert.NoError(t, SetPullFilesViewed(2, 1, map[string]string{
+		"README.md": "4b4851ad51df6a7d9f25c979345979eaeb5b349f",
+		"deleted":   "",
+	}))
+	viewed, err := GetPullViewedFiles(2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"README.md": "4b4851ad51df6a7d9f25c979345979eaeb5b349f",
+		"deleted":   "",
+	}, viewed)
+
+	// marking a file again replaces the viewed version
+	assert.NoError(t, SetPullFilesViewed(2, 1, map[string]string{"README.md": "ad1d9f8ac1b4d3fd6f5ccf4d1ca5cbca75d2ac5e"}))
+	assert.NoError(t, UnsetPullFilesViewed(2, 1, []string{"deleted"}))
+	viewed, err = GetPullViewedFiles(2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"README.md": "ad1d9f8ac1b4d3fd6f5ccf4d1ca5cbca75d2ac5e"}, viewed)
+
+	// the files of other users are not affected
+	viewed, err = GetPullViewedFiles(1, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, viewed)
+}
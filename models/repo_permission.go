@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"code.gitea.io/gitea/modules/log"
+	api "code.gitea.io/gitea/modules/structs"
 )
 
 // Permission contains all the permissions related variables to a repository for a user
@@ -201,6 +202,24 @@ func getUserRepoPermission(e Engine, repo *Repository, user *User) (perm Permiss
 		return
 	}
 
+	// Organizations requiring two-factor authentication treat users who have not enrolled it
+	// like strangers until they do
+	if repo.Owner.IsOrganization() && repo.Owner.RequireTwoFactor {
+		var hasTwoFactor bool
+		if hasTwoFactor, err = hasTwoFactorByUID(e, user.ID); err != nil {
+			return
+		}
+		if !hasTwoFactor {
+			if repo.IsPrivate || user.IsRestricted || repo.Owner.Visibility == api.VisibleTypePrivate {
+				perm.AccessMode = AccessModeNone
+				perm.Units = nil
+			} else {
+				perm.AccessMode = AccessModeRead
+			}
+			return
+		}
+	}
+
 	// plain user
 	perm.AccessMode, err = accessLevel(e, user, repo)
 	if err != nil {
@@ -255,3 +255,42 @@ func TestRepoPermissionPrivateOrgRepo(t *testing.T) {
 		assert.True(t, perm.CanWrite(unit.Type))
 	}
 }
+
+func TestRepoPermissionOrgRequireTwoFactor(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	org := AssertExistsAndLoadBean(t, &User{ID: 17}).(*User)
+	org.RequireTwoFactor = true
+	assert.NoError(t, UpdateUserCols(org, "require_two_factor"))
+
+	// private organization repo
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 24}).(*Repository)
+	assert.NoError(t, repo.getUnits(x))
+
+	// org member team owner without two-factor authentication
+	owner := AssertExistsAndLoadBean(t, &User{ID: 15}).(*User)
+	perm, err := GetUserRepoPermission(repo, owner)
+	assert.NoError(t, err)
+	for _, unit := range repo.Units {
+		assert.False(t, perm.CanRead(unit.Type))
+		assert.False(t, perm.CanWrite(unit.Type))
+	}
+
+	_, err = x.Insert(&TwoFactor{UID: owner.ID})
+	assert.NoError(t, err)
+	perm, err = GetUserRepoPermission(repo, owner)
+	assert.NoError(t, err)
+	for _, unit := range repo.Units {
+		assert.True(t, perm.CanRead(unit.Type))
+		assert.True(t, perm.CanWrite(unit.Type))
+	}
+
+	// admin is not affected
+	admin := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	perm, err = GetUserRepoPermission(repo, admin)
+	assert.NoError(t, err)
+	for _, unit := range repo.Units {
+		assert.True(t, perm.CanRead(unit.Type))
+		assert.True(t, perm.CanWrite(unit.Type))
+	}
+}
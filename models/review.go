@@ -523,6 +523,23 @@ func getReviewByIssueIDAndUserID(e Engine, issueID, userID int64) (*Review, erro
 	return review, nil
 }
 
+// GetLatestSubmittedReview returns the latest submitted review of a reviewer on a pull request which knows the reviewed commit
+func GetLatestSubmittedReview(issueID, reviewerID int64) (*Review, error) {
+	review := new(Review)
+	has, err := x.Where(builder.Eq{"issue_id": issueID, "reviewer_id": reviewerID, "original_author_id": 0}).
+		And(builder.In("type", ReviewTypeApprove, ReviewTypeComment, ReviewTypeReject)).
+		And(builder.Neq{"commit_id": ""}).
+		Desc("id").
+		Get(review)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrReviewNotExist{}
+	}
+	return review, nil
+}
+
 // GetTeamReviewerByIssueIDAndTeamID get the latest review requst of reviewer team for a pull request
 func GetTeamReviewerByIssueIDAndTeamID(issueID, teamID int64) (review *Review, err error) {
 	return getTeamReviewerByIssueIDAndTeamID(x, issueID, teamID)
@@ -86,6 +86,19 @@ func TestGetCurrentReview(t *testing.T) {
 	assert.Nil(t, review2)
 }
 
+func TestGetLatestSubmittedReview(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	review, err := GetLatestSubmittedReview(3, 4)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 8, review.ID)
+	assert.Equal(t, "8091a55037cd59e47293aca02981b5a67076b364", review.CommitID)
+
+	// the review has no commit
+	_, err = GetLatestSubmittedReview(2, 1)
+	assert.True(t, IsErrReviewNotExist(err))
+}
+
 func TestCreateReview(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
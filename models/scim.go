@@ -0,0 +1,126 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+)
+
+// SCIMResourceType represents the type of a resource provisioned through SCIM
+type SCIMResourceType string
+
+// Enumerate all the SCIM resource types
+const (
+	SCIMResourceTypeUser  SCIMResourceType = "User"
+	SCIMResourceTypeGroup SCIMResourceType = "Group"
+)
+
+// SCIMExternalID is the identifier a SCIM client assigned to a user or a team
+type SCIMExternalID struct {
+	ID           int64            `xorm:"pk autoincr"`
+	ResourceType SCIMResourceType `xorm:"VARCHAR(10) UNIQUE(s) UNIQUE(e) NOT NULL"`
+	ResourceID   int64            `xorm:"UNIQUE(s) NOT NULL"`
+	ExternalID   string           `xorm:"VARCHAR(255) UNIQUE(e) NOT NULL"`
+}
+
+// ErrSCIMExternalIDAlreadyUsed represents an external id assigned to another resource
+type ErrSCIMExternalIDAlreadyUsed struct {
+	ResourceType SCIMResourceType
+	ExternalID   string
+}
+
+// IsErrSCIMExternalIDAlreadyUsed checks if an error is a ErrSCIMExternalIDAlreadyUsed.
+func IsErrSCIMExternalIDAlreadyUsed(err error) bool {
+	_, ok := err.(ErrSCIMExternalIDAlreadyUsed)
+	return ok
+}
+
+func (err ErrSCIMExternalIDAlreadyUsed) Error() string {
+	return fmt.Sprintf("external id is already used [type: %s, external_id: %s]", err.ResourceType, err.ExternalID)
+}
+
+// GetSCIMExternalID returns the external id of a resource, or an empty string if it has none
+func GetSCIMExternalID(resourceType SCIMResourceType, resourceID int64) (string, error) {
+	extID := &SCIMExternalID{ResourceType: resourceType, ResourceID: resourceID}
+	if _, err := x.Get(extID); err != nil {
+		return "", err
+	}
+	return extID.ExternalID, nil
+}
+
+// GetSCIMResourceIDByExternalID returns the id of the resource with the given external id, or 0 if there is none
+func GetSCIMResourceIDByExternalID(resourceType SCIMResourceType, externalID string) (int64, error) {
+	extID := &SCIMExternalID{ResourceType: resourceType, ExternalID: externalID}
+	if _, err := x.Get(extID); err != nil {
+		return 0, err
+	}
+	return extID.ResourceID, nil
+}
+
+// SetSCIMExternalID sets the external id of a resource, an empty external id removes it
+func SetSCIMExternalID(resourceType SCIMResourceType, resourceID int64, externalID string) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	if externalID != "" {
+		other := &SCIMExternalID{ResourceType: resourceType, ExternalID: externalID}
+		has, err := sess.Get(other)
+		if err != nil {
+			return err
+		}
+		if has && other.ResourceID != resourceID {
+			return ErrSCIMExternalIDAlreadyUsed{ResourceType: resourceType, ExternalID: externalID}
+		}
+	}
+
+	if _, err := sess.Delete(&SCIMExternalID{ResourceType: resourceType, ResourceID: resourceID}); err != nil {
+		return err
+	}
+	if externalID != "" {
+		if _, err := sess.Insert(&SCIMExternalID{
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			ExternalID:   externalID,
+		}); err != nil {
+			return err
+		}
+	}
+	return sess.Commit()
+}
+
+// ListSCIMUsers returns a page of the individual users ordered by id and their total number.
+// The start is the zero-based offset of the page.
+func ListSCIMUsers(start, count int) ([]*User, int64, error) {
+	total, err := x.Where("type = ?", UserTypeIndividual).Count(new(User))
+	if err != nil {
+		return nil, 0, err
+	}
+	users := make([]*User, 0, count)
+	if count > 0 {
+		if err := x.Where("type = ?", UserTypeIndividual).Asc("id").Limit(count, start).Find(&users); err != nil {
+			return nil, 0, err
+		}
+	}
+	return users, total, nil
+}
+
+// ListSCIMTeams returns a page of the teams of all organizations ordered by id and their total number.
+// The start is the zero-based offset of the page.
+func ListSCIMTeams(start, count int) ([]*Team, int64, error) {
+	total, err := x.Count(new(Team))
+	if err != nil {
+		return nil, 0, err
+	}
+	teams := make([]*Team, 0, count)
+	if count > 0 {
+		if err := x.Asc("id").Limit(count, start).Find(&teams); err != nil {
+			return nil, 0, err
+		}
+	}
+	return teams, total, nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// StorageBlob represents a deduplicated content within a storage
+type StorageBlob struct {
+	ID          int64              `xorm:"pk autoincr"`
+	Storage     string             `xorm:"UNIQUE(s) NOT NULL"`
+	Hash        string             `xorm:"UNIQUE(s) NOT NULL"`
+	Size        int64              `xorm:"NOT NULL DEFAULT 0"`
+	RefCount    int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+}
+
+// StorageBlobRef represents a path of a storage referring to a deduplicated content
+type StorageBlobRef struct {
+	ID          int64              `xorm:"pk autoincr"`
+	Storage     string             `xorm:"UNIQUE(s) NOT NULL"`
+	Path        string             `xorm:"UNIQUE(s) NOT NULL"`
+	Hash        string             `xorm:"INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+// ErrStorageBlobNotExist represents a "StorageBlobNotExist" kind of error.
+type ErrStorageBlobNotExist struct {
+	Storage string
+	Hash    string
+}
+
+// IsErrStorageBlobNotExist checks if an error is a ErrStorageBlobNotExist.
+func IsErrStorageBlobNotExist(err error) bool {
+	_, ok := err.(ErrStorageBlobNotExist)
+	return ok
+}
+
+func (err ErrStorageBlobNotExist) Error() string {
+	return fmt.Sprintf("storage blob does not exist [storage: %s, hash: %s]", err.Storage, err.Hash)
+}
+
+func init() {
+	storage.RegisterDedupRefStore(storageBlobRefStore{})
+}
+
+// GetStorageBlob returns the deduplicated content of the storage with the provided hash
+func GetStorageBlob(storageName, hash string) (*StorageBlob, error) {
+	blob := &StorageBlob{}
+	has, err := x.Where("storage = ? AND hash = ?", storageName, hash).Get(blob)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrStorageBlobNotExist{storageName, hash}
+	}
+	return blob, nil
+}
+
+// CountStorageBlobRefs returns the number of references and the number of distinct contents of a storage
+func CountStorageBlobRefs(storageName string) (refs, blobs int64, err error) {
+	if refs, err = x.Where("storage = ?", storageName).Count(new(StorageBlobRef)); err != nil {
+		return 0, 0, err
+	}
+	blobs, err = x.Where("storage = ?", storageName).Count(new(StorageBlob))
+	return refs, blobs, err
+}
+
+// IterateStorageBlobs iterates the deduplicated contents of a storage
+func IterateStorageBlobs(storageName string, f func(blob *StorageBlob) error) error {
+	var start int
+	const batchSize = 100
+	for {
+		var blobs = make([]*StorageBlob, 0, batchSize)
+		if err := x.Where("storage = ?", storageName).OrderBy("id").Limit(batchSize, start).Find(&blobs); err != nil {
+			return err
+		}
+		if len(blobs) == 0 {
+			return nil
+		}
+		start += len(blobs)
+
+		for _, blob := range blobs {
+			if err := f(blob); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func getStorageBlobRef(e Engine, storageName, path string) (*StorageBlobRef, error) {
+	ref := &StorageBlobRef{}
+	has, err := e.Where("storage = ? AND path = ?", storageName, path).Get(ref)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, storage.ErrDedupRefNotExist
+	}
+	return ref, nil
+}
+
+// increaseStorageBlobRefCount increases the reference count of the content and
+// returns true if the content has been referenced for the first time
+func increaseStorageBlobRefCount(e Engine, storageName, hash string, size int64) (bool, error) {
+	res, err := e.Exec("UPDATE `storage_blob` SET ref_count = ref_count + 1 WHERE storage = ? AND hash = ?", storageName, hash)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else if n > 0 {
+		return false, nil
+	}
+
+	_, err = e.Insert(&StorageBlob{
+		Storage:  storageName,
+		Hash:     hash,
+		Size:     size,
+		RefCount: 1,
+	})
+	return true, err
+}
+
+// decreaseStorageBlobRefCount decreases the reference count of the content and
+// removes it if it is no longer referenced. The content has to be released by the
+// caller once the transaction has been committed, if true is returned.
+func decreaseStorageBlobRefCount(e Engine, storageName, hash string) (bool, error) {
+	if _, err := e.Exec("UPDATE `storage_blob` SET ref_count = ref_count - 1 WHERE storage = ? AND hash = ?", storageName, hash); err != nil {
+		return false, err
+	}
+
+	blob := &StorageBlob{}
+	has, err := e.Where("storage = ? AND hash = ?", storageName, hash).Get(blob)
+	if err != nil {
+		return false, err
+	} else if has && blob.RefCount > 0 {
+		return false, nil
+	}
+
+	if _, err := e.Where("storage = ? AND hash = ?", storageName, hash).Delete(new(StorageBlob)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// storageBlobRefStore implements storage.DedupRefStore on top of the database
+type storageBlobRefStore struct{}
+
+// GetDedupRef returns the content hash the path refers to
+func (storageBlobRefStore) GetDedupRef(storageName, path string) (string, error) {
+	ref, err := getStorageBlobRef(x, storageName, path)
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash, nil
+}
+
+// AddDedupRef lets path refer to the content hash
+func (storageBlobRefStore) AddDedupRef(storageName, path, hash string, size int64, release func(hash string) error) (bool, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return false, err
+	}
+
+	var oldHash string
+	var releaseOld bool
+	ref, err := getStorageBlobRef(sess, storageName, path)
+	if err == storage.ErrDedupRefNotExist {
+		ref = &StorageBlobRef{
+			Storage: storageName,
+			Path:    path,
+			Hash:    hash,
+		}
+		if _, err := sess.Insert(ref); err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	} else if ref.Hash == hash {
+		return false, sess.Commit()
+	} else {
+		oldHash = ref.Hash
+		ref.Hash = hash
+		if _, err := sess.ID(ref.ID).Cols("hash").Update(ref); err != nil {
+			return false, err
+		}
+		if releaseOld, err = decreaseStorageBlobRefCount(sess, storageName, oldHash); err != nil {
+			return false, err
+		}
+	}
+
+	created, err := increaseStorageBlobRefCount(sess, storageName, hash, size)
+	if err != nil {
+		return false, err
+	}
+	if err := sess.Commit(); err != nil {
+		return false, err
+	}
+
+	// the content is only released once no committed reference can point to it
+	if releaseOld {
+		return created, release(oldHash)
+	}
+	return created, nil
+}
+
+// RemoveDedupRef removes the reference of path
+func (storageBlobRefStore) RemoveDedupRef(storageName, path string, release func(hash string) error) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	ref, err := getStorageBlobRef(sess, storageName, path)
+	if err != nil {
+		return err
+	}
+	if _, err := sess.ID(ref.ID).Delete(new(StorageBlobRef)); err != nil {
+		return err
+	}
+	released, err := decreaseStorageBlobRefCount(sess, storageName, ref.Hash)
+	if err != nil {
+		return err
+	}
+	if err := sess.Commit(); err != nil {
+		return err
+	}
+
+	// the content is only released once no committed reference can point to it
+	if released {
+		return release(ref.Hash)
+	}
+	return nil
+}
+
+// IsDedupContentReferenced returns true if any path of the storage refers to the content hash
+func (storageBlobRefStore) IsDedupContentReferenced(storageName, hash string) (bool, error) {
+	return x.Where("storage = ? AND hash = ? AND ref_count > 0", storageName, hash).Exist(new(StorageBlob))
+}
+
+// IterateDedupRefs iterates over all references of the storage
+func (storageBlobRefStore) IterateDedupRefs(storageName string, fn func(path, hash string) error) error {
+	var start int
+	const batchSize = 100
+	for {
+		var refs = make([]*StorageBlobRef, 0, batchSize)
+		if err := x.Where("storage = ?", storageName).OrderBy("id").Limit(batchSize, start).Find(&refs); err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+		start += len(refs)
+
+		for _, ref := range refs {
+			if err := fn(ref.Path, ref.Hash); err != nil {
+				return err
+			}
+		}
+	}
+}
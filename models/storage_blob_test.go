@@ -0,0 +1,219 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupStorage(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	dir, err := ioutil.TempDir("", "dedup-storage")
+	assert.NoError(t, err)
+	defer util.RemoveAll(dir)
+
+	local, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: dir})
+	assert.NoError(t, err)
+	dedup, err := storage.NewDedupStorage("test", local)
+	assert.NoError(t, err)
+
+	const content = "deduplicated content"
+	const hash = "1c415cdbe305f131819a4df258abac612981f83c0322abc721bbf78589f0dc96"
+	_, err = dedup.Save("a/first", strings.NewReader(content))
+	assert.NoError(t, err)
+	_, err = dedup.Save("b/second", strings.NewReader(content))
+	assert.NoError(t, err)
+
+	blob, err := GetStorageBlob("test", hash)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, blob.RefCount)
+	assert.EqualValues(t, len(content), blob.Size)
+
+	_, err = local.Stat("a/first")
+	assert.True(t, os.IsNotExist(err))
+	_, err = local.Stat(storage.DedupContentPath(hash))
+	assert.NoError(t, err)
+
+	obj, err := dedup.Open("b/second")
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(obj)
+	obj.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	info, err := dedup.Stat("a/first")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", info.Name())
+	assert.EqualValues(t, len(content), info.Size())
+
+	assert.NoError(t, dedup.Delete("a/first"))
+	blob, err = GetStorageBlob("test", hash)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, blob.RefCount)
+
+	// Overwriting the last reference releases the old content
+	_, err = dedup.Save("b/second", strings.NewReader("other content"))
+	assert.NoError(t, err)
+	_, err = GetStorageBlob("test", hash)
+	assert.True(t, IsErrStorageBlobNotExist(err))
+	_, err = local.Stat(storage.DedupContentPath(hash))
+	assert.True(t, os.IsNotExist(err))
+
+	refs, blobs, err := CountStorageBlobRefs("test")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, refs)
+	assert.EqualValues(t, 1, blobs)
+}
+
+func TestStorageBlobRefStore_ReleaseAfterCommit(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	store := storageBlobRefStore{}
+	var released []string
+	release := func(hash string) error {
+		// the content must only be released once the blob is gone for everyone
+		_, err := GetStorageBlob("test", hash)
+		assert.True(t, IsErrStorageBlobNotExist(err))
+		released = append(released, hash)
+		return nil
+	}
+
+	_, err := store.AddDedupRef("test", "path", "first", 1, release)
+	assert.NoError(t, err)
+	_, err = store.AddDedupRef("test", "path", "second", 1, release)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first"}, released)
+
+	assert.NoError(t, store.RemoveDedupRef("test", "path", release))
+	assert.Equal(t, []string{"first", "second"}, released)
+}
+
+// racingDedupRefStore references the content again between removing its last reference and releasing it
+type racingDedupRefStore struct {
+	storageBlobRefStore
+}
+
+func (s racingDedupRefStore) RemoveDedupRef(storageName, path string, release func(hash string) error) error {
+	return s.storageBlobRefStore.RemoveDedupRef(storageName, path, func(hash string) error {
+		if _, err := s.AddDedupRef(storageName, path+"-again", hash, 0, release); err != nil {
+			return err
+		}
+		return release(hash)
+	})
+}
+
+func TestDedupStorage_ReleaseReferencedAgain(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	storage.RegisterDedupRefStore(racingDedupRefStore{})
+	defer storage.RegisterDedupRefStore(storageBlobRefStore{})
+
+	dir, err := ioutil.TempDir("", "dedup-storage")
+	assert.NoError(t, err)
+	defer util.RemoveAll(dir)
+
+	local, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: dir})
+	assert.NoError(t, err)
+	dedup, err := storage.NewDedupStorage("test-race", local)
+	assert.NoError(t, err)
+
+	_, err = dedup.Save("path", strings.NewReader("content"))
+	assert.NoError(t, err)
+	assert.NoError(t, dedup.Delete("path"))
+
+	// the content is kept for the reference added before it was released
+	obj, err := dedup.Open("path-again")
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(obj)
+	obj.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestDedupStorage_Deduplicate(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	dir, err := ioutil.TempDir("", "dedup-storage")
+	assert.NoError(t, err)
+	defer util.RemoveAll(dir)
+
+	local, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: dir})
+	assert.NoError(t, err)
+	_, err = local.Save("legacy", strings.NewReader("legacy content"))
+	assert.NoError(t, err)
+
+	dedup, err := storage.NewDedupStorage("test-deduplicate", local)
+	assert.NoError(t, err)
+
+	// Objects stored before deduplication are still served
+	_, err = dedup.Stat("legacy")
+	assert.NoError(t, err)
+
+	done, err := dedup.Deduplicate("legacy")
+	assert.NoError(t, err)
+	assert.True(t, done)
+	_, err = local.Stat("legacy")
+	assert.True(t, os.IsNotExist(err))
+
+	done, err = dedup.Deduplicate("legacy")
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	var paths []string
+	assert.NoError(t, dedup.IterateObjects(func(path string, obj storage.Object) error {
+		if !strings.HasPrefix(path, "tmp") {
+			paths = append(paths, path)
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"legacy"}, paths)
+
+	assert.NoError(t, dedup.Delete("legacy"))
+	refs, blobs, err := CountStorageBlobRefs("test-deduplicate")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, refs)
+	assert.EqualValues(t, 0, blobs)
+}
+
+func TestDedupStorage_IterateObjectsMissingContent(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	dir, err := ioutil.TempDir("", "dedup-storage")
+	assert.NoError(t, err)
+	defer util.RemoveAll(dir)
+
+	local, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: dir})
+	assert.NoError(t, err)
+	dedup, err := storage.NewDedupStorage("test-missing", local)
+	assert.NoError(t, err)
+
+	_, err = dedup.Save("broken", strings.NewReader("missing content"))
+	assert.NoError(t, err)
+	_, err = dedup.Save("intact", strings.NewReader("intact content"))
+	assert.NoError(t, err)
+	hash, err := storageBlobRefStore{}.GetDedupRef("test-missing", "broken")
+	assert.NoError(t, err)
+	assert.NoError(t, local.Delete(storage.DedupContentPath(hash)))
+
+	// a missing content does not stop the iteration
+	var paths []string
+	assert.NoError(t, dedup.IterateObjects(func(path string, obj storage.Object) error {
+		if !strings.HasPrefix(path, "tmp") {
+			paths = append(paths, path)
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"intact"}, paths)
+}
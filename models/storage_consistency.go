@@ -0,0 +1,312 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/storage"
+)
+
+// OrphanedStorageObject represents an object of a storage which is not referenced by any database row
+type OrphanedStorageObject struct {
+	Path    string
+	Size    int64
+	storage storage.ObjectStorage
+}
+
+// Delete removes the orphaned object from its storage
+func (o *OrphanedStorageObject) Delete() error {
+	return o.storage.Delete(o.Path)
+}
+
+// MissingStorageObject represents a database row whose object is missing from its storage
+type MissingStorageObject struct {
+	Description string
+	Path        string
+	fix         func() error
+}
+
+// CanFix returns true if the database row can be fixed without losing data. Rows whose
+// objects are their content, like attachments and LFS objects, are only reported, the
+// object has to be restored from a backup or the row deleted by hand.
+func (m *MissingStorageObject) CanFix() bool {
+	return m.fix != nil
+}
+
+// Fix resets the reference of the database row to the missing object
+func (m *MissingStorageObject) Fix() error {
+	if m.fix == nil {
+		return fmt.Errorf("%s can not be fixed without deleting it", m.Description)
+	}
+	return m.fix()
+}
+
+type storageConsistencyCheck struct {
+	storage func() storage.ObjectStorage
+	// isReferenced returns whether a path of the storage is referenced by the database,
+	// known is false if the path does not look like an object of the storage
+	isReferenced func(p string) (known, referenced bool, err error)
+	// iterateReferences iterates over the paths referenced by the database,
+	// fix is nil if the row can not be fixed without deleting it
+	iterateReferences func(fn func(p, description string, fix func() error) error) error
+}
+
+var storageConsistencyChecks = map[string]*storageConsistencyCheck{
+	"attachments": {
+		storage: func() storage.ObjectStorage { return storage.Attachments },
+		isReferenced: func(p string) (bool, bool, error) {
+			parts := strings.Split(p, "/")
+			if len(parts) != 3 || len(parts[2]) < 2 || parts[0] != parts[2][0:1] || parts[1] != parts[2][1:2] {
+				return false, false, nil
+			}
+			has, err := x.Where("uuid = ?", parts[2]).Exist(new(Attachment))
+			return true, has, err
+		},
+		iterateReferences: func(fn func(p, description string, fix func() error) error) error {
+			return IterateAttachment(func(attach *Attachment) error {
+				return fn(attach.RelativePath(), fmt.Sprintf("attachment %d %q", attach.ID, attach.Name), nil)
+			})
+		},
+	},
+	"lfs": {
+		storage: func() storage.ObjectStorage { return storage.LFS },
+		isReferenced: func(p string) (bool, bool, error) {
+			parts := strings.Split(p, "/")
+			if len(parts) != 3 || len(parts[0]) != 2 || len(parts[1]) != 2 || !isHexString(parts[0]+parts[1]+parts[2]) {
+				return false, false, nil
+			}
+			has, err := x.Exist(&LFSMetaObject{Oid: parts[0] + parts[1] + parts[2]})
+			return true, has, err
+		},
+		iterateReferences: func(fn func(p, description string, fix func() error) error) error {
+			return IterateLFS(func(mo *LFSMetaObject) error {
+				return fn(mo.RelativePath(), fmt.Sprintf("LFS object %s of repository %d", mo.Oid, mo.RepositoryID), nil)
+			})
+		},
+	},
+	"avatars": {
+		storage: func() storage.ObjectStorage { return storage.Avatars },
+		isReferenced: func(p string) (bool, bool, error) {
+			if strings.Contains(p, "/") {
+				return false, false, nil
+			}
+			has, err := x.Where("avatar = ?", p).Exist(new(User))
+			return true, has, err
+		},
+		iterateReferences: func(fn func(p, description string, fix func() error) error) error {
+			return IterateUser(func(u *User) error {
+				if len(u.Avatar) == 0 {
+					return nil
+				}
+				return fn(u.CustomAvatarRelativePath(), fmt.Sprintf("avatar of user %s", u.Name), func() error {
+					u.UseCustomAvatar = false
+					u.Avatar = ""
+					_, err := x.ID(u.ID).Cols("avatar, use_custom_avatar").Update(u)
+					return err
+				})
+			})
+		},
+	},
+	"repo-avatars": {
+		storage: func() storage.ObjectStorage { return storage.RepoAvatars },
+		isReferenced: func(p string) (bool, bool, error) {
+			if strings.Contains(p, "/") {
+				return false, false, nil
+			}
+			has, err := x.Where("avatar = ?", p).Exist(new(Repository))
+			return true, has, err
+		},
+		iterateReferences: func(fn func(p, description string, fix func() error) error) error {
+			return IterateRepository(func(repo *Repository) error {
+				if len(repo.Avatar) == 0 {
+					return nil
+				}
+				return fn(repo.CustomAvatarRelativePath(), fmt.Sprintf("avatar of repository %s", repo.FullName()), func() error {
+					repo.Avatar = ""
+					_, err := x.ID(repo.ID).Cols("avatar").Update(repo)
+					return err
+				})
+			})
+		},
+	},
+}
+
+// StorageConsistencyNames returns the names of the storages which can be checked for consistency
+func StorageConsistencyNames() []string {
+	return []string{"attachments", "lfs", "avatars", "repo-avatars"}
+}
+
+func getStorageConsistencyCheck(name string) (*storageConsistencyCheck, error) {
+	check, ok := storageConsistencyChecks[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage: %s", name)
+	}
+	return check, nil
+}
+
+func isHexString(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// FindOrphanedStorageObjects returns the objects of the named storage which are not referenced
+// by any database row. Objects modified within olderThan are skipped as they may be in the
+// process of being uploaded.
+func FindOrphanedStorageObjects(ctx context.Context, name string, olderThan time.Duration) ([]*OrphanedStorageObject, error) {
+	check, err := getStorageConsistencyCheck(name)
+	if err != nil {
+		return nil, err
+	}
+	objStorage := check.storage()
+	threshold := time.Now().Add(-olderThan)
+
+	orphans := make([]*OrphanedStorageObject, 0, 10)
+	if err := objStorage.IterateObjects(func(p string, obj storage.Object) error {
+		select {
+		case <-ctx.Done():
+			return ErrCancelledf("before checking %s in %s storage", p, name)
+		default:
+		}
+
+		p = filepath.ToSlash(p)
+		known, referenced, err := check.isReferenced(p)
+		if err != nil || !known || referenced {
+			return err
+		}
+
+		info, err := obj.Stat()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(threshold) {
+			return nil
+		}
+		orphans = append(orphans, &OrphanedStorageObject{
+			Path:    p,
+			Size:    info.Size(),
+			storage: objStorage,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	dedupStorage, ok := objStorage.(*storage.DedupStorage)
+	if !ok {
+		return orphans, nil
+	}
+
+	// Deduplicated contents are orphaned if they have no reference counted blob
+	if err := dedupStorage.Unwrap().IterateObjects(func(p string, obj storage.Object) error {
+		if !storage.IsDedupContentPath(p) {
+			return nil
+		}
+		p = filepath.ToSlash(p)
+		if _, err := GetStorageBlob(dedupStorage.Name(), path.Base(p)); err == nil {
+			return nil
+		} else if !IsErrStorageBlobNotExist(err) {
+			return err
+		}
+
+		info, err := obj.Stat()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(threshold) {
+			return nil
+		}
+		orphans = append(orphans, &OrphanedStorageObject{
+			Path:    p,
+			Size:    info.Size(),
+			storage: dedupStorage.Unwrap(),
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// FindMissingStorageObjects returns the database rows whose objects are missing from the named storage
+func FindMissingStorageObjects(ctx context.Context, name string) ([]*MissingStorageObject, error) {
+	check, err := getStorageConsistencyCheck(name)
+	if err != nil {
+		return nil, err
+	}
+	objStorage := check.storage()
+
+	missing := make([]*MissingStorageObject, 0, 10)
+	if err := check.iterateReferences(func(p, description string, fix func() error) error {
+		select {
+		case <-ctx.Done():
+			return ErrCancelledf("before checking %s in %s storage", description, name)
+		default:
+		}
+
+		if _, err := objStorage.Stat(p); err == nil {
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, &MissingStorageObject{
+			Description: description,
+			Path:        p,
+			fix:         fix,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return missing, nil
+}
+
+// GarbageCollectStorages deletes the orphaned objects of all storages which are older than olderThan
+// and optionally fixes the database rows whose objects are missing if possible without losing data
+func GarbageCollectStorages(ctx context.Context, olderThan time.Duration, fixMissing bool) error {
+	for _, name := range StorageConsistencyNames() {
+		orphans, err := FindOrphanedStorageObjects(ctx, name, olderThan)
+		if err != nil {
+			return fmt.Errorf("FindOrphanedStorageObjects[%s]: %v", name, err)
+		}
+		var size int64
+		for _, orphan := range orphans {
+			if err := orphan.Delete(); err != nil {
+				return fmt.Errorf("Delete orphaned %s in %s storage: %v", orphan.Path, name, err)
+			}
+			size += orphan.Size
+		}
+		if len(orphans) > 0 {
+			log.Info("Deleted %d orphaned objects (%d bytes) from %s storage", len(orphans), size, name)
+		}
+
+		missing, err := FindMissingStorageObjects(ctx, name)
+		if err != nil {
+			return fmt.Errorf("FindMissingStorageObjects[%s]: %v", name, err)
+		}
+		for _, m := range missing {
+			if !fixMissing || !m.CanFix() {
+				log.Warn("Object %s of %s is missing from %s storage", m.Path, m.Description, name)
+				continue
+			}
+			if err := m.Fix(); err != nil {
+				return fmt.Errorf("Fix %s with missing object in %s storage: %v", m.Description, name, err)
+			}
+			log.Info("Fixed %s whose object %s was missing from %s storage", m.Description, m.Path, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/storage"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useEmptyTestStorage replaces the storage with an empty one until the returned function is called
+func useEmptyTestStorage(t *testing.T, objStorage *storage.ObjectStorage) func() {
+	dir, err := ioutil.TempDir("", "storage-consistency")
+	assert.NoError(t, err)
+	local, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: dir})
+	assert.NoError(t, err)
+
+	old := *objStorage
+	*objStorage = local
+	return func() {
+		*objStorage = old
+		_ = util.RemoveAll(dir)
+	}
+}
+
+func TestFindOrphanedStorageObjects(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer useEmptyTestStorage(t, &storage.Attachments)()
+
+	const orphanPath = "f/0/f0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	referencedPath := AttachmentRelativePath("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+	_, err := storage.Attachments.Save(orphanPath, strings.NewReader("orphan"))
+	assert.NoError(t, err)
+	_, err = storage.Attachments.Save(referencedPath, strings.NewReader("referenced"))
+	assert.NoError(t, err)
+	_, err = storage.Attachments.Save("unknown/layout", strings.NewReader("unknown"))
+	assert.NoError(t, err)
+
+	orphans, err := FindOrphanedStorageObjects(context.Background(), "attachments", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, orphans, 1) {
+		assert.Equal(t, orphanPath, orphans[0].Path)
+		assert.EqualValues(t, 6, orphans[0].Size)
+		assert.NoError(t, orphans[0].Delete())
+	}
+	_, err = storage.Attachments.Stat(orphanPath)
+	assert.Error(t, err)
+	_, err = storage.Attachments.Stat(referencedPath)
+	assert.NoError(t, err)
+
+	_, err = FindOrphanedStorageObjects(context.Background(), "unknown", 0)
+	assert.Error(t, err)
+}
+
+func TestFindMissingStorageObjects(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer useEmptyTestStorage(t, &storage.Attachments)()
+
+	existing := AssertExistsAndLoadBean(t, &Attachment{ID: 1}).(*Attachment)
+	_, err := storage.Attachments.Save(existing.RelativePath(), strings.NewReader("exists"))
+	assert.NoError(t, err)
+
+	total := getCount(t, x, &Attachment{})
+	missing, err := FindMissingStorageObjects(context.Background(), "attachments")
+	assert.NoError(t, err)
+	assert.Len(t, missing, int(total)-1)
+
+	// attachments are reported, but not deleted
+	for _, m := range missing {
+		assert.NotEqual(t, existing.RelativePath(), m.Path)
+		assert.False(t, m.CanFix())
+		assert.Error(t, m.Fix())
+	}
+	assertCount(t, &Attachment{}, int(total))
+}
+
+func TestFindMissingStorageObjects_Avatars(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer useEmptyTestStorage(t, &storage.Avatars)()
+
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.NotEmpty(t, user.Avatar)
+
+	missing, err := FindMissingStorageObjects(context.Background(), "avatars")
+	assert.NoError(t, err)
+	var found bool
+	for _, m := range missing {
+		if m.Path != user.CustomAvatarRelativePath() {
+			continue
+		}
+		found = true
+		assert.True(t, m.CanFix())
+		assert.NoError(t, m.Fix())
+	}
+	assert.True(t, found)
+
+	user = AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.Empty(t, user.Avatar)
+	assert.False(t, user.UseCustomAvatar)
+}
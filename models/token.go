@@ -6,11 +6,13 @@
 package models
 
 import (
+	"context"
 	"crypto/subtle"
 	"time"
 
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/generate"
+	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/timeutil"
 
 	gouuid "github.com/google/uuid"
@@ -24,18 +26,98 @@ type AccessToken struct {
 	Token          string `xorm:"-"`
 	TokenHash      string `xorm:"UNIQUE"` // sha256 of token
 	TokenSalt      string
-	TokenLastEight string `xorm:"token_last_eight"`
+	TokenLastEight string           `xorm:"token_last_eight"`
+	Scope          AccessTokenScope `xorm:"TEXT"`
+
+	// A token restricted to a repository or an organization can only access
+	// that repository or the organization and its repositories
+	RepoID int64       `xorm:"NOT NULL DEFAULT 0"`
+	Repo   *Repository `xorm:"-"`
+	OrgID  int64       `xorm:"NOT NULL DEFAULT 0"`
+	Org    *User       `xorm:"-"`
 
 	CreatedUnix       timeutil.TimeStamp `xorm:"INDEX created"`
 	UpdatedUnix       timeutil.TimeStamp `xorm:"INDEX updated"`
+	ExpiresUnix       timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	LastUsedUnix      timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
 	HasRecentActivity bool               `xorm:"-"`
 	HasUsed           bool               `xorm:"-"`
 }
 
 // AfterLoad is invoked from XORM after setting the values of all fields of this object.
 func (t *AccessToken) AfterLoad() {
-	t.HasUsed = t.UpdatedUnix > t.CreatedUnix
-	t.HasRecentActivity = t.UpdatedUnix.AddDuration(7*24*time.Hour) > timeutil.TimeStampNow()
+	t.HasUsed = t.LastUsedUnix > 0
+	t.HasRecentActivity = t.LastUsedUnix.AddDuration(7*24*time.Hour) > timeutil.TimeStampNow()
+}
+
+// LoadAttributes loads the repository or organization the token is restricted to.
+// Both stay nil if the repository or organization has been deleted meanwhile.
+func (t *AccessToken) LoadAttributes() error {
+	if t.RepoID > 0 && t.Repo == nil {
+		repo, err := GetRepositoryByID(t.RepoID)
+		if err != nil {
+			if IsErrRepoNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err = repo.GetOwner(); err != nil {
+			if IsErrUserNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		t.Repo = repo
+	}
+	if t.OrgID > 0 && t.Org == nil {
+		org, err := GetUserByID(t.OrgID)
+		if err != nil {
+			if IsErrUserNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		t.Org = org
+	}
+	return nil
+}
+
+// IsRestrictionDeleted returns if the repository or organization the token is restricted to
+// has been deleted, LoadAttributes has to be called before
+func (t *AccessToken) IsRestrictionDeleted() bool {
+	return (t.RepoID > 0 && t.Repo == nil) || (t.OrgID > 0 && t.Org == nil)
+}
+
+// IsExpired returns if the token has expired
+func (t *AccessToken) IsExpired() bool {
+	return t.ExpiresUnix > 0 && t.ExpiresUnix <= timeutil.TimeStampNow()
+}
+
+// IsRestricted returns if the token is restricted to a repository or an organization
+func (t *AccessToken) IsRestricted() bool {
+	return t.RepoID > 0 || t.OrgID > 0
+}
+
+// CanAccessRepo returns if the restriction of the token allows to access the repository
+func (t *AccessToken) CanAccessRepo(repo *Repository) bool {
+	if t.RepoID > 0 {
+		return t.RepoID == repo.ID
+	}
+	if t.OrgID > 0 {
+		return t.OrgID == repo.OwnerID
+	}
+	return true
+}
+
+// CanAccessOrg returns if the restriction of the token allows to access the organization
+func (t *AccessToken) CanAccessOrg(orgID int64) bool {
+	if t.RepoID > 0 {
+		return false
+	}
+	if t.OrgID > 0 {
+		return t.OrgID == orgID
+	}
+	return true
 }
 
 // NewAccessToken creates new access token.
@@ -71,6 +153,9 @@ func GetAccessTokenBySHA(token string) (*AccessToken, error) {
 	for _, t := range tokens {
 		tempHash := hashToken(token, t.TokenSalt)
 		if subtle.ConstantTimeCompare([]byte(t.TokenHash), []byte(tempHash)) == 1 {
+			if t.IsExpired() {
+				return nil, ErrAccessTokenNotExist{token}
+			}
 			return &t, nil
 		}
 	}
@@ -116,6 +201,35 @@ func UpdateAccessToken(t *AccessToken) error {
 	return err
 }
 
+// UpdateAccessTokenLastUsed records that the access token has just been used.
+func UpdateAccessTokenLastUsed(t *AccessToken) error {
+	t.LastUsedUnix = timeutil.TimeStampNow()
+	_, err := x.ID(t.ID).Cols("last_used_unix").NoAutoTime().Update(t)
+	return err
+}
+
+// DeleteExpiredAccessTokens deletes the access tokens which expired before the given duration.
+func DeleteExpiredAccessTokens(ctx context.Context, olderThan time.Duration) error {
+	log.Trace("Doing: DeleteExpiredAccessTokens")
+
+	select {
+	case <-ctx.Done():
+		return ErrCancelledf("before deleting expired access tokens")
+	default:
+	}
+
+	deletes, err := x.
+		Where("expires_unix > 0 AND expires_unix < ?", time.Now().Add(-olderThan).Unix()).
+		Delete(new(AccessToken))
+	if err != nil {
+		return err
+	}
+	log.Trace("Deleted %d expired access tokens", deletes)
+
+	log.Trace("Finished: DeleteExpiredAccessTokens")
+	return nil
+}
+
 // DeleteAccessTokenByID deletes access token by given ID.
 func DeleteAccessTokenByID(id, userID int64) error {
 	cnt, err := x.ID(id).Delete(&AccessToken{
@@ -0,0 +1,116 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+)
+
+// AccessTokenScopeCategory represents a kind of resources an access token can be granted access to
+type AccessTokenScopeCategory string
+
+// Enumerate all the access token scope categories
+const (
+	AccessTokenScopeCategoryRepo    AccessTokenScopeCategory = "repo"
+	AccessTokenScopeCategoryIssue   AccessTokenScopeCategory = "issue"
+	AccessTokenScopeCategoryPackage AccessTokenScopeCategory = "package"
+	AccessTokenScopeCategoryOrg     AccessTokenScopeCategory = "org"
+	AccessTokenScopeCategoryUser    AccessTokenScopeCategory = "user"
+	AccessTokenScopeCategoryAdmin   AccessTokenScopeCategory = "admin"
+	AccessTokenScopeCategorySCIM    AccessTokenScopeCategory = "scim"
+)
+
+// AccessTokenScopeCategories lists all the access token scope categories in display order
+var AccessTokenScopeCategories = []AccessTokenScopeCategory{
+	AccessTokenScopeCategoryRepo,
+	AccessTokenScopeCategoryIssue,
+	AccessTokenScopeCategoryPackage,
+	AccessTokenScopeCategoryOrg,
+	AccessTokenScopeCategoryUser,
+	AccessTokenScopeCategoryAdmin,
+	AccessTokenScopeCategorySCIM,
+}
+
+// ReadScope returns the scope granting read access to the category
+func (category AccessTokenScopeCategory) ReadScope() string {
+	return string(category) + ":read"
+}
+
+// WriteScope returns the scope granting read and write access to the category
+func (category AccessTokenScopeCategory) WriteScope() string {
+	return string(category) + ":write"
+}
+
+// AccessTokenScope is a comma separated list of the scopes granted to an access token,
+// e.g. "repo:write,issue:read"
+type AccessTokenScope string
+
+// AccessTokenScopeAll grants full access to the account, it is the scope of the tokens
+// created before scopes were introduced and of the tokens created without scopes
+const AccessTokenScopeAll AccessTokenScope = "all"
+
+// ParseAccessTokenScope validates and normalizes a list of scopes. A write scope implies the read
+// scope of its category, so the latter is dropped if both are given. No scopes grant full access.
+func ParseAccessTokenScope(scopes []string) (AccessTokenScope, error) {
+	granted := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if scope == string(AccessTokenScopeAll) {
+			return AccessTokenScopeAll, nil
+		}
+		if !isValidAccessTokenScope(scope) {
+			return "", ErrAccessTokenScopeInvalid{Scope: scope}
+		}
+		granted[scope] = true
+	}
+	if len(granted) == 0 {
+		return AccessTokenScopeAll, nil
+	}
+
+	normalized := make([]string, 0, len(granted))
+	for _, category := range AccessTokenScopeCategories {
+		if granted[category.WriteScope()] {
+			normalized = append(normalized, category.WriteScope())
+		} else if granted[category.ReadScope()] {
+			normalized = append(normalized, category.ReadScope())
+		}
+	}
+	return AccessTokenScope(strings.Join(normalized, ",")), nil
+}
+
+func isValidAccessTokenScope(scope string) bool {
+	for _, category := range AccessTokenScopeCategories {
+		if scope == category.ReadScope() || scope == category.WriteScope() {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes returns the list of scopes
+func (s AccessTokenScope) Scopes() []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(string(s), ",")
+}
+
+// HasScope returns if the scope grants read, or write if asked for, access to the resources of the category
+func (s AccessTokenScope) HasScope(category AccessTokenScopeCategory, write bool) bool {
+	for _, scope := range s.Scopes() {
+		switch scope {
+		case string(AccessTokenScopeAll), category.WriteScope():
+			return true
+		case category.ReadScope():
+			if !write {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccessTokenScope(t *testing.T) {
+	for _, test := range []struct {
+		Scopes   []string
+		Expected AccessTokenScope
+	}{
+		{[]string{}, AccessTokenScopeAll},
+		{[]string{""}, AccessTokenScopeAll},
+		{[]string{"repo:read"}, "repo:read"},
+		{[]string{"package:write", "repo:read"}, "repo:read,package:write"},
+		{[]string{"user:read", " Repo:Write ", ""}, "repo:write,user:read"},
+		{[]string{"issue:read", "issue:write", "issue:read"}, "issue:write"},
+		{[]string{"admin:write", "all"}, AccessTokenScopeAll},
+	} {
+		scope, err := ParseAccessTokenScope(test.Scopes)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Expected, scope)
+	}
+
+	_, err := ParseAccessTokenScope([]string{"repo:read", "packages:write"})
+	assert.True(t, IsErrAccessTokenScopeInvalid(err))
+	_, err = ParseAccessTokenScope([]string{"repo"})
+	assert.True(t, IsErrAccessTokenScopeInvalid(err))
+}
+
+func TestAccessTokenScope_HasScope(t *testing.T) {
+	scope := AccessTokenScope("repo:write,issue:read")
+	assert.True(t, scope.HasScope(AccessTokenScopeCategoryRepo, false))
+	assert.True(t, scope.HasScope(AccessTokenScopeCategoryRepo, true))
+	assert.True(t, scope.HasScope(AccessTokenScopeCategoryIssue, false))
+	assert.False(t, scope.HasScope(AccessTokenScopeCategoryIssue, true))
+	assert.False(t, scope.HasScope(AccessTokenScopeCategoryAdmin, false))
+
+	assert.True(t, AccessTokenScopeAll.HasScope(AccessTokenScopeCategoryAdmin, true))
+	assert.False(t, AccessTokenScope("").HasScope(AccessTokenScopeCategoryUser, false))
+}
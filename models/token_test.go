@@ -128,3 +128,23 @@ func TestDeleteAccessTokenByID(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, IsErrAccessTokenNotExist(err))
 }
+
+func TestAccessTokenLoadAttributes(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	token := &AccessToken{RepoID: 1}
+	assert.NoError(t, token.LoadAttributes())
+	assert.EqualValues(t, 1, token.Repo.ID)
+	assert.False(t, token.IsRestrictionDeleted())
+
+	// a token restricted to a deleted repository or organization is still listed
+	token = &AccessToken{RepoID: NonexistentID}
+	assert.NoError(t, token.LoadAttributes())
+	assert.Nil(t, token.Repo)
+	assert.True(t, token.IsRestrictionDeleted())
+
+	token = &AccessToken{OrgID: NonexistentID}
+	assert.NoError(t, token.LoadAttributes())
+	assert.Nil(t, token.Org)
+	assert.True(t, token.IsRestrictionDeleted())
+}
@@ -111,6 +111,25 @@ func GetTwoFactorByUID(uid int64) (*TwoFactor, error) {
 	return twofa, nil
 }
 
+func hasTwoFactorByUID(e Engine, uid int64) (bool, error) {
+	return e.Where("uid=?", uid).Exist(new(TwoFactor))
+}
+
+// HasTwoFactorByUID returns true if the user has enrolled two-factor authentication.
+func HasTwoFactorByUID(uid int64) (bool, error) {
+	return hasTwoFactorByUID(x, uid)
+}
+
+// MustEnrollTwoFactor returns true if the user has to enrol two-factor authentication
+// before being allowed to sign in, as required by ENFORCE_TWO_FACTOR_AUTH for local users.
+func (u *User) MustEnrollTwoFactor() (bool, error) {
+	if !setting.EnforceTwoFactorAuth || !u.IsLocal() || u.IsOrganization() {
+		return false, nil
+	}
+	has, err := HasTwoFactorByUID(u.ID)
+	return !has, err
+}
+
 // DeleteTwoFactorByID deletes two-factor authentication token by given ID.
 func DeleteTwoFactorByID(id, userID int64) error {
 	cnt, err := x.ID(id).Delete(&TwoFactor{
@@ -147,6 +147,11 @@ type User struct {
 	AllowCreateOrganization bool `xorm:"DEFAULT true"`
 	ProhibitLogin           bool `xorm:"NOT NULL DEFAULT false"`
 
+	// Failed sign-in attempts
+	NumFailedLogins     int                `xorm:"NOT NULL DEFAULT 0"`
+	LastFailedLoginUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	LockedUntilUnix     timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+
 	// Avatar
 	Avatar          string `xorm:"VARCHAR(2048) NOT NULL"`
 	AvatarEmail     string `xorm:"NOT NULL"`
@@ -166,6 +171,7 @@ type User struct {
 	MembersIsPublic           map[int64]bool      `xorm:"-"`
 	Visibility                structs.VisibleType `xorm:"NOT NULL DEFAULT 0"`
 	RepoAdminChangeTeamAccess bool                `xorm:"NOT NULL DEFAULT false"`
+	RequireTwoFactor          bool                `xorm:"NOT NULL DEFAULT false"`
 
 	// Preferences
 	DiffViewStyle       string `xorm:"NOT NULL DEFAULT ''"`
@@ -1169,10 +1175,12 @@ func deleteUser(e Engine, u *User) error {
 		&IssueUser{UID: u.ID},
 		&EmailAddress{UID: u.ID},
 		&UserOpenID{UID: u.ID},
+		&SCIMExternalID{ResourceType: SCIMResourceTypeUser, ResourceID: u.ID},
 		&Reaction{UserID: u.ID},
 		&TeamUser{UID: u.ID},
 		&Collaboration{UserID: u.ID},
 		&Stopwatch{UserID: u.ID},
+		&PullViewedFile{UserID: u.ID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
@@ -1822,6 +1830,88 @@ func synchronizeLdapSSHPublicKeys(usr *User, s *LoginSource, sshPublicKeys []str
 	return sshKeysNeedUpdate
 }
 
+// synchronizeLdapGroupTeams adds a user to the teams mapped to its LDAP groups and,
+// if enabled, removes it from the mapped teams of groups it is no longer a member of.
+func synchronizeLdapGroupTeams(usr *User, s *LoginSource, groups []string) error {
+	mapping, err := s.LDAP().GroupTeamMapping()
+	if err != nil {
+		return err
+	}
+	return SyncGroupTeamMapping(usr, s, mapping, groups, s.LDAP().GroupTeamMapRemoval)
+}
+
+// SyncGroupTeamMapping adds a user to the teams mapped to the groups of the login source it is
+// a member of and, if performRemoval is set, removes it from the mapped teams of the other groups.
+// Groups, organizations and teams are matched case-insensitively.
+func SyncGroupTeamMapping(usr *User, s *LoginSource, mapping map[string]map[string][]string, groups []string, performRemoval bool) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	log.Trace("SyncGroupTeamMapping[%s]: Handling group team synchronization for user %s", s.Name, usr.Name)
+
+	isMemberOf := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		isMemberOf[strings.ToLower(group)] = true
+	}
+
+	// Collect the mapped teams per organization and whether the user should be a member
+	shouldBeMember := make(map[string]map[string]bool)
+	for group, orgs := range mapping {
+		inGroup := isMemberOf[strings.ToLower(group)]
+		for orgName, teamNames := range orgs {
+			orgName = strings.ToLower(orgName)
+			if shouldBeMember[orgName] == nil {
+				shouldBeMember[orgName] = make(map[string]bool)
+			}
+			for _, teamName := range teamNames {
+				teamName = strings.ToLower(teamName)
+				shouldBeMember[orgName][teamName] = shouldBeMember[orgName][teamName] || inGroup
+			}
+		}
+	}
+
+	for orgName, teams := range shouldBeMember {
+		org, err := GetOrgByName(orgName)
+		if err != nil {
+			if IsErrOrgNotExist(err) {
+				log.Warn("SyncGroupTeamMapping[%s]: Mapped organization %s does not exist", s.Name, orgName)
+				continue
+			}
+			return err
+		}
+
+		for teamName, member := range teams {
+			team, err := org.GetTeam(teamName)
+			if err != nil {
+				if IsErrTeamNotExist(err) {
+					log.Warn("SyncGroupTeamMapping[%s]: Mapped team %s of organization %s does not exist", s.Name, teamName, org.Name)
+					continue
+				}
+				return err
+			}
+
+			isMember, err := IsTeamMember(org.ID, team.ID, usr.ID)
+			if err != nil {
+				return err
+			}
+
+			if member && !isMember {
+				log.Trace("SyncGroupTeamMapping[%s]: Adding user %s to team %s of organization %s", s.Name, usr.Name, team.Name, org.Name)
+				if err := AddTeamMember(team, usr.ID); err != nil {
+					log.Error("SyncGroupTeamMapping[%s]: Error adding user %s to team %s of organization %s: %v", s.Name, usr.Name, team.Name, org.Name, err)
+				}
+			} else if !member && isMember && performRemoval {
+				log.Trace("SyncGroupTeamMapping[%s]: Removing user %s from team %s of organization %s", s.Name, usr.Name, team.Name, org.Name)
+				if err := RemoveTeamMember(team, usr.ID); err != nil {
+					log.Error("SyncGroupTeamMapping[%s]: Error removing user %s from team %s of organization %s: %v", s.Name, usr.Name, team.Name, org.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // SyncExternalUsers is used to synchronize users with external authorization source
 func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 	log.Trace("Doing: SyncExternalUsers")
@@ -1848,6 +1938,7 @@ func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 
 			var existingUsers []int64
 			var isAttributeSSHPublicKeySet = len(strings.TrimSpace(s.LDAP().AttributeSSHPublicKey)) > 0
+			var isGroupTeamMapSet = s.LDAP().GroupsEnabled && s.LDAP().HasGroupTeamMap()
 			var sshKeysNeedUpdate bool
 
 			// Find all users with this login type
@@ -1934,10 +2025,17 @@ func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 
 					if err != nil {
 						log.Error("SyncExternalUsers[%s]: Error creating user %s: %v", s.Name, su.Username, err)
-					} else if isAttributeSSHPublicKeySet {
-						log.Trace("SyncExternalUsers[%s]: Adding LDAP Public SSH Keys for user %s", s.Name, usr.Name)
-						if addLdapSSHPublicKeys(usr, s, su.SSHPublicKey) {
-							sshKeysNeedUpdate = true
+					} else {
+						if isAttributeSSHPublicKeySet {
+							log.Trace("SyncExternalUsers[%s]: Adding LDAP Public SSH Keys for user %s", s.Name, usr.Name)
+							if addLdapSSHPublicKeys(usr, s, su.SSHPublicKey) {
+								sshKeysNeedUpdate = true
+							}
+						}
+						if isGroupTeamMapSet {
+							if err = synchronizeLdapGroupTeams(usr, s, su.Groups); err != nil {
+								log.Error("SyncExternalUsers[%s]: Error synchronizing teams of user %s: %v", s.Name, usr.Name, err)
+							}
 						}
 					}
 				} else if updateExisting {
@@ -1948,6 +2046,13 @@ func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 						sshKeysNeedUpdate = true
 					}
 
+					// Synchronize team memberships if a group team map is set
+					if isGroupTeamMapSet {
+						if err = synchronizeLdapGroupTeams(usr, s, su.Groups); err != nil {
+							log.Error("SyncExternalUsers[%s]: Error synchronizing teams of user %s: %v", s.Name, usr.Name, err)
+						}
+					}
+
 					// Check if user data has changed
 					if (len(s.LDAP().AdminFilter) > 0 && usr.IsAdmin != su.IsAdmin) ||
 						(len(s.LDAP().RestrictedFilter) > 0 && usr.IsRestricted != su.IsRestricted) ||
@@ -0,0 +1,138 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"net"
+	"strconv"
+
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// IsLockedOut returns true if the account is locked because of too many failed sign-in attempts
+func (u *User) IsLockedOut() bool {
+	return u.LockedUntilUnix > timeutil.TimeStampNow()
+}
+
+// recordFailedLogin counts a failed sign-in attempt of the user and locks the account once
+// LOGIN_MAX_FAILED_ATTEMPTS is reached. It returns true if the account got locked.
+func recordFailedLogin(u *User) (bool, error) {
+	if setting.LoginMaxFailedAttempts <= 0 {
+		return false, nil
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return false, err
+	}
+
+	// the attempts are counted by the database, so concurrent attempts are not lost,
+	// and are forgotten after the lockout duration
+	now := timeutil.TimeStampNow()
+	if _, err := sess.Exec("UPDATE `user` SET num_failed_logins = CASE WHEN last_failed_login_unix <= ? THEN 1 ELSE num_failed_logins + 1 END, last_failed_login_unix = ? WHERE id = ?",
+		now.AddDuration(-setting.LoginLockoutDuration), now, u.ID); err != nil {
+		return false, err
+	}
+	counts := &User{}
+	if _, err := sess.ID(u.ID).Cols("num_failed_logins", "last_failed_login_unix").Get(counts); err != nil {
+		return false, err
+	}
+	u.NumFailedLogins = counts.NumFailedLogins
+	u.LastFailedLoginUnix = counts.LastFailedLoginUnix
+
+	locked := u.NumFailedLogins >= setting.LoginMaxFailedAttempts
+	if locked {
+		u.NumFailedLogins = 0
+		u.LockedUntilUnix = now.AddDuration(setting.LoginLockoutDuration)
+		if _, err := sess.ID(u.ID).Cols("num_failed_logins", "locked_until_unix").NoAutoTime().Update(u); err != nil {
+			return false, err
+		}
+	}
+	if err := sess.Commit(); err != nil {
+		return false, err
+	}
+
+	if locked {
+		log.Warn("User %s is locked until %s after too many failed sign-in attempts", u.Name, u.LockedUntilUnix.FormatLong())
+	}
+	return locked, nil
+}
+
+// resetFailedLogins forgets the failed sign-in attempts of the user after a successful sign-in
+func resetFailedLogins(e Engine, u *User) error {
+	if u.NumFailedLogins == 0 {
+		return nil
+	}
+	u.NumFailedLogins = 0
+	_, err := e.ID(u.ID).Cols("num_failed_logins").NoAutoTime().Update(u)
+	return err
+}
+
+// UnlockUser unlocks an account which was locked because of too many failed sign-in attempts
+func UnlockUser(u *User) error {
+	u.NumFailedLogins = 0
+	u.LockedUntilUnix = 0
+	_, err := x.ID(u.ID).Cols("num_failed_logins", "locked_until_unix").NoAutoTime().Update(u)
+	return err
+}
+
+func failedLoginsCacheKey(remoteAddr string) string {
+	// the port changes with each connection
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+	return "failed_logins_" + remoteAddr
+}
+
+func getFailedLoginsFromAddr(key string) int {
+	switch value := cache.GetCache().Get(key).(type) {
+	case int:
+		return value
+	case string:
+		v, _ := strconv.Atoi(value)
+		return v
+	default:
+		return 0
+	}
+}
+
+// IsLoginRateLimited returns true if sign-in attempts from the remote address are rejected
+// because LOGIN_MAX_FAILED_ATTEMPTS_PER_IP failed attempts have been made from it
+func IsLoginRateLimited(remoteAddr string) bool {
+	if setting.LoginMaxFailedAttemptsPerIP <= 0 || cache.GetCache() == nil || remoteAddr == "" {
+		return false
+	}
+	return getFailedLoginsFromAddr(failedLoginsCacheKey(remoteAddr)) >= setting.LoginMaxFailedAttemptsPerIP
+}
+
+// RecordFailedLoginFromAddr counts a failed sign-in attempt from the remote address
+func RecordFailedLoginFromAddr(remoteAddr string) {
+	if setting.LoginMaxFailedAttemptsPerIP <= 0 || cache.GetCache() == nil || remoteAddr == "" {
+		return
+	}
+	key := failedLoginsCacheKey(remoteAddr)
+	expire := int64(setting.LoginIPLockoutDuration.Seconds())
+
+	// the counter is increased by the cache, so concurrent attempts are not lost,
+	// and only created by the first attempt
+	if err := cache.GetCache().Incr(key); err != nil {
+		if err := cache.GetCache().Put(key, 1, expire); err != nil {
+			log.Error("Unable to count failed sign-in attempt from %s: %v", remoteAddr, err)
+		}
+		return
+	}
+
+	if count := getFailedLoginsFromAddr(key); count == setting.LoginMaxFailedAttemptsPerIP {
+		log.Warn("Rejecting sign-in attempts from %s after too many failed attempts", remoteAddr)
+		// the attempts are rejected for the whole duration from now on
+		if err := cache.GetCache().Put(key, count, expire); err != nil {
+			log.Error("Unable to count failed sign-in attempt from %s: %v", remoteAddr, err)
+		}
+	}
+}
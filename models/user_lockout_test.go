@@ -0,0 +1,98 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSignIn_Lockout(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	defer func(attempts int, duration time.Duration) {
+		setting.LoginMaxFailedAttempts = attempts
+		setting.LoginLockoutDuration = duration
+	}(setting.LoginMaxFailedAttempts, setting.LoginLockoutDuration)
+	setting.LoginMaxFailedAttempts = 3
+	setting.LoginLockoutDuration = time.Hour
+
+	for i := 0; i < 2; i++ {
+		_, err := UserSignIn("user2", "wrong password")
+		assert.True(t, IsErrUserNotExist(err))
+	}
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.EqualValues(t, 2, user.NumFailedLogins)
+	assert.False(t, user.IsLockedOut())
+
+	_, err := UserSignIn("user2", "wrong password")
+	assert.True(t, IsErrUserLockedOut(err))
+	assert.True(t, err.(ErrUserLockedOut).JustLocked)
+
+	// the correct password is rejected while the account is locked
+	_, err = UserSignIn("user2", "password")
+	assert.True(t, IsErrUserLockedOut(err))
+	assert.False(t, err.(ErrUserLockedOut).JustLocked)
+
+	user = AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.True(t, user.IsLockedOut())
+	assert.NoError(t, UnlockUser(user))
+	assert.False(t, user.IsLockedOut())
+
+	u, err := UserSignIn("user2", "password")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, u.ID)
+}
+
+func TestUserSignIn_ResetFailedLogins(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	defer func(attempts int, duration time.Duration) {
+		setting.LoginMaxFailedAttempts = attempts
+		setting.LoginLockoutDuration = duration
+	}(setting.LoginMaxFailedAttempts, setting.LoginLockoutDuration)
+	setting.LoginMaxFailedAttempts = 3
+	setting.LoginLockoutDuration = time.Hour
+
+	_, err := UserSignIn("user2", "wrong password")
+	assert.True(t, IsErrUserNotExist(err))
+	AssertExistsAndLoadBean(t, &User{ID: 2, NumFailedLogins: 1})
+
+	_, err = UserSignIn("user2", "password")
+	assert.NoError(t, err)
+	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.EqualValues(t, 0, user.NumFailedLogins)
+}
+
+func TestFailedLoginsCacheKey(t *testing.T) {
+	assert.Equal(t, "failed_logins_127.0.0.1", failedLoginsCacheKey("127.0.0.1:3000"))
+	assert.Equal(t, "failed_logins_::1", failedLoginsCacheKey("[::1]:3000"))
+	assert.Equal(t, "failed_logins_127.0.0.1", failedLoginsCacheKey("127.0.0.1"))
+}
+
+func TestRecordFailedLoginFromAddr(t *testing.T) {
+	setting.CacheService.Cache = setting.Cache{Enabled: true, Adapter: "memory", Interval: 60}
+	assert.NoError(t, cache.NewContext())
+
+	defer func(attempts int, duration time.Duration) {
+		setting.LoginMaxFailedAttemptsPerIP = attempts
+		setting.LoginIPLockoutDuration = duration
+	}(setting.LoginMaxFailedAttemptsPerIP, setting.LoginIPLockoutDuration)
+	setting.LoginMaxFailedAttemptsPerIP = 3
+	setting.LoginIPLockoutDuration = time.Hour
+
+	for i := 0; i < 2; i++ {
+		RecordFailedLoginFromAddr("192.0.2.1:1234")
+	}
+	assert.False(t, IsLoginRateLimited("192.0.2.1:4321"))
+	RecordFailedLoginFromAddr("192.0.2.1:1234")
+	assert.True(t, IsLoginRateLimited("192.0.2.1:4321"))
+	assert.False(t, IsLoginRateLimited("192.0.2.2:1234"))
+}
@@ -15,6 +15,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 
 	"github.com/go-ldap/ldap/v3"
+	jsoniter "github.com/json-iterator/go"
 )
 
 // SecurityProtocol protocol type
@@ -55,6 +56,8 @@ type Source struct {
 	GroupFilter           string // Group Name Filter
 	GroupMemberUID        string // Group Attribute containing array of UserUID
 	UserUID               string // User Attribute listed in Group
+	GroupTeamMap          string // JSON map of LDAP group DNs to organization teams
+	GroupTeamMapRemoval   bool   // Remove users from mapped teams of groups they are no longer a member of
 }
 
 // SearchResult : user data
@@ -66,6 +69,41 @@ type SearchResult struct {
 	SSHPublicKey []string // SSH Public Key
 	IsAdmin      bool     // if user is administrator
 	IsRestricted bool     // if user is restricted
+	Groups       []string // DNs of the LDAP groups the user is a member of
+}
+
+// HasGroupTeamMap returns if a mapping of LDAP groups to teams is configured
+func (ls *Source) HasGroupTeamMap() bool {
+	return len(strings.TrimSpace(ls.GroupTeamMap)) > 0
+}
+
+// GroupTeamMapping parses the mapping of LDAP group DNs to organization teams, e.g.
+// {"cn=developers,ou=groups,dc=example,dc=org": {"MyOrg": ["Developers", "Readers"]}}
+func (ls *Source) GroupTeamMapping() (map[string]map[string][]string, error) {
+	mapping := make(map[string]map[string][]string)
+	if !ls.HasGroupTeamMap() {
+		return mapping, nil
+	}
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal([]byte(ls.GroupTeamMap), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid group team map: %v", err)
+	}
+	for group, orgs := range mapping {
+		if strings.TrimSpace(group) == "" {
+			return nil, fmt.Errorf("invalid group team map: empty group DN")
+		}
+		for org, teams := range orgs {
+			if strings.TrimSpace(org) == "" {
+				return nil, fmt.Errorf("invalid group team map: empty organization name for group %s", group)
+			}
+			for _, team := range teams {
+				if strings.TrimSpace(team) == "" {
+					return nil, fmt.Errorf("invalid group team map: empty team name for organization %s", org)
+				}
+			}
+		}
+	}
+	return mapping, nil
 }
 
 func (ls *Source) sanitizedUserQuery(username string) (string, bool) {
@@ -229,6 +267,52 @@ func checkRestricted(l *ldap.Conn, ls *Source, userDN string) bool {
 	return false
 }
 
+// groupMemberKey returns the value identifying a user in the member attribute of a group
+func (ls *Source) groupMemberKey(userDN, uid string) string {
+	if ls.UserUID == "dn" {
+		return userDN
+	}
+	return uid
+}
+
+// listGroupMemberships searches the groups matching the group filter and returns their DNs keyed by member
+func (ls *Source) listGroupMemberships(l *ldap.Conn) (map[string][]string, bool) {
+	groupFilter, ok := ls.sanitizedGroupFilter(ls.GroupFilter)
+	if !ok {
+		return nil, false
+	}
+	groupDN, ok := ls.sanitizedGroupDN(ls.GroupDN)
+	if !ok {
+		return nil, false
+	}
+
+	log.Trace("Fetching groups '%v' with filter '%s' and base '%s'", ls.GroupMemberUID, groupFilter, groupDN)
+	groupSearch := ldap.NewSearchRequest(
+		groupDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, groupFilter,
+		[]string{ls.GroupMemberUID},
+		nil)
+
+	srg, err := l.Search(groupSearch)
+	if err != nil {
+		log.Error("LDAP group search failed: %v", err)
+		return nil, false
+	} else if len(srg.Entries) < 1 {
+		log.Error("LDAP group search failed: 0 entries")
+		return nil, false
+	}
+
+	memberships := make(map[string][]string)
+	for _, group := range srg.Entries {
+		for _, member := range group.GetAttributeValues(ls.GroupMemberUID) {
+			if member == "" {
+				continue
+			}
+			memberships[member] = append(memberships[member], group.DN)
+		}
+	}
+	return memberships, true
+}
+
 // SearchEntry : search an LDAP source if an entry (name, passwd) is valid and in the specific filter
 func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResult {
 	// See https://tools.ietf.org/search/rfc4513#section-5.1.2
@@ -342,43 +426,15 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 	uid := sr.Entries[0].GetAttributeValue(ls.UserUID)
 
 	// Check group membership
+	var groups []string
 	if ls.GroupsEnabled {
-		groupFilter, ok := ls.sanitizedGroupFilter(ls.GroupFilter)
-		if !ok {
-			return nil
-		}
-		groupDN, ok := ls.sanitizedGroupDN(ls.GroupDN)
+		memberships, ok := ls.listGroupMemberships(l)
 		if !ok {
 			return nil
 		}
 
-		log.Trace("Fetching groups '%v' with filter '%s' and base '%s'", ls.GroupMemberUID, groupFilter, groupDN)
-		groupSearch := ldap.NewSearchRequest(
-			groupDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, groupFilter,
-			[]string{ls.GroupMemberUID},
-			nil)
-
-		srg, err := l.Search(groupSearch)
-		if err != nil {
-			log.Error("LDAP group search failed: %v", err)
-			return nil
-		} else if len(srg.Entries) < 1 {
-			log.Error("LDAP group search failed: 0 entries")
-			return nil
-		}
-
-		isMember := false
-	Entries:
-		for _, group := range srg.Entries {
-			for _, member := range group.GetAttributeValues(ls.GroupMemberUID) {
-				if (ls.UserUID == "dn" && member == sr.Entries[0].DN) || member == uid {
-					isMember = true
-					break Entries
-				}
-			}
-		}
-
-		if !isMember {
+		groups = memberships[ls.groupMemberKey(sr.Entries[0].DN, uid)]
+		if len(groups) == 0 {
 			log.Error("LDAP group membership test failed")
 			return nil
 		}
@@ -409,6 +465,7 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 		SSHPublicKey: sshPublicKey,
 		IsAdmin:      isAdmin,
 		IsRestricted: isRestricted,
+		Groups:       groups,
 	}
 }
 
@@ -446,6 +503,9 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 	if isAttributeSSHPublicKeySet {
 		attribs = append(attribs, ls.AttributeSSHPublicKey)
 	}
+	if ls.GroupsEnabled && ls.HasGroupTeamMap() && len(strings.TrimSpace(ls.UserUID)) > 0 {
+		attribs = append(attribs, ls.UserUID)
+	}
 
 	log.Trace("Fetching attributes '%v', '%v', '%v', '%v', '%v' with filter %s and base %s", ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail, ls.AttributeSSHPublicKey, userFilter, ls.UserBase)
 	search := ldap.NewSearchRequest(
@@ -463,6 +523,14 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 		return nil, err
 	}
 
+	var memberships map[string][]string
+	if ls.GroupsEnabled && ls.HasGroupTeamMap() {
+		var ok bool
+		if memberships, ok = ls.listGroupMemberships(l); !ok {
+			return nil, fmt.Errorf("unable to list the groups of LDAP source %s", ls.Name)
+		}
+	}
+
 	result := make([]*SearchResult, len(sr.Entries))
 
 	for i, v := range sr.Entries {
@@ -479,6 +547,9 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 		if isAttributeSSHPublicKeySet {
 			result[i].SSHPublicKey = v.GetAttributeValues(ls.AttributeSSHPublicKey)
 		}
+		if memberships != nil {
+			result[i].Groups = memberships[ls.groupMemberKey(v.DN, v.GetAttributeValue(ls.UserUID))]
+		}
 	}
 
 	return result, nil
@@ -0,0 +1,47 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceGroupTeamMapping(t *testing.T) {
+	source := &Source{}
+	assert.False(t, source.HasGroupTeamMap())
+	mapping, err := source.GroupTeamMapping()
+	assert.NoError(t, err)
+	assert.Empty(t, mapping)
+
+	source.GroupTeamMap = `{"cn=developers,ou=group,dc=example,dc=org": {"MyOrg": ["Developers", "Readers"]}}`
+	assert.True(t, source.HasGroupTeamMap())
+	mapping, err = source.GroupTeamMapping()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]string{
+		"cn=developers,ou=group,dc=example,dc=org": {"MyOrg": {"Developers", "Readers"}},
+	}, mapping)
+
+	for _, invalid := range []string{
+		`not json`,
+		`["cn=developers"]`,
+		`{"": {"MyOrg": ["Developers"]}}`,
+		`{"cn=developers": {"": ["Developers"]}}`,
+		`{"cn=developers": {"MyOrg": [""]}}`,
+	} {
+		source.GroupTeamMap = invalid
+		_, err = source.GroupTeamMapping()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSourceGroupMemberKey(t *testing.T) {
+	source := &Source{UserUID: "uid"}
+	assert.Equal(t, "jdoe", source.groupMemberKey("uid=jdoe,ou=people,dc=example,dc=org", "jdoe"))
+
+	source.UserUID = "dn"
+	assert.Equal(t, "uid=jdoe,ou=people,dc=example,dc=org", source.groupMemberKey("uid=jdoe,ou=people,dc=example,dc=org", ""))
+}
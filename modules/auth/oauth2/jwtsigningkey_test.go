@@ -0,0 +1,112 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func signAndVerify(t *testing.T, key JWTSigningKey) {
+	token := jwt.NewWithClaims(key.SigningMethod(), jwt.StandardClaims{Subject: "1"})
+	key.PreProcessToken(token)
+	signed, err := token.SignedString(key.SignKey())
+	assert.NoError(t, err)
+
+	parsed, err := jwt.ParseWithClaims(signed, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, key.SigningMethod().Alg(), token.Method.Alg())
+		return key.VerifyKey(), nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, parsed.Valid)
+	assert.Equal(t, "1", parsed.Claims.(*jwt.StandardClaims).Subject)
+}
+
+func TestCreateJWTSigningKey(t *testing.T) {
+	hmacKey, err := CreateJWTSigningKey("HS256", []byte("secret"))
+	assert.NoError(t, err)
+	assert.True(t, hmacKey.IsSymmetric())
+	signAndVerify(t, hmacKey)
+
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaKey, err := CreateJWTSigningKey("RS256", rsaPrivateKey)
+	assert.NoError(t, err)
+	assert.False(t, rsaKey.IsSymmetric())
+	signAndVerify(t, rsaKey)
+	jwk, err := rsaKey.ToJWK()
+	assert.NoError(t, err)
+	assert.Equal(t, "RSA", jwk.KeyType)
+	assert.Equal(t, "RS256", jwk.Algorithm)
+	assert.Equal(t, "AQAB", jwk.Exponent)
+	assert.NotEmpty(t, jwk.KeyID)
+
+	ecdsaPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecdsaKey, err := CreateJWTSigningKey("ES256", ecdsaPrivateKey)
+	assert.NoError(t, err)
+	signAndVerify(t, ecdsaKey)
+	jwk, err = ecdsaKey.ToJWK()
+	assert.NoError(t, err)
+	assert.Equal(t, "EC", jwk.KeyType)
+	assert.Equal(t, "P-256", jwk.Curve)
+	assert.Len(t, jwk.X, 43)
+	assert.Len(t, jwk.Y, 43)
+
+	_, err = CreateJWTSigningKey("RS256", ecdsaPrivateKey)
+	assert.Equal(t, jwt.ErrInvalidKeyType, err)
+	_, err = CreateJWTSigningKey("none", nil)
+	assert.Equal(t, ErrInvalidAlgorithmType{"none"}, err)
+
+	set, err := NewJSONWebKeySet(hmacKey, rsaKey, ecdsaKey)
+	assert.NoError(t, err)
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, "sig", set.Keys[0].Use)
+	assert.Equal(t, "RSA", set.Keys[0].KeyType)
+	assert.Equal(t, "EC", set.Keys[1].KeyType)
+}
+
+func TestInitSigningKey(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	oldAlgorithm, oldKeyFile := setting.OAuth2.JWTSigningAlgorithm, setting.OAuth2.JWTSigningPrivateKeyFile
+	defer func() {
+		setting.OAuth2.JWTSigningAlgorithm, setting.OAuth2.JWTSigningPrivateKeyFile = oldAlgorithm, oldKeyFile
+		DefaultSigningKey = nil
+	}()
+
+	setting.OAuth2.JWTSigningAlgorithm = "ES256"
+	setting.OAuth2.JWTSigningPrivateKeyFile = filepath.Join(tmpDir, "jwt", "private.pem")
+	assert.NoError(t, InitSigningKey())
+	assert.FileExists(t, setting.OAuth2.JWTSigningPrivateKeyFile)
+	first, err := DefaultSigningKey.ToJWK()
+	assert.NoError(t, err)
+
+	// the key is loaded from disk on the next start
+	assert.NoError(t, InitSigningKey())
+	second, err := DefaultSigningKey.ToJWK()
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// the key on disk does not match the algorithm
+	setting.OAuth2.JWTSigningAlgorithm = "RS256"
+	assert.Error(t, InitSigningKey())
+
+	setting.OAuth2.JWTSigningAlgorithm = "foo"
+	assert.Error(t, InitSigningKey())
+}
@@ -70,6 +70,9 @@ func Init(x *xorm.Engine) error {
 		return req.Header.Get(providerHeaderKey), nil
 	}
 
+	if setting.OAuth2.Enable {
+		return InitSigningKey()
+	}
 	return nil
 }
 
@@ -0,0 +1,488 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package saml provides a SAML 2.0 service provider which allows users to sign in
+// through an external identity provider
+package saml
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/log"
+
+	saml2 "github.com/russellhaering/gosaml2"
+	"github.com/russellhaering/gosaml2/types"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// Source holds the configuration of a SAML identity provider
+type Source struct {
+	IdentityProviderMetadata    string // Metadata XML of the identity provider
+	IdentityProviderMetadataURL string // URL the metadata is (re-)imported from
+	ServiceProviderCertificate  string // PEM encoded certificate of this service provider
+	ServiceProviderPrivateKey   string // PEM encoded private key of this service provider
+	SignRequests                bool   // sign authentication requests
+	WantAssertionsSigned        bool   // require the assertion itself to be signed, not only the response
+	NameIDFormat                string // requested NameID format, empty lets the identity provider choose
+	AttributeUsername           string // Username attribute, falls back to the NameID
+	AttributeEmail              string // E-mail attribute
+	AttributeFullName           string // Full name attribute
+	AttributeGroups             string // Group membership attribute
+	RequiredGroup               string // Group a user must be a member of to sign in
+	AdminGroup                  string // Group whose members are site administrators
+	RestrictedGroup             string // Group whose members are restricted users
+	AutoCreateUsers             bool   // create users on their first sign in
+	AllowUnsolicited            bool   // accept responses not requested by this service provider (IdP-initiated sign in)
+}
+
+// IdentityProvider holds the settings read from the metadata of an identity provider
+type IdentityProvider struct {
+	EntityID     string
+	SSOURL       string
+	SSOBinding   string
+	Certificates []*x509.Certificate
+}
+
+// Assertion holds the user information of a validated assertion
+type Assertion struct {
+	NameID       string
+	Username     string
+	Email        string
+	FullName     string
+	Groups       []string
+	IsAdmin      bool
+	IsRestricted bool
+}
+
+var (
+	// ErrNoIdentityProvider is returned when the metadata does not describe an identity provider
+	ErrNoIdentityProvider = errors.New("metadata does not contain an IDPSSODescriptor")
+	// ErrNoSingleSignOnService is returned when the identity provider has no supported SSO endpoint
+	ErrNoSingleSignOnService = errors.New("identity provider has no HTTP-Redirect or HTTP-POST SingleSignOnService")
+	// ErrNoSigningCertificate is returned when the identity provider metadata contains no signing certificate
+	ErrNoSigningCertificate = errors.New("identity provider metadata contains no signing certificate")
+	// ErrAssertionNotSigned is returned when the assertion is required to be signed but is not
+	ErrAssertionNotSigned = errors.New("assertion is not signed")
+	// ErrNotInRequiredGroup is returned when the user is not a member of the required group
+	ErrNotInRequiredGroup = errors.New("user is not a member of the required group")
+	// ErrUnsolicitedResponse is returned for a response which was not requested, unless these are allowed
+	ErrUnsolicitedResponse = errors.New("response was not requested by this service provider")
+	// ErrInResponseToMismatch is returned for a response to another authentication request
+	ErrInResponseToMismatch = errors.New("response does not belong to the authentication request")
+	// ErrAssertionReplayed is returned when an assertion has already been used to sign in
+	ErrAssertionReplayed = errors.New("assertion has already been used")
+	// ErrAssertionCount is returned for a response which does not contain exactly one assertion
+	ErrAssertionCount = errors.New("response has to contain exactly one assertion")
+)
+
+// entitiesDescriptor allows metadata documents which wrap the entity of the identity provider
+type entitiesDescriptor struct {
+	XMLName           xml.Name                 `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntitiesDescriptor"`
+	EntityDescriptors []types.EntityDescriptor `xml:"EntityDescriptor"`
+}
+
+// ParseIdentityProviderMetadata reads the entity ID, single sign-on endpoint and
+// signing certificates from the given metadata XML
+func ParseIdentityProviderMetadata(metadata []byte) (*IdentityProvider, error) {
+	var descriptor *types.EntityDescriptor
+
+	entity := &types.EntityDescriptor{}
+	if err := xml.Unmarshal(metadata, entity); err == nil {
+		descriptor = entity
+	} else {
+		entities := &entitiesDescriptor{}
+		if err := xml.Unmarshal(metadata, entities); err != nil {
+			return nil, fmt.Errorf("unable to parse metadata: %v", err)
+		}
+		for i := range entities.EntityDescriptors {
+			if entities.EntityDescriptors[i].IDPSSODescriptor != nil {
+				descriptor = &entities.EntityDescriptors[i]
+				break
+			}
+		}
+	}
+	if descriptor == nil || descriptor.IDPSSODescriptor == nil {
+		return nil, ErrNoIdentityProvider
+	}
+
+	idp := &IdentityProvider{
+		EntityID: descriptor.EntityID,
+	}
+
+	// Prefer the redirect binding, the authentication request is then a plain link
+	for _, binding := range []string{saml2.BindingHttpRedirect, saml2.BindingHttpPost} {
+		for _, service := range descriptor.IDPSSODescriptor.SingleSignOnServices {
+			if service.Binding == binding {
+				idp.SSOURL = service.Location
+				idp.SSOBinding = binding
+				break
+			}
+		}
+		if idp.SSOURL != "" {
+			break
+		}
+	}
+	if idp.SSOURL == "" {
+		return nil, ErrNoSingleSignOnService
+	}
+
+	for _, key := range descriptor.IDPSSODescriptor.KeyDescriptors {
+		if key.Use != "" && key.Use != "signing" {
+			continue
+		}
+		for _, cert := range key.KeyInfo.X509Data.X509Certificates {
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(cert.Data), ""))
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode certificate: %v", err)
+			}
+			parsed, err := x509.ParseCertificate(data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse certificate: %v", err)
+			}
+			idp.Certificates = append(idp.Certificates, parsed)
+		}
+	}
+	if len(idp.Certificates) == 0 {
+		return nil, ErrNoSigningCertificate
+	}
+
+	return idp, nil
+}
+
+// FetchIdentityProviderMetadata downloads the metadata of an identity provider
+func FetchIdentityProviderMetadata(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching %s: %s", url, resp.Status)
+	}
+
+	// Metadata of federations can be large, but anything beyond this is not an identity provider
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// GenerateServiceProviderKeyPair generates a self-signed certificate and private key
+// used to sign authentication requests and decrypt assertions
+func GenerateServiceProviderKeyPair(commonName string) (certPEM, keyPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	return certPEM, keyPEM, nil
+}
+
+// IdentityProvider parses the stored metadata of the identity provider
+func (source *Source) IdentityProvider() (*IdentityProvider, error) {
+	return ParseIdentityProviderMetadata([]byte(source.IdentityProviderMetadata))
+}
+
+// ServiceProvider creates the service provider for the given entity ID and assertion consumer URL
+func (source *Source) ServiceProvider(entityID, acsURL string) (*saml2.SAMLServiceProvider, error) {
+	idp, err := source.IdentityProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := tls.X509KeyPair([]byte(source.ServiceProviderCertificate), []byte(source.ServiceProviderPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid service provider key pair: %v", err)
+	}
+
+	return &saml2.SAMLServiceProvider{
+		IdentityProviderSSOURL:      idp.SSOURL,
+		IdentityProviderSSOBinding:  idp.SSOBinding,
+		IdentityProviderIssuer:      idp.EntityID,
+		AssertionConsumerServiceURL: acsURL,
+		ServiceProviderIssuer:       entityID,
+		AudienceURI:                 entityID,
+		SignAuthnRequests:           source.SignRequests,
+		NameIdFormat:                source.NameIDFormat,
+		IDPCertificateStore:         &dsig.MemoryX509CertificateStore{Roots: idp.Certificates},
+		SPKeyStore:                  dsig.TLSCertKeyStore(keyPair),
+		AllowMissingAttributes:      true,
+	}, nil
+}
+
+// AuthRequest builds an authentication request for the identity provider. Depending on
+// the binding of the identity provider it is returned as URL to redirect to, or as HTML
+// page which posts the request. The ID of the request has to be passed to Authenticate
+// along with the response.
+func (source *Source) AuthRequest(entityID, acsURL string) (requestID, redirectURL string, postBody []byte, err error) {
+	sp, err := source.ServiceProvider(entityID, acsURL)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if sp.IdentityProviderSSOBinding == saml2.BindingHttpPost {
+		doc, err := sp.BuildAuthRequestDocument()
+		if err != nil {
+			return "", "", nil, err
+		}
+		postBody, err = sp.BuildAuthBodyPostFromDocument("", doc)
+		return doc.Root().SelectAttrValue("ID", ""), "", postBody, err
+	}
+
+	// With the redirect binding the signature is part of the query, not of the request
+	doc, err := sp.BuildAuthRequestDocumentNoSig()
+	if err != nil {
+		return "", "", nil, err
+	}
+	redirectURL, err = sp.BuildAuthURLRedirect("", doc)
+	return doc.Root().SelectAttrValue("ID", ""), redirectURL, nil, err
+}
+
+var repostTemplate = template.Must(template.New("saml-repost-form").Parse(`` +
+	`<form method="POST" action="{{.URL}}" id="SAMLResponseForm">` +
+	`<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}" />` +
+	`<input type="hidden" name="same_site" value="1" />` +
+	`<input id="SAMLSubmitButton" type="submit" value="Submit" />` +
+	`</form>` +
+	`<script>document.getElementById('SAMLSubmitButton').style.visibility="hidden";` +
+	`document.getElementById('SAMLResponseForm').submit();</script>`))
+
+// RepostBody returns an HTML page which posts the response of the identity provider to the
+// assertion consumer service once more. Unlike the cross-site POST of the identity provider
+// this request carries cookies restricted to same-site requests.
+func RepostBody(acsURL, encodedResponse string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := repostTemplate.Execute(&buf, struct {
+		URL          string
+		SAMLResponse string
+	}{
+		URL:          acsURL,
+		SAMLResponse: encodedResponse,
+	})
+	return buf.Bytes(), err
+}
+
+// Metadata returns the metadata XML of the service provider
+func (source *Source) Metadata(entityID, acsURL string) ([]byte, error) {
+	sp, err := source.ServiceProvider(entityID, acsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := sp.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if source.NameIDFormat != "" {
+		metadata.SPSSODescriptor.NameIDFormats = []string{source.NameIDFormat}
+	}
+
+	data, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Authenticate validates the signature, issuer, audience, recipient and validity period
+// of an encoded SAML response and maps the attributes of its assertion. The response has
+// to answer the authentication request with the given ID, an empty ID only allows
+// unsolicited responses if the source accepts them. Every assertion is accepted once.
+func (source *Source) Authenticate(entityID, acsURL, encodedResponse, requestID string) (*Assertion, error) {
+	sp, err := source.ServiceProvider(entityID, acsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := sp.RetrieveAssertionInfo(encodedResponse)
+	if err != nil {
+		return nil, err
+	}
+	// the checks below only look at the first assertion, further ones must not carry other claims
+	if len(info.Assertions) != 1 {
+		return nil, ErrAssertionCount
+	}
+
+	if strings.TrimSpace(info.NameID) == "" {
+		return nil, errors.New("assertion has an empty NameID")
+	}
+	if info.WarningInfo.InvalidTime {
+		return nil, errors.New("assertion is expired or not yet valid")
+	}
+	if info.WarningInfo.NotInAudience {
+		return nil, errors.New("assertion is not intended for this service provider")
+	}
+	if source.WantAssertionsSigned && !info.Assertions[0].SignatureValidated {
+		return nil, ErrAssertionNotSigned
+	}
+	if err := source.verifyInResponseTo(&info.Assertions[0], requestID); err != nil {
+		return nil, err
+	}
+	if !usedAssertions.markUsed(entityID+"/"+info.Assertions[0].ID, assertionExpiry(&info.Assertions[0])) {
+		return nil, ErrAssertionReplayed
+	}
+
+	assertion := source.MapAttributes(info.NameID, info.Values)
+	if source.RequiredGroup != "" && !assertion.InGroup(source.RequiredGroup) {
+		return nil, ErrNotInRequiredGroup
+	}
+	return assertion, nil
+}
+
+// verifyInResponseTo checks that the assertion answers the authentication request
+func (source *Source) verifyInResponseTo(assertion *types.Assertion, requestID string) error {
+	inResponseTo := ""
+	if assertion.Subject != nil && assertion.Subject.SubjectConfirmation != nil && assertion.Subject.SubjectConfirmation.SubjectConfirmationData != nil {
+		inResponseTo = assertion.Subject.SubjectConfirmation.SubjectConfirmationData.InResponseTo
+	}
+
+	if inResponseTo == "" {
+		if !source.AllowUnsolicited {
+			return ErrUnsolicitedResponse
+		}
+		return nil
+	}
+	if inResponseTo != requestID {
+		return ErrInResponseToMismatch
+	}
+	return nil
+}
+
+// assertionReplayWindow is how long the ID of an assertion without expiry is remembered
+const assertionReplayWindow = 24 * time.Hour
+
+// assertionExpiry returns the time after which the assertion is no longer accepted
+func assertionExpiry(assertion *types.Assertion) time.Time {
+	var expires time.Time
+	notOnOrAfter := make([]string, 0, 2)
+	if assertion.Conditions != nil {
+		notOnOrAfter = append(notOnOrAfter, assertion.Conditions.NotOnOrAfter)
+	}
+	if assertion.Subject != nil && assertion.Subject.SubjectConfirmation != nil && assertion.Subject.SubjectConfirmation.SubjectConfirmationData != nil {
+		notOnOrAfter = append(notOnOrAfter, assertion.Subject.SubjectConfirmation.SubjectConfirmationData.NotOnOrAfter)
+	}
+	for _, value := range notOnOrAfter {
+		if t, err := time.Parse(time.RFC3339, value); err == nil && t.After(expires) {
+			expires = t
+		}
+	}
+	if expires.IsZero() {
+		return time.Now().Add(assertionReplayWindow)
+	}
+	return expires
+}
+
+// assertionIDCache remembers the IDs of used assertions until they expire. The configured
+// cache is preferred, so all instances sharing it reject an assertion used on another one.
+type assertionIDCache struct {
+	lock sync.Mutex
+	ids  map[string]time.Time
+}
+
+var usedAssertions = &assertionIDCache{ids: make(map[string]time.Time)}
+
+// markUsed records the assertion ID and returns false if it has been used before
+func (c *assertionIDCache) markUsed(id string, expires time.Time) bool {
+	if mc := cache.GetCache(); mc != nil {
+		key := "saml_assertion_" + id
+		if mc.IsExist(key) {
+			return false
+		}
+		if err := mc.Put(key, true, int64(time.Until(expires).Seconds())+1); err != nil {
+			log.Error("Unable to cache ID of SAML assertion: %v", err)
+		}
+		return true
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	for used, usedExpires := range c.ids {
+		if usedExpires.Before(now) {
+			delete(c.ids, used)
+		}
+	}
+	if _, ok := c.ids[id]; ok {
+		return false
+	}
+	c.ids[id] = expires
+	return true
+}
+
+// MapAttributes maps the attributes of an assertion to the user information
+func (source *Source) MapAttributes(nameID string, values saml2.Values) *Assertion {
+	assertion := &Assertion{
+		NameID:   nameID,
+		Username: nameID,
+	}
+	if source.AttributeUsername != "" && values.Get(source.AttributeUsername) != "" {
+		assertion.Username = values.Get(source.AttributeUsername)
+	}
+	// NameIDs are often e-mail addresses, which are no valid usernames
+	if idx := strings.Index(assertion.Username, "@"); idx > 0 {
+		assertion.Username = assertion.Username[:idx]
+	}
+	if source.AttributeEmail != "" {
+		assertion.Email = strings.TrimSpace(values.Get(source.AttributeEmail))
+	}
+	if source.AttributeFullName != "" {
+		assertion.FullName = strings.TrimSpace(values.Get(source.AttributeFullName))
+	}
+	if source.AttributeGroups != "" {
+		assertion.Groups = values.GetAll(source.AttributeGroups)
+	}
+
+	assertion.IsAdmin = source.AdminGroup != "" && assertion.InGroup(source.AdminGroup)
+	assertion.IsRestricted = source.RestrictedGroup != "" && assertion.InGroup(source.RestrictedGroup)
+	return assertion
+}
+
+// InGroup returns true if the assertion lists the given group
+func (assertion *Assertion) InGroup(group string) bool {
+	for _, g := range assertion.Groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
+}
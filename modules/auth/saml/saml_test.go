@@ -0,0 +1,198 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	saml2 "github.com/russellhaering/gosaml2"
+	"github.com/russellhaering/gosaml2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testIdentityProviderMetadata(t *testing.T, wrap bool) string {
+	cert, _, err := GenerateServiceProviderKeyPair("idp.example.com")
+	assert.NoError(t, err)
+	block, _ := pem.Decode([]byte(cert))
+	assert.NotNil(t, block)
+
+	entity := fmt.Sprintf(`<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com/metadata">
+  <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <KeyDescriptor use="signing">
+      <KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#">
+        <X509Data><X509Certificate>%s</X509Certificate></X509Data>
+      </KeyInfo>
+    </KeyDescriptor>
+    <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://idp.example.com/sso/post"/>
+    <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso/redirect"/>
+  </IDPSSODescriptor>
+</EntityDescriptor>`, encodeBase64Lines(block.Bytes))
+	if wrap {
+		return `<EntitiesDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata">` + entity + `</EntitiesDescriptor>`
+	}
+	return entity
+}
+
+func encodeBase64Lines(data []byte) string {
+	encoded := string(pem.EncodeToMemory(&pem.Block{Type: "X", Bytes: data}))
+	lines := strings.Split(strings.TrimSpace(encoded), "\n")
+	return strings.Join(lines[1:len(lines)-1], "\n")
+}
+
+func TestParseIdentityProviderMetadata(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		idp, err := ParseIdentityProviderMetadata([]byte(testIdentityProviderMetadata(t, wrap)))
+		assert.NoError(t, err)
+		assert.Equal(t, "https://idp.example.com/metadata", idp.EntityID)
+		assert.Equal(t, "https://idp.example.com/sso/redirect", idp.SSOURL)
+		assert.Equal(t, saml2.BindingHttpRedirect, idp.SSOBinding)
+		assert.Len(t, idp.Certificates, 1)
+		assert.Equal(t, "idp.example.com", idp.Certificates[0].Subject.CommonName)
+	}
+
+	_, err := ParseIdentityProviderMetadata([]byte(`<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="sp"/>`))
+	assert.Equal(t, ErrNoIdentityProvider, err)
+
+	_, err = ParseIdentityProviderMetadata([]byte("not xml"))
+	assert.Error(t, err)
+}
+
+func TestSourceMetadata(t *testing.T) {
+	cert, key, err := GenerateServiceProviderKeyPair("gitea")
+	assert.NoError(t, err)
+
+	source := &Source{
+		IdentityProviderMetadata:   testIdentityProviderMetadata(t, false),
+		ServiceProviderCertificate: cert,
+		ServiceProviderPrivateKey:  key,
+	}
+	metadata, err := source.Metadata("https://gitea.example.com/user/saml/corp/metadata", "https://gitea.example.com/user/saml/corp/acs")
+	assert.NoError(t, err)
+	assert.Contains(t, string(metadata), `entityID="https://gitea.example.com/user/saml/corp/metadata"`)
+	assert.Contains(t, string(metadata), `Location="https://gitea.example.com/user/saml/corp/acs"`)
+
+	source.ServiceProviderPrivateKey = ""
+	_, err = source.Metadata("sp", "acs")
+	assert.Error(t, err)
+}
+
+func TestSourceMapAttributes(t *testing.T) {
+	values := func(m map[string][]string) saml2.Values {
+		vals := saml2.Values{}
+		for name, list := range m {
+			attr := types.Attribute{Name: name}
+			for _, v := range list {
+				attr.Values = append(attr.Values, types.AttributeValue{Value: v})
+			}
+			vals[name] = attr
+		}
+		return vals
+	}
+
+	source := &Source{
+		AttributeUsername: "uid",
+		AttributeEmail:    "mail",
+		AttributeFullName: "displayName",
+		AttributeGroups:   "memberOf",
+		AdminGroup:        "gitea-admins",
+		RestrictedGroup:   "contractors",
+	}
+
+	assertion := source.MapAttributes("jdoe@example.com", values(map[string][]string{
+		"uid":         {"john"},
+		"mail":        {" john@example.com "},
+		"displayName": {"John Doe"},
+		"memberOf":    {"developers", "Gitea-Admins"},
+	}))
+	assert.Equal(t, "jdoe@example.com", assertion.NameID)
+	assert.Equal(t, "john", assertion.Username)
+	assert.Equal(t, "john@example.com", assertion.Email)
+	assert.Equal(t, "John Doe", assertion.FullName)
+	assert.Equal(t, []string{"developers", "Gitea-Admins"}, assertion.Groups)
+	assert.True(t, assertion.IsAdmin)
+	assert.False(t, assertion.IsRestricted)
+
+	// Without a username attribute the local part of the NameID is used
+	assertion = source.MapAttributes("jdoe@example.com", values(map[string][]string{
+		"memberOf": {"contractors"},
+	}))
+	assert.Equal(t, "jdoe", assertion.Username)
+	assert.Empty(t, assertion.Email)
+	assert.False(t, assertion.IsAdmin)
+	assert.True(t, assertion.IsRestricted)
+}
+
+func TestSourceAuthRequest(t *testing.T) {
+	cert, key, err := GenerateServiceProviderKeyPair("gitea")
+	assert.NoError(t, err)
+
+	source := &Source{
+		IdentityProviderMetadata:   testIdentityProviderMetadata(t, false),
+		ServiceProviderCertificate: cert,
+		ServiceProviderPrivateKey:  key,
+	}
+	requestID, redirectURL, postBody, err := source.AuthRequest("sp", "acs")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, requestID)
+	assert.True(t, strings.HasPrefix(redirectURL, "https://idp.example.com/sso/redirect?SAMLRequest="))
+	assert.Nil(t, postBody)
+
+	otherID, _, _, err := source.AuthRequest("sp", "acs")
+	assert.NoError(t, err)
+	assert.NotEqual(t, requestID, otherID)
+}
+
+func TestSourceVerifyInResponseTo(t *testing.T) {
+	assertion := func(inResponseTo string) *types.Assertion {
+		return &types.Assertion{
+			Subject: &types.Subject{
+				SubjectConfirmation: &types.SubjectConfirmation{
+					SubjectConfirmationData: &types.SubjectConfirmationData{InResponseTo: inResponseTo},
+				},
+			},
+		}
+	}
+
+	source := &Source{}
+	assert.NoError(t, source.verifyInResponseTo(assertion("id-1"), "id-1"))
+	assert.Equal(t, ErrInResponseToMismatch, source.verifyInResponseTo(assertion("id-1"), "id-2"))
+	assert.Equal(t, ErrInResponseToMismatch, source.verifyInResponseTo(assertion("id-1"), ""))
+	assert.Equal(t, ErrUnsolicitedResponse, source.verifyInResponseTo(assertion(""), "id-1"))
+	assert.Equal(t, ErrUnsolicitedResponse, source.verifyInResponseTo(&types.Assertion{}, ""))
+
+	source.AllowUnsolicited = true
+	assert.NoError(t, source.verifyInResponseTo(assertion(""), ""))
+	assert.Equal(t, ErrInResponseToMismatch, source.verifyInResponseTo(assertion("id-1"), "id-2"))
+}
+
+func TestAssertionReplay(t *testing.T) {
+	notOnOrAfter := time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second)
+	assertion := &types.Assertion{
+		Conditions: &types.Conditions{NotOnOrAfter: notOnOrAfter.Format(time.RFC3339)},
+	}
+	assert.True(t, assertionExpiry(assertion).Equal(notOnOrAfter))
+	assert.True(t, assertionExpiry(&types.Assertion{}).After(time.Now().Add(assertionReplayWindow-time.Minute)))
+
+	ids := &assertionIDCache{ids: make(map[string]time.Time)}
+	assert.True(t, ids.markUsed("sp/assertion-1", notOnOrAfter))
+	assert.False(t, ids.markUsed("sp/assertion-1", notOnOrAfter))
+	assert.True(t, ids.markUsed("sp/assertion-2", notOnOrAfter))
+
+	// expired IDs are forgotten, the assertions are rejected as expired anyway
+	assert.True(t, ids.markUsed("sp/assertion-3", time.Now().Add(-time.Minute)))
+	assert.True(t, ids.markUsed("sp/assertion-3", notOnOrAfter))
+}
+
+func TestRepostBody(t *testing.T) {
+	body, err := RepostBody("https://gitea.example.com/user/saml/corp/acs", `PHNhbWw+"<>`)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `action="https://gitea.example.com/user/saml/corp/acs"`)
+	assert.Contains(t, string(body), `name="same_site" value="1"`)
+	assert.NotContains(t, string(body), `"<>`)
+}
@@ -13,7 +13,7 @@ import (
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
-	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/services/lockout"
 )
 
 // Ensure the struct implements the interface.
@@ -83,24 +83,29 @@ func (b *Basic) VerifyAuthData(req *http.Request, w http.ResponseWriter, store D
 	}
 	token, err := models.GetAccessTokenBySHA(authToken)
 	if err == nil {
+		if !isAccessTokenAllowed(req, token) {
+			return nil
+		}
 		u, err = models.GetUserByID(token.UID)
 		if err != nil {
 			log.Error("GetUserByID:  %v", err)
 			return nil
 		}
 
-		token.UpdatedUnix = timeutil.TimeStampNow()
-		if err = models.UpdateAccessToken(token); err != nil {
-			log.Error("UpdateAccessToken:  %v", err)
+		if err = models.UpdateAccessTokenLastUsed(token); err != nil {
+			log.Error("UpdateAccessTokenLastUsed:  %v", err)
 		}
+		store.GetData()["ApiToken"] = token
 	} else if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) {
 		log.Error("GetAccessTokenBySha: %v", err)
 	}
 
 	if u == nil {
-		u, err = models.UserSignIn(uname, passwd)
+		u, err = lockout.UserSignIn(uname, passwd, req.RemoteAddr)
 		if err != nil {
-			if !models.IsErrUserNotExist(err) {
+			if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+				log.Info("Failed authentication attempt for %s from %s: %v", uname, req.RemoteAddr, err)
+			} else if !models.IsErrUserNotExist(err) {
 				log.Error("UserSignIn: %v", err)
 			}
 			return nil
@@ -109,5 +114,9 @@ func (b *Basic) VerifyAuthData(req *http.Request, w http.ResponseWriter, store D
 		store.GetData()["IsApiToken"] = true
 	}
 
+	if isTwoFactorEnrollmentMissing(u, req.RemoteAddr) {
+		return nil
+	}
+
 	return u
 }
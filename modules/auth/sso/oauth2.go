@@ -12,7 +12,6 @@ import (
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/log"
-	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web/middleware"
 )
 
@@ -99,11 +98,14 @@ func (o *OAuth2) userIDFromToken(req *http.Request, store DataStore) int64 {
 		}
 		return 0
 	}
-	t.UpdatedUnix = timeutil.TimeStampNow()
-	if err = models.UpdateAccessToken(t); err != nil {
-		log.Error("UpdateAccessToken: %v", err)
+	if !isAccessTokenAllowed(req, t) {
+		return 0
+	}
+	if err = models.UpdateAccessTokenLastUsed(t); err != nil {
+		log.Error("UpdateAccessTokenLastUsed: %v", err)
 	}
 	store.GetData()["IsApiToken"] = true
+	store.GetData()["ApiToken"] = t
 	return t.UID
 }
 
@@ -139,5 +141,9 @@ func (o *OAuth2) VerifyAuthData(req *http.Request, w http.ResponseWriter, store
 		return nil
 	}
 
+	if isTwoFactorEnrollmentMissing(user, req.RemoteAddr) {
+		return nil
+	}
+
 	return user
 }
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"code.gitea.io/gitea/models"
@@ -98,6 +99,27 @@ func isAttachmentDownload(req *http.Request) bool {
 	return strings.HasPrefix(req.URL.Path, "/attachments/") && req.Method == "GET"
 }
 
+var lfsPathPattern = regexp.MustCompile(`^/[^/]+/[^/]+/info/lfs/`)
+
+// isLFSPath check if request is for the LFS server of a repository
+func isLFSPath(req *http.Request) bool {
+	return lfsPathPattern.MatchString(req.URL.Path)
+}
+
+// isAccessTokenAllowed returns false for access tokens limited to some scopes or restricted to a
+// repository or organization if the request is not handled by the API or the LFS server, which
+// are the only places enforcing these limits
+func isAccessTokenAllowed(req *http.Request, token *models.AccessToken) bool {
+	if middleware.IsAPIPath(req) || isLFSPath(req) {
+		return true
+	}
+	if token.Scope != models.AccessTokenScopeAll || token.IsRestricted() {
+		log.Info("Rejecting scoped or restricted access token %d of user %d for %s %s", token.ID, token.UID, req.Method, req.URL.Path)
+		return false
+	}
+	return true
+}
+
 // handleSignIn clears existing session variables and stores new ones for the specified user object
 func handleSignIn(resp http.ResponseWriter, req *http.Request, sess SessionStore, user *models.User) {
 	_ = sess.Delete("openid_verified_uri")
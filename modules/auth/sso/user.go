@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
 )
 
 // SignedInUser returns the user object of signed user.
@@ -31,3 +32,17 @@ func SignedInUser(req *http.Request, w http.ResponseWriter, ds DataStore, sess S
 
 	return nil, false
 }
+
+// isTwoFactorEnrollmentMissing returns true if the user has to enrol two-factor authentication
+// before passwords and tokens of the user are accepted, see ENFORCE_TWO_FACTOR_AUTH
+func isTwoFactorEnrollmentMissing(u *models.User, remoteAddr string) bool {
+	mustEnroll, err := u.MustEnrollTwoFactor()
+	if err != nil {
+		log.Error("MustEnrollTwoFactor: %v", err)
+		return true
+	}
+	if mustEnroll {
+		log.Info("Rejecting authentication of %s from %s without two-factor authentication enrolled", u.Name, remoteAddr)
+	}
+	return mustEnroll
+}
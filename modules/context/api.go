@@ -184,6 +184,12 @@ func (ctx *APIContext) SetLinkHeader(total, pageSize int) {
 	}
 }
 
+// AccessToken returns the personal access token the request has been authenticated with, if any
+func (ctx *APIContext) AccessToken() *models.AccessToken {
+	token, _ := ctx.Data["ApiToken"].(*models.AccessToken)
+	return token
+}
+
 // RequireCSRF requires a validated a CSRF token
 func (ctx *APIContext) RequireCSRF() {
 	headerToken := ctx.Req.Header.Get(ctx.csrf.GetHeaderName())
@@ -268,6 +274,13 @@ func APIContexter() func(http.Handler) http.Handler {
 
 			ctx.Data["CsrfToken"] = html.EscapeString(ctx.csrf.GetToken())
 
+			if ctx.IsSigned {
+				ctx.handleImpersonation()
+				if ctx.Written() {
+					return
+				}
+			}
+
 			next.ServeHTTP(ctx.Resp, ctx.Req)
 		})
 	}
@@ -6,6 +6,8 @@
 package context
 
 import (
+	"strings"
+
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -51,6 +53,17 @@ func Toggle(options *ToggleOptions) func(ctx *Context) {
 				// make sure that the form cannot be accessed by users who don't need this
 				ctx.Redirect(setting.AppSubURL + "/")
 				return
+			} else if setting.EnforceTwoFactorAuth && !isTwoFactorEnrollmentPath(ctx.Req.URL.Path) {
+				mustEnroll, err := ctx.User.MustEnrollTwoFactor()
+				if err != nil {
+					ctx.ServerError("MustEnrollTwoFactor", err)
+					return
+				}
+				if mustEnroll {
+					ctx.Flash.Warning(ctx.Tr("auth.twofa_enrollment_required"))
+					ctx.Redirect(setting.AppSubURL + "/user/settings/security")
+					return
+				}
 			}
 		}
 
@@ -128,6 +141,20 @@ func ToggleAPI(options *ToggleOptions) func(ctx *APIContext) {
 				})
 				return
 			}
+
+			if setting.EnforceTwoFactorAuth {
+				mustEnroll, err := ctx.User.MustEnrollTwoFactor()
+				if err != nil {
+					ctx.InternalServerError(err)
+					return
+				}
+				if mustEnroll {
+					ctx.JSON(403, map[string]string{
+						"message": "You must enroll two-factor authentication. Enroll at: " + setting.AppURL + "user/settings/security",
+					})
+					return
+				}
+			}
 		}
 
 		// Redirect to dashboard if user tries to visit any non-login page.
@@ -183,3 +210,9 @@ func ToggleAPI(options *ToggleOptions) func(ctx *APIContext) {
 		}
 	}
 }
+
+// isTwoFactorEnrollmentPath returns true for the pages users who have to enrol two-factor
+// authentication can still visit
+func isTwoFactorEnrollmentPath(path string) bool {
+	return strings.HasPrefix(path, "/user/settings/security") || path == "/user/logout" || path == "/user/events"
+}
@@ -701,6 +701,13 @@ func Contexter() func(next http.Handler) http.Handler {
 				}
 			}
 
+			if ctx.IsSigned && !ctx.IsBasicAuth {
+				ctx.handleImpersonation()
+				if ctx.Written() {
+					return
+				}
+			}
+
 			next.ServeHTTP(ctx.Resp, ctx.Req)
 		})
 	}
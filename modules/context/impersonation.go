@@ -0,0 +1,239 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package context
+
+import (
+	"net/http"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"gitea.com/go-chi/session"
+)
+
+// Session keys of an administrator viewing the site as another user
+const (
+	impersonatorUIDSessionKey         = "impersonator_uid"
+	impersonationExpiresSessionKey    = "impersonation_expires"
+	impersonationAllowWriteSessionKey = "impersonation_allow_write"
+)
+
+const impersonationNoticeFormat = "Administrator %s %s viewing the site as %s from %s"
+
+// StartImpersonation lets the signed in administrator view the site as the user until
+// IMPERSONATION_DURATION has passed. Changes are rejected unless allowWrite is set.
+func (ctx *Context) StartImpersonation(u *models.User, allowWrite bool) error {
+	admin := ctx.User
+	expires := timeutil.TimeStampNow().AddDuration(setting.Admin.ImpersonationDuration)
+
+	for key, value := range map[string]interface{}{
+		impersonatorUIDSessionKey:         admin.ID,
+		impersonationExpiresSessionKey:    int64(expires),
+		impersonationAllowWriteSessionKey: allowWrite,
+		"uid":                             u.ID,
+		"uname":                           u.Name,
+	} {
+		if err := ctx.Session.Set(key, value); err != nil {
+			return err
+		}
+	}
+
+	action := "started"
+	if allowWrite {
+		action = "started (changes allowed)"
+	}
+	log.Info(impersonationNoticeFormat, admin.Name, action, u.Name, ctx.RemoteAddr())
+	return models.CreateNotice(models.NoticeImpersonation, impersonationNoticeFormat, admin.Name, action, u.Name, ctx.RemoteAddr())
+}
+
+// impersonation is the session state of an administrator viewing the site as another user
+type impersonation struct {
+	adminID    int64
+	expires    timeutil.TimeStamp
+	allowWrite bool
+}
+
+func getImpersonation(sess session.Store) (*impersonation, bool) {
+	adminID, ok := sess.Get(impersonatorUIDSessionKey).(int64)
+	if !ok {
+		return nil, false
+	}
+	expires, _ := sess.Get(impersonationExpiresSessionKey).(int64)
+	allowWrite, _ := sess.Get(impersonationAllowWriteSessionKey).(bool)
+	return &impersonation{
+		adminID:    adminID,
+		expires:    timeutil.TimeStamp(expires),
+		allowWrite: allowWrite,
+	}, true
+}
+
+// hasEnded returns true if the time is up or impersonation has been disabled meanwhile
+func (imp *impersonation) hasEnded() bool {
+	return !setting.Admin.EnableImpersonation || imp.expires <= timeutil.TimeStampNow()
+}
+
+// isChangeRequest returns true for requests which may change data
+func isChangeRequest(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+// isAuthorizationRequest returns true for the pages granting applications access to the account
+func isAuthorizationRequest(path string) bool {
+	return strings.HasPrefix(path, "/login/oauth/authorize") ||
+		strings.HasPrefix(path, "/login/oauth/grant") ||
+		strings.HasPrefix(path, "/login/device")
+}
+
+// IsImpersonating returns true if an administrator is viewing the site as the signed in user
+func (ctx *Context) IsImpersonating() bool {
+	_, ok := getImpersonation(ctx.Session)
+	return ok
+}
+
+// StopImpersonation signs the administrator back in after viewing the site as another user
+func (ctx *Context) StopImpersonation() error {
+	return ctx.stopImpersonation("stopped")
+}
+
+func (ctx *Context) stopImpersonation(action string) error {
+	adminID, _ := ctx.Session.Get(impersonatorUIDSessionKey).(int64)
+	admin, err := models.GetUserByID(adminID)
+	if models.IsErrUserNotExist(err) {
+		ctx.signOutImpersonation(adminID)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, key := range []string{impersonatorUIDSessionKey, impersonationExpiresSessionKey, impersonationAllowWriteSessionKey} {
+		if err := ctx.Session.Delete(key); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Session.Set("uid", admin.ID); err != nil {
+		return err
+	}
+	if err := ctx.Session.Set("uname", admin.Name); err != nil {
+		return err
+	}
+
+	log.Info(impersonationNoticeFormat, admin.Name, action, ctx.User.Name, ctx.RemoteAddr())
+	if err := models.CreateNotice(models.NoticeImpersonation, impersonationNoticeFormat, admin.Name, action, ctx.User.Name, ctx.RemoteAddr()); err != nil {
+		return err
+	}
+
+	ctx.User = admin
+	ctx.Data["SignedUser"] = admin
+	ctx.Data["SignedUserID"] = admin.ID
+	ctx.Data["SignedUserName"] = admin.Name
+	ctx.Data["IsAdmin"] = admin.IsAdmin
+	return nil
+}
+
+// signOutImpersonation ends viewing the site as another user for an administrator who no
+// longer exists by signing the session out, which cannot be handed back to the administrator
+func (ctx *Context) signOutImpersonation(adminID int64) {
+	log.Warn("Signing out the session of deleted administrator %d viewing the site as %s from %s", adminID, ctx.User.Name, ctx.RemoteAddr())
+	_ = ctx.Session.Flush()
+	_ = ctx.Session.Destroy(ctx.Resp, ctx.Req)
+	ctx.DeleteCookie(setting.CookieUserName)
+	ctx.DeleteCookie(setting.CookieRememberName)
+	ctx.Redirect(setting.AppSubURL + "/user/login")
+}
+
+// handleImpersonation ends expired impersonation sessions, shows the banner and rejects
+// changes the administrator has not allowed
+func (ctx *Context) handleImpersonation() {
+	imp, ok := getImpersonation(ctx.Session)
+	if !ok {
+		return
+	}
+
+	if imp.hasEnded() {
+		if err := ctx.stopImpersonation("stopped (expired)"); err != nil {
+			ctx.ServerError("StopImpersonation", err)
+			return
+		} else if ctx.Written() {
+			return
+		}
+		ctx.Flash.Info(ctx.Tr("admin.impersonation.expired"), true)
+		return
+	}
+
+	admin, err := models.GetUserByID(imp.adminID)
+	if models.IsErrUserNotExist(err) {
+		ctx.signOutImpersonation(imp.adminID)
+		return
+	} else if err != nil {
+		ctx.ServerError("GetUserByID", err)
+		return
+	}
+
+	ctx.Data["Impersonator"] = admin
+	ctx.Data["ImpersonationExpires"] = imp.expires
+	ctx.Data["ImpersonationAllowWrite"] = imp.allowWrite
+
+	// applications must not be authorized on behalf of the user, even if changes are allowed
+	if isAuthorizationRequest(ctx.Req.URL.Path) {
+		log.Warn("Administrator %s viewing the site as %s was prevented from %s %s", admin.Name, ctx.User.Name, ctx.Req.Method, ctx.Req.URL.Path)
+		ctx.Flash.Error(ctx.Tr("admin.impersonation.oauth_blocked"))
+		ctx.Redirect(setting.AppSubURL + "/")
+		return
+	}
+
+	if !isChangeRequest(ctx.Req.Method) {
+		return
+	}
+	if ctx.Req.URL.Path == "/user/impersonation/stop" || ctx.Req.URL.Path == "/user/logout" {
+		return
+	}
+	if imp.allowWrite {
+		log.Info("Administrator %s viewing the site as %s: %s %s", admin.Name, ctx.User.Name, ctx.Req.Method, ctx.Req.URL.Path)
+		return
+	}
+
+	log.Warn("Administrator %s viewing the site as %s was prevented from %s %s", admin.Name, ctx.User.Name, ctx.Req.Method, ctx.Req.URL.Path)
+	ctx.Flash.Error(ctx.Tr("admin.impersonation.change_blocked"))
+	ctx.RedirectToFirst(ctx.Req.Referer())
+}
+
+// handleImpersonation applies the limits of viewing the site as another user to API requests
+// authenticated by the session of the administrator. Access tokens and basic auth of the user
+// are not affected.
+func (ctx *APIContext) handleImpersonation() {
+	if ctx.IsBasicAuth || ctx.Data["IsApiToken"] == true {
+		return
+	}
+	imp, ok := getImpersonation(ctx.Session)
+	if !ok {
+		return
+	}
+
+	if imp.hasEnded() {
+		ctx.JSON(http.StatusUnauthorized, map[string]string{
+			"message": "Viewing the site as another user has ended.",
+		})
+		return
+	}
+	if !isChangeRequest(ctx.Req.Method) {
+		return
+	}
+	if imp.allowWrite {
+		log.Info("Administrator %d viewing the site as %s: %s %s", imp.adminID, ctx.User.Name, ctx.Req.Method, ctx.Req.URL.Path)
+		return
+	}
+
+	log.Warn("Administrator %d viewing the site as %s was prevented from %s %s", imp.adminID, ctx.User.Name, ctx.Req.Method, ctx.Req.URL.Path)
+	ctx.JSON(http.StatusForbidden, map[string]string{
+		"message": "Changes are blocked while viewing the site as another user.",
+	})
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"code.gitea.io/gitea/models"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// ToPullMergeQueueEntry convert a merge queue entry to api format
+func ToPullMergeQueueEntry(entry *models.PullMergeQueueEntry, position int, doer *models.User) (*api.PullMergeQueueEntry, error) {
+	if err := entry.LoadAttributes(); err != nil {
+		return nil, err
+	}
+	if err := entry.PullRequest.LoadIssue(); err != nil {
+		return nil, err
+	}
+	if err := entry.PullRequest.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+	entry.PullRequest.Issue.Repo = entry.PullRequest.BaseRepo
+
+	return &api.PullMergeQueueEntry{
+		Position:            position,
+		Number:              entry.PullRequest.Index,
+		Title:               entry.PullRequest.Issue.Title,
+		HTMLURL:             entry.PullRequest.Issue.HTMLURL(),
+		BaseBranch:          entry.BaseBranch,
+		MergeStyle:          string(entry.MergeStyle),
+		QueuedBy:            ToUser(entry.Doer, doer != nil, doer != nil && (doer.IsAdmin || doer.ID == entry.DoerID)),
+		BaseCommitID:        entry.BaseCommitID,
+		SpeculativeCommitID: entry.SpeculativeCommitID,
+		Queued:              entry.CreatedUnix.AsTime(),
+	}, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package convert
+
+import (
+	"code.gitea.io/gitea/models"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// ToAccessToken convert a personal access token to api format, the token itself is only known after its creation
+func ToAccessToken(t *models.AccessToken) (*api.AccessToken, error) {
+	if err := t.LoadAttributes(); err != nil {
+		return nil, err
+	}
+
+	token := &api.AccessToken{
+		ID:             t.ID,
+		Name:           t.Name,
+		Token:          t.Token,
+		TokenLastEight: t.TokenLastEight,
+		Scopes:         t.Scope.Scopes(),
+	}
+	if t.Repo != nil {
+		token.Repository = t.Repo.FullName()
+	}
+	if t.Org != nil {
+		token.Organization = t.Org.Name
+	}
+	if t.ExpiresUnix > 0 {
+		expires := t.ExpiresUnix.AsTime()
+		token.Expires = &expires
+	}
+	if t.LastUsedUnix > 0 {
+		lastUsed := t.LastUsedUnix.AsTime()
+		token.LastUsed = &lastUsed
+	}
+	return token, nil
+}
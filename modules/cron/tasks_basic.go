@@ -13,6 +13,7 @@ import (
 	repository_service "code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
 	mirror_service "code.gitea.io/gitea/services/mirror"
+	pull_service "code.gitea.io/gitea/services/pull"
 )
 
 func registerUpdateMirrorTask() {
@@ -125,6 +126,41 @@ func registerCleanupHookTaskTable() {
 	})
 }
 
+func registerDeleteExpiredAccessTokens() {
+	RegisterTaskFatal("delete_expired_access_tokens", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+		},
+		OlderThan: 720 * time.Hour,
+	}, func(ctx context.Context, _ *models.User, config Config) error {
+		realConfig := config.(*OlderThanConfig)
+		return models.DeleteExpiredAccessTokens(ctx, realConfig.OlderThan)
+	})
+}
+
+func registerDeleteExpiredOAuth2DeviceCodes() {
+	RegisterTaskFatal("delete_expired_oauth2_device_codes", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@every 1h",
+	}, func(ctx context.Context, _ *models.User, _ Config) error {
+		return models.DeleteExpiredOAuth2DeviceCodes(ctx)
+	})
+}
+
+func registerCheckMergeQueues() {
+	RegisterTaskFatal("check_merge_queues", &BaseConfig{
+		Enabled:         true,
+		RunAtStart:      false,
+		Schedule:        "@every 10m",
+		NoSuccessNotice: true,
+	}, func(ctx context.Context, _ *models.User, _ Config) error {
+		return pull_service.CheckMergeQueues(ctx)
+	})
+}
+
 func initBasicTasks() {
 	registerUpdateMirrorTask()
 	registerRepoHealthCheck()
@@ -136,4 +172,9 @@ func initBasicTasks() {
 		registerUpdateMigrationPosterID()
 	}
 	registerCleanupHookTaskTable()
+	registerDeleteExpiredAccessTokens()
+	if setting.OAuth2.Enable {
+		registerDeleteExpiredOAuth2DeviceCodes()
+	}
+	registerCheckMergeQueues()
 }
@@ -117,6 +117,27 @@ func registerRemoveRandomAvatars() {
 	})
 }
 
+func registerGarbageCollectStorages() {
+	type StorageGCConfig struct {
+		OlderThanConfig
+		FixMissing bool
+	}
+	RegisterTaskFatal("gc_storages", &StorageGCConfig{
+		OlderThanConfig: OlderThanConfig{
+			BaseConfig: BaseConfig{
+				Enabled:    false,
+				RunAtStart: false,
+				Schedule:   "@every 168h",
+			},
+			OlderThan: 24 * time.Hour,
+		},
+		FixMissing: false,
+	}, func(ctx context.Context, _ *models.User, config Config) error {
+		gcConfig := config.(*StorageGCConfig)
+		return models.GarbageCollectStorages(ctx, gcConfig.OlderThan, gcConfig.FixMissing)
+	})
+}
+
 func initExtendedTasks() {
 	registerDeleteInactiveUsers()
 	registerDeleteRepositoryArchives()
@@ -127,4 +148,5 @@ func initExtendedTasks() {
 	registerReinitMissingRepositories()
 	registerDeleteMissingRepositories()
 	registerRemoveRandomAvatars()
+	registerGarbageCollectStorages()
 }
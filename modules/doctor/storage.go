@@ -0,0 +1,121 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package doctor
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/models/migrations"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/storage"
+)
+
+// storageOrphanMinAge is the minimum age of an unreferenced object before it is
+// considered orphaned, as objects are stored before their database rows are inserted
+const storageOrphanMinAge = time.Hour
+
+func initStorage(logger log.Logger) error {
+	if err := models.NewEngine(context.Background(), migrations.EnsureUpToDate); err != nil {
+		logger.Critical("Model version on the database does not match the current Gitea version. Storages will not be checked until the database is upgraded")
+		return err
+	}
+	if err := storage.Init(); err != nil {
+		logger.Critical("Error: %v whilst initializing the storages", err)
+		return err
+	}
+	return nil
+}
+
+func checkStorageOrphans(logger log.Logger, autofix bool) error {
+	if err := initStorage(logger); err != nil {
+		return err
+	}
+
+	for _, name := range models.StorageConsistencyNames() {
+		orphans, err := models.FindOrphanedStorageObjects(context.Background(), name, storageOrphanMinAge)
+		if err != nil {
+			logger.Critical("Error: %v whilst searching orphaned objects in %s storage", err, name)
+			return err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+
+		var size int64
+		for _, orphan := range orphans {
+			size += orphan.Size
+			if !autofix {
+				logger.Warn("Orphaned object %s in %s storage (%d bytes)", orphan.Path, name, orphan.Size)
+				continue
+			}
+			if err := orphan.Delete(); err != nil {
+				logger.Critical("Error: %v whilst deleting orphaned object %s from %s storage", err, orphan.Path, name)
+				return err
+			}
+		}
+		if autofix {
+			logger.Info("%d orphaned objects (%d bytes) deleted from %s storage", len(orphans), size, name)
+		} else {
+			logger.Warn("%d orphaned objects (%d bytes) in %s storage", len(orphans), size, name)
+		}
+	}
+	return nil
+}
+
+func checkStorageMissing(logger log.Logger, autofix bool) error {
+	if err := initStorage(logger); err != nil {
+		return err
+	}
+
+	for _, name := range models.StorageConsistencyNames() {
+		missing, err := models.FindMissingStorageObjects(context.Background(), name)
+		if err != nil {
+			logger.Critical("Error: %v whilst searching missing objects in %s storage", err, name)
+			return err
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		fixed := 0
+		for _, m := range missing {
+			if !autofix || !m.CanFix() {
+				logger.Warn("Object %s of %s is missing from %s storage", m.Path, m.Description, name)
+				continue
+			}
+			if err := m.Fix(); err != nil {
+				logger.Critical("Error: %v whilst fixing %s", err, m.Description)
+				return err
+			}
+			fixed++
+		}
+		if fixed > 0 {
+			logger.Info("%d database entries with missing objects in %s storage fixed", fixed, name)
+		}
+		if fixed < len(missing) {
+			logger.Warn("%d database entries with missing objects in %s storage, restore the objects from a backup or delete the entries", len(missing)-fixed, name)
+		}
+	}
+	return nil
+}
+
+func init() {
+	Register(&Check{
+		Title:     "Check for storage objects which are not referenced by the database",
+		Name:      "storage-orphans",
+		IsDefault: false,
+		Run:       checkStorageOrphans,
+		Priority:  8,
+	})
+	Register(&Check{
+		Title:     "Check for database entries whose storage objects are missing",
+		Name:      "storage-missing",
+		IsDefault: false,
+		Run:       checkStorageMissing,
+		Priority:  8,
+	})
+}
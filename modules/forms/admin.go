@@ -49,6 +49,7 @@ type AdminEditUserForm struct {
 	AllowCreateOrganization bool
 	ProhibitLogin           bool
 	Reset2FA                bool `form:"reset_2fa"`
+	UnlockAccount           bool
 }
 
 // Validate validates form fields
@@ -15,57 +15,81 @@ import (
 
 // AuthenticationForm form for authentication
 type AuthenticationForm struct {
-	ID                            int64
-	Type                          int    `binding:"Range(2,7)"`
-	Name                          string `binding:"Required;MaxSize(30)"`
-	Host                          string
-	Port                          int
-	BindDN                        string
-	BindPassword                  string
-	UserBase                      string
-	UserDN                        string
-	AttributeUsername             string
-	AttributeName                 string
-	AttributeSurname              string
-	AttributeMail                 string
-	AttributeSSHPublicKey         string
-	AttributesInBind              bool
-	UsePagedSearch                bool
-	SearchPageSize                int
-	Filter                        string
-	AdminFilter                   string
-	GroupsEnabled                 bool
-	GroupDN                       string
-	GroupFilter                   string
-	GroupMemberUID                string
-	UserUID                       string
-	RestrictedFilter              string
-	AllowDeactivateAll            bool
-	IsActive                      bool
-	IsSyncEnabled                 bool
-	SMTPAuth                      string
-	SMTPHost                      string
-	SMTPPort                      int
-	AllowedDomains                string
-	SecurityProtocol              int `binding:"Range(0,2)"`
-	TLS                           bool
-	SkipVerify                    bool
-	PAMServiceName                string
-	Oauth2Provider                string
-	Oauth2Key                     string
-	Oauth2Secret                  string
-	OpenIDConnectAutoDiscoveryURL string
-	Oauth2UseCustomURL            bool
-	Oauth2TokenURL                string
-	Oauth2AuthURL                 string
-	Oauth2ProfileURL              string
-	Oauth2EmailURL                string
-	Oauth2IconURL                 string
-	SSPIAutoCreateUsers           bool
-	SSPIAutoActivateUsers         bool
-	SSPIStripDomainNames          bool
-	SSPISeparatorReplacement      string `binding:"AlphaDashDot;MaxSize(5)"`
-	SSPIDefaultLanguage           string
+	ID                              int64
+	Type                            int    `binding:"Range(2,8)"`
+	Name                            string `binding:"Required;MaxSize(30)"`
+	Host                            string
+	Port                            int
+	BindDN                          string
+	BindPassword                    string
+	UserBase                        string
+	UserDN                          string
+	AttributeUsername               string
+	AttributeName                   string
+	AttributeSurname                string
+	AttributeMail                   string
+	AttributeSSHPublicKey           string
+	AttributesInBind                bool
+	UsePagedSearch                  bool
+	SearchPageSize                  int
+	Filter                          string
+	AdminFilter                     string
+	GroupsEnabled                   bool
+	GroupDN                         string
+	GroupFilter                     string
+	GroupMemberUID                  string
+	UserUID                         string
+	GroupTeamMap                    string
+	GroupTeamMapRemoval             bool
+	RestrictedFilter                string
+	AllowDeactivateAll              bool
+	IsActive                        bool
+	IsSyncEnabled                   bool
+	SMTPAuth                        string
+	SMTPHost                        string
+	SMTPPort                        int
+	AllowedDomains                  string
+	SecurityProtocol                int `binding:"Range(0,2)"`
+	TLS                             bool
+	SkipVerify                      bool
+	PAMServiceName                  string
+	Oauth2Provider                  string
+	Oauth2Key                       string
+	Oauth2Secret                    string
+	OpenIDConnectAutoDiscoveryURL   string
+	Oauth2UseCustomURL              bool
+	Oauth2TokenURL                  string
+	Oauth2AuthURL                   string
+	Oauth2ProfileURL                string
+	Oauth2EmailURL                  string
+	Oauth2IconURL                   string
+	Oauth2GroupClaimName            string
+	Oauth2RequiredGroup             string
+	Oauth2AdminGroup                string
+	Oauth2RestrictedGroup           string
+	Oauth2GroupTeamMap              string
+	Oauth2GroupTeamMapRemoval       bool
+	SSPIAutoCreateUsers             bool
+	SSPIAutoActivateUsers           bool
+	SSPIStripDomainNames            bool
+	SSPISeparatorReplacement        string `binding:"AlphaDashDot;MaxSize(5)"`
+	SSPIDefaultLanguage             string
+	SAMLIdentityProviderMetadataURL string
+	SAMLIdentityProviderMetadata    string
+	SAMLServiceProviderCertificate  string
+	SAMLServiceProviderPrivateKey   string
+	SAMLSignRequests                bool
+	SAMLWantAssertionsSigned        bool
+	SAMLAllowUnsolicited            bool
+	SAMLNameIDFormat                string
+	SAMLAttributeUsername           string
+	SAMLAttributeEmail              string
+	SAMLAttributeFullName           string
+	SAMLAttributeGroups             string
+	SAMLRequiredGroup               string
+	SAMLAdminGroup                  string
+	SAMLRestrictedGroup             string
+	SAMLAutoCreateUsers             bool
 }
 
 // Validate validates fields
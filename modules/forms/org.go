@@ -46,6 +46,7 @@ type UpdateOrgSettingForm struct {
 	Visibility                structs.VisibleType
 	MaxRepoCreation           int
 	RepoAdminChangeTeamAccess bool
+	RequireTwoFactor          bool
 }
 
 // Validate validates the fields
@@ -206,6 +206,7 @@ type ProtectBranchForm struct {
 	DismissStaleApprovals         bool
 	RequireSignedCommits          bool
 	ProtectedFilePatterns         string
+	EnableMergeQueue              bool
 }
 
 // Validate validates the fields
@@ -574,14 +575,16 @@ func (f *MergePullRequestForm) Validate(req *http.Request, errs binding.Errors)
 
 // CodeCommentForm form for adding code comments for PRs
 type CodeCommentForm struct {
-	Origin         string `binding:"Required;In(timeline,diff)"`
-	Content        string `binding:"Required"`
-	Side           string `binding:"Required;In(previous,proposed)"`
-	Line           int64
-	TreePath       string `form:"path" binding:"Required"`
-	IsReview       bool   `form:"is_review"`
-	Reply          int64  `form:"reply"`
-	LatestCommitID string
+	Origin            string `binding:"Required;In(timeline,diff)"`
+	Content           string `binding:"Required"`
+	Side              string `binding:"Required;In(previous,proposed)"`
+	Line              int64
+	TreePath          string `form:"path" binding:"Required"`
+	IsReview          bool   `form:"is_review"`
+	Reply             int64  `form:"reply"`
+	LatestCommitID    string
+	DiffStartCommitID string `form:"diff_start_cid"`
+	DiffEndCommitID   string `form:"diff_end_cid"`
 }
 
 // Validate validates the fields
@@ -590,6 +593,30 @@ func (f *CodeCommentForm) Validate(req *http.Request, errs binding.Errors) bindi
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// ViewedFileForm form for marking a file of a pull request as viewed
+type ViewedFileForm struct {
+	TreePath string `form:"path" binding:"Required"`
+	BlobSHA  string `form:"sha" binding:"MaxSize(40)"`
+	Viewed   bool
+}
+
+// Validate validates the fields
+func (f *ViewedFileForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// ApplySuggestionsForm form for applying the suggestions of code comments
+type ApplySuggestionsForm struct {
+	CommentIDs string `form:"comment_ids" binding:"Required"`
+}
+
+// Validate validates the fields
+func (f *ApplySuggestionsForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // SubmitReviewForm for submitting a finished code review
 type SubmitReviewForm struct {
 	Content  string
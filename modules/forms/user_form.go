@@ -206,6 +206,9 @@ type AccessTokenForm struct {
 
 	// PKCE support
 	CodeVerifier string `json:"code_verifier"`
+
+	// device authorization grant (RFC 8628)
+	DeviceCode string `json:"device_code"`
 }
 
 // Validate validates the fields
@@ -214,6 +217,45 @@ func (f *AccessTokenForm) Validate(req *http.Request, errs binding.Errors) bindi
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// DeviceAuthorizationForm for requesting a device code and a user code (RFC 8628)
+type DeviceAuthorizationForm struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope"`
+}
+
+// Validate validates the fields
+func (f *DeviceAuthorizationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// DeviceVerificationForm for approving or denying the device authorization request of a user code
+type DeviceVerificationForm struct {
+	UserCode string `binding:"Required"`
+	Granted  bool
+}
+
+// Validate validates the fields
+func (f *DeviceVerificationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OAuth2TokenForm for introspecting (RFC 7662) or revoking (RFC 7009) access and refresh tokens
+type OAuth2TokenForm struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+}
+
+// Validate validates the fields
+func (f *OAuth2TokenForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 //   __________________________________________.___ _______    ________  _________
 //  /   _____/\_   _____/\__    ___/\__    ___/|   |\      \  /  _____/ /   _____/
 //  \_____  \  |    __)_   |    |     |    |   |   |/   |   \/   \  ___ \_____  \
@@ -335,7 +377,11 @@ func (f *AddKeyForm) Validate(req *http.Request, errs binding.Errors) binding.Er
 
 // NewAccessTokenForm form for creating access token
 type NewAccessTokenForm struct {
-	Name string `binding:"Required;MaxSize(255)"`
+	Name         string `binding:"Required;MaxSize(255)"`
+	Scope        []string
+	Repository   string
+	Organization string
+	Expires      string
 }
 
 // Validate validates the fields
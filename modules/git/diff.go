@@ -25,6 +25,8 @@ type RawDiffType string
 const (
 	RawDiffNormal RawDiffType = "diff"
 	RawDiffPatch  RawDiffType = "patch"
+	// RawDiffWord is a diff which marks the changed words like git diff --word-diff
+	RawDiffWord RawDiffType = "word-diff"
 )
 
 // GetRawDiff dumps diff results of repository in given commit ID to io.Writer.
@@ -59,15 +61,20 @@ func GetRepoRawDiffForFile(repo *Repository, startCommit, endCommit string, diff
 
 	var cmd *exec.Cmd
 	switch diffType {
-	case RawDiffNormal:
+	case RawDiffNormal, RawDiffWord:
+		var args []string
 		if len(startCommit) != 0 {
-			cmd = exec.CommandContext(ctx, GitExecutable, append([]string{"diff", "-M", startCommit, endCommit}, fileArgs...)...)
+			args = []string{"diff", "-M", startCommit, endCommit}
 		} else if commit.ParentCount() == 0 {
-			cmd = exec.CommandContext(ctx, GitExecutable, append([]string{"show", endCommit}, fileArgs...)...)
+			args = []string{"show", endCommit}
 		} else {
 			c, _ := commit.Parent(0)
-			cmd = exec.CommandContext(ctx, GitExecutable, append([]string{"diff", "-M", c.ID.String(), endCommit}, fileArgs...)...)
+			args = []string{"diff", "-M", c.ID.String(), endCommit}
 		}
+		if diffType == RawDiffWord {
+			args = append([]string{args[0], "--word-diff=plain"}, args[1:]...)
+		}
+		cmd = exec.CommandContext(ctx, GitExecutable, append(args, fileArgs...)...)
 	case RawDiffPatch:
 		if len(startCommit) != 0 {
 			query := fmt.Sprintf("%s...%s", endCommit, startCommit)
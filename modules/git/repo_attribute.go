@@ -7,6 +7,17 @@ package git
 import (
 	"bytes"
 	"fmt"
+	"os"
+
+	"code.gitea.io/gitea/modules/util"
+)
+
+// Linguist attributes overriding the detection of generated, vendored and
+// documentation files
+const (
+	LinguistGeneratedAttribute     = "linguist-generated"
+	LinguistVendoredAttribute      = "linguist-vendored"
+	LinguistDocumentationAttribute = "linguist-documentation"
 )
 
 // CheckAttributeOpts represents the possible options to CheckAttribute
@@ -15,9 +26,13 @@ type CheckAttributeOpts struct {
 	AllAttributes bool
 	Attributes    []string
 	Filenames     []string
+	// IndexFile makes attributes to be read from the .gitattributes files in
+	// the given index instead of the working tree, e.g. one created by
+	// ReadTreeToTemporaryIndex, which is needed for bare repositories
+	IndexFile string
 }
 
-// CheckAttribute return the Blame object of file
+// CheckAttribute return the attributes of the given files
 func (repo *Repository) CheckAttribute(opts CheckAttributeOpts) (map[string]map[string]string, error) {
 	err := LoadGitVersion()
 	if err != nil {
@@ -40,21 +55,29 @@ func (repo *Repository) CheckAttribute(opts CheckAttributeOpts) (map[string]map[
 	}
 
 	// git check-attr --cached first appears in git 1.7.8
-	if opts.CachedOnly && CheckGitVersionAtLeast("1.7.8") == nil {
+	if (opts.CachedOnly || opts.IndexFile != "") && CheckGitVersionAtLeast("1.7.8") == nil {
 		cmdArgs = append(cmdArgs, "--cached")
 	}
 
-	cmdArgs = append(cmdArgs, "--")
+	// Pass the filenames on stdin, there may be too many for the command line
+	cmdArgs = append(cmdArgs, "--stdin")
 
+	stdIn := new(bytes.Buffer)
 	for _, arg := range opts.Filenames {
 		if arg != "" {
-			cmdArgs = append(cmdArgs, arg)
+			stdIn.WriteString(arg)
+			stdIn.WriteByte('\000')
 		}
 	}
 
+	var env []string
+	if opts.IndexFile != "" {
+		env = append(os.Environ(), "GIT_INDEX_FILE="+opts.IndexFile)
+	}
+
 	cmd := NewCommand(cmdArgs...)
 
-	if err := cmd.RunInDirPipeline(repo.Path, stdOut, stdErr); err != nil {
+	if err := cmd.RunInDirTimeoutEnvFullPipeline(env, -1, repo.Path, stdOut, stdErr, stdIn); err != nil {
 		return nil, fmt.Errorf("Failed to run check-attr: %v\n%s\n%s", err, stdOut.String(), stdErr.String())
 	}
 
@@ -80,3 +103,50 @@ func (repo *Repository) CheckAttribute(opts CheckAttributeOpts) (map[string]map[
 
 	return name2attribute2info, nil
 }
+
+// LinguistAttributes represents the linguist overrides set for a file,
+// util.OptionalBoolNone means that the attribute is unspecified
+type LinguistAttributes struct {
+	Generated     util.OptionalBool
+	Vendored      util.OptionalBool
+	Documentation util.OptionalBool
+}
+
+// GetLinguistAttributes returns the linguist overrides set by the .gitattributes
+// files of the given treeish for the given files
+func (repo *Repository) GetLinguistAttributes(treeish string, filenames []string) (map[string]LinguistAttributes, error) {
+	indexFilename, cancel, err := repo.ReadTreeToTemporaryIndex(treeish)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	name2attribute2info, err := repo.CheckAttribute(CheckAttributeOpts{
+		Attributes: []string{LinguistGeneratedAttribute, LinguistVendoredAttribute, LinguistDocumentationAttribute},
+		Filenames:  filenames,
+		IndexFile:  indexFilename,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name2attributes := make(map[string]LinguistAttributes, len(name2attribute2info))
+	for name, attribute2info := range name2attribute2info {
+		name2attributes[name] = LinguistAttributes{
+			Generated:     attributeToOptionalBool(attribute2info[LinguistGeneratedAttribute]),
+			Vendored:      attributeToOptionalBool(attribute2info[LinguistVendoredAttribute]),
+			Documentation: attributeToOptionalBool(attribute2info[LinguistDocumentationAttribute]),
+		}
+	}
+	return name2attributes, nil
+}
+
+func attributeToOptionalBool(info string) util.OptionalBool {
+	switch info {
+	case "set", "true":
+		return util.OptionalBoolTrue
+	case "unset", "false":
+		return util.OptionalBoolFalse
+	}
+	return util.OptionalBoolNone
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_GetLinguistAttributes(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "linguist")
+	assert.NoError(t, err)
+	defer util.RemoveAll(tmpDir)
+
+	workDir := filepath.Join(tmpDir, "work")
+	assert.NoError(t, InitRepository(workDir, false))
+	assert.NoError(t, os.MkdirAll(filepath.Join(workDir, "docs"), 0755))
+	files := map[string]string{
+		".gitattributes": "*.pb.go linguist-generated\ndocs/** linguist-documentation\nvendor/** -linguist-vendored\n",
+		"api.pb.go":      "package api\n",
+		"main.go":        "package main\n",
+		"docs/index.md":  "# Docs\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(workDir, name), []byte(content), 0644))
+	}
+	assert.NoError(t, AddChanges(workDir, true))
+	assert.NoError(t, CommitChanges(workDir, CommitChangesOptions{
+		Committer: &Signature{Name: "Gitea", Email: "gitea@example.com"},
+		Author:    &Signature{Name: "Gitea", Email: "gitea@example.com"},
+		Message:   "Initial commit",
+	}))
+
+	// attributes are read from the given tree, so a bare repository works too
+	barePath := filepath.Join(tmpDir, "bare.git")
+	assert.NoError(t, Clone(workDir, barePath, CloneRepoOptions{Bare: true, Quiet: true}))
+	repo, err := OpenRepository(barePath)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	attributes, err := repo.GetLinguistAttributes("HEAD", []string{"api.pb.go", "main.go", "docs/index.md", "vendor/lib.go"})
+	assert.NoError(t, err)
+	assert.True(t, attributes["api.pb.go"].Generated.IsTrue())
+	assert.True(t, attributes["api.pb.go"].Vendored.IsNone())
+	assert.True(t, attributes["main.go"].Generated.IsNone())
+	assert.True(t, attributes["docs/index.md"].Documentation.IsTrue())
+	assert.True(t, attributes["vendor/lib.go"].Vendored.IsFalse())
+}
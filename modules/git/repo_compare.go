@@ -217,6 +217,13 @@ func (repo *Repository) GetDiff(base, head string, w io.Writer) error {
 		RunInDirPipeline(repo.Path, w, nil)
 }
 
+// GetWordDiff generates and returns diff data between given revisions in
+// which the changed words are marked like [-removed-]{+added+}.
+func (repo *Repository) GetWordDiff(base, head string, w io.Writer) error {
+	return NewCommand("diff", "-p", "--word-diff=plain", base, head).
+		RunInDirPipeline(repo.Path, w, nil)
+}
+
 // GetPatch generates and returns format-patch data between given revisions.
 func (repo *Repository) GetPatch(base, head string, w io.Writer) error {
 	stderr := new(bytes.Buffer)
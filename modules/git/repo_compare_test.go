@@ -31,3 +31,14 @@ func TestGetFormatPatch(t *testing.T) {
 	assert.Regexp(t, "^From 8d92fc95", patch)
 	assert.Contains(t, patch, "Subject: [PATCH] Add file2.txt")
 }
+
+func TestGetWordDiff(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	repo, err := OpenRepository(bareRepo1Path)
+	assert.NoError(t, err)
+	defer repo.Close()
+	rd := &bytes.Buffer{}
+	err = repo.GetWordDiff("8d92fc95^", "8d92fc95", rd)
+	assert.NoError(t, err)
+	assert.Contains(t, rd.String(), "+++ b/file2.txt\n@@ -0,0 +1 @@\n{+file2+}\n")
+}
@@ -6,7 +6,13 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"code.gitea.io/gitea/modules/util"
 )
 
 // ReadTreeToIndex reads a treeish to the index
@@ -35,6 +41,27 @@ func (repo *Repository) readTreeToIndex(id SHA1) error {
 	return nil
 }
 
+// ReadTreeToTemporaryIndex reads a treeish to a temporary index file, the
+// returned cancel function removes it again
+func (repo *Repository) ReadTreeToTemporaryIndex(treeish string) (filename string, cancel context.CancelFunc, err error) {
+	tmpDir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		return "", nil, err
+	}
+	cancel = func() {
+		if err := util.RemoveAll(tmpDir); err != nil {
+			log("failed to remove tmp index directory %s: %v", tmpDir, err)
+		}
+	}
+
+	filename = filepath.Join(tmpDir, ".tmp-index")
+	if _, err := NewCommand("read-tree", treeish).RunInDirWithEnv(repo.Path, append(os.Environ(), "GIT_INDEX_FILE="+filename)); err != nil {
+		cancel()
+		return "", nil, err
+	}
+	return filename, cancel, nil
+}
+
 // EmptyIndex empties the index
 func (repo *Repository) EmptyIndex() error {
 	_, err := NewCommand("read-tree", "--empty").RunInDir(repo.Path)
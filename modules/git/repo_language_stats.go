@@ -4,5 +4,20 @@
 
 package git
 
+import "github.com/go-enry/go-enry/v2"
+
 const fileSizeLimit int64 = 16 * 1024 // 16 KiB
 const bigFileSize int64 = 1024 * 1024 // 1 MiB
+
+// isLinguistExcluded reports whether a file is excluded from the language stats
+// without looking at its content. The linguist attributes take precedence over
+// the detection by filename.
+func isLinguistExcluded(filename string, attributes LinguistAttributes) bool {
+	if attributes.Generated.IsTrue() {
+		return true
+	}
+	if attributes.Vendored.IsTrue() || (attributes.Vendored.IsNone() && enry.IsVendor(filename)) {
+		return true
+	}
+	return attributes.Documentation.IsTrue() || (attributes.Documentation.IsNone() && enry.IsDocumentation(filename))
+}
@@ -41,10 +41,24 @@ func (repo *Repository) GetLanguageStats(commitID string) (map[string]int64, err
 		return nil, err
 	}
 
+	var filenames []string
+	err = tree.Files().ForEach(func(f *object.File) error {
+		filenames = append(filenames, f.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	name2attributes, err := repo.GetLinguistAttributes(commitID, filenames)
+	if err != nil {
+		log("Unable to get linguist attributes for: %s. Err: %v", commitID, err)
+	}
+
 	sizes := make(map[string]int64)
 	err = tree.Files().ForEach(func(f *object.File) error {
-		if f.Size == 0 || enry.IsVendor(f.Name) || enry.IsDotFile(f.Name) ||
-			enry.IsDocumentation(f.Name) || enry.IsConfiguration(f.Name) {
+		attributes := name2attributes[f.Name]
+		if f.Size == 0 || isLinguistExcluded(f.Name, attributes) || enry.IsDotFile(f.Name) ||
+			enry.IsConfiguration(f.Name) {
 			return nil
 		}
 
@@ -53,12 +67,10 @@ func (repo *Repository) GetLanguageStats(commitID string) (map[string]int64, err
 		if f.Size <= bigFileSize {
 			content, _ = readFile(f, fileSizeLimit)
 		}
-		if enry.IsGenerated(f.Name, content) {
+		if attributes.Generated.IsNone() && enry.IsGenerated(f.Name, content) {
 			return nil
 		}
 
-		// TODO: Use .gitattributes file for linguist overrides
-
 		language := analyze.GetCodeLanguage(f.Name, content)
 		if language == enry.OtherLanguage || language == "" {
 			return nil
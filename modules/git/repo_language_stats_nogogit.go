@@ -61,14 +61,24 @@ func (repo *Repository) GetLanguageStats(commitID string) (map[string]int64, err
 		return nil, err
 	}
 
+	filenames := make([]string, 0, len(entries))
+	for _, f := range entries {
+		filenames = append(filenames, f.Name())
+	}
+	name2attributes, err := repo.GetLinguistAttributes(commitID, filenames)
+	if err != nil {
+		log("Unable to get linguist attributes for: %s. Err: %v", commitID, err)
+	}
+
 	contentBuf := bytes.Buffer{}
 	var content []byte
 	sizes := make(map[string]int64)
 	for _, f := range entries {
 		contentBuf.Reset()
 		content = contentBuf.Bytes()
-		if f.Size() == 0 || enry.IsVendor(f.Name()) || enry.IsDotFile(f.Name()) ||
-			enry.IsDocumentation(f.Name()) || enry.IsConfiguration(f.Name()) {
+		attributes := name2attributes[f.Name()]
+		if f.Size() == 0 || isLinguistExcluded(f.Name(), attributes) || enry.IsDotFile(f.Name()) ||
+			enry.IsConfiguration(f.Name()) {
 			continue
 		}
 
@@ -101,11 +111,10 @@ func (repo *Repository) GetLanguageStats(commitID string) (map[string]int64, err
 				return nil, err
 			}
 		}
-		if enry.IsGenerated(f.Name(), content) {
+		if attributes.Generated.IsNone() && enry.IsGenerated(f.Name(), content) {
 			continue
 		}
 
-		// TODO: Use .gitattributes file for linguist overrides
 		// FIXME: Why can't we split this and the IsGenerated tests to avoid reading the blob unless absolutely necessary?
 		// - eg. do the all the detection tests using filename first before reading content.
 		language := analyze.GetCodeLanguage(f.Name(), content)
@@ -602,6 +602,14 @@ func authenticate(ctx *context.Context, repository *models.Repository, authoriza
 		accessMode = models.AccessModeWrite
 	}
 
+	// access tokens resolved by the sso plugins may be limited in scope or to other repositories
+	if token, ok := ctx.Data["ApiToken"].(*models.AccessToken); ok {
+		if !token.Scope.HasScope(models.AccessTokenScopeCategoryRepo, requireWrite) || !token.CanAccessRepo(repository) {
+			log.Warn("Access token %d of user %-v is not allowed to access LFS of repo %-v", token.ID, ctx.User, repository)
+			return false
+		}
+	}
+
 	// ctx.IsSigned is unnecessary here, this will be checked in perm.CanAccess
 	perm, err := models.GetUserRepoPermission(repository, ctx.User)
 	if err != nil {
@@ -0,0 +1,922 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/markup/common"
+)
+
+func init() {
+	markup.RegisterParser(Parser{})
+}
+
+// Parser implements markup.Parser for AsciiDoc
+type Parser struct {
+}
+
+// Name implements markup.Parser
+func (Parser) Name() string {
+	return "asciidoc"
+}
+
+// Extensions implements markup.Parser
+func (Parser) Extensions() []string {
+	return []string{".adoc", ".asciidoc"}
+}
+
+// Render implements markup.Parser
+func (Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	return Render(rawBytes, urlPrefix, metas, isWiki)
+}
+
+// RenderString renders AsciiDoc string to HTML string
+func RenderString(rawContent string, urlPrefix string, metas map[string]string, isWiki bool) string {
+	return string(Render([]byte(rawContent), urlPrefix, metas, isWiki))
+}
+
+// Render renders AsciiDoc to HTML. Only the commonly used subset of the
+// language which Asciidoctor understands is supported, includes are ignored.
+func Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	r := &renderer{
+		urlPrefix:     urlPrefix,
+		isWiki:        isWiki,
+		attributes:    map[string]string{},
+		ids:           common.NewHeadingIDs(),
+		sectionTitles: map[string]string{},
+	}
+	for name, value := range defaultAttributes {
+		r.attributes[name] = value
+	}
+
+	content := strings.ReplaceAll(string(rawBytes), "\r\n", "\n")
+	r.renderBlocks(r.preprocess(strings.Split(content, "\n")))
+	r.renderFootnotes()
+
+	// cross references without text show the title of the referenced section
+	return xrefTextRegexp.ReplaceAllFunc(r.buf.Bytes(), func(m []byte) []byte {
+		id := string(m[1 : len(m)-1])
+		if title, ok := r.sectionTitles[id]; ok {
+			return []byte(title)
+		}
+		return []byte("[" + html.EscapeString(id) + "]")
+	})
+}
+
+var defaultAttributes = map[string]string{
+	"idprefix":    "_",
+	"idseparator": "_",
+	"empty":       "",
+	"sp":          " ",
+	"nbsp":        "&#160;",
+	"zwsp":        "&#8203;",
+	"amp":         "&amp;",
+	"lt":          "&lt;",
+	"gt":          "&gt;",
+	"quot":        "&#34;",
+	"apos":        "&#39;",
+	"vbar":        "|",
+	"plus":        "&#43;",
+	"caret":       "^",
+	"cpp":         "C++",
+	"tilde":       "~",
+	"startsb":     "[",
+	"endsb":       "]",
+	"env-gitea":   "",
+}
+
+var admonitionLabels = map[string]string{
+	"NOTE":      "Note",
+	"TIP":       "Tip",
+	"IMPORTANT": "Important",
+	"WARNING":   "Warning",
+	"CAUTION":   "Caution",
+}
+
+var (
+	attributeEntryRegexp = regexp.MustCompile(`^:(!?)(\w[\w-]*)(!?):(?:\s+(.*))?$`)
+	conditionalRegexp    = regexp.MustCompile(`^(ifdef|ifndef|ifeval|endif)::([^\[]*)\[(.*)\]$`)
+	delimiterRegexp      = regexp.MustCompile(`^(-{4,}|\.{4,}|={4,}|\*{4,}|_{4,}|\+{4,}|/{4,}|--|\|={3,})$`)
+	headingRegexp        = regexp.MustCompile(`^(={1,6}|#{1,6})\s+(\S.*?)(?:\s+=+)?$`)
+	anchorRegexp         = regexp.MustCompile(`^\[\[([\w:.-]+)(?:,[^\]]*)?\]\]$`)
+	blockAttributeRegexp = regexp.MustCompile(`^\[([^\[\]]*)\]$`)
+	blockTitleRegexp     = regexp.MustCompile(`^\.([^.\s].*)$`)
+	blockImageRegexp     = regexp.MustCompile(`^image::([^\[\s]+)\[(.*)\]$`)
+	blockMacroRegexp     = regexp.MustCompile(`^(include|toc|video|audio)::[^\[]*\[.*\]$`)
+	admonitionRegexp     = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	thematicBreakRegexp  = regexp.MustCompile(`^('{3}|-{3}|\*{3}|- - -|\* \* \*)$`)
+	unorderedItemRegexp  = regexp.MustCompile(`^\s*(\*{1,5}|-)\s+(.*)$`)
+	orderedItemRegexp    = regexp.MustCompile(`^\s*(\.{1,5}|\d+\.)\s+(.*)$`)
+	descriptionRegexp    = regexp.MustCompile(`^\s*(\S.*?)(:{2,4}|;;)(?:\s+(.*))?$`)
+	checklistRegexp      = regexp.MustCompile(`^\[([ xX*])\]\s+(.*)$`)
+	tableColumnsRegexp   = regexp.MustCompile(`^(\d+)\*`)
+	cellSpecRegexp       = regexp.MustCompile(`^(\d*(\.\d+)?[+*])?[<^>]?(\.[<^>])?\d*%?[adehlmsv]?$`)
+	languageRegexp       = regexp.MustCompile(`^[\w-]+$`)
+	xrefTextRegexp       = regexp.MustCompile("\x01[^\x01]*\x01")
+)
+
+type renderer struct {
+	buf           bytes.Buffer
+	urlPrefix     string
+	isWiki        bool
+	attributes    map[string]string
+	ids           *common.HeadingIDs
+	sectionTitles map[string]string
+	footnotes     []string
+}
+
+// blockMeta holds the id, title and attributes given in the lines preceding a block
+type blockMeta struct {
+	id         string
+	title      string
+	style      string
+	positional []string
+	named      map[string]string
+	options    map[string]bool
+}
+
+func (m *blockMeta) positionalAttribute(i int) string {
+	if i < len(m.positional) {
+		return m.positional[i]
+	}
+	return ""
+}
+
+// parseAttributes parses the content of a block attribute line like [source,go,id=main]
+func (m *blockMeta) parseAttributes(list string) {
+	if m.named == nil {
+		m.named = map[string]string{}
+		m.options = map[string]bool{}
+	}
+	for i, attr := range splitAttributes(list) {
+		if idx := strings.IndexByte(attr, '='); idx > 0 {
+			name, value := strings.TrimSpace(attr[:idx]), strings.Trim(strings.TrimSpace(attr[idx+1:]), `"'`)
+			switch name {
+			case "id":
+				m.id = value
+			case "opts", "options":
+				for _, opt := range strings.Split(value, ",") {
+					m.options[strings.TrimSpace(opt)] = true
+				}
+			default:
+				m.named[name] = value
+			}
+			continue
+		}
+		if i == 0 {
+			// the first positional attribute may use the shorthand syntax style#id.role%option
+			attr = m.parseShorthand(attr)
+		}
+		m.positional = append(m.positional, attr)
+	}
+}
+
+func (m *blockMeta) parseShorthand(attr string) string {
+	end := strings.IndexAny(attr, "#.%")
+	if end < 0 {
+		m.style = attr
+		return attr
+	}
+	m.style = attr[:end]
+	for rest := attr[end:]; len(rest) > 0; {
+		next := strings.IndexAny(rest[1:], "#.%")
+		if next < 0 {
+			next = len(rest)
+		} else {
+			next++
+		}
+		switch value := rest[1:next]; rest[0] {
+		case '#':
+			m.id = value
+		case '%':
+			m.options[value] = true
+		}
+		rest = rest[next:]
+	}
+	return m.style
+}
+
+func splitAttributes(list string) []string {
+	var attrs []string
+	var quote rune
+	start := 0
+	for i, c := range list {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			attrs = append(attrs, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(list[start:]); len(rest) > 0 || len(attrs) > 0 {
+		attrs = append(attrs, rest)
+	}
+	return attrs
+}
+
+// preprocess evaluates the ifdef and ifndef conditionals
+func (r *renderer) preprocess(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	var excluded []bool
+	isExcluded := func() bool {
+		for _, e := range excluded {
+			if e {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, line := range lines {
+		m := conditionalRegexp.FindStringSubmatch(strings.TrimRight(line, " \t"))
+		if m == nil {
+			if !isExcluded() {
+				r.setAttribute(line)
+				result = append(result, line)
+			}
+			continue
+		}
+		switch m[1] {
+		case "endif":
+			if len(excluded) > 0 {
+				excluded = excluded[:len(excluded)-1]
+			}
+		case "ifeval":
+			// expressions are not evaluated, their content is always shown
+			excluded = append(excluded, false)
+		default:
+			include := r.attributesDefined(m[2]) == (m[1] == "ifdef")
+			if len(m[3]) > 0 {
+				if include && !isExcluded() {
+					result = append(result, m[3])
+				}
+				continue
+			}
+			excluded = append(excluded, !include)
+		}
+	}
+	return result
+}
+
+// attributesDefined checks whether any of the comma separated or all of the plus separated attributes are defined
+func (r *renderer) attributesDefined(names string) bool {
+	if strings.Contains(names, "+") {
+		for _, name := range strings.Split(names, "+") {
+			if _, ok := r.attributes[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	for _, name := range strings.Split(names, ",") {
+		if _, ok := r.attributes[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setAttribute handles a line if it is an attribute entry
+func (r *renderer) setAttribute(line string) bool {
+	m := attributeEntryRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	if len(m[1]) > 0 || len(m[3]) > 0 {
+		delete(r.attributes, m[2])
+	} else {
+		r.attributes[m[2]] = m[4]
+	}
+	return true
+}
+
+func (r *renderer) renderBlocks(lines []string) {
+	meta := &blockMeta{}
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " \t")
+
+		if len(line) == 0 {
+			i++
+			continue
+		}
+		if delimiterRegexp.MatchString(line) && line[0] == '/' {
+			i = findClosingDelimiter(lines, i) + 1
+			continue
+		}
+		if strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "///") {
+			i++
+			continue
+		}
+
+		if r.setAttribute(line) || blockMacroRegexp.MatchString(line) || line == "<<<" {
+			i++
+			continue
+		}
+		if m := anchorRegexp.FindStringSubmatch(line); m != nil {
+			meta.id = m[1]
+			i++
+			continue
+		}
+		if m := blockAttributeRegexp.FindStringSubmatch(line); m != nil {
+			meta.parseAttributes(m[1])
+			i++
+			continue
+		}
+		if m := blockTitleRegexp.FindStringSubmatch(line); m != nil {
+			meta.title = m[1]
+			i++
+			continue
+		}
+
+		switch {
+		case headingRegexp.MatchString(line):
+			m := headingRegexp.FindStringSubmatch(line)
+			r.renderHeading(len(m[1]), m[2], meta)
+			i++
+			if len(m[1]) == 1 && line[0] == '=' {
+				// the author and revision lines of the document header are not shown
+				for i < len(lines) && len(strings.TrimSpace(lines[i])) > 0 {
+					r.setAttribute(lines[i])
+					i++
+				}
+			}
+		case thematicBreakRegexp.MatchString(line):
+			r.buf.WriteString("<hr>\n")
+			i++
+		case blockImageRegexp.MatchString(line):
+			m := blockImageRegexp.FindStringSubmatch(line)
+			meta.parseAttributes(m[2])
+			r.renderBlockImage(m[1], meta)
+			i++
+		case strings.HasPrefix(line, "```"):
+			end := i + 1
+			for end < len(lines) && strings.TrimRight(lines[end], " \t") != "```" {
+				end++
+			}
+			meta.style = "source"
+			meta.positional = []string{"source", strings.TrimSpace(line[3:])}
+			r.renderListing(lines[i+1:min(end, len(lines))], meta)
+			i = end + 1
+		case delimiterRegexp.MatchString(line):
+			end := findClosingDelimiter(lines, i)
+			r.renderDelimitedBlock(line, lines[i+1:min(end, len(lines))], meta)
+			i = end + 1
+		case isListItem(line):
+			i = r.renderList(lines, i)
+		case strings.HasPrefix(line, "> ") || line == ">":
+			end := i
+			var quoted []string
+			for end < len(lines) && (strings.HasPrefix(lines[end], "> ") || strings.TrimRight(lines[end], " \t") == ">") {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(lines[end], ">"), " "))
+				end++
+			}
+			r.buf.WriteString("<blockquote>\n")
+			r.renderBlocks(quoted)
+			r.buf.WriteString("</blockquote>\n")
+			i = end
+		case line[0] == ' ' || line[0] == '\t':
+			end := paragraphEnd(lines, i)
+			r.renderLiteral(dedent(lines[i:end]), meta)
+			i = end
+		default:
+			end := paragraphEnd(lines, i)
+			r.renderParagraph(lines[i:end], meta)
+			i = end
+		}
+		meta = &blockMeta{}
+	}
+}
+
+// findClosingDelimiter returns the index of the line closing the delimited block opened at i
+func findClosingDelimiter(lines []string, i int) int {
+	delimiter := strings.TrimRight(lines[i], " \t")
+	for j := i + 1; j < len(lines); j++ {
+		if strings.TrimRight(lines[j], " \t") == delimiter {
+			return j
+		}
+	}
+	return len(lines)
+}
+
+func paragraphEnd(lines []string, i int) int {
+	for i++; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if len(line) == 0 || delimiterRegexp.MatchString(line) {
+			break
+		}
+	}
+	return i
+}
+
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if trimmed := strings.TrimLeft(line, " \t"); len(trimmed) > 0 {
+			if n := len(line) - len(trimmed); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		result[i] = line
+	}
+	return result
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (r *renderer) renderTitle(meta *blockMeta) {
+	if len(meta.title) > 0 {
+		r.buf.WriteString("<p><strong>" + r.inline(meta.title) + "</strong></p>\n")
+	}
+}
+
+func (r *renderer) renderHeading(marks int, title string, meta *blockMeta) {
+	content := r.inline(title)
+	id := meta.id
+	if len(id) == 0 {
+		id = r.sectionID(content)
+	}
+	id = r.ids.Put(id)
+	r.sectionTitles[id] = content
+	fmt.Fprintf(&r.buf, "<h%d id=\"%s\">%s</h%d>\n", marks, escapeAttr(id), content, marks)
+}
+
+var (
+	invalidSectionIDCharsRegexp = regexp.MustCompile(`<[^>]+>|&(?:[a-z][a-z]+\d{0,2}|#\d\d\d{0,4}|#x[\da-f][\da-f][\da-f]{0,3});|[^ \pL\pN_\-.]+`)
+	sectionIDSeparatorsRegexp   = regexp.MustCompile(`[ .\-]+`)
+)
+
+// sectionID generates the id of a section the way Asciidoctor does, which
+// is configured by the idprefix and idseparator attributes
+func (r *renderer) sectionID(title string) string {
+	id := invalidSectionIDCharsRegexp.ReplaceAllString(strings.ToLower(title), "")
+	separator := r.attributes["idseparator"]
+	id = sectionIDSeparatorsRegexp.ReplaceAllString(id, separator)
+	if len(separator) > 0 {
+		id = strings.Trim(id, separator)
+	}
+	return r.attributes["idprefix"] + id
+}
+
+func (r *renderer) renderBlockImage(target string, meta *blockMeta) {
+	src := markup.ResolveMediaLink(r.urlPrefix, target, r.isWiki)
+	alt := meta.positionalAttribute(0)
+	if len(alt) == 0 {
+		alt = imageAlt(target)
+	}
+	var img strings.Builder
+	fmt.Fprintf(&img, `<img src="%s" alt="%s"`, html.EscapeString(src), html.EscapeString(alt))
+	for _, dimension := range []string{"width", "height"} {
+		value := meta.named[dimension]
+		if len(value) == 0 && dimension == "width" {
+			value = meta.positionalAttribute(1)
+		} else if len(value) == 0 {
+			value = meta.positionalAttribute(2)
+		}
+		if len(value) > 0 {
+			fmt.Fprintf(&img, ` %s="%s"`, dimension, html.EscapeString(value))
+		}
+	}
+	img.WriteString(">")
+
+	link := src
+	if target, ok := meta.named["link"]; ok {
+		link = markup.ResolveLink(r.urlPrefix, target, r.isWiki)
+	}
+	r.buf.WriteString("<figure>")
+	fmt.Fprintf(&r.buf, `<a href="%s">%s</a>`, html.EscapeString(link), img.String())
+	if len(meta.title) > 0 {
+		r.buf.WriteString("<figcaption>" + r.inline(meta.title) + "</figcaption>")
+	}
+	r.buf.WriteString("</figure>\n")
+}
+
+// imageAlt generates the alternative text of an image from its file name
+func imageAlt(target string) string {
+	name := target[strings.LastIndex(target, "/")+1:]
+	if idx := strings.LastIndexByte(name, '.'); idx > 0 {
+		name = name[:idx]
+	}
+	return strings.NewReplacer("-", " ", "_", " ").Replace(name)
+}
+
+func (r *renderer) renderDelimitedBlock(delimiter string, content []string, meta *blockMeta) {
+	if _, ok := admonitionLabels[meta.style]; ok && delimiter[0] != '-' && delimiter[0] != '.' {
+		r.renderAdmonition(meta.style, func() { r.renderBlocks(content) }, meta)
+		return
+	}
+
+	switch delimiter[0] {
+	case '-':
+		if delimiter == "--" {
+			switch meta.style {
+			case "source", "listing":
+				r.renderListing(content, meta)
+			case "literal":
+				r.renderLiteral(content, meta)
+			case "quote", "verse":
+				r.renderQuote(content, meta)
+			default:
+				r.renderTitle(meta)
+				r.renderBlocks(content)
+			}
+			return
+		}
+		r.renderListing(content, meta)
+	case '.':
+		r.renderLiteral(content, meta)
+	case '_':
+		r.renderQuote(content, meta)
+	case '+':
+		r.renderTitle(meta)
+		r.buf.WriteString(strings.Join(content, "\n"))
+		r.buf.WriteByte('\n')
+	case '|':
+		r.renderTable(content, meta)
+	default:
+		// example and sidebar blocks
+		r.renderTitle(meta)
+		r.buf.WriteString("<div>\n")
+		r.renderBlocks(content)
+		r.buf.WriteString("</div>\n")
+	}
+}
+
+func (r *renderer) renderListing(content []string, meta *blockMeta) {
+	r.renderTitle(meta)
+	language := meta.positionalAttribute(1)
+	if len(language) == 0 && meta.style == "source" {
+		language = r.attributes["source-language"]
+	}
+	if languageRegexp.MatchString(language) {
+		fmt.Fprintf(&r.buf, `<pre><code class="language-%s">`, strings.ToLower(language))
+	} else {
+		r.buf.WriteString("<pre><code>")
+	}
+	r.buf.WriteString(html.EscapeString(strings.Join(content, "\n")))
+	r.buf.WriteString("</code></pre>\n")
+}
+
+func (r *renderer) renderLiteral(content []string, meta *blockMeta) {
+	if meta.style == "source" || meta.style == "listing" {
+		r.renderListing(content, meta)
+		return
+	}
+	r.renderTitle(meta)
+	r.buf.WriteString("<pre>")
+	r.buf.WriteString(html.EscapeString(strings.Join(content, "\n")))
+	r.buf.WriteString("</pre>\n")
+}
+
+func (r *renderer) renderQuote(content []string, meta *blockMeta) {
+	r.renderTitle(meta)
+	r.buf.WriteString("<blockquote>\n")
+	if meta.style == "verse" {
+		r.buf.WriteString("<p>" + strings.ReplaceAll(r.inline(strings.Join(content, "\n")), "\n", "<br>\n") + "</p>\n")
+	} else {
+		r.renderBlocks(content)
+	}
+	var attribution []string
+	for _, attr := range []string{meta.positionalAttribute(1), meta.positionalAttribute(2)} {
+		if len(attr) > 0 {
+			attribution = append(attribution, r.inline(attr))
+		}
+	}
+	if len(attribution) > 0 {
+		r.buf.WriteString("<p>&#8212; " + strings.Join(attribution, ", ") + "</p>\n")
+	}
+	r.buf.WriteString("</blockquote>\n")
+}
+
+func (r *renderer) renderAdmonition(name string, renderContent func(), meta *blockMeta) {
+	r.buf.WriteString("<blockquote>\n<p><strong>" + admonitionLabels[name] + "</strong></p>\n")
+	r.renderTitle(meta)
+	renderContent()
+	r.buf.WriteString("</blockquote>\n")
+}
+
+func (r *renderer) renderParagraph(lines []string, meta *blockMeta) {
+	if _, ok := admonitionLabels[meta.style]; ok {
+		r.renderAdmonition(meta.style, func() { r.renderParagraph(lines, &blockMeta{}) }, meta)
+		return
+	}
+	switch meta.style {
+	case "source", "listing":
+		r.renderListing(lines, meta)
+		return
+	case "literal":
+		r.renderLiteral(lines, meta)
+		return
+	case "quote", "verse":
+		r.renderQuote(lines, meta)
+		return
+	}
+
+	if m := admonitionRegexp.FindStringSubmatch(lines[0]); m != nil {
+		rest := append([]string{m[2]}, lines[1:]...)
+		r.renderAdmonition(m[1], func() { r.renderParagraph(rest, &blockMeta{}) }, meta)
+		return
+	}
+
+	r.renderTitle(meta)
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSpace(line)
+	}
+	r.buf.WriteString("<p>" + r.inline(strings.Join(trimmed, "\n")) + "</p>\n")
+}
+
+type listItem struct {
+	marker string
+	term   string
+	text   []string
+	blocks [][]string
+}
+
+// matchListItem returns the marker, the description term and the text of a list item
+func matchListItem(line string) (marker, term, text string, ok bool) {
+	if m := unorderedItemRegexp.FindStringSubmatch(line); m != nil {
+		return m[1], "", m[2], true
+	}
+	if m := orderedItemRegexp.FindStringSubmatch(line); m != nil {
+		if m[1][0] != '.' {
+			m[1] = "."
+		}
+		return m[1], "", m[2], true
+	}
+	if m := descriptionRegexp.FindStringSubmatch(line); m != nil {
+		return m[2], m[1], m[3], true
+	}
+	return "", "", "", false
+}
+
+func isListItem(line string) bool {
+	_, _, _, ok := matchListItem(line)
+	return ok
+}
+
+// blockEnd returns the end of the block starting at i, which is attached to a list item
+func blockEnd(lines []string, i int) int {
+	for i < len(lines) {
+		line := strings.TrimRight(lines[i], " \t")
+		if !blockAttributeRegexp.MatchString(line) && !blockTitleRegexp.MatchString(line) && !anchorRegexp.MatchString(line) {
+			break
+		}
+		i++
+	}
+	if i < len(lines) && delimiterRegexp.MatchString(strings.TrimRight(lines[i], " \t")) {
+		return min(findClosingDelimiter(lines, i)+1, len(lines))
+	}
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if len(line) == 0 || line == "+" || isListItem(lines[i]) {
+			break
+		}
+	}
+	return i
+}
+
+func (r *renderer) renderList(lines []string, i int) int {
+	var items []*listItem
+	for i < len(lines) {
+		marker, term, text, ok := matchListItem(lines[i])
+		if !ok {
+			break
+		}
+		item := &listItem{marker: marker, term: term}
+		if len(text) > 0 {
+			item.text = append(item.text, text)
+		}
+		for i++; i < len(lines); i++ {
+			line := strings.TrimSpace(lines[i])
+			if len(line) == 0 || line == "+" || isListItem(lines[i]) || delimiterRegexp.MatchString(line) {
+				break
+			}
+			item.text = append(item.text, line)
+		}
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "+" {
+			end := blockEnd(lines, i+1)
+			item.blocks = append(item.blocks, lines[i+1:end])
+			i = end
+		}
+		items = append(items, item)
+
+		// items of a list may be separated by blank lines
+		next := i
+		for next < len(lines) && len(strings.TrimSpace(lines[next])) == 0 {
+			next++
+		}
+		if next == len(lines) || !isListItem(lines[next]) {
+			break
+		}
+		i = next
+	}
+	r.writeList(items)
+	return i
+}
+
+func listTags(marker string) (list, item string) {
+	switch marker[0] {
+	case '*', '-':
+		return "ul", "li"
+	case '.':
+		return "ol", "li"
+	default:
+		return "dl", "dd"
+	}
+}
+
+// writeList writes the items of a list, an item with a marker which is not
+// used by the enclosing lists starts a nested list
+func (r *renderer) writeList(items []*listItem) {
+	var markers []string
+	closeItem := func() {
+		_, item := listTags(markers[len(markers)-1])
+		r.buf.WriteString("</" + item + ">\n")
+	}
+	closeList := func() {
+		closeItem()
+		list, _ := listTags(markers[len(markers)-1])
+		r.buf.WriteString("</" + list + ">\n")
+		markers = markers[:len(markers)-1]
+	}
+
+	for _, item := range items {
+		depth := -1
+		for i, marker := range markers {
+			if marker == item.marker {
+				depth = i
+			}
+		}
+		if depth >= 0 {
+			for len(markers) > depth+1 {
+				closeList()
+			}
+			closeItem()
+		} else {
+			list, _ := listTags(item.marker)
+			r.buf.WriteString("<" + list + ">\n")
+			markers = append(markers, item.marker)
+		}
+
+		text := strings.Join(item.text, "\n")
+		if list, _ := listTags(item.marker); list == "dl" {
+			r.buf.WriteString("<dt>" + r.inline(item.term) + "</dt>\n<dd>")
+		} else if m := checklistRegexp.FindStringSubmatch(text); m != nil && list == "ul" {
+			checked := ""
+			if m[1] != " " {
+				checked = " checked"
+			}
+			r.buf.WriteString(`<li class="task-list-item"><input type="checkbox" disabled` + checked + `>`)
+			text = m[2]
+		} else {
+			r.buf.WriteString("<li>")
+		}
+		r.buf.WriteString(r.inline(text))
+		for _, block := range item.blocks {
+			r.buf.WriteByte('\n')
+			r.renderBlocks(block)
+		}
+	}
+	for len(markers) > 0 {
+		closeList()
+	}
+}
+
+func (r *renderer) renderTable(content []string, meta *blockMeta) {
+	var cells []string
+	columns := 0
+	implicitHeader := false
+	for i, line := range content {
+		parts := splitCells(line)
+		if len(parts) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "|") && !cellSpecRegexp.MatchString(strings.TrimSpace(parts[0])) {
+			// text continuing the previous cell
+			if len(cells) > 0 {
+				cells[len(cells)-1] += "\n" + strings.TrimSpace(parts[0])
+			}
+		}
+		if columns == 0 {
+			columns = len(parts) - 1
+			implicitHeader = i == 0 && len(content) > 1 && len(strings.TrimSpace(content[1])) == 0
+		}
+		for _, part := range parts[1:] {
+			cells = append(cells, strings.TrimSpace(part))
+		}
+	}
+	if cols := meta.named["cols"]; len(cols) > 0 {
+		columns = countColumns(cols)
+	}
+	if columns <= 0 {
+		return
+	}
+
+	r.buf.WriteString("<table>\n")
+	if len(meta.title) > 0 {
+		r.buf.WriteString("<caption>" + r.inline(meta.title) + "</caption>\n")
+	}
+	header := meta.options["header"] || (implicitHeader && !meta.options["noheader"])
+	for row := 0; row*columns < len(cells); row++ {
+		tag := "td"
+		if row == 0 && header {
+			tag = "th"
+			r.buf.WriteString("<thead>\n")
+		} else if row == 0 || (row == 1 && header) {
+			r.buf.WriteString("<tbody>\n")
+		}
+		r.buf.WriteString("<tr>")
+		for col := 0; col < columns; col++ {
+			cell := ""
+			if idx := row*columns + col; idx < len(cells) {
+				cell = r.inline(cells[idx])
+			}
+			r.buf.WriteString("<" + tag + ">" + cell + "</" + tag + ">")
+		}
+		r.buf.WriteString("</tr>\n")
+		if row == 0 && header {
+			r.buf.WriteString("</thead>\n")
+		}
+	}
+	if len(cells) > 0 && !(header && len(cells) <= columns) {
+		r.buf.WriteString("</tbody>\n")
+	}
+	r.buf.WriteString("</table>\n")
+}
+
+// splitCells splits a line of a table at the unescaped cell separators,
+// the first element is the text before the first separator
+func splitCells(line string) []string {
+	if len(strings.TrimSpace(line)) == 0 {
+		return nil
+	}
+	var parts []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '|' {
+			i++
+			continue
+		}
+		if line[i] == '|' {
+			parts = append(parts, strings.ReplaceAll(line[start:i], `\|`, "|"))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.ReplaceAll(line[start:], `\|`, "|"))
+}
+
+func countColumns(cols string) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(cols)); err == nil {
+		return n
+	}
+	columns := 0
+	for _, col := range strings.Split(cols, ",") {
+		if m := tableColumnsRegexp.FindStringSubmatch(strings.TrimSpace(col)); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			columns += n
+		} else {
+			columns++
+		}
+	}
+	return columns
+}
+
+func (r *renderer) renderFootnotes() {
+	if len(r.footnotes) == 0 {
+		return
+	}
+	r.buf.WriteString("<hr>\n<ol>\n")
+	for i, footnote := range r.footnotes {
+		fmt.Fprintf(&r.buf, "<li id=\"_footnotedef_%d\">%s</li>\n", i+1, footnote)
+	}
+	r.buf.WriteString("</ol>\n")
+}
+
+func escapeAttr(s string) string {
+	return strings.ReplaceAll(s, `"`, "&#34;")
+}
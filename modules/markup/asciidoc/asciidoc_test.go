@@ -0,0 +1,157 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const AppURL = "http://localhost:3000/"
+const Repo = "gogits/gogs"
+const AppSubURL = AppURL + Repo + "/"
+
+func test(t *testing.T, input, expected string) {
+	buffer := RenderString(input, setting.AppSubURL, nil, false)
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(buffer))
+}
+
+func TestRender_Headings(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "= Title\nJane Doe <jane@example.com>\n\n== Getting Started\n\n=== Getting Started",
+		`<h1 id="_title">Title</h1>
+<h2 id="_getting_started">Getting Started</h2>
+<h3 id="_getting_started-1">Getting Started</h3>`)
+	test(t, ":idprefix:\n:idseparator: -\n\n== Getting *Started*",
+		`<h2 id="getting-started">Getting <strong>Started</strong></h2>`)
+	test(t, "[[install]]\n== Installation\n\nSee <<install>> and <<install,the installation>>.",
+		`<h2 id="install">Installation</h2>
+<p>See <a href="#user-content-install">Installation</a> and <a href="#user-content-install">the installation</a>.</p>`)
+}
+
+func TestRender_Inline(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "*bold* _emphasis_ `code` #mark# x^2^ H~2~O a*b*c **un**constrained",
+		`<p><strong>bold</strong> <em>emphasis</em> <code>code</code> <mark>mark</mark> x<sup>2</sup> H<sub>2</sub>O a*b*c <strong>un</strong>constrained</p>`)
+	test(t, "pass:[<u>raw</u>] `+*literal* <b>+` {cpp} {undefined}",
+		`<p><u>raw</u> <code>*literal* &lt;b&gt;</code> C++ {undefined}</p>`)
+	test(t, "first line +\nsecond line",
+		"<p>first line<br>\nsecond line</p>")
+}
+
+func TestRender_Links(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, ":url: https://gitea.io\n\n{url}[Gitea^] link:docs/install.adoc[install] xref:other.adoc#intro[] mailto:a@b.c[]",
+		`<p><a href="https://gitea.io">Gitea</a> <a href="`+util.URLJoin(AppSubURL, "docs/install.adoc")+`">install</a> <a href="`+util.URLJoin(AppSubURL, "other.adoc#intro")+`">other.adoc#intro</a> <a href="mailto:a@b.c">a@b.c</a></p>`)
+	test(t, "image:icon.png[Icon] kbd:[Ctrl+C]",
+		`<p><img src="`+util.URLJoin(AppURL, Repo, "icon.png")+`" alt="Icon"> <kbd>Ctrl</kbd>+<kbd>C</kbd></p>`)
+
+	setting.AppSubURL = AppURL + Repo + "/src/branch/master/"
+	defer func() { setting.AppSubURL = AppSubURL }()
+	test(t, ".Logo\nimage::images/logo.png[]",
+		`<figure><a href="`+AppURL+Repo+`/media/branch/master/images/logo.png"><img src="`+AppURL+Repo+`/media/branch/master/images/logo.png" alt="logo"></a><figcaption>Logo</figcaption></figure>`)
+}
+
+func TestRender_Blocks(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "[source,go]\n----\nfmt.Println(\"<hi>\")\n----",
+		`<pre><code class="language-go">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>`)
+	test(t, "....\n*literal*\n....\n\n  indented literal",
+		"<pre>*literal*</pre>\n<pre>indented literal</pre>")
+	test(t, "NOTE: Read this.\n\n[WARNING]\n====\nCareful.\n====",
+		`<blockquote>
+<p><strong>Note</strong></p>
+<p>Read this.</p>
+</blockquote>
+<blockquote>
+<p><strong>Warning</strong></p>
+<p>Careful.</p>
+</blockquote>`)
+	test(t, "[quote, Jane Doe]\n____\nQuoted text.\n____",
+		`<blockquote>
+<p>Quoted text.</p>
+<p>&#8212; Jane Doe</p>
+</blockquote>`)
+	test(t, "// comment\n////\nblock comment\n////\n\n'''",
+		"<hr>")
+	test(t, ":name: value\n\nifdef::name[]\nshown\nendif::[]\nifndef::name[]\nhidden\nendif::[]\nifdef::undefined[hidden]",
+		"<p>shown</p>")
+}
+
+func TestRender_Lists(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "* one\n** nested\n* [x] done\n+\n----\ncode\n----",
+		`<ul>
+<li>one<ul>
+<li>nested</li>
+</ul>
+</li>
+<li class="task-list-item"><input type="checkbox" disabled checked>done
+<pre><code>code</code></pre>
+</li>
+</ul>`)
+	test(t, ". first\n. second\n\nCPU:: The brain",
+		`<ol>
+<li>first</li>
+<li>second<dl>
+<dt>CPU</dt>
+<dd>The brain</dd>
+</dl>
+</li>
+</ol>`)
+}
+
+func TestRender_Tables(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, ".Values\n|===\n|Name |Value\n\n|a |*1*\n|b\n|2\n|===",
+		`<table>
+<caption>Values</caption>
+<thead>
+<tr><th>Name</th><th>Value</th></tr>
+</thead>
+<tbody>
+<tr><td>a</td><td><strong>1</strong></td></tr>
+<tr><td>b</td><td>2</td></tr>
+</tbody>
+</table>`)
+	test(t, "[cols=\"2*\"]\n|===\n|a\n|b\n|===",
+		`<table>
+<tbody>
+<tr><td>a</td><td>b</td></tr>
+</tbody>
+</table>`)
+}
+
+func TestRender_PostProcess(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+	markup.Init()
+
+	metas := map[string]string{"user": "gogits", "repo": "gogs"}
+	buffer := markup.RenderString("README.adoc", "== Usage\n\nFixes #12, see <<_usage>>.\n\n* [x] #mark#", AppSubURL, metas)
+	assert.Equal(t, `<h2 id="user-content-_usage">Usage</h2>
+<p><span>Fixes</span> <a href="`+util.URLJoin(AppURL, "gogits", "gogs", "issues", "12")+`" class="ref-issue" rel="nofollow">#12</a>, see <a href="#user-content-_usage" rel="nofollow">Usage</a>.</p>
+<ul>
+<li class="task-list-item"><input type="checkbox" disabled="" checked=""/><mark>mark</mark></li>
+</ul>`, strings.TrimSpace(buffer))
+}
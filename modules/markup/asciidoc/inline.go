@@ -0,0 +1,239 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/markup"
+)
+
+var (
+	passthroughRegexp  = regexp.MustCompile(`\+\+\+(.+?)\+\+\+|pass:\[(.*?)\]|` + "`\\+(.+?)\\+`" + `|\+\+(.+?)\+\+|(^|[^\w+])\+([^\s+]|[^\s+].*?[^\s])\+`)
+	placeholderRegexp  = regexp.MustCompile("\x00(\\d+)\x00")
+	attributeRefRegexp = regexp.MustCompile(`\{(\w[\w-]*)\}`)
+	lineBreakRegexp    = regexp.MustCompile(`(?m) \+$`)
+	inlineMacroRegexp  = regexp.MustCompile(`image:([^:\s\[][^\s\[]*)\[([^\]]*)\]` +
+		`|\b(link|xref|mailto):([^\s\[]+)\[([^\]]*)\]` +
+		`|\b((?:https?|ftp|irc)://[^\s\[\]"<>]+)\[([^\]]*)\]` +
+		`|&lt;&lt;([^,\s&]+?)(?:,\s*(.*?))?&gt;&gt;` +
+		`|kbd:\[(.*?)\]` +
+		`|footnote:\[(.*?)\]` +
+		`|\[\[([\w:.-]+)(?:,[^\]]*)?\]\]`)
+
+	specialCharsReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	replacementsReplacer = strings.NewReplacer("(C)", "&#169;", "(R)", "&#174;", "(TM)", "&#8482;", " -- ", "&#8201;&#8212;&#8201;", "...", "&#8230;&#8203;")
+)
+
+// quotes are the inline formatting marks in the order Asciidoctor applies them
+var quotes = []struct {
+	delimiter   string
+	tag         string
+	constrained bool
+}{
+	{"**", "strong", false},
+	{"*", "strong", true},
+	{"``", "code", false},
+	{"`", "code", true},
+	{"__", "em", false},
+	{"_", "em", true},
+	{"##", "mark", false},
+	{"#", "mark", true},
+}
+
+// inline applies the inline substitutions of Asciidoctor to text and returns HTML
+func (r *renderer) inline(text string) string {
+	var passthroughs []string
+	text = replaceAllSubmatchFunc(passthroughRegexp, text, func(groups []string) string {
+		var prefix, content string
+		switch {
+		case len(groups[1]) > 0:
+			content = groups[1]
+		case len(groups[2]) > 0:
+			content = groups[2]
+		case len(groups[3]) > 0:
+			content = "<code>" + html.EscapeString(groups[3]) + "</code>"
+		case len(groups[4]) > 0:
+			content = html.EscapeString(groups[4])
+		default:
+			prefix, content = groups[5], html.EscapeString(groups[6])
+		}
+		passthroughs = append(passthroughs, content)
+		return prefix + "\x00" + strconv.Itoa(len(passthroughs)-1) + "\x00"
+	})
+
+	text = specialCharsReplacer.Replace(text)
+	for _, quote := range quotes {
+		text = replaceQuoted(text, quote.delimiter, quote.tag, quote.constrained)
+	}
+	text = replaceAllSubmatchFunc(superscriptRegexp, text, func(groups []string) string {
+		return "<sup>" + groups[1] + "</sup>"
+	})
+	text = replaceAllSubmatchFunc(subscriptRegexp, text, func(groups []string) string {
+		return "<sub>" + groups[1] + "</sub>"
+	})
+	text = attributeRefRegexp.ReplaceAllStringFunc(text, func(ref string) string {
+		if value, ok := r.attributes[ref[1:len(ref)-1]]; ok {
+			return value
+		}
+		return ref
+	})
+	text = replacementsReplacer.Replace(text)
+	text = replaceAllSubmatchFunc(inlineMacroRegexp, text, r.inlineMacro)
+	text = lineBreakRegexp.ReplaceAllString(text, "<br>")
+
+	return placeholderRegexp.ReplaceAllStringFunc(text, func(placeholder string) string {
+		idx, _ := strconv.Atoi(placeholder[1 : len(placeholder)-1])
+		return passthroughs[idx]
+	})
+}
+
+var (
+	superscriptRegexp = regexp.MustCompile(`\^(\S+?)\^`)
+	subscriptRegexp   = regexp.MustCompile(`~(\S+?)~`)
+)
+
+func (r *renderer) inlineMacro(groups []string) string {
+	switch {
+	case len(groups[1]) > 0:
+		src := markup.ResolveMediaLink(r.urlPrefix, html.UnescapeString(groups[1]), r.isWiki)
+		alt := groups[2]
+		if idx := strings.IndexByte(alt, ','); idx >= 0 {
+			alt = alt[:idx]
+		}
+		if len(alt) == 0 {
+			alt = html.EscapeString(imageAlt(groups[1]))
+		}
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(src), escapeAttr(alt))
+	case len(groups[3]) > 0:
+		target, text := html.UnescapeString(groups[4]), strings.TrimSuffix(groups[5], "^")
+		switch groups[3] {
+		case "mailto":
+			if len(text) == 0 {
+				text = groups[4]
+			}
+			return r.link("mailto:"+target, text)
+		case "xref":
+			return r.xref(target, text)
+		}
+		return r.link(target, text)
+	case len(groups[6]) > 0:
+		return r.link(html.UnescapeString(groups[6]), strings.TrimSuffix(groups[7], "^"))
+	case len(groups[8]) > 0:
+		return r.xref(html.UnescapeString(groups[8]), groups[9])
+	case len(groups[10]) > 0:
+		keys := strings.Split(groups[10], "+")
+		for i, key := range keys {
+			keys[i] = "<kbd>" + strings.TrimSpace(key) + "</kbd>"
+		}
+		return strings.Join(keys, "+")
+	case len(groups[11]) > 0:
+		r.footnotes = append(r.footnotes, groups[11])
+		n := strconv.Itoa(len(r.footnotes))
+		return `<sup><a href="` + markup.ResolveLink(r.urlPrefix, "#_footnotedef_"+n, r.isWiki) + `">[` + n + `]</a></sup>`
+	case len(groups[12]) > 0:
+		return `<a id="` + escapeAttr(groups[12]) + `"></a>`
+	}
+	return groups[0]
+}
+
+func (r *renderer) link(target, text string) string {
+	href := markup.ResolveLink(r.urlPrefix, target, r.isWiki)
+	if len(text) == 0 {
+		text = html.EscapeString(target)
+	}
+	return `<a href="` + html.EscapeString(href) + `">` + text + `</a>`
+}
+
+// xref links to a section of this or another document, for which the
+// target is either an id, a path to a document or path#id
+func (r *renderer) xref(target, text string) string {
+	path, id := target, ""
+	if idx := strings.IndexByte(target, '#'); idx >= 0 {
+		path, id = target[:idx], target[idx+1:]
+	} else if !strings.HasSuffix(target, ".adoc") {
+		path, id = "", target
+	}
+	if len(path) == 0 && len(text) == 0 {
+		// replaced with the title of the section once the document is rendered
+		text = "\x01" + id + "\x01"
+	}
+	if len(id) > 0 {
+		path += "#" + id
+	}
+	if len(text) == 0 {
+		text = html.EscapeString(target)
+	}
+	href := markup.ResolveLink(r.urlPrefix, path, r.isWiki)
+	return `<a href="` + html.EscapeString(href) + `">` + text + `</a>`
+}
+
+// replaceQuoted wraps the text enclosed by delimiter in tag. A constrained
+// delimiter must be bounded by non-word characters, like *strong* but not
+// in a*b*c, while unconstrained ones like **strong** may be used anywhere.
+func replaceQuoted(text, delimiter, tag string, constrained bool) string {
+	if !strings.Contains(text, delimiter) {
+		return text
+	}
+	var sb strings.Builder
+	n := len(delimiter)
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], delimiter) && (!constrained || i == 0 || !isWordByte(text[i-1])) &&
+			i+n < len(text) && !isSpaceByte(text[i+n]) {
+			if end := findClosingQuote(text, i+n, delimiter, constrained); end >= 0 {
+				sb.WriteString("<" + tag + ">" + text[i+n:end] + "</" + tag + ">")
+				i = end + n
+				continue
+			}
+		}
+		sb.WriteByte(text[i])
+		i++
+	}
+	return sb.String()
+}
+
+func findClosingQuote(text string, from int, delimiter string, constrained bool) int {
+	n := len(delimiter)
+	for j := from + 1; j+n <= len(text); j++ {
+		if !strings.HasPrefix(text[j:], delimiter) || isSpaceByte(text[j-1]) {
+			continue
+		}
+		if !constrained || j+n == len(text) || !isWordByte(text[j+n]) && text[j+n] != delimiter[0] {
+			return j
+		}
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// replaceAllSubmatchFunc is like regexp.ReplaceAllStringFunc, but passes the submatches to repl
+func replaceAllSubmatchFunc(re *regexp.Regexp, text string, repl func(groups []string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, idx := range re.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(text[last:idx[0]])
+		groups := make([]string, len(idx)/2)
+		for i := range groups {
+			if idx[2*i] >= 0 {
+				groups[i] = text[idx[2*i]:idx[2*i+1]]
+			}
+		}
+		sb.WriteString(repl(groups))
+		last = idx[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
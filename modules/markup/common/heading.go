@@ -0,0 +1,43 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"strconv"
+)
+
+// HeadingIDs generates unique ids for the headings of a document
+type HeadingIDs struct {
+	values map[string]bool
+}
+
+// NewHeadingIDs returns a new HeadingIDs
+func NewHeadingIDs() *HeadingIDs {
+	return &HeadingIDs{values: map[string]bool{}}
+}
+
+// Generate returns the id for a heading of given text, numbered if it is already in use
+func (h *HeadingIDs) Generate(text string) string {
+	id := string(CleanValue([]byte(text)))
+	if len(id) == 0 {
+		id = "heading"
+	}
+	return h.Put(id)
+}
+
+// Put marks an explicitly given id as used, numbering it if it was already in use
+func (h *HeadingIDs) Put(id string) string {
+	if !h.values[id] {
+		h.values[id] = true
+		return id
+	}
+	for i := 1; ; i++ {
+		numbered := id + "-" + strconv.Itoa(i)
+		if !h.values[numbered] {
+			h.values[numbered] = true
+			return numbered
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// A MathInline struct represents inline math delimited by $ or $$
+type MathInline struct {
+	ast.BaseInline
+	Segment text.Segment
+	Display bool
+}
+
+// Dump implements Node.Dump.
+func (n *MathInline) Dump(source []byte, level int) {
+	m := map[string]string{}
+	m["Content"] = string(n.Segment.Value(source))
+	ast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindMathInline is the NodeKind for MathInline
+var KindMathInline = ast.NewNodeKind("GiteaMathInline")
+
+// Kind implements Node.Kind.
+func (n *MathInline) Kind() ast.NodeKind {
+	return KindMathInline
+}
+
+// NewMathInline returns a new MathInline node.
+func NewMathInline(segment text.Segment, display bool) *MathInline {
+	return &MathInline{
+		Segment: segment,
+		Display: display,
+	}
+}
+
+// A MathBlock struct represents a block of display math delimited by $$ lines
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+// Dump implements Node.Dump.
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindMathBlock is the NodeKind for MathBlock
+var KindMathBlock = ast.NewNodeKind("GiteaMathBlock")
+
+// Kind implements Node.Kind.
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+// IsRaw implements Node.IsRaw.
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// NewMathBlock returns a new MathBlock node.
+func NewMathBlock() *MathBlock {
+	return &MathBlock{}
+}
+
+var mathDelimiter = []byte("$$")
+
+type mathBlockParser struct {
+}
+
+var defaultMathBlockParser = &mathBlockParser{}
+
+// NewMathBlockParser returns a new BlockParser that parses blocks of display math.
+func NewMathBlockParser() parser.BlockParser {
+	return defaultMathBlockParser
+}
+
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+
+	node := NewMathBlock()
+	rest := line[pos+len(mathDelimiter):]
+	restStart := segment.Start + pos + len(mathDelimiter)
+	right := util.TrimRightSpaceLength(rest)
+	rest = rest[:len(rest)-right]
+	if bytes.HasSuffix(rest, mathDelimiter) {
+		// $$ ... $$ on a single line
+		rest = rest[:len(rest)-len(mathDelimiter)]
+		if util.IsBlank(rest) {
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(restStart, restStart+len(rest)))
+		node.closed = true
+	} else if !util.IsBlank(rest) {
+		node.Lines().Append(text.NewSegment(restStart, restStart+len(rest)))
+	}
+	return node, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*MathBlock).closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimRightSpace(util.TrimLeftSpace(line))
+	if bytes.HasSuffix(trimmed, mathDelimiter) {
+		if content := trimmed[:len(trimmed)-len(mathDelimiter)]; !util.IsBlank(content) {
+			start := segment.Start + util.TrimLeftSpaceLength(line)
+			node.Lines().Append(text.NewSegment(start, start+len(content)))
+		}
+		newline := 1
+		if line[len(line)-1] != '\n' {
+			newline = 0
+		}
+		reader.Advance(segment.Len() - newline)
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type mathInlineParser struct {
+}
+
+var defaultMathInlineParser = &mathInlineParser{}
+
+// NewMathInlineParser returns a new InlineParser that parses inline math.
+func NewMathInlineParser() parser.InlineParser {
+	return defaultMathInlineParser
+}
+
+func (s *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse parses $...$ and $$...$$. Like pandoc, the opening delimiter must not
+// be followed by a space and the closing one must not be preceded by a space
+// nor followed by a digit, so that amounts of money are not mistaken for math.
+func (s *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+	if len(line) <= opener || util.IsSpace(line[opener]) || line[opener] == '$' {
+		return nil
+	}
+
+	for i := opener; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] != '$' || !bytes.HasPrefix(line[i:], mathDelimiter[:opener]) || util.IsSpace(line[i-1]) {
+			continue
+		}
+		if next := i + opener; next < len(line) && line[next] >= '0' && line[next] <= '9' {
+			continue
+		}
+		node := NewMathInline(text.NewSegment(segment.Start+opener, segment.Start+i), opener == 2)
+		block.Advance(i + opener)
+		return node
+	}
+	return nil
+}
+
+// MathHTMLRenderer is a renderer.NodeRenderer implementation that renders
+// math nodes as code elements, which are typeset on the client side.
+type MathHTMLRenderer struct {
+	html.Config
+}
+
+// NewMathHTMLRenderer returns a new MathHTMLRenderer.
+func NewMathHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &MathHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *MathHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, r.renderMathInline)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+func (r *MathHTMLRenderer) renderMathInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*MathInline)
+		if n.Display {
+			_, _ = w.WriteString(`<code class="language-math display">`)
+		} else {
+			_, _ = w.WriteString(`<code class="language-math">`)
+		}
+		_, _ = w.Write(util.EscapeHTML(n.Segment.Value(source)))
+		_, _ = w.WriteString(`</code>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *MathHTMLRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<pre class="is-loading"><code class="language-math display">`)
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			value := line.Value(source)
+			_, _ = w.Write(util.EscapeHTML(value))
+			if i < lines.Len()-1 && !bytes.HasSuffix(value, []byte{'\n'}) {
+				_ = w.WriteByte('\n')
+			}
+		}
+		_, _ = w.WriteString(`</code></pre>`)
+		_ = w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type mathExtension struct{}
+
+// MathExtension represents the Gitea Math extension
+var MathExtension = &mathExtension{}
+
+// Extend extends the markdown converter with the Gitea Math parser
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(NewMathBlockParser(), 701),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(NewMathInlineParser(), 501),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewMathHTMLRenderer(), 500),
+	))
+}
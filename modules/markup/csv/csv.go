@@ -38,9 +38,8 @@ func (Parser) Extensions() []string {
 }
 
 // Render implements markup.Parser
-func (p Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
-	rd := csv.NewReader(bytes.NewReader(rawBytes))
-	rd.Comma = p.bestDelimiter(rawBytes)
+func (Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	rd := NewReader(rawBytes)
 	var tmpBlock bytes.Buffer
 	tmpBlock.WriteString(`<table class="table">`)
 	for {
@@ -64,9 +63,16 @@ func (p Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]strin
 	return tmpBlock.Bytes()
 }
 
-// bestDelimiter scores the input CSV data against delimiters, and returns the best match.
+// NewReader creates a csv.Reader for rawBytes which uses the best matching delimiter
+func NewReader(rawBytes []byte) *csv.Reader {
+	rd := csv.NewReader(bytes.NewReader(rawBytes))
+	rd.Comma = BestDelimiter(rawBytes)
+	return rd
+}
+
+// BestDelimiter scores the input CSV data against delimiters, and returns the best match.
 // Reads at most 10k bytes & 10 lines.
-func (p Parser) bestDelimiter(data []byte) rune {
+func BestDelimiter(data []byte) rune {
 	maxLines := 10
 	maxBytes := util.Min(len(data), 1e4)
 	text := string(data[:maxBytes])
@@ -78,7 +84,7 @@ func (p Parser) bestDelimiter(data []byte) rune {
 	bestDelim := delimiters[0]
 	bestScore := 0.0
 	for _, delim := range delimiters {
-		score := p.scoreDelimiter(lines, delim)
+		score := scoreDelimiter(lines, delim)
 		if score > bestScore {
 			bestScore = score
 			bestDelim = delim
@@ -89,7 +95,7 @@ func (p Parser) bestDelimiter(data []byte) rune {
 }
 
 // scoreDelimiter uses a count & regularity metric to evaluate a delimiter against lines of CSV
-func (Parser) scoreDelimiter(lines []string, delim rune) (score float64) {
+func scoreDelimiter(lines []string, delim rune) (score float64) {
 	countTotal := 0
 	countLineMax := 0
 	linesNotEqual := 0
@@ -6,15 +6,20 @@ package external
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
+	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/process"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/util"
 )
@@ -43,6 +48,17 @@ func (p *Parser) Extensions() []string {
 	return p.FileExtensions
 }
 
+// SanitizerRules returns the sanitizer rules which only apply to this tool
+func (p *Parser) SanitizerRules() []setting.MarkupSanitizerRule {
+	return p.MarkupParser.SanitizerRules
+}
+
+// DisplayInIFrame reports whether the output of the tool has to be displayed
+// unsanitized in a sandboxed iframe
+func (p *Parser) DisplayInIFrame() bool {
+	return p.RenderContentMode == setting.RenderContentModeIframe
+}
+
 func envMark(envName string) string {
 	if runtime.GOOS == "windows" {
 		return "%" + envName + "%"
@@ -93,7 +109,17 @@ func (p *Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]stri
 		args = append(args, f.Name())
 	}
 
-	cmd := exec.Command(commands[0], args...)
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(graceful.GetManager().HammerContext(), timeout)
+	defer cancel()
+
+	pid := process.GetManager().Add(fmt.Sprintf("Render %s [command: %s]", p.Name(), commands[0]), cancel)
+	defer process.GetManager().Remove(pid)
+
+	cmd := exec.CommandContext(ctx, commands[0], args...)
 	cmd.Env = append(
 		os.Environ(),
 		"GITEA_PREFIX_SRC="+urlPrefix,
@@ -104,7 +130,7 @@ func (p *Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]stri
 	}
 	cmd.Stdout = buf
 	if err := cmd.Run(); err != nil {
-		log.Error("%s render run command %s %v failed: %v", p.Name(), commands[0], args, err)
+		log.Error("%s render run command %s %v failed: %v (%v)", p.Name(), commands[0], args, err, ctx.Err())
 		return []byte("")
 	}
 	return buf.Bytes()
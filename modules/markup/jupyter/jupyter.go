@@ -0,0 +1,250 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/modules/highlight"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/markup/markdown"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+func init() {
+	markup.RegisterParser(Parser{})
+}
+
+// ansiEscapeRegexp matches the terminal color codes found in tracebacks and stream outputs
+var ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// notebookClassRegexp matches the classes of the notebook layout
+var notebookClassRegexp = regexp.MustCompile(`^nb-[\w-]+(\s+nb-[\w-]+)*$`)
+
+// languageRegexp matches the languages and file extensions from the notebook metadata which are used
+var languageRegexp = regexp.MustCompile(`^[\w+-]+$`)
+
+// imageMimeTypes are the image outputs which are embedded as data uris, in order of preference
+var imageMimeTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// Parser implements markup.Parser for Jupyter notebooks
+type Parser struct {
+}
+
+// Name implements markup.Parser
+func (Parser) Name() string {
+	return "jupyter"
+}
+
+// Extensions implements markup.Parser
+func (Parser) Extensions() []string {
+	return []string{".ipynb"}
+}
+
+// ExtendSanitizerPolicy implements markup.SanitizerPolicyParser. The layout classes
+// and the images of outputs are only allowed in rendered notebooks.
+func (Parser) ExtendSanitizerPolicy(policy *bluemonday.Policy) {
+	policy.AllowAttrs("class").Matching(notebookClassRegexp).OnElements("div")
+	// data uris are validated to be base64 encoded gif, jpeg, png or webp images
+	policy.AllowDataURIImages()
+}
+
+// multilineString is a string which notebooks may store as a list of lines
+type multilineString string
+
+// UnmarshalJSON implements json.Unmarshaler. Values which are neither a string
+// nor a list of strings (e.g. application/json outputs) are ignored.
+func (s *multilineString) UnmarshalJSON(data []byte) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = multilineString(str)
+	case '[':
+		var lines []string
+		if err := json.Unmarshal(data, &lines); err != nil {
+			return nil
+		}
+		*s = multilineString(strings.Join(lines, ""))
+	}
+	return nil
+}
+
+type notebook struct {
+	Metadata struct {
+		LanguageInfo struct {
+			Name          string `json:"name"`
+			FileExtension string `json:"file_extension"`
+		} `json:"language_info"`
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+	Cells []notebookCell `json:"cells"`
+}
+
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         multilineString  `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType     string                     `json:"output_type"`
+	Name           string                     `json:"name"`
+	Text           multilineString            `json:"text"`
+	Data           map[string]multilineString `json:"data"`
+	ExecutionCount *int                       `json:"execution_count"`
+	EName          string                     `json:"ename"`
+	EValue         string                     `json:"evalue"`
+	Traceback      []string                   `json:"traceback"`
+}
+
+// fileName returns a file name from which highlight can determine the language of the code cells.
+// Languages which are not made of word characters fall back to plain text.
+func (nb *notebook) fileName() string {
+	if ext := strings.TrimPrefix(nb.Metadata.LanguageInfo.FileExtension, "."); len(ext) > 0 {
+		if !languageRegexp.MatchString(ext) {
+			return "notebook.txt"
+		}
+		return "notebook." + ext
+	}
+	language := nb.Metadata.LanguageInfo.Name
+	if len(language) == 0 {
+		language = nb.Metadata.Kernelspec.Language
+	}
+	switch strings.ToLower(language) {
+	case "", "python":
+		return "notebook.py"
+	case "r":
+		return "notebook.r"
+	case "julia":
+		return "notebook.jl"
+	default:
+		if !languageRegexp.MatchString(language) {
+			return "notebook.txt"
+		}
+		return "notebook." + strings.ToLower(language)
+	}
+}
+
+// Render renders a Jupyter notebook to HTML
+func Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	var nb notebook
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal(rawBytes, &nb); err != nil {
+		log.Debug("Unable to parse notebook: %v", err)
+		return []byte(`<pre>` + html.EscapeString(string(rawBytes)) + `</pre>`)
+	}
+
+	fileName := nb.fileName()
+	language := strings.TrimPrefix(fileName[strings.LastIndex(fileName, "."):], ".")
+
+	var buf bytes.Buffer
+	buf.WriteString(`<div class="nb-notebook">`)
+	for _, cell := range nb.Cells {
+		switch cell.CellType {
+		case "markdown":
+			buf.WriteString(`<div class="nb-cell nb-markdown-cell">`)
+			buf.Write(markdown.Parser{}.Render([]byte(cell.Source), urlPrefix, metas, isWiki))
+			buf.WriteString(`</div>`)
+		case "code":
+			buf.WriteString(`<div class="nb-cell nb-code-cell"><div class="nb-input">`)
+			buf.WriteString(`<div class="nb-prompt">` + prompt("In", cell.ExecutionCount) + `</div>`)
+			fmt.Fprintf(&buf, `<pre><code class="chroma language-%s">%s</code></pre></div>`, html.EscapeString(language), highlight.Code(fileName, string(cell.Source)))
+			for _, output := range cell.Outputs {
+				renderOutput(&buf, output, urlPrefix, metas, isWiki)
+			}
+			buf.WriteString(`</div>`)
+		default:
+			buf.WriteString(`<div class="nb-cell nb-raw-cell"><pre>`)
+			buf.WriteString(html.EscapeString(string(cell.Source)))
+			buf.WriteString(`</pre></div>`)
+		}
+	}
+	buf.WriteString(`</div>`)
+	return buf.Bytes()
+}
+
+func prompt(name string, executionCount *int) string {
+	if executionCount == nil {
+		return name + " [ ]:"
+	}
+	return fmt.Sprintf("%s [%d]:", name, *executionCount)
+}
+
+func renderOutput(buf *bytes.Buffer, output notebookOutput, urlPrefix string, metas map[string]string, isWiki bool) {
+	switch output.OutputType {
+	case "stream":
+		class := "nb-output nb-stream"
+		if output.Name == "stderr" {
+			class = "nb-output nb-stderr"
+		}
+		buf.WriteString(`<div class="` + class + `"><pre>`)
+		buf.WriteString(html.EscapeString(ansiEscapeRegexp.ReplaceAllString(string(output.Text), "")))
+		buf.WriteString(`</pre></div>`)
+	case "error":
+		buf.WriteString(`<div class="nb-output nb-error"><pre>`)
+		if len(output.Traceback) > 0 {
+			buf.WriteString(html.EscapeString(ansiEscapeRegexp.ReplaceAllString(strings.Join(output.Traceback, "\n"), "")))
+		} else {
+			buf.WriteString(html.EscapeString(output.EName + ": " + output.EValue))
+		}
+		buf.WriteString(`</pre></div>`)
+	case "execute_result", "display_data":
+		buf.WriteString(`<div class="nb-output nb-result">`)
+		if output.OutputType == "execute_result" {
+			buf.WriteString(`<div class="nb-prompt">` + prompt("Out", output.ExecutionCount) + `</div>`)
+		}
+		renderOutputData(buf, output.Data, urlPrefix, metas, isWiki)
+		buf.WriteString(`</div>`)
+	}
+}
+
+// renderOutputData renders the richest representation of a mime bundle, the result is sanitized by markup.Render
+func renderOutputData(buf *bytes.Buffer, data map[string]multilineString, urlPrefix string, metas map[string]string, isWiki bool) {
+	for _, mimeType := range imageMimeTypes {
+		if image, ok := data[mimeType]; ok {
+			encoded := strings.Join(strings.Fields(string(image)), "")
+			fmt.Fprintf(buf, `<img src="data:%s;base64,%s" alt="output">`, mimeType, encoded)
+			return
+		}
+	}
+	if content, ok := data["text/html"]; ok {
+		buf.WriteString(`<div class="nb-html">`)
+		buf.WriteString(string(content))
+		buf.WriteString(`</div>`)
+		return
+	}
+	if content, ok := data["text/markdown"]; ok {
+		buf.Write(markdown.Parser{}.Render([]byte(content), urlPrefix, metas, isWiki))
+		return
+	}
+	if content, ok := data["text/plain"]; ok {
+		buf.WriteString(`<pre>`)
+		buf.WriteString(html.EscapeString(ansiEscapeRegexp.ReplaceAllString(string(content), "")))
+		buf.WriteString(`</pre>`)
+	}
+}
+
+// Render implements markup.Parser
+func (Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	return Render(rawBytes, urlPrefix, metas, isWiki)
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/ini.v1"
+)
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": ["# Title\n", "Some *text*"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [
+    {"name": "stdout", "output_type": "stream", "text": ["<b>hello</b>\n"]},
+    {"name": "stderr", "output_type": "stream", "text": "warning\n"},
+    {
+     "data": {"image/png": "iVBORw0K\nGgo=\n", "text/plain": ["<Figure>"]},
+     "metadata": {},
+     "output_type": "display_data"
+    },
+    {
+     "data": {"text/html": ["<table onclick=\"alert(1)\"><tr><td>1</td></tr></table>", "<script>alert(1)</script>"]},
+     "execution_count": 1,
+     "metadata": {},
+     "output_type": "execute_result"
+    },
+    {
+     "ename": "ValueError",
+     "evalue": "bad",
+     "output_type": "error",
+     "traceback": ["\u001b[0;31mValueError\u001b[0m: bad"]
+    }
+   ],
+   "source": ["print('<b>hello</b>')"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": null,
+   "metadata": {},
+   "outputs": [],
+   "source": []
+  }
+ ],
+ "metadata": {"language_info": {"name": "python", "file_extension": ".py"}},
+ "nbformat": 4,
+ "nbformat_minor": 4
+}`
+
+func TestRender(t *testing.T) {
+	setting.AppURL = "http://localhost:3000/"
+	setting.Cfg = ini.Empty()
+	res := Render([]byte(testNotebook), "", nil, false)
+	assert.Contains(t, string(res), `<div class="nb-cell nb-markdown-cell"><h1 id="user-content-title">Title</h1>`)
+	assert.Contains(t, string(res), `<div class="nb-prompt">In [1]:</div><pre><code class="chroma language-py">`)
+	assert.Contains(t, string(res), `<div class="nb-output nb-stream"><pre>&lt;b&gt;hello&lt;/b&gt;`)
+	assert.Contains(t, string(res), `<div class="nb-output nb-stderr"><pre>warning`)
+	assert.Contains(t, string(res), `<img src="data:image/png;base64,iVBORw0KGgo=" alt="output">`)
+	assert.NotContains(t, string(res), `&lt;Figure&gt;`)
+	assert.Contains(t, string(res), `<div class="nb-prompt">Out [1]:</div>`)
+	assert.Contains(t, string(res), `<pre>ValueError: bad</pre>`)
+	assert.Contains(t, string(res), `<div class="nb-prompt">In [ ]:</div>`)
+
+	res = Render([]byte("not a notebook <script>"), "", nil, false)
+	assert.Equal(t, "<pre>not a notebook &lt;script&gt;</pre>", string(res))
+}
+
+func TestRender_Language(t *testing.T) {
+	setting.AppURL = "http://localhost:3000/"
+	setting.Cfg = ini.Empty()
+	for metadata, class := range map[string]string{
+		`{"kernelspec": {"language": "R"}}`:                            "language-r",
+		`{"language_info": {"name": "go"}}`:                            "language-go",
+		`{"language_info": {"file_extension": ".py\" onclick=\"x"}}`:   "language-txt",
+		`{"language_info": {"name": "x\"><script>alert(1)</script>"}}`: "language-txt",
+	} {
+		res := Render([]byte(`{"cells": [{"cell_type": "code", "source": "1"}], "metadata": `+metadata+`}`), "", nil, false)
+		assert.Contains(t, string(res), `<pre><code class="chroma `+class+`">`, metadata)
+		assert.NotContains(t, string(res), "<script>", metadata)
+	}
+}
+
+func TestRender_Sanitized(t *testing.T) {
+	setting.AppURL = "http://localhost:3000/"
+	setting.Cfg = ini.Empty()
+	res := markup.Render("test.ipynb", []byte(testNotebook), "", nil)
+	assert.Contains(t, string(res), `<div class="nb-output nb-result"><div class="nb-prompt">Out [1]:</div><div class="nb-html"><table><tbody><tr><td>1</td></tr></tbody></table></div>`)
+	assert.Contains(t, string(res), `<img src="data:image/png;base64,iVBORw0KGgo=" alt="output"/>`)
+	assert.False(t, strings.Contains(string(res), "<script>"))
+	assert.False(t, strings.Contains(string(res), "onclick"))
+
+	// the notebook markup is not allowed outside of notebooks
+	assert.Equal(t, `<div>cell</div>`, markup.Sanitize(`<div class="nb-cell">cell</div>`))
+	assert.Equal(t, ``, markup.Sanitize(`<img src="data:image/png;base64,iVBORw0KGgo=">`))
+	res = markup.Render("test.md", []byte(`<img src="data:image/png;base64,iVBORw0KGgo=">`), "", nil)
+	assert.NotContains(t, string(res), "data:image")
+}
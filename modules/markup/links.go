@@ -0,0 +1,40 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"strings"
+
+	"code.gitea.io/gitea/modules/util"
+)
+
+// ResolveLink returns the href of a link found in a rendered document. Links
+// relative to the document are joined with urlPrefix and anchors are prefixed
+// the same way as the ids of the rendered document.
+func ResolveLink(urlPrefix, link string, isWiki bool) string {
+	if len(link) == 0 || isLinkStr(link) || strings.HasPrefix(link, "mailto:") {
+		return link
+	}
+	if link[0] == '#' {
+		return "#user-content-" + link[1:]
+	}
+	if isWiki {
+		link = util.URLJoin("wiki", link)
+	}
+	return util.URLJoin(urlPrefix, link)
+}
+
+// ResolveMediaLink returns the src of an image or video found in a rendered
+// document, relative ones are served from the media or raw wiki endpoints.
+func ResolveMediaLink(urlPrefix, link string, isWiki bool) string {
+	if len(link) == 0 || isLinkStr(link) || strings.HasPrefix(link, "data:") {
+		return link
+	}
+	if isWiki {
+		urlPrefix = util.URLJoin(urlPrefix, "wiki", "raw")
+	}
+	urlPrefix = strings.Replace(urlPrefix, "/src/", "/media/", 1)
+	return util.URLJoin(urlPrefix, link)
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markdown
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"html"
+	"strings"
+	"sync"
+
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Diagram is a block holding the source of a diagram, taken from a fenced
+// code block whose language has a registered DiagramRenderer
+type Diagram struct {
+	ast.BaseBlock
+	Language string
+}
+
+// Dump implements Node.Dump .
+func (n *Diagram) Dump(source []byte, level int) {
+	m := map[string]string{}
+	m["Language"] = n.Language
+	ast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindDiagram is the NodeKind for Diagram
+var KindDiagram = ast.NewNodeKind("Diagram")
+
+// Kind implements Node.Kind.
+func (n *Diagram) Kind() ast.NodeKind {
+	return KindDiagram
+}
+
+// IsRaw implements Node.IsRaw.
+func (n *Diagram) IsRaw() bool {
+	return true
+}
+
+// NewDiagram returns a new Diagram node.
+func NewDiagram(language string) *Diagram {
+	return &Diagram{
+		BaseBlock: ast.BaseBlock{},
+		Language:  language,
+	}
+}
+
+// DiagramRenderer renders the source of a diagram to HTML
+type DiagramRenderer interface {
+	RenderDiagram(w util.BufWriter, language string, source []byte) error
+}
+
+var (
+	diagramRenderers     = map[string]DiagramRenderer{}
+	diagramRenderersLock sync.RWMutex
+)
+
+// RegisterDiagramRenderer registers the renderer for fenced code blocks of the given language
+func RegisterDiagramRenderer(language string, renderer DiagramRenderer) {
+	diagramRenderersLock.Lock()
+	diagramRenderers[strings.ToLower(language)] = renderer
+	diagramRenderersLock.Unlock()
+}
+
+func getDiagramRenderer(language string) DiagramRenderer {
+	diagramRenderersLock.RLock()
+	defer diagramRenderersLock.RUnlock()
+	return diagramRenderers[strings.ToLower(language)]
+}
+
+// ClientSideDiagramRenderer leaves the diagram to a script of the frontend,
+// which finds it by the language-* class of the code element
+type ClientSideDiagramRenderer struct{}
+
+// RenderDiagram implements DiagramRenderer
+func (ClientSideDiagramRenderer) RenderDiagram(w util.BufWriter, language string, source []byte) error {
+	if _, err := w.WriteString(`<pre class="is-loading"><code class="language-` + language + `">`); err != nil {
+		return err
+	}
+	if _, err := w.Write(util.EscapeHTML(source)); err != nil {
+		return err
+	}
+	_, err := w.WriteString("</code></pre>\n")
+	return err
+}
+
+const plantUMLAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+
+var plantUMLEncoding = base64.NewEncoding(plantUMLAlphabet).WithPadding(base64.NoPadding)
+
+// PlantUMLServerRenderer links the diagram as an image rendered by a PlantUML server
+type PlantUMLServerRenderer struct {
+	ServerURL string
+}
+
+// RenderDiagram implements DiagramRenderer
+func (r PlantUMLServerRenderer) RenderDiagram(w util.BufWriter, language string, source []byte) error {
+	encoded, err := EncodePlantUML(source)
+	if err != nil {
+		return err
+	}
+	src := strings.TrimSuffix(r.ServerURL, "/") + "/svg/" + encoded
+	_, err = w.WriteString(`<p><img src="` + html.EscapeString(src) + `" alt="PlantUML diagram"></p>` + "\n")
+	return err
+}
+
+// EncodePlantUML encodes the source of a diagram the way PlantUML servers
+// expect it in their URLs: deflated and base64 encoded with their own alphabet
+func EncodePlantUML(source []byte) (string, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err = fw.Write(source); err != nil {
+		return "", err
+	}
+	if err = fw.Close(); err != nil {
+		return "", err
+	}
+	// PlantUML pads the last group with zero bytes instead of shortening it,
+	// these are ignored after the end of the deflate stream
+	for buf.Len()%3 != 0 {
+		buf.WriteByte(0)
+	}
+	return plantUMLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// initDiagramRenderers registers the built-in diagram renderers according to the settings
+func initDiagramRenderers() {
+	RegisterDiagramRenderer("mermaid", ClientSideDiagramRenderer{})
+	if setting.Markdown.PlantUMLServerURL != "" {
+		RegisterDiagramRenderer("plantuml", PlantUMLServerRenderer{ServerURL: setting.Markdown.PlantUMLServerURL})
+	}
+}
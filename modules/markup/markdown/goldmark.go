@@ -59,6 +59,7 @@ func (g *ASTTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 		toc = make([]Header, 0, 100)
 	}
 
+	var diagramBlocks []*ast.FencedCodeBlock
 	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
@@ -124,6 +125,13 @@ func (g *ASTTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 				link = []byte("#user-content-" + string(link)[1:])
 			}
 			v.Destination = link
+		case *ast.FencedCodeBlock:
+			language := strings.ToLower(string(v.Language(reader.Source())))
+			if getDiagramRenderer(language) == nil {
+				break
+			}
+			// replacing the node here would end the walk of its siblings
+			diagramBlocks = append(diagramBlocks, v)
 		case *ast.List:
 			if v.HasChildren() {
 				children := make([]ast.Node, 0, v.ChildCount())
@@ -165,6 +173,12 @@ func (g *ASTTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 		return ast.WalkContinue, nil
 	})
 
+	for _, block := range diagramBlocks {
+		diagram := NewDiagram(strings.ToLower(string(block.Language(reader.Source()))))
+		diagram.SetLines(block.Lines())
+		block.Parent().ReplaceChild(block.Parent(), block, diagram)
+	}
+
 	if createTOC && len(toc) > 0 {
 		lang := rc.Lang
 		if len(lang) == 0 {
@@ -251,6 +265,7 @@ func (r *HTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(KindDetails, r.renderDetails)
 	reg.Register(KindSummary, r.renderSummary)
 	reg.Register(KindIcon, r.renderIcon)
+	reg.Register(KindDiagram, r.renderDiagram)
 	reg.Register(KindTaskCheckBoxListItem, r.renderTaskCheckBoxListItem)
 	reg.Register(east.KindTaskCheckBox, r.renderTaskCheckBox)
 }
@@ -341,6 +356,29 @@ func (r *HTMLRenderer) renderIcon(w util.BufWriter, source []byte, node ast.Node
 	return ast.WalkContinue, nil
 }
 
+func (r *HTMLRenderer) renderDiagram(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*Diagram)
+	renderer := getDiagramRenderer(n.Language)
+	if renderer == nil {
+		return ast.WalkSkipChildren, nil
+	}
+
+	var diagramSource bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		diagramSource.Write(line.Value(source))
+	}
+	if err := renderer.RenderDiagram(w, n.Language, diagramSource.Bytes()); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkSkipChildren, nil
+}
+
 func (r *HTMLRenderer) renderTaskCheckBoxListItem(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*TaskCheckBoxListItem)
 	if entering {
@@ -7,7 +7,6 @@ package markdown
 
 import (
 	"bytes"
-	"strings"
 	"sync"
 
 	"code.gitea.io/gitea/modules/log"
@@ -46,58 +45,47 @@ func NewGiteaParseContext(urlPrefix string, metas map[string]string, isWiki bool
 // render renders Markdown to HTML without handling special links.
 func render(body []byte, urlPrefix string, metas map[string]string, wikiMarkdown bool) []byte {
 	once.Do(func() {
-		converter = goldmark.New(
-			goldmark.WithExtensions(extension.Table,
-				extension.Strikethrough,
-				extension.TaskList,
-				extension.DefinitionList,
-				common.FootnoteExtension,
-				highlighting.NewHighlighting(
-					highlighting.WithFormatOptions(
-						chromahtml.WithClasses(true),
-						chromahtml.PreventSurroundingPre(true),
-					),
-					highlighting.WithWrapperRenderer(func(w util.BufWriter, c highlighting.CodeBlockContext, entering bool) {
-						if entering {
-							language, _ := c.Language()
-							if language == nil {
-								language = []byte("text")
-							}
-
-							languageStr := string(language)
-
-							preClasses := []string{}
-							if languageStr == "mermaid" {
-								preClasses = append(preClasses, "is-loading")
-							}
-
-							if len(preClasses) > 0 {
-								_, err := w.WriteString(`<pre class="` + strings.Join(preClasses, " ") + `">`)
-								if err != nil {
-									return
-								}
-							} else {
-								_, err := w.WriteString(`<pre>`)
-								if err != nil {
-									return
-								}
-							}
-
-							// include language-x class as part of commonmark spec
-							_, err := w.WriteString(`<code class="chroma language-` + string(language) + `">`)
-							if err != nil {
-								return
-							}
-						} else {
-							_, err := w.WriteString("</code></pre>")
-							if err != nil {
-								return
-							}
-						}
-					}),
+		initDiagramRenderers()
+
+		extensions := []goldmark.Extender{
+			extension.Table,
+			extension.Strikethrough,
+			extension.TaskList,
+			extension.DefinitionList,
+			common.FootnoteExtension,
+			highlighting.NewHighlighting(
+				highlighting.WithFormatOptions(
+					chromahtml.WithClasses(true),
+					chromahtml.PreventSurroundingPre(true),
 				),
-				meta.Meta,
+				highlighting.WithWrapperRenderer(func(w util.BufWriter, c highlighting.CodeBlockContext, entering bool) {
+					if entering {
+						language, _ := c.Language()
+						if language == nil {
+							language = []byte("text")
+						}
+
+						// include language-x class as part of commonmark spec
+						_, err := w.WriteString(`<pre><code class="chroma language-` + string(language) + `">`)
+						if err != nil {
+							return
+						}
+					} else {
+						_, err := w.WriteString("</code></pre>")
+						if err != nil {
+							return
+						}
+					}
+				}),
 			),
+			meta.Meta,
+		}
+		if setting.Markdown.EnableMath {
+			extensions = append(extensions, common.MathExtension)
+		}
+
+		converter = goldmark.New(
+			goldmark.WithExtensions(extensions...),
 			goldmark.WithParserOptions(
 				parser.WithAttribute(),
 				parser.WithAutoHeadingID(),
@@ -5,6 +5,10 @@
 package markdown_test
 
 import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -26,6 +30,11 @@ var localMetas = map[string]string{
 	"repoPath": "../../../integrations/gitea-repositories-meta/user13/repo11.git/",
 }
 
+func init() {
+	// the converter is only created once, so math has to be enabled before the first test
+	setting.Markdown.EnableMath = true
+}
+
 func TestRender_StandardLinks(t *testing.T) {
 	setting.AppURL = AppURL
 	setting.AppSubURL = AppSubURL
@@ -308,3 +317,68 @@ func TestRender_RenderParagraphs(t *testing.T) {
 	test(t, "A\n\nB\nC\n", 2)
 	test(t, "A\n\n\nB\nC\n", 2)
 }
+
+func TestRender_Math(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test := func(input, expected string) {
+		buffer := RenderString(input, setting.AppSubURL, nil)
+		assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(buffer))
+	}
+
+	test("Euler: $e^{i\\pi} + 1 = 0$",
+		`<p>Euler: <code class="language-math">e^{i\pi} + 1 = 0</code></p>`)
+	test("Display: $$a < b$$",
+		`<p>Display: <code class="language-math display">a &lt; b</code></p>`)
+	test("It costs $5 and $10.",
+		`<p>It costs $5 and $10.</p>`)
+	test("$ a $",
+		`<p>$ a $</p>`)
+	test("$$\n\\sum_{i=0}^n i\n$$",
+		`<pre class="is-loading"><code class="language-math display">\sum_{i=0}^n i
+</code></pre>`)
+	test("`$x$`",
+		`<p><code>$x$</code></p>`)
+	test("$$\nx\n$$\nafter",
+		`<pre class="is-loading"><code class="language-math display">x
+</code></pre>
+<p>after</p>`)
+	test("$$ x^2 $$",
+		`<pre class="is-loading"><code class="language-math display"> x^2 </code></pre>`)
+}
+
+func TestRender_Diagrams(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test := func(input, expected string) {
+		buffer := RenderString(input, setting.AppSubURL, nil)
+		assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(buffer))
+	}
+
+	test("```mermaid\ngraph LR\n  A --> B\n```",
+		`<pre class="is-loading"><code class="language-mermaid">graph LR
+  A --&gt; B
+</code></pre>`)
+
+	RegisterDiagramRenderer("plantuml", PlantUMLServerRenderer{ServerURL: "https://plantuml.example.com/"})
+	encoded, err := EncodePlantUML([]byte("Alice -> Bob\n"))
+	assert.NoError(t, err)
+	test("```plantuml\nAlice -> Bob\n```",
+		`<p><img src="https://plantuml.example.com/svg/`+encoded+`" alt="PlantUML diagram"/></p>`)
+}
+
+func TestEncodePlantUML(t *testing.T) {
+	source := "@startuml\nBob -> Alice : hello\n@enduml\n"
+	encoded, err := EncodePlantUML([]byte(source))
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9A-Za-z_-]+$", encoded)
+
+	deflated, err := base64.NewEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_").
+		WithPadding(base64.NoPadding).DecodeString(encoded)
+	assert.NoError(t, err)
+	decoded, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	assert.NoError(t, err)
+	assert.Equal(t, source, string(decoded))
+}
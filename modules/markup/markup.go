@@ -10,6 +10,8 @@ import (
 
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/microcosm-cc/bluemonday"
 )
 
 // Init initialize regexps for markdown parsing
@@ -23,12 +25,24 @@ func Init() {
 	// since setting maybe changed extensions, this will reload all parser extensions mapping
 	extParsers = make(map[string]Parser)
 	for _, parser := range parsers {
-		for _, ext := range parser.Extensions() {
-			extParsers[strings.ToLower(ext)] = parser
+		if _, ok := parser.(ExternalParser); !ok {
+			registerExtensions(parser)
+		}
+	}
+	// external renderers configured by the admin take precedence over the native ones
+	for _, parser := range parsers {
+		if _, ok := parser.(ExternalParser); ok {
+			registerExtensions(parser)
 		}
 	}
 }
 
+func registerExtensions(parser Parser) {
+	for _, ext := range parser.Extensions() {
+		extParsers[strings.ToLower(ext)] = parser
+	}
+}
+
 // Parser defines an interface for parsering markup file to HTML
 type Parser interface {
 	Name() string // markup format name
@@ -36,6 +50,22 @@ type Parser interface {
 	Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte
 }
 
+// ExternalParser defines an interface for parsers backed by external tools,
+// which may carry their own sanitizer rules and may have to be displayed
+// in a sandboxed iframe instead of being sanitized
+type ExternalParser interface {
+	Parser
+	SanitizerRules() []setting.MarkupSanitizerRule
+	DisplayInIFrame() bool
+}
+
+// SanitizerPolicyParser defines an interface for built-in parsers whose output
+// needs more than the default sanitizer policy allows, e.g. embedded images
+type SanitizerPolicyParser interface {
+	Parser
+	ExtendSanitizerPolicy(policy *bluemonday.Policy)
+}
+
 var (
 	extParsers = make(map[string]Parser)
 	parsers    = make(map[string]Parser)
@@ -44,9 +74,7 @@ var (
 // RegisterParser registers a new markup file parser
 func RegisterParser(parser Parser) {
 	parsers[parser.Name()] = parser
-	for _, ext := range parser.Extensions() {
-		extParsers[strings.ToLower(ext)] = parser
-	}
+	registerExtensions(parser)
 }
 
 // GetParserByFileName get parser by filename
@@ -87,7 +115,7 @@ func render(parser Parser, rawBytes []byte, urlPrefix string, metas map[string]s
 	if err != nil {
 		log.Error("PostProcess: %v", err)
 	}
-	return SanitizeBytes(result)
+	return sanitizeRendererBytes(parser.Name(), result)
 }
 
 func renderByType(tp string, rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
@@ -105,6 +133,24 @@ func renderFile(filename string, rawBytes []byte, urlPrefix string, metas map[st
 	return nil
 }
 
+// RenderUnsanitized renders markup file to HTML without post processing and
+// sanitizing it, it must only be served to browsers within a sandboxed iframe.
+func RenderUnsanitized(filename string, rawBytes []byte, urlPrefix string, metas map[string]string) []byte {
+	if parser := GetParserByFileName(filename); parser != nil {
+		return parser.Render(rawBytes, urlPrefix, metas, false)
+	}
+	return nil
+}
+
+// IsIFrameRenderedFile reports whether the markup file of given name has to
+// be displayed in a sandboxed iframe
+func IsIFrameRenderedFile(filename string) bool {
+	if parser, ok := GetParserByFileName(filename).(ExternalParser); ok {
+		return parser.DisplayInIFrame()
+	}
+	return false
+}
+
 // Type returns if markup format via the filename
 func Type(filename string) string {
 	if parser := GetParserByFileName(filename); parser != nil {
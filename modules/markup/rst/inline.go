@@ -0,0 +1,296 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/markup/common"
+)
+
+// References and substitutions may be defined after their use, so they are
+// written as placeholders which are resolved once the document is rendered:
+// \x01name\x02text\x02fallback\x01 for references and \x03name\x02fallback\x03
+// for substitutions, the fallback is used if the name is not defined.
+var (
+	referencePlaceholderRegexp    = regexp.MustCompile("\x01([^\x01\x02]*)\x02([^\x01\x02]*)\x02([^\x01\x02]*)\x01")
+	substitutionPlaceholderRegexp = regexp.MustCompile("\x03([^\x02\x03]*)\x02([^\x02\x03]*)\x03")
+
+	roleRegexp         = regexp.MustCompile(`^:([\w.+:-]+):` + "`")
+	suffixRoleRegexp   = regexp.MustCompile(`^:([\w.+:-]+):`)
+	footnoteRefRegexp  = regexp.MustCompile(`^\[(\d+|#[\w-]*|\*|[\w-]+)\]_`)
+	wordRefRegexp      = regexp.MustCompile(`^([^\W_](?:[\w.+:-]*[^\W_])?)(__?)`)
+	embeddedLinkRegexp = regexp.MustCompile(`(?s)^(.*?)\s*<([^<>]+)>$`)
+)
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// isStartBoundary reports whether inline markup may start after c
+func isStartBoundary(c byte) bool {
+	return isSpaceByte(c) || strings.IndexByte(`-:/'"<([{`, c) >= 0
+}
+
+// isEndBoundary reports whether inline markup may end before c
+func isEndBoundary(c byte) bool {
+	return isSpaceByte(c) || strings.IndexByte(`-.,:;!?\/'")]}>`, c) >= 0
+}
+
+// findEnd returns the position of the end-string of inline markup, which
+// must not follow whitespace. The suffix function may accept text like the
+// _ of references following the end-string and returns its length.
+func findEnd(text string, from int, end string, suffix func(rest string) int) (int, int) {
+	for j := from + 1; j+len(end) <= len(text); j++ {
+		if !strings.HasPrefix(text[j:], end) || isSpaceByte(text[j-1]) || text[j-1] == '\\' {
+			continue
+		}
+		n := 0
+		if suffix != nil {
+			n = suffix(text[j+len(end):])
+		}
+		if after := j + len(end) + n; after == len(text) || isEndBoundary(text[after]) {
+			return j, n
+		}
+	}
+	return -1, 0
+}
+
+func referenceSuffix(rest string) int {
+	switch {
+	case strings.HasPrefix(rest, "__"):
+		return 2
+	case strings.HasPrefix(rest, "_"):
+		return 1
+	}
+	if m := suffixRoleRegexp.FindString(rest); len(m) > 0 {
+		return len(m)
+	}
+	return 0
+}
+
+// inline renders the inline markup of text to HTML
+func (r *renderer) inline(text string) string {
+	var sb strings.Builder
+	plainStart := 0
+	flush := func(i int) {
+		sb.WriteString(html.EscapeString(text[plainStart:i]))
+	}
+	for i := 0; i < len(text); {
+		if text[i] == '\\' && i+1 < len(text) {
+			flush(i)
+			if !isSpaceByte(text[i+1]) {
+				sb.WriteString(html.EscapeString(text[i+1 : i+2]))
+			}
+			i += 2
+			plainStart = i
+			continue
+		}
+		if i == 0 || isStartBoundary(text[i-1]) {
+			if n, result := r.inlineMarkup(text, i); n > 0 {
+				flush(i)
+				sb.WriteString(result)
+				i += n
+				plainStart = i
+				continue
+			}
+		}
+		i++
+	}
+	flush(len(text))
+	return sb.String()
+}
+
+// inlineMarkup renders the inline markup starting at i and returns its length
+func (r *renderer) inlineMarkup(text string, i int) (int, string) {
+	rest := text[i:]
+	if len(rest) < 2 || isSpaceByte(rest[1]) {
+		return 0, ""
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "``"):
+		if end, _ := findEnd(text, i+2, "``", nil); end >= 0 {
+			return end + 2 - i, "<code>" + html.EscapeString(text[i+2:end]) + "</code>"
+		}
+	case strings.HasPrefix(rest, "**"):
+		if end, _ := findEnd(text, i+2, "**", nil); end >= 0 {
+			return end + 2 - i, "<strong>" + r.inline(text[i+2:end]) + "</strong>"
+		}
+	case rest[0] == '*':
+		if end, _ := findEnd(text, i+1, "*", nil); end >= 0 {
+			return end + 1 - i, "<em>" + r.inline(text[i+1:end]) + "</em>"
+		}
+	case rest[0] == '`':
+		if end, n := findEnd(text, i+1, "`", referenceSuffix); end >= 0 {
+			content, suffix := text[i+1:end], text[end+1:end+1+n]
+			switch {
+			case suffix == "_" || suffix == "__":
+				return end + 1 + n - i, r.reference(content, suffix == "__")
+			case len(suffix) > 0:
+				return end + 1 + n - i, r.role(suffix[1:len(suffix)-1], content)
+			}
+			return end + 1 - i, r.role("", content)
+		}
+	case rest[0] == ':':
+		if m := roleRegexp.FindString(rest); len(m) > 0 {
+			if end, _ := findEnd(text, i+len(m), "`", nil); end >= 0 {
+				return end + 1 - i, r.role(m[1:len(m)-2], text[i+len(m):end])
+			}
+		}
+	case rest[0] == '|':
+		if end, n := findEnd(text, i+1, "|", referenceSuffix); end >= 0 && n <= 2 {
+			name := normalizeName(text[i+1 : end])
+			return end + 1 + n - i, "\x03" + html.EscapeString(name) + "\x02" + html.EscapeString(text[i:end+1]) + "\x03"
+		}
+	case rest[0] == '[':
+		if m := footnoteRefRegexp.FindStringSubmatch(rest); m != nil && (len(m[0]) == len(rest) || isEndBoundary(rest[len(m[0])])) {
+			return len(m[0]), r.footnoteReference(m[1])
+		}
+	case strings.HasPrefix(rest, "_`"):
+		if end, _ := findEnd(text, i+2, "`", nil); end >= 0 {
+			name := text[i+2 : end]
+			id := string(common.CleanValue([]byte(name)))
+			r.addTarget(name, "#"+id)
+			return end + 1 - i, `<span id="` + html.EscapeString(id) + `">` + r.inline(name) + "</span>"
+		}
+	default:
+		if m := wordRefRegexp.FindStringSubmatch(rest); m != nil && (len(m[0]) == len(rest) || isEndBoundary(rest[len(m[0])])) {
+			return len(m[0]), r.referencePlaceholder(m[1], m[2] == "__", html.EscapeString(m[1]), html.EscapeString(m[0]))
+		}
+	}
+	return 0, ""
+}
+
+// reference renders a hyperlink reference like `text <url>`_ or `name`_
+func (r *renderer) reference(content string, anonymous bool) string {
+	if m := embeddedLinkRegexp.FindStringSubmatch(content); m != nil {
+		text, target := m[1], strings.Join(strings.Fields(m[2]), "")
+		if len(text) == 0 {
+			text = target
+		}
+		if strings.HasSuffix(target, "_") {
+			// embedded reference to a named target
+			return r.referencePlaceholder(strings.TrimSuffix(target, "_"), false, r.inline(text), html.EscapeString(text))
+		}
+		if !anonymous {
+			r.addTarget(text, target)
+		}
+		return r.link(target, r.inline(text))
+	}
+	return r.referencePlaceholder(content, anonymous, r.inline(content), html.EscapeString(content))
+}
+
+func (r *renderer) referencePlaceholder(name string, anonymous bool, text, fallback string) string {
+	if anonymous {
+		name = "__" + strconv.Itoa(r.anonymousRefs)
+		r.anonymousRefs++
+	} else {
+		name = normalizeName(name)
+	}
+	return "\x01" + html.EscapeString(name) + "\x02" + text + "\x02" + fallback + "\x01"
+}
+
+func (r *renderer) link(target, text string) string {
+	href := markup.ResolveLink(r.urlPrefix, target, r.isWiki)
+	return `<a href="` + html.EscapeString(href) + `">` + text + `</a>`
+}
+
+// role renders interpreted text, the roles of Sphinx which are not known are shown as code
+func (r *renderer) role(name, content string) string {
+	switch name {
+	case "", "title-reference", "title", "t":
+		return "<cite>" + r.inline(content) + "</cite>"
+	case "emphasis":
+		return "<em>" + html.EscapeString(content) + "</em>"
+	case "strong":
+		return "<strong>" + html.EscapeString(content) + "</strong>"
+	case "sub", "subscript":
+		return "<sub>" + html.EscapeString(content) + "</sub>"
+	case "sup", "superscript":
+		return "<sup>" + html.EscapeString(content) + "</sup>"
+	case "abbr", "abbreviation", "acronym":
+		return "<abbr>" + html.EscapeString(content) + "</abbr>"
+	case "kbd":
+		return "<kbd>" + html.EscapeString(content) + "</kbd>"
+	case "math":
+		return `<code class="language-math">` + html.EscapeString(content) + "</code>"
+	case "doc", "ref":
+		text, target := content, content
+		if m := embeddedLinkRegexp.FindStringSubmatch(content); m != nil {
+			text, target = m[1], m[2]
+		}
+		if name == "doc" {
+			return r.link(target+".rst", html.EscapeString(text))
+		}
+		return html.EscapeString(text)
+	}
+	return "<code>" + html.EscapeString(content) + "</code>"
+}
+
+// footnoteLabel returns the number of an auto-numbered footnote with a label like #note
+func (r *renderer) footnoteLabel(label string) string {
+	if number, ok := r.footnotes[label]; ok {
+		return number
+	}
+	r.autoFootnoteRefs++
+	r.autoFootnoteDefs++
+	number := strconv.Itoa(r.autoFootnoteRefs)
+	r.footnotes[label] = number
+	return number
+}
+
+func (r *renderer) footnoteReference(label string) string {
+	switch {
+	case label == "#" || label == "*":
+		r.autoFootnoteRefs++
+		label = strconv.Itoa(r.autoFootnoteRefs)
+	case strings.HasPrefix(label, "#"):
+		label = r.footnoteLabel(label)
+	}
+	href := markup.ResolveLink(r.urlPrefix, "#footnote-"+label, r.isWiki)
+	return `<sup><a href="` + html.EscapeString(href) + `">[` + html.EscapeString(label) + `]</a></sup>`
+}
+
+// resolveReferences replaces the placeholders of references and substitutions
+func (r *renderer) resolveReferences(rendered []byte) []byte {
+	rendered = substitutionPlaceholderRegexp.ReplaceAllFunc(rendered, func(m []byte) []byte {
+		groups := substitutionPlaceholderRegexp.FindSubmatch(m)
+		if value, ok := r.substitutions[html.UnescapeString(string(groups[1]))]; ok {
+			return []byte(value)
+		}
+		return groups[2]
+	})
+	return referencePlaceholderRegexp.ReplaceAllFunc(rendered, func(m []byte) []byte {
+		groups := referencePlaceholderRegexp.FindSubmatch(m)
+		if target, ok := r.resolveTarget(html.UnescapeString(string(groups[1]))); ok {
+			return []byte(r.link(target, string(groups[2])))
+		}
+		return groups[3]
+	})
+}
+
+func (r *renderer) resolveTarget(name string) (string, bool) {
+	if strings.HasPrefix(name, "__") {
+		idx, _ := strconv.Atoi(name[2:])
+		if idx < len(r.anonymousTargets) {
+			return r.anonymousTargets[idx], true
+		}
+		return "", false
+	}
+	// follow indirect targets, but not endlessly
+	for i := 0; i < 10; i++ {
+		target, ok := r.targets[name]
+		if !ok || !strings.HasPrefix(target, "\x00") {
+			return target, ok
+		}
+		name = target[1:]
+	}
+	return "", false
+}
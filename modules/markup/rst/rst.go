@@ -0,0 +1,758 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/markup/common"
+)
+
+func init() {
+	markup.RegisterParser(Parser{})
+}
+
+// Parser implements markup.Parser for reStructuredText
+type Parser struct {
+}
+
+// Name implements markup.Parser
+func (Parser) Name() string {
+	return "restructuredtext"
+}
+
+// Extensions implements markup.Parser
+func (Parser) Extensions() []string {
+	return []string{".rst", ".rest"}
+}
+
+// Render implements markup.Parser
+func (Parser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	return Render(rawBytes, urlPrefix, metas, isWiki)
+}
+
+// RenderString renders reStructuredText string to HTML string
+func RenderString(rawContent string, urlPrefix string, metas map[string]string, isWiki bool) string {
+	return string(Render([]byte(rawContent), urlPrefix, metas, isWiki))
+}
+
+// Render renders reStructuredText to HTML. The directives and roles of
+// docutils which make sense for documents in a repository are supported.
+func Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	r := &renderer{
+		urlPrefix:     urlPrefix,
+		isWiki:        isWiki,
+		ids:           common.NewHeadingIDs(),
+		targets:       map[string]string{},
+		substitutions: map[string]string{},
+		footnotes:     map[string]string{},
+	}
+
+	content := strings.ReplaceAll(string(rawBytes), "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\t", "        ")
+	r.renderBlocks(strings.Split(content, "\n"))
+	return r.resolveReferences(r.buf.Bytes())
+}
+
+var (
+	bulletItemRegexp     = regexp.MustCompile(`^([-*+•])( +|$)`)
+	enumeratedItemRegexp = regexp.MustCompile(`^\(?(\d+|#)[.)]( +|$)`)
+	fieldRegexp          = regexp.MustCompile(`^:((?:[^:\\]|\\.)+):(?:\s+(.*))?$`)
+	lineBlockRegexp      = regexp.MustCompile(`^\|( +|$)`)
+	gridTableRegexp      = regexp.MustCompile(`^\+([-=]+\+)+$`)
+	simpleTableRegexp    = regexp.MustCompile(`^=+( +=+)+$`)
+	directiveRegexp      = regexp.MustCompile(`^\.\.\s+([\w-]+(?::[\w-]+)*)::(?:\s+(.*))?$`)
+	substitutionRegexp   = regexp.MustCompile(`^\.\.\s+\|([^|]+)\|\s+([\w-]+)::(?:\s+(.*))?$`)
+	targetRegexp         = regexp.MustCompile(`^\.\.\s+_(` + "`[^`]+`" + `|[^:]+|_):(?:\s+(.*))?$`)
+	anonymousTargetRegex = regexp.MustCompile(`^__\s+(.*)$`)
+	footnoteRegexp       = regexp.MustCompile(`^\.\.\s+\[(\d+|#[\w-]*|\*|[\w-]+)\](?:\s+(.*))?$`)
+	optionRegexp         = regexp.MustCompile(`^:([\w-]+):(?:\s+(.*))?$`)
+	languageRegexp       = regexp.MustCompile(`^[\w-]+$`)
+)
+
+const adornmentChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+var admonitionLabels = map[string]string{
+	"attention": "Attention",
+	"caution":   "Caution",
+	"danger":    "Danger",
+	"error":     "Error",
+	"hint":      "Hint",
+	"important": "Important",
+	"note":      "Note",
+	"tip":       "Tip",
+	"warning":   "Warning",
+	"seealso":   "See also",
+}
+
+type renderer struct {
+	buf              bytes.Buffer
+	urlPrefix        string
+	isWiki           bool
+	ids              *common.HeadingIDs
+	sectionStyles    []string
+	targets          map[string]string
+	anonymousTargets []string
+	anonymousRefs    int
+	substitutions    map[string]string
+	footnotes        map[string]string
+	autoFootnoteRefs int
+	autoFootnoteDefs int
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isAdornment reports whether line is an underline, overline or transition,
+// which consist of at least three repeated punctuation characters
+func isAdornment(line string) bool {
+	line = strings.TrimRight(line, " ")
+	if len(line) < 3 || !strings.ContainsRune(adornmentChars, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+func isBlank(line string) bool {
+	return len(strings.TrimSpace(line)) == 0
+}
+
+// indentedBlock returns the end of the indented block starting at i, trailing blank lines excluded
+func indentedBlock(lines []string, i int) int {
+	end := i
+	for j := i; j < len(lines); j++ {
+		if isBlank(lines[j]) {
+			continue
+		}
+		if indentation(lines[j]) == 0 {
+			break
+		}
+		end = j + 1
+	}
+	return end
+}
+
+// dedent removes the common indentation of lines
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if !isBlank(line) {
+			if n := indentation(line); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	return dedentBy(lines, indent)
+}
+
+func dedentBy(lines []string, indent int) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if n := indentation(line); n < indent {
+			line = line[n:]
+		} else if indent > 0 {
+			line = line[indent:]
+		}
+		result[i] = line
+	}
+	return result
+}
+
+func (r *renderer) renderBlocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimRight(lines[i], " ")
+		if len(line) == 0 {
+			i++
+			continue
+		}
+
+		var next string
+		if i+1 < len(lines) {
+			next = strings.TrimRight(lines[i+1], " ")
+		}
+
+		switch {
+		case indentation(line) > 0:
+			end := indentedBlock(lines, i)
+			r.buf.WriteString("<blockquote>\n")
+			r.renderBlocks(dedent(lines[i:end]))
+			r.buf.WriteString("</blockquote>\n")
+			i = end
+		case line == ".." && isBlank(next):
+			// an empty comment, which may end a list or separate block quotes
+			i++
+		case strings.HasPrefix(line, ".. ") || line == "..":
+			end := indentedBlock(lines, i+1)
+			r.renderExplicitMarkup(line, lines[i+1:end])
+			i = end
+		case anonymousTargetRegex.MatchString(line):
+			r.anonymousTargets = append(r.anonymousTargets, strings.TrimSpace(anonymousTargetRegex.FindStringSubmatch(line)[1]))
+			i++
+		case isAdornment(line) && i+2 < len(lines) && !isBlank(next) &&
+			strings.TrimRight(lines[i+2], " ") == line:
+			r.renderSection(strings.TrimSpace(next), line[:1]+"o")
+			i += 3
+		case isAdornment(line) && utf8.RuneCountInString(line) >= 4 && (i+1 == len(lines) || isBlank(next)):
+			r.buf.WriteString("<hr>\n")
+			i++
+		case len(next) > 0 && isAdornment(next) && indentation(next) == 0 && !gridTableRegexp.MatchString(line) &&
+			!simpleTableRegexp.MatchString(next):
+			r.renderSection(line, next[:1])
+			i += 2
+		case gridTableRegexp.MatchString(line):
+			end := i + 1
+			for end < len(lines) && !isBlank(lines[end]) && (lines[end][0] == '+' || lines[end][0] == '|') {
+				end++
+			}
+			r.renderGridTable(lines[i:end])
+			i = end
+		case simpleTableRegexp.MatchString(line):
+			i = r.renderSimpleTable(lines, i)
+		case bulletItemRegexp.MatchString(line):
+			i = r.renderList(lines, i, bulletItemRegexp, "ul")
+		case enumeratedItemRegexp.MatchString(line):
+			i = r.renderList(lines, i, enumeratedItemRegexp, "ol")
+		case fieldRegexp.MatchString(line):
+			i = r.renderFieldList(lines, i)
+		case lineBlockRegexp.MatchString(line):
+			var content []string
+			for ; i < len(lines) && lineBlockRegexp.MatchString(lines[i]); i++ {
+				content = append(content, r.inline(strings.TrimSpace(lines[i][1:])))
+			}
+			r.buf.WriteString("<p>" + strings.Join(content, "<br>\n") + "</p>\n")
+		case strings.HasPrefix(line, ">>> "):
+			end := i
+			for end < len(lines) && !isBlank(lines[end]) {
+				end++
+			}
+			r.renderLiteral(lines[i:end], "python")
+			i = end
+		case len(next) > 0 && indentation(next) > 0:
+			i = r.renderDefinitionList(lines, i)
+		default:
+			i = r.renderParagraph(lines, i)
+		}
+	}
+}
+
+func (r *renderer) renderSection(title, style string) {
+	level := -1
+	for i, s := range r.sectionStyles {
+		if s == style {
+			level = i
+		}
+	}
+	if level < 0 {
+		r.sectionStyles = append(r.sectionStyles, style)
+		level = len(r.sectionStyles) - 1
+	}
+	if level > 5 {
+		level = 5
+	}
+
+	id := r.ids.Generate(title)
+	r.addTarget(title, "#"+id)
+	fmt.Fprintf(&r.buf, "<h%d id=\"%s\">%s</h%d>\n", level+1, id, r.inline(title), level+1)
+}
+
+// normalizeName normalizes the name of a reference or target, these are
+// whitespace-neutral and case-insensitive
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (r *renderer) addTarget(name, target string) {
+	if name = normalizeName(name); len(name) > 0 {
+		if _, ok := r.targets[name]; !ok {
+			r.targets[name] = target
+		}
+	}
+}
+
+// renderParagraph renders the paragraph starting at i and the literal block
+// following it if it ends with ::
+func (r *renderer) renderParagraph(lines []string, i int) int {
+	var paragraph []string
+	for ; i < len(lines) && !isBlank(lines[i]) && indentation(lines[i]) == 0; i++ {
+		paragraph = append(paragraph, strings.TrimRight(lines[i], " "))
+	}
+
+	last := paragraph[len(paragraph)-1]
+	isLiteral := strings.HasSuffix(last, "::")
+	if isLiteral {
+		switch {
+		case last == "::":
+			paragraph = paragraph[:len(paragraph)-1]
+		case strings.HasSuffix(last, " ::"):
+			paragraph[len(paragraph)-1] = strings.TrimSuffix(last, " ::")
+		default:
+			paragraph[len(paragraph)-1] = strings.TrimSuffix(last, ":")
+		}
+	}
+	if len(paragraph) > 0 {
+		r.buf.WriteString("<p>" + r.inline(strings.Join(paragraph, "\n")) + "</p>\n")
+	}
+
+	if isLiteral {
+		start := i
+		for start < len(lines) && isBlank(lines[start]) {
+			start++
+		}
+		if start < len(lines) && indentation(lines[start]) > 0 {
+			end := indentedBlock(lines, start)
+			r.renderLiteral(dedent(lines[start:end]), "")
+			return end
+		}
+	}
+	return i
+}
+
+func (r *renderer) renderLiteral(lines []string, language string) {
+	if languageRegexp.MatchString(language) {
+		fmt.Fprintf(&r.buf, `<pre><code class="language-%s">`, strings.ToLower(language))
+	} else {
+		r.buf.WriteString("<pre><code>")
+	}
+	r.buf.WriteString(html.EscapeString(strings.Join(lines, "\n")))
+	r.buf.WriteString("</code></pre>\n")
+}
+
+// renderBody renders the body of a list item or definition, a leading
+// paragraph is not wrapped in <p> to keep simple lists compact
+func (r *renderer) renderBody(lines []string) {
+	end := 0
+	for end < len(lines) && !isBlank(lines[end]) && indentation(lines[end]) == 0 {
+		end++
+	}
+	if end > 0 && (end == len(lines) || isBlank(lines[end])) && !strings.HasSuffix(strings.TrimSpace(lines[end-1]), "::") &&
+		!bulletItemRegexp.MatchString(lines[0]) && !enumeratedItemRegexp.MatchString(lines[0]) &&
+		!strings.HasPrefix(lines[0], "..") && !fieldRegexp.MatchString(lines[0]) {
+		paragraph := make([]string, end)
+		for i := range paragraph {
+			paragraph[i] = strings.TrimSpace(lines[i])
+		}
+		r.buf.WriteString(r.inline(strings.Join(paragraph, "\n")))
+		if end < len(lines) {
+			r.buf.WriteByte('\n')
+		}
+		lines = lines[end:]
+	}
+	r.renderBlocks(lines)
+}
+
+// itemBody returns the lines of the item starting at i, whose first line
+// starts after the marker, and the index of the line following the item
+func itemBody(lines []string, i, markerWidth int) ([]string, int) {
+	body := []string{lines[i][markerWidth:]}
+	end := i + 1
+	for j := i + 1; j < len(lines); j++ {
+		if isBlank(lines[j]) {
+			continue
+		}
+		if indentation(lines[j]) < markerWidth {
+			break
+		}
+		end = j + 1
+	}
+	body = append(body, dedentBy(lines[i+1:end], markerWidth)...)
+	return body, end
+}
+
+func (r *renderer) renderList(lines []string, i int, marker *regexp.Regexp, tag string) int {
+	first := marker.FindStringSubmatch(lines[i])
+	if tag == "ol" && first[1] != "#" && first[1] != "1" {
+		fmt.Fprintf(&r.buf, "<ol start=\"%s\">\n", first[1])
+	} else {
+		r.buf.WriteString("<" + tag + ">\n")
+	}
+	for i < len(lines) {
+		m := marker.FindStringSubmatch(lines[i])
+		if m == nil || (tag == "ul" && m[1] != first[1]) {
+			break
+		}
+		body, end := itemBody(lines, i, len(m[0]))
+		r.buf.WriteString("<li>")
+		r.renderBody(body)
+		r.buf.WriteString("</li>\n")
+
+		i = end
+		for i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+	}
+	r.buf.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func (r *renderer) renderDefinitionList(lines []string, i int) int {
+	r.buf.WriteString("<dl>\n")
+	for i+1 < len(lines) && !isBlank(lines[i]) && indentation(lines[i]) == 0 &&
+		!isBlank(lines[i+1]) && indentation(lines[i+1]) > 0 {
+		term := strings.TrimSpace(lines[i])
+		if idx := strings.Index(term, " : "); idx >= 0 {
+			// classifiers are not shown
+			term = term[:idx]
+		}
+		end := indentedBlock(lines, i+1)
+		r.buf.WriteString("<dt>" + r.inline(term) + "</dt>\n<dd>")
+		r.renderBody(dedent(lines[i+1 : end]))
+		r.buf.WriteString("</dd>\n")
+
+		i = end
+		for i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+	}
+	r.buf.WriteString("</dl>\n")
+	return i
+}
+
+func (r *renderer) renderFieldList(lines []string, i int) int {
+	r.buf.WriteString("<dl>\n")
+	for i < len(lines) {
+		m := fieldRegexp.FindStringSubmatch(strings.TrimRight(lines[i], " "))
+		if m == nil {
+			break
+		}
+		end := indentedBlock(lines, i+1)
+		body := append([]string{m[2]}, dedent(lines[i+1:end])...)
+		r.buf.WriteString("<dt>" + r.inline(m[1]) + "</dt>\n<dd>")
+		r.renderBody(body)
+		r.buf.WriteString("</dd>\n")
+
+		i = end
+		for i < len(lines) && isBlank(lines[i]) {
+			i++
+		}
+	}
+	r.buf.WriteString("</dl>\n")
+	return i
+}
+
+// directive is an explicit markup block like .. image:: path
+type directive struct {
+	name    string
+	args    string
+	options map[string]string
+	content []string
+}
+
+func parseDirective(name, args string, block []string) *directive {
+	d := &directive{
+		name:    strings.ToLower(name),
+		args:    strings.TrimSpace(args),
+		options: map[string]string{},
+	}
+	block = dedent(block)
+	i := 0
+	// the arguments may continue on the following lines, which are followed by the options
+	for ; i < len(block) && !isBlank(block[i]) && !optionRegexp.MatchString(block[i]); i++ {
+		d.args = strings.TrimSpace(d.args + " " + strings.TrimSpace(block[i]))
+	}
+	for ; i < len(block) && !isBlank(block[i]); i++ {
+		if m := optionRegexp.FindStringSubmatch(block[i]); m != nil {
+			d.options[m[1]] = strings.TrimSpace(m[2])
+		}
+	}
+	for i < len(block) && isBlank(block[i]) {
+		i++
+	}
+	d.content = block[i:]
+	return d
+}
+
+func (r *renderer) renderExplicitMarkup(line string, block []string) {
+	if m := footnoteRegexp.FindStringSubmatch(line); m != nil {
+		label := m[1]
+		if label == "#" || label == "*" {
+			r.autoFootnoteDefs++
+			label = strconv.Itoa(r.autoFootnoteDefs)
+		} else if strings.HasPrefix(label, "#") {
+			label = r.footnoteLabel(label)
+		}
+		fmt.Fprintf(&r.buf, "<p id=\"footnote-%s\"><sup>%s</sup> ", html.EscapeString(label), html.EscapeString(label))
+		r.renderBody(append([]string{m[2]}, dedent(block)...))
+		r.buf.WriteString("</p>\n")
+		return
+	}
+	if m := targetRegexp.FindStringSubmatch(line); m != nil {
+		target := strings.TrimSpace(m[2])
+		for _, l := range block {
+			target += strings.TrimSpace(l)
+		}
+		name := strings.Trim(m[1], "`")
+		if name == "_" {
+			r.anonymousTargets = append(r.anonymousTargets, target)
+			return
+		}
+		if len(target) == 0 {
+			// an internal target refers to the location of its definition
+			id := string(common.CleanValue([]byte(name)))
+			r.addTarget(name, "#"+id)
+			fmt.Fprintf(&r.buf, "<a id=\"%s\"></a>\n", id)
+			return
+		}
+		if strings.HasSuffix(target, "_") && !strings.Contains(target, "/") {
+			// indirect target referring to another one
+			r.addTarget(name, "\x00"+normalizeName(strings.Trim(strings.TrimSuffix(target, "_"), "`")))
+			return
+		}
+		r.addTarget(name, strings.ReplaceAll(target, `\_`, "_"))
+		return
+	}
+	if m := substitutionRegexp.FindStringSubmatch(line); m != nil {
+		d := parseDirective(m[2], m[3], block)
+		var value string
+		switch d.name {
+		case "replace":
+			value = r.inline(strings.Join(append([]string{d.args}, d.content...), " "))
+		case "image":
+			value = r.image(d)
+		case "unicode":
+			value = html.EscapeString(d.args)
+		}
+		r.substitutions[normalizeName(m[1])] = value
+		return
+	}
+	if m := directiveRegexp.FindStringSubmatch(line); m != nil {
+		r.renderDirective(parseDirective(m[1], m[2], block))
+	}
+	// anything else is a comment
+}
+
+func (r *renderer) renderDirective(d *directive) {
+	switch d.name {
+	case "image":
+		r.buf.WriteString("<p>" + r.image(d) + "</p>\n")
+	case "figure":
+		r.buf.WriteString("<figure>" + r.image(d))
+		if len(d.content) > 0 {
+			end := 0
+			for end < len(d.content) && !isBlank(d.content[end]) {
+				end++
+			}
+			r.buf.WriteString("<figcaption>" + r.inline(strings.Join(d.content[:end], "\n")) + "</figcaption>")
+			d.content = d.content[end:]
+		}
+		r.buf.WriteString("</figure>\n")
+		r.renderBlocks(d.content)
+	case "code", "code-block", "sourcecode", "highlight":
+		r.renderLiteral(trimBlankLines(d.content), d.args)
+	case "math":
+		r.buf.WriteString(`<pre class="is-loading"><code class="language-math display">`)
+		r.buf.WriteString(html.EscapeString(strings.Join(trimBlankLines(append([]string{d.args}, d.content...)), "\n")))
+		r.buf.WriteString("</code></pre>\n")
+	case "admonition":
+		r.renderAdmonition(r.inline(d.args), d.content)
+	case "topic", "sidebar", "rubric":
+		if len(d.args) > 0 {
+			r.buf.WriteString("<p><strong>" + r.inline(d.args) + "</strong></p>\n")
+		}
+		r.renderBlocks(d.content)
+	case "raw":
+		if strings.EqualFold(d.args, "html") {
+			r.buf.WriteString(strings.Join(d.content, "\n") + "\n")
+		}
+	case "container", "compound", "rst-class":
+		r.renderBlocks(d.content)
+	default:
+		if label, ok := admonitionLabels[d.name]; ok {
+			content := d.content
+			if len(d.args) > 0 {
+				content = append([]string{d.args, ""}, content...)
+			}
+			r.renderAdmonition(label, content)
+		}
+		// other directives like contents or include are not supported
+	}
+}
+
+func (r *renderer) renderAdmonition(title string, content []string) {
+	r.buf.WriteString("<blockquote>\n<p><strong>" + title + "</strong></p>\n")
+	r.renderBlocks(content)
+	r.buf.WriteString("</blockquote>\n")
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (r *renderer) image(d *directive) string {
+	src := markup.ResolveMediaLink(r.urlPrefix, d.args, r.isWiki)
+	alt, ok := d.options["alt"]
+	if !ok {
+		alt = d.args[strings.LastIndex(d.args, "/")+1:]
+	}
+	img := fmt.Sprintf(`<img src="%s" alt="%s"`, html.EscapeString(src), html.EscapeString(alt))
+	for _, dimension := range []string{"width", "height"} {
+		if value, ok := d.options[dimension]; ok {
+			img += fmt.Sprintf(` %s="%s"`, dimension, html.EscapeString(strings.TrimSuffix(value, "px")))
+		}
+	}
+	img += ">"
+	if target, ok := d.options["target"]; ok {
+		href := markup.ResolveLink(r.urlPrefix, strings.TrimSpace(target), r.isWiki)
+		img = `<a href="` + html.EscapeString(href) + `">` + img + `</a>`
+	}
+	return img
+}
+
+// tableRow holds the text of the cells of a table row
+type tableRow struct {
+	cells  []string
+	header bool
+}
+
+func (r *renderer) writeTable(rows []tableRow) {
+	r.buf.WriteString("<table>\n")
+	inBody := false
+	for i, row := range rows {
+		tag := "td"
+		if row.header {
+			tag = "th"
+			if i == 0 {
+				r.buf.WriteString("<thead>\n")
+			}
+		} else if !inBody {
+			if i > 0 {
+				r.buf.WriteString("</thead>\n")
+			}
+			r.buf.WriteString("<tbody>\n")
+			inBody = true
+		}
+		r.buf.WriteString("<tr>")
+		for _, cell := range row.cells {
+			r.buf.WriteString("<" + tag + ">" + r.inline(cell) + "</" + tag + ">")
+		}
+		r.buf.WriteString("</tr>\n")
+	}
+	if inBody {
+		r.buf.WriteString("</tbody>\n")
+	} else if len(rows) > 0 {
+		r.buf.WriteString("</thead>\n")
+	}
+	r.buf.WriteString("</table>\n")
+}
+
+// columnText returns the text of line between the rune offsets start and end
+func columnText(line []rune, start, end int) string {
+	if start >= len(line) {
+		return ""
+	}
+	if end < 0 || end > len(line) {
+		end = len(line)
+	}
+	return strings.TrimSpace(string(line[start:end]))
+}
+
+// renderGridTable renders a grid table, cells spanning several columns or rows are not supported
+func (r *renderer) renderGridTable(lines []string) {
+	var columns []int
+	for i, c := range []rune(strings.TrimRight(lines[0], " ")) {
+		if c == '+' {
+			columns = append(columns, i)
+		}
+	}
+
+	var rows []tableRow
+	var current []string
+	hasHeader := false
+	for _, line := range lines[1:] {
+		runes := []rune(strings.TrimRight(line, " "))
+		if gridTableRegexp.MatchString(string(runes)) {
+			if current != nil {
+				rows = append(rows, tableRow{cells: current, header: strings.Contains(line, "=")})
+				hasHeader = hasHeader || strings.Contains(line, "=")
+			}
+			current = nil
+			continue
+		}
+		if current == nil {
+			current = make([]string, len(columns)-1)
+		}
+		for c := 0; c+1 < len(columns); c++ {
+			if text := columnText(runes, columns[c]+1, columns[c+1]); len(text) > 0 {
+				current[c] = strings.TrimSpace(current[c] + "\n" + text)
+			}
+		}
+	}
+	if hasHeader {
+		// the rows above the header separator are header rows
+		header := true
+		for i := range rows {
+			wasHeader := rows[i].header
+			rows[i].header = header
+			if wasHeader {
+				header = false
+			}
+		}
+	}
+	r.writeTable(rows)
+}
+
+// renderSimpleTable renders the simple table starting at i
+func (r *renderer) renderSimpleTable(lines []string, i int) int {
+	border := strings.TrimRight(lines[i], " ")
+	var starts []int
+	for c, ch := range []rune(border) {
+		if ch == '=' && (c == 0 || border[c-1] == ' ') {
+			starts = append(starts, c)
+		}
+	}
+
+	var rows []tableRow
+	borders := 1
+	for i++; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " ")
+		if simpleTableRegexp.MatchString(line) || (strings.Trim(line, "= ") == "" && len(line) > 0) {
+			borders++
+			if borders == 2 && i+1 < len(lines) && !isBlank(lines[i+1]) {
+				for j := range rows {
+					rows[j].header = true
+				}
+				continue
+			}
+			i++
+			break
+		}
+		if len(line) == 0 {
+			continue
+		}
+		runes := []rune(line)
+		row := tableRow{cells: make([]string, len(starts))}
+		for c := range starts {
+			end := -1
+			if c+1 < len(starts) {
+				end = starts[c+1]
+			}
+			row.cells[c] = columnText(runes, starts[c], end)
+		}
+		if len(row.cells[0]) == 0 && len(rows) > 0 {
+			// a row with an empty first column continues the previous one
+			prev := rows[len(rows)-1]
+			for c, text := range row.cells {
+				prev.cells[c] = strings.TrimSpace(prev.cells[c] + "\n" + text)
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	r.writeTable(rows)
+	return i
+}
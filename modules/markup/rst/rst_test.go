@@ -0,0 +1,127 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const AppURL = "http://localhost:3000/"
+const Repo = "gogits/gogs"
+const AppSubURL = AppURL + Repo + "/"
+
+func test(t *testing.T, input, expected string) {
+	buffer := RenderString(input, setting.AppSubURL, nil, false)
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(buffer))
+}
+
+func TestRender_Sections(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "=====\nTitle\n=====\n\nSection\n=======\n\nSub section\n-----------\n\nSection\n=======\n\nSee `Sub section`_.",
+		`<h1 id="title">Title</h1>
+<h2 id="section">Section</h2>
+<h3 id="sub-section">Sub section</h3>
+<h2 id="section-1">Section</h2>
+<p>See <a href="#user-content-sub-section">Sub section</a>.</p>`)
+	test(t, "Paragraph\n\n----\n\nParagraph",
+		"<p>Paragraph</p>\n<hr>\n<p>Paragraph</p>")
+}
+
+func TestRender_Inline(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "**strong** *emphasis* ``code <b>`` `title` :sub:`2` :py:func:`main` :math:`x^2` a*b*c \\*escaped\\*",
+		`<p><strong>strong</strong> <em>emphasis</em> <code>code &lt;b&gt;</code> <cite>title</cite> <sub>2</sub> <code>main</code> <code class="language-math">x^2</code> a*b*c *escaped*</p>`)
+	test(t, "|version| and |unknown|\n\n.. |version| replace:: **1.0**",
+		"<p><strong>1.0</strong> and |unknown|</p>")
+}
+
+func TestRender_Links(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "`Gitea <https://gitea.io>`_, Python_, `docs <docs/index.rst>`__, `other`__, snake_case and undefined_.\n\n"+
+		".. _Python: https://www.python.org/\n__ https://example.com/",
+		`<p><a href="https://gitea.io">Gitea</a>, <a href="https://www.python.org/">Python</a>, <a href="`+util.URLJoin(AppSubURL, "docs/index.rst")+`">docs</a>, <a href="https://example.com/">other</a>, snake_case and undefined_.</p>`)
+	test(t, ".. image:: images/logo.png\n   :alt: Logo\n   :width: 200px",
+		`<p><img src="`+util.URLJoin(AppSubURL, "images/logo.png")+`" alt="Logo" width="200"></p>`)
+	test(t, "Note [#]_.\n\n.. [#] The note.",
+		`<p>Note <sup><a href="#user-content-footnote-1">[1]</a></sup>.</p>
+<p id="footnote-1"><sup>1</sup> The note.</p>`)
+}
+
+func TestRender_Blocks(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "Example::\n\n    <code>\n\n.. code-block:: go\n\n   func main() {}",
+		`<p>Example:</p>
+<pre><code>&lt;code&gt;</code></pre>
+<pre><code class="language-go">func main() {}</code></pre>`)
+	test(t, ".. warning:: Careful.\n\n.. a comment\n\n   continued\n\n..\n\n   Quoted.",
+		`<blockquote>
+<p><strong>Warning</strong></p>
+<p>Careful.</p>
+</blockquote>
+<blockquote>
+<p>Quoted.</p>
+</blockquote>`)
+	test(t, "* one\n* two\n\n  - nested\n\n3. three\n#. four\n\nTerm : classifier\n   Definition.\n\n:Author: Jane",
+		`<ul>
+<li>one</li>
+<li>two
+<ul>
+<li>nested</li>
+</ul>
+</li>
+</ul>
+<ol start="3">
+<li>three</li>
+<li>four</li>
+</ol>
+<dl>
+<dt>Term</dt>
+<dd>Definition.</dd>
+</dl>
+<dl>
+<dt>Author</dt>
+<dd>Jane</dd>
+</dl>`)
+}
+
+func TestRender_Tables(t *testing.T) {
+	setting.AppURL = AppURL
+	setting.AppSubURL = AppSubURL
+
+	test(t, "+------+-------+\n| Name | Value |\n+======+=======+\n| a    | **1** |\n| b    |       |\n+------+-------+",
+		`<table>
+<thead>
+<tr><th>Name</th><th>Value</th></tr>
+</thead>
+<tbody>
+<tr><td>a
+b</td><td><strong>1</strong></td></tr>
+</tbody>
+</table>`)
+	test(t, "=====  =====\nA      B\n=====  =====\nx      y\n       z\n=====  =====",
+		`<table>
+<thead>
+<tr><th>A</th><th>B</th></tr>
+</thead>
+<tbody>
+<tr><td>x</td><td>y
+z</td></tr>
+</tbody>
+</table>`)
+}
@@ -6,8 +6,11 @@
 package markup
 
 import (
+	"regexp"
 	"testing"
 
+	"code.gitea.io/gitea/modules/setting"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +46,16 @@ func Test_Sanitizer(t *testing.T) {
 		`<input type="checkbox" disabled=""/>unchecked`, `<input type="checkbox" disabled=""/>unchecked`,
 		`<span class="emoji dropdown">NAUGHTY</span>`, `<span>NAUGHTY</span>`,
 		`<span class="emoji">contents</span>`, `<span class="emoji">contents</span>`,
+
+		// Math
+		`<code class="language-math">x</code>`, `<code class="language-math">x</code>`,
+		`<code class="language-math display">x</code>`, `<code class="language-math display">x</code>`,
+		`<code class="language-math display ui">x</code>`, `<code>x</code>`,
+
+		// Jupyter notebook markup is only allowed in rendered notebooks
+		`<div class="nb-cell nb-code-cell">cell</div>`, `<div>cell</div>`,
+		`<img src="data:image/png;base64,iVBORw0KGgo=">`, ``,
+		`<a href="data:text/html;base64,PHNjcmlwdD48L3NjcmlwdD4=">link</a>`, `link`,
 	}
 
 	for i := 0; i < len(testCases); i += 2 {
@@ -50,3 +63,34 @@ func Test_Sanitizer(t *testing.T) {
 		assert.Equal(t, testCases[i+1], string(SanitizeBytes([]byte(testCases[i]))))
 	}
 }
+
+type sanitizerTestParser struct {
+	rules []setting.MarkupSanitizerRule
+}
+
+func (p *sanitizerTestParser) Name() string         { return "sanitizer-test" }
+func (p *sanitizerTestParser) Extensions() []string { return []string{".sanitizer-test"} }
+func (p *sanitizerTestParser) Render(rawBytes []byte, urlPrefix string, metas map[string]string, isWiki bool) []byte {
+	return rawBytes
+}
+func (p *sanitizerTestParser) SanitizerRules() []setting.MarkupSanitizerRule { return p.rules }
+func (p *sanitizerTestParser) DisplayInIFrame() bool                         { return false }
+
+func Test_SanitizerRendererRules(t *testing.T) {
+	RegisterParser(&sanitizerTestParser{
+		rules: []setting.MarkupSanitizerRule{
+			{Element: "div", AllowAttr: "class", Regexp: regexp.MustCompile(`^admonition$`)},
+		},
+	})
+	defer func() {
+		delete(parsers, "sanitizer-test")
+		delete(extParsers, ".sanitizer-test")
+		ReplaceSanitizer()
+	}()
+	ReplaceSanitizer()
+
+	input := `<div class="admonition">note</div>`
+	assert.Equal(t, `<div class="admonition">note</div>`, string(sanitizeRendererBytes("sanitizer-test", []byte(input))))
+	assert.Equal(t, `<div>note</div>`, string(sanitizeRendererBytes("markdown", []byte(input))))
+	assert.Equal(t, `<div>note</div>`, string(SanitizeBytes([]byte(input))))
+}
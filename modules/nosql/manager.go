@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/nats-io/nats.go"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
@@ -21,6 +22,7 @@ type Manager struct {
 
 	RedisConnections   map[string]*redisClientHolder
 	LevelDBConnections map[string]*levelDBHolder
+	NATSConnections    map[string]*natsConnHolder
 }
 
 type redisClientHolder struct {
@@ -39,6 +41,11 @@ type levelDBHolder struct {
 	db    *leveldb.DB
 }
 
+type natsConnHolder struct {
+	count int64
+	conn  *nats.Conn
+}
+
 func init() {
 	_ = GetManager()
 }
@@ -49,6 +56,7 @@ func GetManager() *Manager {
 		manager = &Manager{
 			RedisConnections:   make(map[string]*redisClientHolder),
 			LevelDBConnections: make(map[string]*levelDBHolder),
+			NATSConnections:    make(map[string]*natsConnHolder),
 		}
 	}
 	return manager
@@ -5,7 +5,10 @@
 package private
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
@@ -84,6 +87,193 @@ func FlushQueues(timeout time.Duration, nonBlocking bool) (int, string) {
 	return http.StatusOK, "Flushed"
 }
 
+// QueueInfo represents the state of a managed queue
+type QueueInfo struct {
+	QID                  int64
+	Name                 string
+	Type                 string
+	NumberOfWorkers      int
+	NumberOfPendingItems int64
+	IsPaused             bool
+}
+
+// ListQueues calls the internal queues function
+func ListQueues() ([]QueueInfo, int, string) {
+	reqURL := setting.LocalURL + "api/internal/manager/queues"
+
+	req := newInternalRequest(reqURL, "GET")
+	resp, err := req.Response()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	var infos []QueueInfo
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Sprintf("Unable to decode response: %v", err)
+	}
+	return infos, http.StatusOK, ""
+}
+
+func queueRequest(name, action, method string) (*http.Response, error) {
+	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/manager/queues/%s/%s", url.PathEscape(name), action)
+	return newInternalRequest(reqURL, method).Response()
+}
+
+// PauseQueue calls the internal pause queue function
+func PauseQueue(name string) (int, string) {
+	resp, err := queueRequest(name, "pause", "POST")
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	return http.StatusOK, "Paused"
+}
+
+// ResumeQueue calls the internal resume queue function
+func ResumeQueue(name string) (int, string) {
+	resp, err := queueRequest(name, "resume", "POST")
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	return http.StatusOK, "Resumed"
+}
+
+// FlushQueue calls the internal flush queue function
+func FlushQueue(name string, timeout time.Duration, nonBlocking bool) (int, string) {
+	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/manager/queues/%s/flush", url.PathEscape(name))
+
+	req := newInternalRequest(reqURL, "POST")
+	if timeout > 0 {
+		req.SetTimeout(timeout+10*time.Second, timeout+10*time.Second)
+	}
+	req = req.Header("Content-Type", "application/json")
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	jsonBytes, _ := json.Marshal(FlushOptions{
+		Timeout:     timeout,
+		NonBlocking: nonBlocking,
+	})
+	req.Body(jsonBytes)
+	resp, err := req.Response()
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	return http.StatusOK, "Flushed"
+}
+
+// PeekQueue calls the internal peek queue function
+func PeekQueue(name string, count int) ([]string, int, string) {
+	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/manager/queues/%s/peek?count=%d", url.PathEscape(name), count)
+
+	req := newInternalRequest(reqURL, "GET")
+	resp, err := req.Response()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	var items []string
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Sprintf("Unable to decode response: %v", err)
+	}
+	return items, http.StatusOK, ""
+}
+
+// ExportQueue calls the internal export queue function and writes the pending items to w.
+// It returns the number of written items, which are kept in the queue.
+func ExportQueue(name string, w io.Writer) (int64, int, string) {
+	resp, err := queueRequest(name, "export", "POST")
+	if err != nil {
+		return 0, http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	counter := &lineCountWriter{w: w}
+	if _, err := io.Copy(counter, resp.Body); err != nil {
+		return counter.lines, http.StatusInternalServerError, fmt.Sprintf("Unable to write exported items: %v", err)
+	}
+	return counter.lines, http.StatusOK, fmt.Sprintf("Exported %d items", counter.lines)
+}
+
+// lineCountWriter counts the complete lines written to w
+type lineCountWriter struct {
+	w     io.Writer
+	lines int64
+}
+
+func (c *lineCountWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	return n, err
+}
+
+// RemovePendingQueueItems calls the internal function to remove the first count pending items of a queue
+func RemovePendingQueueItems(name string, count int64) (int, string) {
+	resp, err := queueRequest(name, fmt.Sprintf("remove?count=%d", count), "POST")
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	msg, _ := ioutil.ReadAll(resp.Body)
+	return http.StatusOK, string(msg)
+}
+
+// ImportQueue calls the internal import queue function with previously exported items
+func ImportQueue(name string, data []byte) (int, string) {
+	reqURL := setting.LocalURL + fmt.Sprintf("api/internal/manager/queues/%s/import", url.PathEscape(name))
+
+	req := newInternalRequest(reqURL, "POST")
+	req.Body(data)
+	resp, err := req.Response()
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Sprintf("Unable to contact gitea: %v", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, decodeJSONError(resp).Err
+	}
+
+	msg, _ := ioutil.ReadAll(resp.Body)
+	return http.StatusOK, string(msg)
+}
+
 // PauseLogging pauses logging
 func PauseLogging() (int, string) {
 	reqURL := setting.LocalURL + "api/internal/manager/pause-logging"
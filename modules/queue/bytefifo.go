@@ -23,7 +23,15 @@ type UniqueByteFIFO interface {
 	Has(data []byte) (bool, error)
 }
 
-var _ (ByteFIFO) = &DummyByteFIFO{}
+// PeekableByteFIFO defines a ByteFIFO whose contents can be inspected without removing them
+type PeekableByteFIFO interface {
+	ByteFIFO
+	// Iterate calls fn with the items from the start of the fifo without removing them.
+	// The iteration stops at the first error returned by fn.
+	Iterate(fn func(data []byte) error) error
+}
+
+var _ (PeekableByteFIFO) = &DummyByteFIFO{}
 
 // DummyByteFIFO represents a dummy fifo
 type DummyByteFIFO struct{}
@@ -48,6 +56,11 @@ func (*DummyByteFIFO) Len() int64 {
 	return 0
 }
 
+// Iterate returns nil
+func (*DummyByteFIFO) Iterate(fn func(data []byte) error) error {
+	return nil
+}
+
 var _ (UniqueByteFIFO) = &DummyUniqueByteFIFO{}
 
 // DummyUniqueByteFIFO represents a dummy unique fifo
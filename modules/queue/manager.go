@@ -5,8 +5,12 @@
 package queue
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
 	"sync"
@@ -53,6 +57,34 @@ type Flushable interface {
 	IsEmpty() bool
 }
 
+// Pausable represents a pool or queue that is Pausable
+type Pausable interface {
+	// IsPaused will return if the pool or queue is paused
+	IsPaused() bool
+	// Pause will pause the pool or queue
+	Pause()
+	// Resume will resume the pool or queue
+	Resume()
+	// IsPausedIsResumed will return a bool indicating if the pool or queue is paused and a channel that will be closed when it is resumed
+	IsPausedIsResumed() (paused, resumed <-chan struct{})
+}
+
+// ErrQueueNotPaused is returned when the pending items of a running queue are exported or removed
+var ErrQueueNotPaused = fmt.Errorf("queue is not paused")
+
+// Inspectable represents a queue whose pending items are held in a fifo which can be inspected
+type Inspectable interface {
+	// NumberOfPendingItems returns the number of items waiting in the fifo
+	NumberOfPendingItems() int64
+	// IteratePending calls fn with the items waiting in the fifo without removing them.
+	// The iteration stops at the first error returned by fn.
+	IteratePending(fn func(data []byte) error) error
+	// RemovePending pops up to n items from the start of the fifo
+	RemovePending(n int64) (int64, error)
+	// PushPending pushes previously exported data to the fifo
+	PushPending(data []byte) error
+}
+
 // ManagedPool is a simple interface to get certain details from a worker pool
 type ManagedPool interface {
 	// AddWorkers adds a number of worker as group to the pool with the provided timeout. A CancelFunc is provided to cancel the group
@@ -151,6 +183,19 @@ func (m *Manager) GetManagedQueue(qid int64) *ManagedQueue {
 	return m.Queues[qid]
 }
 
+// GetManagedQueueByName returns the most recently added managed queue with the provided name
+func (m *Manager) GetManagedQueueByName(name string) *ManagedQueue {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var found *ManagedQueue
+	for _, mq := range m.Queues {
+		if mq.Name == name && (found == nil || mq.QID > found.QID) {
+			found = mq
+		}
+	}
+	return found
+}
+
 // FlushAll flushes all the flushable queues attached to this manager
 func (m *Manager) FlushAll(baseCtx context.Context, timeout time.Duration) error {
 	var ctx context.Context
@@ -178,7 +223,8 @@ func (m *Manager) FlushAll(baseCtx context.Context, timeout time.Duration) error
 		wg.Add(len(mqs))
 		allEmpty := true
 		for _, mq := range mqs {
-			if mq.IsEmpty() {
+			if mq.IsEmpty() || mq.IsPaused() {
+				// paused queues are skipped as they would never become empty
 				wg.Done()
 				continue
 			}
@@ -298,6 +344,151 @@ func (q *ManagedQueue) IsEmpty() bool {
 	return true
 }
 
+// IsPaused returns if the queue is paused
+func (q *ManagedQueue) IsPaused() bool {
+	if pausable, ok := q.Managed.(Pausable); ok {
+		return pausable.IsPaused()
+	}
+	return false
+}
+
+// IsPausable returns if the queue can be paused
+func (q *ManagedQueue) IsPausable() bool {
+	_, ok := q.Managed.(Pausable)
+	return ok
+}
+
+// Pause pauses the queue
+func (q *ManagedQueue) Pause() bool {
+	if pausable, ok := q.Managed.(Pausable); ok {
+		pausable.Pause()
+		return true
+	}
+	return false
+}
+
+// Resume resumes the queue
+func (q *ManagedQueue) Resume() bool {
+	if pausable, ok := q.Managed.(Pausable); ok {
+		pausable.Resume()
+		return true
+	}
+	return false
+}
+
+// IsInspectable returns if the pending items of the queue can be inspected
+func (q *ManagedQueue) IsInspectable() bool {
+	_, ok := q.Managed.(Inspectable)
+	return ok
+}
+
+// NumberOfPendingItems returns the number of items waiting in the fifo of the queue
+func (q *ManagedQueue) NumberOfPendingItems() int64 {
+	if inspectable, ok := q.Managed.(Inspectable); ok {
+		return inspectable.NumberOfPendingItems()
+	}
+	return -1
+}
+
+const (
+	// DefaultPeekNumber is the number of items peeked if no positive number is requested
+	DefaultPeekNumber = 10
+	// MaxPeekNumber is the maximum number of items which can be peeked at once
+	MaxPeekNumber = 1000
+)
+
+// errPeekLimitReached stops iterating the pending items once enough have been peeked
+var errPeekLimitReached = errors.New("peek limit reached")
+
+// PeekPending returns up to n items waiting in the fifo of the queue without removing them.
+// n defaults to DefaultPeekNumber and is limited to MaxPeekNumber.
+func (q *ManagedQueue) PeekPending(n int) ([]string, error) {
+	inspectable, ok := q.Managed.(Inspectable)
+	if !ok {
+		return nil, fmt.Errorf("queue %s is not inspectable", q.Name)
+	}
+	if n <= 0 {
+		n = DefaultPeekNumber
+	} else if n > MaxPeekNumber {
+		n = MaxPeekNumber
+	}
+	items := make([]string, 0, n)
+	if err := inspectable.IteratePending(func(data []byte) error {
+		items = append(items, string(data))
+		if len(items) >= n {
+			return errPeekLimitReached
+		}
+		return nil
+	}); err != nil && err != errPeekLimitReached {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ExportPending writes the items waiting in the fifo of the paused queue to w, one item per line,
+// without removing them. Use RemovePending with the returned count to remove them afterwards.
+func (q *ManagedQueue) ExportPending(w io.Writer) (int64, error) {
+	inspectable, ok := q.Managed.(Inspectable)
+	if !ok {
+		return 0, fmt.Errorf("queue %s is not inspectable", q.Name)
+	}
+	if !q.IsPaused() {
+		return 0, ErrQueueNotPaused
+	}
+	var count int64
+	err := inspectable.IteratePending(func(data []byte) error {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte{'\n'}); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// RemovePending removes the first n items waiting in the fifo of the paused queue, e.g. once they
+// have been exported. Items pushed after the export are at the end of the fifo and are kept.
+func (q *ManagedQueue) RemovePending(n int64) (int64, error) {
+	inspectable, ok := q.Managed.(Inspectable)
+	if !ok {
+		return 0, fmt.Errorf("queue %s is not inspectable", q.Name)
+	}
+	if !q.IsPaused() {
+		return 0, ErrQueueNotPaused
+	}
+	return inspectable.RemovePending(n)
+}
+
+// ImportPending pushes the items read from r, one item per line, to the fifo of the queue.
+// Items which are already in a unique queue are skipped.
+func (q *ManagedQueue) ImportPending(r io.Reader) (int64, error) {
+	inspectable, ok := q.Managed.(Inspectable)
+	if !ok {
+		return 0, fmt.Errorf("queue %s is not inspectable", q.Name)
+	}
+	var count int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return count, err
+		}
+		if data := bytes.TrimSpace(line); len(data) > 0 {
+			if pushErr := inspectable.PushPending(data); pushErr != nil && pushErr != ErrAlreadyInQueue {
+				return count, pushErr
+			} else if pushErr == nil {
+				count++
+			}
+		}
+		if err == io.EOF {
+			return count, nil
+		}
+	}
+}
+
 // NumberOfWorkers returns the number of workers in the queue
 func (q *ManagedQueue) NumberOfWorkers() int {
 	if pool, ok := q.Managed.(ManagedPool); ok {
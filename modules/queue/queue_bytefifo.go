@@ -89,6 +89,47 @@ func (q *ByteFIFOQueue) IsEmpty() bool {
 	return q.byteFIFO.Len() == 0
 }
 
+// NumberOfPendingItems returns the number of items waiting in the fifo
+func (q *ByteFIFOQueue) NumberOfPendingItems() int64 {
+	return q.byteFIFO.Len()
+}
+
+// IteratePending calls fn with the items waiting in the fifo without removing them
+func (q *ByteFIFOQueue) IteratePending(fn func(data []byte) error) error {
+	peekable, ok := q.byteFIFO.(PeekableByteFIFO)
+	if !ok {
+		return fmt.Errorf("%s: %s does not support peeking", q.typ, q.name)
+	}
+	return peekable.Iterate(fn)
+}
+
+// RemovePending pops up to n items from the start of the fifo and returns the number of removed items
+func (q *ByteFIFOQueue) RemovePending(n int64) (int64, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var count int64
+	for count < n {
+		bs, err := q.byteFIFO.Pop()
+		if err != nil {
+			return count, err
+		}
+		if len(bs) == 0 {
+			break
+		}
+		count++
+	}
+	return count, nil
+}
+
+// PushPending pushes previously drained data to the end of the fifo
+func (q *ByteFIFOQueue) PushPending(data []byte) error {
+	if _, err := unmarshalAs(data, q.exemplar); err != nil {
+		return fmt.Errorf("Unable to unmarshal data: %s as exemplar: %v in %s: %v", data, q.exemplar, q.name, err)
+	}
+	return q.byteFIFO.PushFunc(data, nil)
+}
+
 // Run runs the bytefifo queue
 func (q *ByteFIFOQueue) Run(atShutdown, atTerminate func(context.Context, func())) {
 	atShutdown(context.Background(), q.Shutdown)
@@ -121,7 +162,25 @@ func (q *ByteFIFOQueue) readToChan() {
 			q.cancel()
 			return
 		default:
+			paused, _ := q.IsPausedIsResumed()
+			select {
+			case <-paused:
+				// leave the data in the fifo whilst the queue is paused
+				_, resumed := q.IsPausedIsResumed()
+				select {
+				case <-resumed:
+				case <-q.closed:
+				}
+				continue
+			default:
+			}
+
 			q.lock.Lock()
+			if q.IsPaused() {
+				// the queue was paused whilst waiting for the lock
+				q.lock.Unlock()
+				continue
+			}
 			bs, err := q.byteFIFO.Pop()
 			if err != nil {
 				q.lock.Unlock()
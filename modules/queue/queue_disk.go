@@ -5,9 +5,13 @@
 package queue
 
 import (
+	"encoding/binary"
+	"fmt"
+
 	"code.gitea.io/gitea/modules/nosql"
 
 	"gitea.com/lunny/levelqueue"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // LevelQueueType is the type for level queue
@@ -55,11 +59,13 @@ func NewLevelQueue(handle HandlerFunc, cfg, exemplar interface{}) (Queue, error)
 	return queue, nil
 }
 
-var _ (ByteFIFO) = &LevelQueueByteFIFO{}
+var _ (PeekableByteFIFO) = &LevelQueueByteFIFO{}
 
 // LevelQueueByteFIFO represents a ByteFIFO formed from a LevelQueue
 type LevelQueueByteFIFO struct {
 	internal   *levelqueue.Queue
+	db         *leveldb.DB
+	prefix     string
 	connection string
 }
 
@@ -78,6 +84,8 @@ func NewLevelQueueByteFIFO(connection, prefix string) (*LevelQueueByteFIFO, erro
 	return &LevelQueueByteFIFO{
 		connection: connection,
 		internal:   internal,
+		db:         db,
+		prefix:     prefix,
 	}, nil
 }
 
@@ -112,6 +120,63 @@ func (fifo *LevelQueueByteFIFO) Len() int64 {
 	return fifo.internal.Len()
 }
 
+// Iterate calls fn with the items from the start of the fifo without removing them
+func (fifo *LevelQueueByteFIFO) Iterate(fn func(data []byte) error) error {
+	return iterateLevelQueue(fifo.db, fifo.prefix, fn)
+}
+
+// iterateLevelQueue reads the items of a levelqueue directly from the db. The levelqueue
+// pushes to its low end and pops from its high end, storing each item under the
+// prefixed varint of its id.
+func iterateLevelQueue(db *leveldb.DB, prefix string, fn func(data []byte) error) error {
+	levelKey := func(value []byte) []byte {
+		if len(prefix) == 0 {
+			return value
+		}
+		return append([]byte(prefix+"-"), value...)
+	}
+	readID := func(key string) (int64, error) {
+		bs, err := db.Get(levelKey([]byte(key)), nil)
+		if err != nil {
+			return 0, err
+		}
+		id, read := binary.Varint(bs)
+		if read <= 0 {
+			return 0, fmt.Errorf("invalid %s id in levelqueue %s", key, prefix)
+		}
+		return id, nil
+	}
+
+	low, err := readID("low")
+	if err == leveldb.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	high, err := readID("high")
+	if err == leveldb.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for id := high; id >= low; id-- {
+		idBytes := make([]byte, 8)
+		binary.PutVarint(idBytes, id)
+		data, err := db.Get(levelKey(idBytes), nil)
+		if err == leveldb.ErrNotFound {
+			// the item has been popped in the meantime
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func init() {
 	queuesMap[LevelQueueType] = NewLevelQueue
 }
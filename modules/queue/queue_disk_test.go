@@ -5,8 +5,10 @@
 package queue
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -150,3 +152,106 @@ func TestLevelQueue(t *testing.T) {
 	}
 	lock.Unlock()
 }
+
+func TestLevelQueue_PauseExportImport(t *testing.T) {
+	handleChan := make(chan *testData)
+	handle := func(data ...Data) {
+		for _, datum := range data {
+			testDatum := datum.(*testData)
+			handleChan <- testDatum
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "level-queue-test-data")
+	assert.NoError(t, err)
+	defer util.RemoveAll(tmpDir)
+
+	queue, err := NewLevelQueue(handle, LevelQueueConfiguration{
+		ByteFIFOQueueConfiguration: ByteFIFOQueueConfiguration{
+			WorkerPoolConfiguration: WorkerPoolConfiguration{
+				QueueLength:  20,
+				BatchLength:  1,
+				BlockTimeout: 1 * time.Second,
+				BoostTimeout: 5 * time.Minute,
+				BoostWorkers: 5,
+				MaxWorkers:   10,
+			},
+			Workers: 1,
+			Name:    "TestLevelQueue_PauseExportImport",
+		},
+		DataDir: tmpDir,
+	}, &testData{})
+	assert.NoError(t, err)
+	levelQueue := queue.(*LevelQueue)
+	defer levelQueue.Terminate()
+
+	mq := GetManager().GetManagedQueueByName("TestLevelQueue_PauseExportImport")
+	if !assert.NotNil(t, mq) {
+		return
+	}
+	assert.True(t, mq.IsPausable())
+	assert.True(t, mq.IsInspectable())
+	assert.True(t, mq.Pause())
+	assert.True(t, mq.IsPaused())
+
+	nilFn := func(_ context.Context, _ func()) {}
+	go queue.Run(nilFn, nilFn)
+
+	for _, datum := range []*testData{{"A", 1}, {"B", 2}, {"C", 3}} {
+		assert.NoError(t, queue.Push(datum))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-handleChan:
+		assert.Fail(t, "Handler processing should be paused")
+	default:
+	}
+	assert.EqualValues(t, 3, mq.NumberOfPendingItems())
+
+	items, err := mq.PeekPending(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"TestString":"A","TestInt":1}`, `{"TestString":"B","TestInt":2}`}, items)
+
+	// invalid numbers peek the default number of items
+	items, err = mq.PeekPending(-1)
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+
+	// exporting keeps the items until they are removed explicitly
+	var buf bytes.Buffer
+	count, err := mq.ExportPending(&buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	assert.EqualValues(t, 3, mq.NumberOfPendingItems())
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 3)
+
+	assert.NoError(t, queue.Push(&testData{"D", 4}))
+	removed, err := mq.RemovePending(count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, removed)
+	items, err = mq.PeekPending(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"TestString":"D","TestInt":4}`}, items)
+
+	_, err = mq.ImportPending(strings.NewReader("not json\n"))
+	assert.Error(t, err)
+
+	count, err = mq.ImportPending(&buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	assert.EqualValues(t, 4, mq.NumberOfPendingItems())
+
+	assert.True(t, mq.Resume())
+	assert.False(t, mq.IsPaused())
+	for _, expected := range []string{"D", "A", "B", "C"} {
+		result := <-handleChan
+		assert.Equal(t, expected, result.TestString)
+	}
+
+	_, err = mq.ExportPending(&buf)
+	assert.Equal(t, ErrQueueNotPaused, err)
+	_, err = mq.RemovePending(1)
+	assert.Equal(t, ErrQueueNotPaused, err)
+	levelQueue.Shutdown()
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/nosql"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSQueueType is the type for NATS JetStream queue
+const NATSQueueType Type = "nats"
+
+// NATSQueueConfiguration is the configuration for the NATS JetStream queue
+type NATSQueueConfiguration struct {
+	ByteFIFOQueueConfiguration
+	NATSByteFIFOConfiguration
+}
+
+// NATSQueue is a queue stored in a NATS JetStream stream
+type NATSQueue struct {
+	*ByteFIFOQueue
+}
+
+// NewNATSQueue creates a NATS JetStream queue
+func NewNATSQueue(handle HandlerFunc, cfg, exemplar interface{}) (Queue, error) {
+	configInterface, err := toConfig(NATSQueueConfiguration{}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	config := configInterface.(NATSQueueConfiguration)
+
+	byteFIFO, err := NewNATSByteFIFO(config.NATSByteFIFOConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	byteFIFOQueue, err := NewByteFIFOQueue(NATSQueueType, byteFIFO, handle, config.ByteFIFOQueueConfiguration, exemplar)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := &NATSQueue{
+		ByteFIFOQueue: byteFIFOQueue,
+	}
+
+	queue.qid = GetManager().Add(queue, NATSQueueType, config, exemplar)
+
+	return queue, nil
+}
+
+const (
+	// natsConsumerName is the name of the durable consumer shared by all Gitea instances
+	natsConsumerName = "gitea"
+	// natsAckWait is the time after which a fetched but unacknowledged message is redelivered
+	natsAckWait = 30 * time.Second
+	// natsFetchWait is the time Pop waits for a message before it reports an empty fifo
+	natsFetchWait = 100 * time.Millisecond
+)
+
+// natsName replaces the characters which are not allowed in stream and bucket names
+func natsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+var _ (PeekableByteFIFO) = &NATSByteFIFO{}
+
+// NATSByteFIFO represents a ByteFIFO formed from a NATS JetStream work queue stream
+// with a durable pull consumer shared by all Gitea instances
+type NATSByteFIFO struct {
+	connection string
+	js         nats.JetStreamContext
+	sub        *nats.Subscription
+	stream     string
+}
+
+// NATSByteFIFOConfiguration is the configuration for the NATSByteFIFO
+type NATSByteFIFOConfiguration struct {
+	ConnectionString string
+	QueueName        string
+}
+
+// NewNATSByteFIFO creates a ByteFIFO formed from a NATS JetStream stream
+func NewNATSByteFIFO(config NATSByteFIFOConfiguration) (*NATSByteFIFO, error) {
+	conn, err := nosql.GetManager().GetNATSConnection(config.ConnectionString)
+	if err != nil {
+		return nil, err
+	}
+	fifo := &NATSByteFIFO{
+		connection: config.ConnectionString,
+		stream:     natsName(config.QueueName),
+	}
+	if err := fifo.init(conn); err != nil {
+		nosql.GetManager().CloseNATSConnection(fifo.connection)
+		return nil, err
+	}
+	return fifo, nil
+}
+
+// init creates the stream and its consumer unless another instance has already done so
+func (fifo *NATSByteFIFO) init(conn *nats.Conn) error {
+	js, err := conn.JetStream()
+	if err != nil {
+		return err
+	}
+	fifo.js = js
+
+	if _, err := js.StreamInfo(fifo.stream); err == nats.ErrStreamNotFound {
+		log.Debug("Creating NATS JetStream stream %s", fifo.stream)
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:      fifo.stream,
+			Subjects:  []string{fifo.stream},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		})
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	fifo.sub, err = js.PullSubscribe(fifo.stream, natsConsumerName, nats.BindStream(fifo.stream), nats.AckWait(natsAckWait))
+	return err
+}
+
+// PushFunc pushes data to the end of the fifo and calls the callback if it is added
+func (fifo *NATSByteFIFO) PushFunc(data []byte, fn func() error) error {
+	if fn != nil {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	_, err := fifo.js.Publish(fifo.stream, data)
+	return err
+}
+
+// Pop pops data from the start of the fifo
+func (fifo *NATSByteFIFO) Pop() ([]byte, error) {
+	msgs, err := fifo.sub.Fetch(1, nats.MaxWait(natsFetchWait))
+	if err == nats.ErrTimeout {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Acknowledge synchronously so that the message is not redelivered to another instance
+	if err := msgs[0].AckSync(); err != nil {
+		return nil, err
+	}
+	return msgs[0].Data, nil
+}
+
+// Close this fifo
+func (fifo *NATSByteFIFO) Close() error {
+	// The subscription is not unsubscribed as that would delete the consumer shared with other instances
+	nosql.GetManager().CloseNATSConnection(fifo.connection)
+	return nil
+}
+
+// Len returns the length of the fifo
+func (fifo *NATSByteFIFO) Len() int64 {
+	info, err := fifo.js.StreamInfo(fifo.stream)
+	if err != nil {
+		log.Error("Error whilst getting length of nats queue %s: Error: %v", fifo.stream, err)
+		return -1
+	}
+	return int64(info.State.Msgs)
+}
+
+// Iterate calls fn with the items from the start of the fifo without removing them
+func (fifo *NATSByteFIFO) Iterate(fn func(data []byte) error) error {
+	info, err := fifo.js.StreamInfo(fifo.stream)
+	if err != nil {
+		return err
+	}
+	for seq := info.State.FirstSeq; seq > 0 && seq <= info.State.LastSeq; seq++ {
+		msg, err := fifo.js.GetMsg(fifo.stream, seq)
+		if err == nats.ErrMsgNotFound {
+			// the message has been acknowledged in the meantime
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := fn(msg.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	queuesMap[NATSQueueType] = NewNATSQueue
+}
@@ -62,6 +62,7 @@ type redisClient interface {
 	RPush(ctx context.Context, key string, args ...interface{}) *redis.IntCmd
 	LPop(ctx context.Context, key string) *redis.StringCmd
 	LLen(ctx context.Context, key string) *redis.IntCmd
+	LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
 	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 	SIsMember(ctx context.Context, key string, member interface{}) *redis.BoolCmd
@@ -69,7 +70,7 @@ type redisClient interface {
 	Close() error
 }
 
-var _ (ByteFIFO) = &RedisByteFIFO{}
+var _ (PeekableByteFIFO) = &RedisByteFIFO{}
 
 // RedisByteFIFO represents a ByteFIFO formed from a redisClient
 type RedisByteFIFO struct {
@@ -131,6 +132,25 @@ func (fifo *RedisByteFIFO) Len() int64 {
 	return val
 }
 
+// Iterate calls fn with the items from the start of the fifo without removing them
+func (fifo *RedisByteFIFO) Iterate(fn func(data []byte) error) error {
+	const batchSize = 100
+	for start := int64(0); ; start += batchSize {
+		vals, err := fifo.client.LRange(fifo.ctx, fifo.queueName, start, start+batchSize-1).Result()
+		if err != nil {
+			return err
+		}
+		for _, val := range vals {
+			if err := fn([]byte(val)); err != nil {
+				return err
+			}
+		}
+		if len(vals) < batchSize {
+			return nil
+		}
+	}
+}
+
 func init() {
 	queuesMap[RedisQueueType] = NewRedisQueue
 }
@@ -8,6 +8,7 @@ import (
 	"code.gitea.io/gitea/modules/nosql"
 
 	"gitea.com/lunny/levelqueue"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // LevelUniqueQueueType is the type for level queue
@@ -60,10 +61,13 @@ func NewLevelUniqueQueue(handle HandlerFunc, cfg, exemplar interface{}) (Queue,
 }
 
 var _ (UniqueByteFIFO) = &LevelUniqueQueueByteFIFO{}
+var _ (PeekableByteFIFO) = &LevelUniqueQueueByteFIFO{}
 
 // LevelUniqueQueueByteFIFO represents a ByteFIFO formed from a LevelUniqueQueue
 type LevelUniqueQueueByteFIFO struct {
 	internal   *levelqueue.UniqueQueue
+	db         *leveldb.DB
+	prefix     string
 	connection string
 }
 
@@ -82,12 +86,18 @@ func NewLevelUniqueQueueByteFIFO(connection, prefix string) (*LevelUniqueQueueBy
 	return &LevelUniqueQueueByteFIFO{
 		connection: connection,
 		internal:   internal,
+		db:         db,
+		prefix:     prefix,
 	}, nil
 }
 
 // PushFunc pushes data to the end of the fifo and calls the callback if it is added
 func (fifo *LevelUniqueQueueByteFIFO) PushFunc(data []byte, fn func() error) error {
-	return fifo.internal.LPushFunc(data, fn)
+	err := fifo.internal.LPushFunc(data, fn)
+	if err == levelqueue.ErrAlreadyInQueue {
+		return ErrAlreadyInQueue
+	}
+	return err
 }
 
 // Pop pops data from the start of the fifo
@@ -104,6 +114,11 @@ func (fifo *LevelUniqueQueueByteFIFO) Len() int64 {
 	return fifo.internal.Len()
 }
 
+// Iterate calls fn with the items from the start of the fifo without removing them
+func (fifo *LevelUniqueQueueByteFIFO) Iterate(fn func(data []byte) error) error {
+	return iterateLevelQueue(fifo.db, fifo.prefix, fn)
+}
+
 // Has returns whether the fifo contains this data
 func (fifo *LevelUniqueQueueByteFIFO) Has(data []byte) (bool, error) {
 	return fifo.internal.Has(data)
@@ -0,0 +1,150 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"code.gitea.io/gitea/modules/log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSUniqueQueueType is the type for NATS JetStream unique queue
+const NATSUniqueQueueType Type = "unique-nats"
+
+// NATSUniqueQueueConfiguration is the configuration for the NATS JetStream unique queue
+type NATSUniqueQueueConfiguration struct {
+	ByteFIFOQueueConfiguration
+	NATSUniqueByteFIFOConfiguration
+}
+
+// NATSUniqueQueue is a unique queue stored in a NATS JetStream stream
+type NATSUniqueQueue struct {
+	*ByteFIFOUniqueQueue
+}
+
+// NewNATSUniqueQueue creates a NATS JetStream unique queue
+func NewNATSUniqueQueue(handle HandlerFunc, cfg, exemplar interface{}) (Queue, error) {
+	configInterface, err := toConfig(NATSUniqueQueueConfiguration{}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	config := configInterface.(NATSUniqueQueueConfiguration)
+
+	byteFIFO, err := NewNATSUniqueByteFIFO(config.NATSUniqueByteFIFOConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	byteFIFOQueue, err := NewByteFIFOUniqueQueue(NATSUniqueQueueType, byteFIFO, handle, config.ByteFIFOQueueConfiguration, exemplar)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := &NATSUniqueQueue{
+		ByteFIFOUniqueQueue: byteFIFOQueue,
+	}
+
+	queue.qid = GetManager().Add(queue, NATSUniqueQueueType, config, exemplar)
+
+	return queue, nil
+}
+
+var _ (UniqueByteFIFO) = &NATSUniqueByteFIFO{}
+
+// NATSUniqueByteFIFO represents a UniqueByteFIFO formed from a NATS JetStream stream.
+// The contents of the fifo are tracked in a JetStream key-value bucket.
+type NATSUniqueByteFIFO struct {
+	NATSByteFIFO
+	set nats.KeyValue
+}
+
+// NATSUniqueByteFIFOConfiguration is the configuration for the NATSUniqueByteFIFO
+type NATSUniqueByteFIFOConfiguration struct {
+	NATSByteFIFOConfiguration
+	SetName string
+}
+
+// NewNATSUniqueByteFIFO creates a UniqueByteFIFO formed from a NATS JetStream stream
+func NewNATSUniqueByteFIFO(config NATSUniqueByteFIFOConfiguration) (*NATSUniqueByteFIFO, error) {
+	internal, err := NewNATSByteFIFO(config.NATSByteFIFOConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	fifo := &NATSUniqueByteFIFO{
+		NATSByteFIFO: *internal,
+	}
+
+	bucket := natsName(config.SetName)
+	if len(bucket) == 0 {
+		bucket = fifo.stream + "_unique"
+	}
+	fifo.set, err = fifo.js.KeyValue(bucket)
+	if err == nats.ErrBucketNotFound {
+		log.Debug("Creating NATS JetStream key-value bucket %s", bucket)
+		fifo.set, err = fifo.js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  bucket,
+			Storage: nats.FileStorage,
+		})
+	}
+	if err != nil {
+		_ = internal.Close()
+		return nil, err
+	}
+	return fifo, nil
+}
+
+func natsSetKey(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// PushFunc pushes data to the end of the fifo and calls the callback if it is added
+func (fifo *NATSUniqueByteFIFO) PushFunc(data []byte, fn func() error) error {
+	// Creating a key only succeeds if it does not exist which makes the check atomic between instances
+	if _, err := fifo.set.Create(natsSetKey(data), nil); err != nil {
+		if has, hasErr := fifo.Has(data); hasErr == nil && has {
+			return ErrAlreadyInQueue
+		}
+		return err
+	}
+
+	if err := fifo.NATSByteFIFO.PushFunc(data, fn); err != nil {
+		if purgeErr := fifo.set.Purge(natsSetKey(data)); purgeErr != nil {
+			log.Error("Unable to remove data from set %s after failed push: %v", fifo.set.Bucket(), purgeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Pop pops data from the start of the fifo
+func (fifo *NATSUniqueByteFIFO) Pop() ([]byte, error) {
+	data, err := fifo.NATSByteFIFO.Pop()
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	if err := fifo.set.Purge(natsSetKey(data)); err != nil {
+		// the data has already been removed from the queue so it must still be handled
+		log.Error("Unable to remove data from set %s: %v", fifo.set.Bucket(), err)
+	}
+	return data, nil
+}
+
+// Has returns whether the fifo contains this data
+func (fifo *NATSUniqueByteFIFO) Has(data []byte) (bool, error) {
+	_, err := fifo.set.Get(natsSetKey(data))
+	if err == nats.ErrKeyNotFound || err == nats.ErrKeyDeleted {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func init() {
+	queuesMap[NATSUniqueQueueType] = NewNATSUniqueQueue
+}
@@ -33,6 +33,8 @@ type WorkerPool struct {
 	boostTimeout       time.Duration
 	boostWorkers       int
 	numInQueue         int64
+	paused             chan struct{}
+	resumed            chan struct{}
 }
 
 // WorkerPoolConfiguration is the basic configuration for a WorkerPool
@@ -60,11 +62,63 @@ func NewWorkerPool(handle HandlerFunc, config WorkerPoolConfiguration) *WorkerPo
 		boostTimeout:       config.BoostTimeout,
 		boostWorkers:       config.BoostWorkers,
 		maxNumberOfWorkers: config.MaxWorkers,
+		paused:             make(chan struct{}),
+		resumed:            make(chan struct{}),
 	}
+	close(pool.resumed)
 
 	return pool
 }
 
+// IsPaused returns if the pool is paused
+func (p *WorkerPool) IsPaused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.isPaused()
+}
+
+// isPaused must be called with the lock held
+func (p *WorkerPool) isPaused() bool {
+	select {
+	case <-p.paused:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPausedIsResumed returns a channel which is closed when the pool is paused and
+// a channel which is closed when the pool is resumed
+func (p *WorkerPool) IsPausedIsResumed() (<-chan struct{}, <-chan struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused, p.resumed
+}
+
+// Pause pauses the pool - the workers will stop taking data from the channel
+func (p *WorkerPool) Pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.paused:
+	default:
+		p.resumed = make(chan struct{})
+		close(p.paused)
+	}
+}
+
+// Resume resumes the pool
+func (p *WorkerPool) Resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.resumed:
+	default:
+		p.paused = make(chan struct{})
+		close(p.resumed)
+	}
+}
+
 // Push pushes the data to the internal channel
 func (p *WorkerPool) Push(data Data) {
 	atomic.AddInt64(&p.numInQueue, 1)
@@ -96,7 +150,7 @@ func (p *WorkerPool) pushBoost(data Data) {
 			util.StopTimer(timer)
 		case <-timer.C:
 			p.lock.Lock()
-			if p.blockTimeout > ourTimeout || (p.numberOfWorkers > p.maxNumberOfWorkers && p.maxNumberOfWorkers >= 0) {
+			if p.blockTimeout > ourTimeout || (p.numberOfWorkers > p.maxNumberOfWorkers && p.maxNumberOfWorkers >= 0) || p.isPaused() {
 				p.lock.Unlock()
 				p.dataChan <- data
 				return
@@ -318,6 +372,28 @@ func (p *WorkerPool) doWork(ctx context.Context) {
 	delay := time.Millisecond * 300
 	var data = make([]Data, 0, p.batchLength)
 	for {
+		paused, _ := p.IsPausedIsResumed()
+		select {
+		case <-paused:
+			log.Trace("Worker for Queue %d Pausing", p.qid)
+			if len(data) > 0 {
+				log.Trace("Handling: %d data, %v", len(data), data)
+				p.handle(data...)
+				atomic.AddInt64(&p.numInQueue, -1*int64(len(data)))
+				data = make([]Data, 0, p.batchLength)
+			}
+			_, resumed := p.IsPausedIsResumed()
+			select {
+			case <-resumed:
+				log.Trace("Worker for Queue %d Resuming", p.qid)
+			case <-ctx.Done():
+				log.Trace("Worker shutting down")
+				return
+			}
+			continue
+		default:
+		}
+
 		select {
 		case <-ctx.Done():
 			if len(data) > 0 {
@@ -348,6 +424,8 @@ func (p *WorkerPool) doWork(ctx context.Context) {
 		default:
 			timer := time.NewTimer(delay)
 			select {
+			case <-paused:
+				util.StopTimer(timer)
 			case <-ctx.Done():
 				util.StopTimer(timer)
 				if len(data) > 0 {
@@ -0,0 +1,248 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	repo_module "code.gitea.io/gitea/modules/repository"
+)
+
+// Suggestion replaces a line of a file with the suggested lines
+type Suggestion struct {
+	TreePath string
+	Line     int64
+	OldLine  string
+	NewLines []string
+}
+
+// ApplySuggestionsOptions holds the options to apply suggestions to a branch
+type ApplySuggestionsOptions struct {
+	LastCommitID string
+	Branch       string
+	Message      string
+	Suggestions  []*Suggestion
+	Signoff      bool
+}
+
+// ApplySuggestions applies the suggestions to the files of a branch with a single commit
+// and returns the ID of the new commit
+func ApplySuggestions(repo *models.Repository, doer *models.User, opts *ApplySuggestionsOptions) (string, error) {
+	if len(opts.Suggestions) == 0 {
+		return "", nil
+	}
+
+	// the branch must exist for this operation
+	if _, err := repo_module.GetBranch(repo, opts.Branch); err != nil {
+		return "", err
+	}
+
+	suggestions := make(map[string][]*Suggestion)
+	treePaths := make([]string, 0, len(opts.Suggestions))
+	for _, suggestion := range opts.Suggestions {
+		treePath := CleanUploadFileName(suggestion.TreePath)
+		if treePath == "" {
+			return "", models.ErrFilenameInvalid{
+				Path: suggestion.TreePath,
+			}
+		}
+		if _, ok := suggestions[treePath]; !ok {
+			treePaths = append(treePaths, treePath)
+		}
+		suggestions[treePath] = append(suggestions[treePath], suggestion)
+	}
+
+	protectedBranch, err := repo.GetBranchProtection(opts.Branch)
+	if err != nil {
+		return "", err
+	}
+	if protectedBranch != nil {
+		if !protectedBranch.CanUserPush(doer.ID) {
+			return "", models.ErrUserCannotCommit{
+				UserName: doer.LowerName,
+			}
+		}
+		if protectedBranch.RequireSignedCommits {
+			_, _, _, err := repo.SignCRUDAction(doer, repo.RepoPath(), opts.Branch)
+			if err != nil {
+				if !models.IsErrWontSign(err) {
+					return "", err
+				}
+				return "", models.ErrUserCannotCommit{
+					UserName: doer.LowerName,
+				}
+			}
+		}
+		patterns := protectedBranch.GetProtectedFilePatterns()
+		for _, treePath := range treePaths {
+			for _, pat := range patterns {
+				if pat.Match(strings.ToLower(treePath)) {
+					return "", models.ErrFilePathProtected{
+						Path: treePath,
+					}
+				}
+			}
+		}
+	}
+
+	for _, treePath := range treePaths {
+		// Check file is not lfs locked, will return nil if lock setting not enabled
+		lfsLock, err := repo.GetTreePathLock(treePath)
+		if err != nil {
+			return "", err
+		}
+		if lfsLock != nil && lfsLock.OwnerID != doer.ID {
+			return "", models.ErrLFSFileLocked{RepoID: repo.ID, Path: treePath, UserName: lfsLock.Owner.Name}
+		}
+	}
+
+	t, err := NewTemporaryUploadRepository(repo)
+	if err != nil {
+		return "", err
+	}
+	defer t.Close()
+	if err := t.Clone(opts.Branch); err != nil {
+		return "", err
+	}
+	if err := t.SetDefaultIndex(); err != nil {
+		return "", err
+	}
+
+	// Get the commit of the branch
+	commit, err := t.GetBranchCommit(opts.Branch)
+	if err != nil {
+		return "", err
+	}
+
+	// The lines of the suggestions refer to LastCommitID, so the files must not
+	// have been changed since then
+	if opts.LastCommitID != "" && commit.ID.String() != opts.LastCommitID {
+		lastCommitID, err := t.gitRepo.ConvertToSHA1(opts.LastCommitID)
+		if err != nil {
+			return "", fmt.Errorf("ApplySuggestions: Invalid last commit ID: %v", err)
+		}
+		for _, treePath := range treePaths {
+			if changed, err := commit.FileChangedSinceCommit(treePath, lastCommitID.String()); err != nil {
+				return "", err
+			} else if changed {
+				return "", models.ErrCommitIDDoesNotMatch{
+					GivenCommitID:   opts.LastCommitID,
+					CurrentCommitID: commit.ID.String(),
+				}
+			}
+		}
+	}
+
+	for _, treePath := range treePaths {
+		entry, err := commit.GetTreeEntryByPath(treePath)
+		if err != nil {
+			return "", err
+		}
+		if !entry.IsRegular() && !entry.IsExecutable() {
+			return "", models.ErrFilePathInvalid{
+				Message: fmt.Sprintf("suggestions can only be applied to regular files [path: %s]", treePath),
+				Path:    treePath,
+				Name:    entry.Name(),
+				Type:    entry.Mode(),
+			}
+		}
+
+		reader, err := entry.Blob().DataAsync()
+		if err != nil {
+			return "", err
+		}
+		content, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return "", err
+		}
+
+		content, err = applySuggestionsToContent(treePath, content, suggestions[treePath])
+		if err != nil {
+			return "", err
+		}
+
+		objectHash, err := t.HashObject(bytes.NewReader(content))
+		if err != nil {
+			return "", err
+		}
+		mode := "100644"
+		if entry.IsExecutable() {
+			mode = "100755"
+		}
+		if err := t.AddObjectToIndex(mode, objectHash, treePath); err != nil {
+			return "", err
+		}
+	}
+
+	// Now write the tree
+	treeHash, err := t.WriteTree()
+	if err != nil {
+		return "", err
+	}
+
+	// Now commit the tree
+	commitHash, err := t.CommitTree(doer, doer, treeHash, strings.TrimSpace(opts.Message), opts.Signoff)
+	if err != nil {
+		return "", err
+	}
+
+	// Then push this tree to the branch
+	if err := t.Push(doer, commitHash, opts.Branch); err != nil {
+		return "", err
+	}
+	return commitHash, nil
+}
+
+// applySuggestionsToContent replaces the lines of the content of a file, the
+// replaced lines must still match the lines the suggestions were made for.
+func applySuggestionsToContent(treePath string, content []byte, suggestions []*Suggestion) ([]byte, error) {
+	lines := bytes.SplitAfter(content, []byte{'\n'})
+
+	sorted := make([]*Suggestion, len(suggestions))
+	copy(sorted, suggestions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Line > sorted[j].Line
+	})
+
+	// replace the lines bottom up, so that the line numbers of the other suggestions stay valid
+	for i, suggestion := range sorted {
+		if i > 0 && sorted[i-1].Line == suggestion.Line {
+			return nil, models.ErrSuggestionConflict{Path: treePath, Line: suggestion.Line}
+		}
+		if suggestion.Line <= 0 || suggestion.Line > int64(len(lines)) {
+			return nil, models.ErrSuggestionOutdated{Path: treePath, Line: suggestion.Line}
+		}
+		idx := suggestion.Line - 1
+		line := lines[idx]
+		body := bytes.TrimRight(line, "\r\n")
+		if string(body) != suggestion.OldLine {
+			return nil, models.ErrSuggestionOutdated{Path: treePath, Line: suggestion.Line}
+		}
+		// keep the line ending of the replaced line
+		ending := line[len(body):]
+		if len(ending) == 0 && len(suggestion.NewLines) > 1 {
+			ending = []byte{'\n'}
+		}
+
+		replacement := make([][]byte, 0, len(suggestion.NewLines))
+		for j, newLine := range suggestion.NewLines {
+			newLine = strings.TrimRight(newLine, "\r")
+			if j == len(suggestion.NewLines)-1 {
+				replacement = append(replacement, append([]byte(newLine), line[len(body):]...))
+			} else {
+				replacement = append(replacement, append([]byte(newLine), ending...))
+			}
+		}
+
+		lines = append(lines[:idx], append(replacement, lines[idx+1:]...)...)
+	}
+	return bytes.Join(lines, nil), nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySuggestionsToContent(t *testing.T) {
+	content := []byte("package main\r\n\r\nfunc main() {\r\n\tprintln(1)\r\n}")
+
+	result, err := applySuggestionsToContent("main.go", content, []*Suggestion{
+		{Line: 4, OldLine: "\tprintln(1)", NewLines: []string{"\tprintln(2)", "\tprintln(3)"}},
+		{Line: 2, OldLine: "", NewLines: []string{}},
+		{Line: 5, OldLine: "}", NewLines: []string{"}", ""}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "package main\r\nfunc main() {\r\n\tprintln(2)\r\n\tprintln(3)\r\n}\n", string(result))
+
+	_, err = applySuggestionsToContent("main.go", content, []*Suggestion{
+		{Line: 4, OldLine: "\tprintln(2)", NewLines: []string{"\tprintln(3)"}},
+	})
+	assert.True(t, models.IsErrSuggestionOutdated(err))
+
+	_, err = applySuggestionsToContent("main.go", content, []*Suggestion{
+		{Line: 7, OldLine: "", NewLines: []string{""}},
+	})
+	assert.True(t, models.IsErrSuggestionOutdated(err))
+
+	_, err = applySuggestionsToContent("main.go", content, []*Suggestion{
+		{Line: 4, OldLine: "\tprintln(1)", NewLines: []string{"\tprintln(2)"}},
+		{Line: 4, OldLine: "\tprintln(1)", NewLines: []string{"\tprintln(3)"}},
+	})
+	assert.True(t, models.IsErrSuggestionConflict(err))
+}
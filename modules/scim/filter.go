@@ -0,0 +1,201 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"strings"
+)
+
+// FilterExpression compares an attribute with a value, e.g. `userName eq "john"`
+type FilterExpression struct {
+	// Attribute is the normalized path of the attribute, see NormalizeAttributePath
+	Attribute string
+	// Operator is the lower-cased comparison operator, only "eq" is supported
+	Operator string
+	Value    string
+}
+
+// Filter is a conjunction of filter expressions
+type Filter []FilterExpression
+
+// NormalizeAttributePath lower-cases an attribute path and removes the schema prefix of core
+// attributes. The value sub-attribute of multi-valued attributes is implied, so "emails.value"
+// is normalized to "emails".
+func NormalizeAttributePath(path string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		prefix := strings.ToLower(schema) + ":"
+		if strings.HasPrefix(path, prefix) {
+			path = path[len(prefix):]
+			break
+		}
+	}
+	return strings.TrimSuffix(path, ".value")
+}
+
+// ParseFilter parses a filter of the form `attribute eq "value" [and ...]`.
+// Other comparison operators and logical operators are not supported.
+func ParseFilter(filter string) (Filter, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Filter
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, NewBadRequestError(ErrorTypeInvalidFilter, "incomplete filter expression")
+		}
+		if tokens[0].quoted || tokens[1].quoted {
+			return nil, NewBadRequestError(ErrorTypeInvalidFilter, "invalid filter expression")
+		}
+		operator := strings.ToLower(tokens[1].value)
+		if operator != "eq" {
+			return nil, NewBadRequestError(ErrorTypeInvalidFilter, "unsupported filter operator: %s", tokens[1].value)
+		}
+		result = append(result, FilterExpression{
+			Attribute: NormalizeAttributePath(tokens[0].value),
+			Operator:  operator,
+			Value:     tokens[2].value,
+		})
+		tokens = tokens[3:]
+
+		if len(tokens) > 0 {
+			if tokens[0].quoted || !strings.EqualFold(tokens[0].value, "and") {
+				return nil, NewBadRequestError(ErrorTypeInvalidFilter, "unsupported logical operator: %s", tokens[0].value)
+			}
+			tokens = tokens[1:]
+			if len(tokens) == 0 {
+				return nil, NewBadRequestError(ErrorTypeInvalidFilter, "incomplete filter expression")
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, NewBadRequestError(ErrorTypeInvalidFilter, "empty filter")
+	}
+	return result, nil
+}
+
+type filterToken struct {
+	value  string
+	quoted bool
+}
+
+func tokenizeFilter(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		switch {
+		case runes[i] == ' ' || runes[i] == '\t':
+			i++
+		case runes[i] == '"':
+			var value strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					value.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, NewBadRequestError(ErrorTypeInvalidFilter, "unterminated string in filter")
+			}
+			tokens = append(tokens, filterToken{value: value.String(), quoted: true})
+		case runes[i] == '(' || runes[i] == ')' || runes[i] == '[' || runes[i] == ']':
+			return nil, NewBadRequestError(ErrorTypeInvalidFilter, "grouping in filters is not supported")
+		default:
+			start := i
+			for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' && runes[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, filterToken{value: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+// Get returns the value the filter compares the attribute with
+func (f Filter) Get(attribute string) (string, bool) {
+	for _, expr := range f {
+		if expr.Attribute == attribute {
+			return expr.Value, true
+		}
+	}
+	return "", false
+}
+
+// MatchesUser returns if the user matches all expressions of the filter
+func (f Filter) MatchesUser(u *User) bool {
+	for _, expr := range f {
+		var values []string
+		switch expr.Attribute {
+		case "id":
+			values = []string{u.ID}
+		case "externalid":
+			values = []string{u.ExternalID}
+		case "username":
+			values = []string{u.UserName}
+		case "displayname":
+			values = []string{u.DisplayName}
+		case "emails":
+			for _, email := range u.Emails {
+				values = append(values, email.Value)
+			}
+		case "active":
+			if u.IsActive() {
+				values = []string{"true"}
+			} else {
+				values = []string{"false"}
+			}
+		default:
+			return false
+		}
+		if !containsFold(values, expr.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesGroup returns if the group matches all expressions of the filter
+func (f Filter) MatchesGroup(g *Group) bool {
+	for _, expr := range f {
+		var values []string
+		switch expr.Attribute {
+		case "id":
+			values = []string{g.ID}
+		case "externalid":
+			values = []string{g.ExternalID}
+		case "displayname":
+			values = []string{g.DisplayName}
+		case "members":
+			values = g.MemberIDs()
+		default:
+			return false
+		}
+		if !containsFold(values, expr.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
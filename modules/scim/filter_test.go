@@ -0,0 +1,93 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	kases := map[string]Filter{
+		`userName eq "john"`: {
+			{Attribute: "username", Operator: "eq", Value: "john"},
+		},
+		`USERNAME EQ "John Doe"`: {
+			{Attribute: "username", Operator: "eq", Value: "John Doe"},
+		},
+		`emails.value eq "john@example.com" and active eq true`: {
+			{Attribute: "emails", Operator: "eq", Value: "john@example.com"},
+			{Attribute: "active", Operator: "eq", Value: "true"},
+		},
+		`urn:ietf:params:scim:schemas:core:2.0:User:externalId eq "a\"b"`: {
+			{Attribute: "externalid", Operator: "eq", Value: `a"b`},
+		},
+		`displayName eq "org:team"`: {
+			{Attribute: "displayname", Operator: "eq", Value: "org:team"},
+		},
+	}
+	for input, expected := range kases {
+		filter, err := ParseFilter(input)
+		assert.NoError(t, err, input)
+		assert.EqualValues(t, expected, filter, input)
+	}
+
+	for _, input := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName co "john"`,
+		`userName eq "john" or userName eq "jane"`,
+		`userName eq "john" and`,
+		`userName eq "john`,
+		`(userName eq "john")`,
+		`emails[type eq "work"]`,
+	} {
+		_, err := ParseFilter(input)
+		assert.Error(t, err, input)
+		assert.True(t, IsError(err), input)
+		assert.Equal(t, ErrorTypeInvalidFilter, err.(*Error).ScimType, input)
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	active := false
+	user := &User{
+		ID:         "2",
+		ExternalID: "ext-2",
+		UserName:   "john",
+		Emails:     []MultiValuedAttribute{{Value: "john@example.com", Primary: true}},
+		Active:     &active,
+	}
+	group := &Group{
+		ID:          "5",
+		DisplayName: "org:team",
+		Members:     []MultiValuedAttribute{{Value: "2"}, {Value: "3"}},
+	}
+
+	kases := []struct {
+		filter string
+		user   bool
+		group  bool
+	}{
+		{`userName eq "JOHN"`, true, false},
+		{`emails eq "john@example.com"`, true, false},
+		{`active eq false`, true, false},
+		{`active eq true`, false, false},
+		{`externalId eq "ext-2" and userName eq "john"`, true, false},
+		{`externalId eq "ext-2" and userName eq "jane"`, false, false},
+		{`displayName eq "org:team"`, false, true},
+		{`members eq "3"`, false, true},
+		{`members.value eq "4"`, false, false},
+		{`title eq "boss"`, false, false},
+	}
+	for _, kase := range kases {
+		filter, err := ParseFilter(kase.filter)
+		assert.NoError(t, err)
+		assert.Equal(t, kase.user, filter.MatchesUser(user), kase.filter)
+		assert.Equal(t, kase.group, filter.MatchesGroup(group), kase.filter)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The operations of a PATCH request
+const (
+	PatchOperationAdd     = "add"
+	PatchOperationRemove  = "remove"
+	PatchOperationReplace = "replace"
+)
+
+// PatchOperation is a single operation of a PATCH request
+type PatchOperation struct {
+	Op    string              `json:"op"`
+	Path  string              `json:"path,omitempty"`
+	Value jsoniter.RawMessage `json:"value,omitempty"`
+}
+
+// PatchRequest represents the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Validate checks the schema and the operations of the request
+func (p *PatchRequest) Validate() error {
+	if !containsFold(p.Schemas, SchemaPatchOp) {
+		return NewBadRequestError(ErrorTypeInvalidSyntax, "missing schema %s", SchemaPatchOp)
+	}
+	for i := range p.Operations {
+		op := strings.ToLower(p.Operations[i].Op)
+		switch op {
+		case PatchOperationAdd, PatchOperationRemove, PatchOperationReplace:
+			p.Operations[i].Op = op
+		default:
+			return NewBadRequestError(ErrorTypeInvalidSyntax, "unsupported operation: %s", p.Operations[i].Op)
+		}
+		if op != PatchOperationRemove && len(p.Operations[i].Value) == 0 {
+			return NewBadRequestError(ErrorTypeInvalidValue, "missing value of %s operation", op)
+		}
+		if op == PatchOperationRemove && p.Operations[i].Path == "" {
+			return NewBadRequestError(ErrorTypeNoTarget, "missing path of remove operation")
+		}
+	}
+	return nil
+}
+
+// ApplyUserPatch applies validated operations to a user. Unknown attributes, e.g. of schema
+// extensions, are ignored as they can not be stored anyway.
+func ApplyUserPatch(u *User, ops []PatchOperation) error {
+	for _, op := range ops {
+		if op.Path != "" {
+			if err := applyUserAttribute(u, op.Op, op.Path, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		attributes, err := decodeObject(op.Value)
+		if err != nil {
+			return err
+		}
+		for path, value := range attributes {
+			if err := applyUserAttribute(u, op.Op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyUserAttribute(u *User, op, path string, value jsoniter.RawMessage) error {
+	remove := op == PatchOperationRemove
+	path = NormalizeAttributePath(path)
+
+	if strings.HasPrefix(path, "emails") && path != "emails" {
+		// e.g. emails[type eq "work"].value, only the primary address is stored
+		if !strings.HasPrefix(path, "emails[") || !strings.HasSuffix(path, "]") {
+			return NewBadRequestError(ErrorTypeInvalidPath, "unsupported path: %s", path)
+		}
+		if remove {
+			return NewBadRequestError(ErrorTypeMutability, "the email address can not be removed")
+		}
+		email, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		u.Emails = []MultiValuedAttribute{{Value: email, Primary: true}}
+		return nil
+	}
+
+	switch path {
+	case "active":
+		if remove {
+			u.Active = nil
+			return nil
+		}
+		active, err := decodeBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case "username":
+		if remove {
+			return NewBadRequestError(ErrorTypeMutability, "userName can not be removed")
+		}
+		return decodeInto(value, &u.UserName)
+	case "displayname":
+		if remove {
+			u.DisplayName = ""
+			return nil
+		}
+		return decodeInto(value, &u.DisplayName)
+	case "externalid":
+		if remove {
+			u.ExternalID = ""
+			return nil
+		}
+		return decodeInto(value, &u.ExternalID)
+	case "password":
+		if remove {
+			return NewBadRequestError(ErrorTypeMutability, "password can not be removed")
+		}
+		return decodeInto(value, &u.Password)
+	case "name":
+		if remove {
+			u.Name = nil
+			return nil
+		}
+		name := &Name{}
+		if op == PatchOperationAdd && u.Name != nil {
+			*name = *u.Name
+		}
+		if err := decodeInto(value, name); err != nil {
+			return err
+		}
+		u.Name = name
+	case "name.givenname", "name.familyname", "name.formatted":
+		if u.Name == nil {
+			u.Name = &Name{}
+		}
+		var s string
+		if !remove {
+			if err := decodeInto(value, &s); err != nil {
+				return err
+			}
+		}
+		switch path {
+		case "name.givenname":
+			u.Name.GivenName = s
+		case "name.familyname":
+			u.Name.FamilyName = s
+		default:
+			u.Name.Formatted = s
+		}
+	case "emails":
+		if remove {
+			return NewBadRequestError(ErrorTypeMutability, "the email address can not be removed")
+		}
+		var emails []MultiValuedAttribute
+		if err := decodeInto(value, &emails); err != nil {
+			return err
+		}
+		u.Emails = emails
+	}
+	return nil
+}
+
+// ApplyGroupPatch applies validated operations to a group
+func ApplyGroupPatch(g *Group, ops []PatchOperation) error {
+	for _, op := range ops {
+		if op.Path != "" {
+			if err := applyGroupAttribute(g, op.Op, op.Path, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		attributes, err := decodeObject(op.Value)
+		if err != nil {
+			return err
+		}
+		for path, value := range attributes {
+			if err := applyGroupAttribute(g, op.Op, path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyGroupAttribute(g *Group, op, rawPath string, value jsoniter.RawMessage) error {
+	remove := op == PatchOperationRemove
+	path := NormalizeAttributePath(rawPath)
+
+	if strings.HasPrefix(path, "members[") && strings.HasSuffix(path, "]") {
+		if op != PatchOperationRemove {
+			return NewBadRequestError(ErrorTypeInvalidPath, "unsupported path: %s", rawPath)
+		}
+		// the filter is taken from the raw path as the normalized one is lower-cased
+		filter, err := ParseFilter(rawPath[strings.Index(rawPath, "[")+1 : strings.LastIndex(rawPath, "]")])
+		if err != nil {
+			return err
+		}
+		member, ok := filter.Get("value")
+		if !ok || len(filter) != 1 {
+			return NewBadRequestError(ErrorTypeInvalidFilter, "members can only be filtered by value")
+		}
+		g.Members = removeMembers(g.Members, []string{member})
+		return nil
+	}
+
+	switch path {
+	case "displayname":
+		if remove {
+			return NewBadRequestError(ErrorTypeMutability, "displayName can not be removed")
+		}
+		return decodeInto(value, &g.DisplayName)
+	case "externalid":
+		if remove {
+			g.ExternalID = ""
+			return nil
+		}
+		return decodeInto(value, &g.ExternalID)
+	case "members":
+		var members []MultiValuedAttribute
+		if len(value) > 0 {
+			if err := decodeInto(value, &members); err != nil {
+				return err
+			}
+		}
+		switch op {
+		case PatchOperationAdd:
+			for _, member := range members {
+				if !containsFold(g.MemberIDs(), member.Value) {
+					g.Members = append(g.Members, member)
+				}
+			}
+		case PatchOperationRemove:
+			if len(members) == 0 {
+				g.Members = nil
+				return nil
+			}
+			ids := make([]string, 0, len(members))
+			for _, member := range members {
+				ids = append(ids, member.Value)
+			}
+			g.Members = removeMembers(g.Members, ids)
+		default:
+			g.Members = members
+		}
+	case "id", "schemas", "meta":
+		// read-only attributes some clients echo back
+	default:
+		return NewBadRequestError(ErrorTypeInvalidPath, "unsupported path: %s", path)
+	}
+	return nil
+}
+
+func removeMembers(members []MultiValuedAttribute, ids []string) []MultiValuedAttribute {
+	result := make([]MultiValuedAttribute, 0, len(members))
+	for _, member := range members {
+		if !containsFold(ids, member.Value) {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+func decodeInto(value jsoniter.RawMessage, v interface{}) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal(value, v); err != nil {
+		return NewBadRequestError(ErrorTypeInvalidValue, "invalid value: %v", err)
+	}
+	return nil
+}
+
+func decodeObject(value jsoniter.RawMessage) (map[string]jsoniter.RawMessage, error) {
+	var attributes map[string]jsoniter.RawMessage
+	if err := decodeInto(value, &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+func decodeString(value jsoniter.RawMessage) (string, error) {
+	var s string
+	err := decodeInto(value, &s)
+	return s, err
+}
+
+// decodeBool accepts booleans as well as their string representation, which some
+// identity providers send instead
+func decodeBool(value jsoniter.RawMessage) (bool, error) {
+	var b bool
+	if err := decodeInto(value, &b); err == nil {
+		return b, nil
+	}
+	s, err := decodeString(value)
+	if err != nil {
+		return false, err
+	}
+	b, err = strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, NewBadRequestError(ErrorTypeInvalidValue, "invalid boolean: %s", s)
+	}
+	return b, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func parsePatchRequest(t *testing.T, body string) *PatchRequest {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	var req PatchRequest
+	assert.NoError(t, json.Unmarshal([]byte(body), &req))
+	return &req
+}
+
+func TestPatchRequestValidate(t *testing.T) {
+	req := parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"Replace","path":"active","value":false}]}`)
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, PatchOperationReplace, req.Operations[0].Op)
+
+	for _, body := range []string{
+		`{"schemas":[],"Operations":[{"op":"replace","path":"active","value":false}]}`,
+		`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"move","path":"active","value":false}]}`,
+		`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"active"}]}`,
+		`{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"remove"}]}`,
+	} {
+		err := parsePatchRequest(t, body).Validate()
+		assert.Error(t, err, body)
+		assert.True(t, IsError(err), body)
+	}
+}
+
+func TestApplyUserPatch(t *testing.T) {
+	newUser := func() *User {
+		return &User{
+			UserName: "john",
+			Name:     &Name{GivenName: "John", FamilyName: "Doe"},
+			Emails:   []MultiValuedAttribute{{Value: "john@example.com", Primary: true}},
+		}
+	}
+
+	// Azure AD sends booleans as strings and capitalized operations
+	user := newUser()
+	req := parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"Replace","path":"active","value":"False"}]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyUserPatch(user, req.Operations))
+	assert.False(t, user.IsActive())
+
+	// Okta replaces attributes without a path
+	user = newUser()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","value":{"id":"2","active":true,"displayName":"Johnny"}}]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyUserPatch(user, req.Operations))
+	assert.True(t, user.IsActive())
+	assert.Equal(t, "Johnny", user.DisplayName)
+
+	user = newUser()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"replace","path":"userName","value":"jdoe"},
+		{"op":"replace","path":"name.givenName","value":"Jonathan"},
+		{"op":"replace","path":"emails[type eq \"work\"].value","value":"jdoe@example.com"},
+		{"op":"add","path":"urn:ietf:params:scim:schemas:core:2.0:User:externalId","value":"ext-1"},
+		{"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"R&D"}
+	]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyUserPatch(user, req.Operations))
+	assert.Equal(t, "jdoe", user.UserName)
+	assert.Equal(t, "Jonathan", user.Name.GivenName)
+	assert.Equal(t, "Doe", user.Name.FamilyName)
+	assert.Equal(t, "jdoe@example.com", user.PrimaryEmail())
+	assert.Equal(t, "ext-1", user.ExternalID)
+
+	user = newUser()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"remove","path":"userName"}]}`)
+	assert.NoError(t, req.Validate())
+	err := ApplyUserPatch(user, req.Operations)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTypeMutability, err.(*Error).ScimType)
+
+	user = newUser()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`)
+	assert.NoError(t, req.Validate())
+	err = ApplyUserPatch(user, req.Operations)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTypeInvalidValue, err.(*Error).ScimType)
+}
+
+func TestApplyGroupPatch(t *testing.T) {
+	newGroup := func() *Group {
+		return &Group{
+			DisplayName: "org:team",
+			Members:     []MultiValuedAttribute{{Value: "2"}, {Value: "3"}},
+		}
+	}
+
+	group := newGroup()
+	req := parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"add","path":"members","value":[{"value":"3"},{"value":"4"}]},
+		{"op":"remove","path":"members[value eq \"2\"]"}
+	]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyGroupPatch(group, req.Operations))
+	assert.Equal(t, []string{"3", "4"}, group.MemberIDs())
+
+	group = newGroup()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"Remove","path":"members","value":[{"value":"3"}]}
+	]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyGroupPatch(group, req.Operations))
+	assert.Equal(t, []string{"2"}, group.MemberIDs())
+
+	group = newGroup()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"replace","value":{"id":"5","displayName":"org:renamed","members":[{"value":"7"}]}}
+	]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyGroupPatch(group, req.Operations))
+	assert.Equal(t, "org:renamed", group.DisplayName)
+	assert.Equal(t, []string{"7"}, group.MemberIDs())
+
+	group = newGroup()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"remove","path":"members"}
+	]}`)
+	assert.NoError(t, req.Validate())
+	assert.NoError(t, ApplyGroupPatch(group, req.Operations))
+	assert.Empty(t, group.Members)
+
+	group = newGroup()
+	req = parsePatchRequest(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[
+		{"op":"replace","path":"description","value":"nope"}
+	]}`)
+	assert.NoError(t, req.Validate())
+	err := ApplyGroupPatch(group, req.Operations)
+	assert.Error(t, err)
+	assert.Equal(t, ErrorTypeInvalidPath, err.(*Error).ScimType)
+}
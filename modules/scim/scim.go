@@ -0,0 +1,242 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package scim provides the resources and messages of the System for Cross-domain
+// Identity Management (SCIM) 2.0 protocol, see RFC 7643 and RFC 7644
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of SCIM messages
+const ContentType = "application/scim+json"
+
+// The URNs of the supported schemas and messages
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// The resource types
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// Meta holds the metadata of a resource
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// Name holds the components of the name of a user
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValuedAttribute is a value of a multi-valued attribute like emails or members
+type MultiValuedAttribute struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User represents a user resource
+type User struct {
+	Schemas     []string               `json:"schemas"`
+	ID          string                 `json:"id,omitempty"`
+	ExternalID  string                 `json:"externalId,omitempty"`
+	UserName    string                 `json:"userName"`
+	Name        *Name                  `json:"name,omitempty"`
+	DisplayName string                 `json:"displayName,omitempty"`
+	Emails      []MultiValuedAttribute `json:"emails,omitempty"`
+	Active      *bool                  `json:"active,omitempty"`
+	Password    string                 `json:"password,omitempty"`
+	Groups      []MultiValuedAttribute `json:"groups,omitempty"`
+	Meta        *Meta                  `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the primary email address of the user, or its first one if none is marked as primary
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	if len(u.Emails) > 0 {
+		return strings.TrimSpace(u.Emails[0].Value)
+	}
+	return ""
+}
+
+// IsActive returns if the user is active, which it is unless stated otherwise
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+// FullName returns the display name of the user or the formatted name as fallback
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+// Group represents a group resource
+type Group struct {
+	Schemas     []string               `json:"schemas"`
+	ID          string                 `json:"id,omitempty"`
+	ExternalID  string                 `json:"externalId,omitempty"`
+	DisplayName string                 `json:"displayName"`
+	Members     []MultiValuedAttribute `json:"members,omitempty"`
+	Meta        *Meta                  `json:"meta,omitempty"`
+}
+
+// MemberIDs returns the values of the members of the group
+func (g *Group) MemberIDs() []string {
+	ids := make([]string, 0, len(g.Members))
+	for _, member := range g.Members {
+		ids = append(ids, member.Value)
+	}
+	return ids
+}
+
+// ListResponse represents the response of a query
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int64         `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// NewListResponse returns a list response of a page of resources
+func NewListResponse(total int64, startIndex int, resources []interface{}) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// The types of errors specified in RFC 7644
+const (
+	ErrorTypeInvalidFilter = "invalidFilter"
+	ErrorTypeUniqueness    = "uniqueness"
+	ErrorTypeMutability    = "mutability"
+	ErrorTypeInvalidSyntax = "invalidSyntax"
+	ErrorTypeInvalidPath   = "invalidPath"
+	ErrorTypeNoTarget      = "noTarget"
+	ErrorTypeInvalidValue  = "invalidValue"
+)
+
+// Error represents an error response
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	Status   string   `json:"status"`
+}
+
+// NewError returns an error response
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		ScimType: scimType,
+		Detail:   detail,
+		Status:   fmt.Sprint(status),
+	}
+}
+
+// NewBadRequestError returns an error response for an invalid request
+func NewBadRequestError(scimType, format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, scimType, fmt.Sprintf(format, args...))
+}
+
+func (err *Error) Error() string {
+	if err.ScimType != "" {
+		return fmt.Sprintf("%s: %s", err.ScimType, err.Detail)
+	}
+	return err.Detail
+}
+
+// IsError checks if an error is an Error
+func IsError(err error) bool {
+	_, ok := err.(*Error)
+	return ok
+}
+
+// AuthenticationScheme describes how clients authenticate
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary,omitempty"`
+}
+
+// Supported tells if an optional feature is supported
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// FilterSupport describes the support of filters
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// BulkSupport describes the support of bulk operations
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// ServiceProviderConfig describes the features supported by the service provider
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	DocumentationURI      string                 `json:"documentationUri,omitempty"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
+
+// ResourceType describes a type of resources
+type ResourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description,omitempty"`
+	Schema      string   `json:"schema"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
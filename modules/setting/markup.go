@@ -7,12 +7,19 @@ package setting
 import (
 	"regexp"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/log"
 
 	"gopkg.in/ini.v1"
 )
 
+// Render content modes of external markup parsers
+const (
+	RenderContentModeSanitized = "sanitized"
+	RenderContentModeIframe    = "iframe"
+)
+
 // ExternalMarkupParsers represents the external markup parsers
 var (
 	ExternalMarkupParsers  []MarkupParser
@@ -26,6 +33,10 @@ type MarkupParser struct {
 	Command        string
 	FileExtensions []string
 	IsInputFile    bool
+	Timeout        time.Duration
+	// RenderContentMode is either RenderContentModeSanitized or RenderContentModeIframe
+	RenderContentMode string
+	SanitizerRules    []MarkupSanitizerRule
 }
 
 // MarkupSanitizerRule defines the policy for whitelisting attributes on
@@ -37,6 +48,9 @@ type MarkupSanitizerRule struct {
 }
 
 func newMarkup() {
+	// Renderers have to be known before the sanitizer sections are parsed,
+	// so that rules can be attached to the renderer they are named after.
+	sanitizerSections := make([]*ini.Section, 0, 5)
 	for _, sec := range Cfg.Section("markup").ChildSections() {
 		name := strings.TrimPrefix(sec.Name(), "markup.")
 		if name == "" {
@@ -45,26 +59,56 @@ func newMarkup() {
 		}
 
 		if name == "sanitizer" || strings.HasPrefix(name, "sanitizer.") {
-			newMarkupSanitizer(name, sec)
+			sanitizerSections = append(sanitizerSections, sec)
 		} else {
 			newMarkupRenderer(name, sec)
 		}
 	}
+
+	for _, sec := range sanitizerSections {
+		newMarkupSanitizer(strings.TrimPrefix(sec.Name(), "markup."), sec)
+	}
 }
 
+// newMarkupSanitizer parses a sanitizer section. Sections named
+// markup.sanitizer.<renderer> or markup.sanitizer.<renderer>.<rule> only apply
+// to the external renderer of that name, all others apply to every renderer.
 func newMarkupSanitizer(name string, sec *ini.Section) {
+	rule, ok := createMarkupSanitizerRule(name, sec)
+	if !ok {
+		return
+	}
+
+	if strings.HasPrefix(name, "sanitizer.") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "sanitizer."), ".", 2)
+		for i := range ExternalMarkupParsers {
+			if ExternalMarkupParsers[i].MarkupName == parts[0] {
+				if len(parts) == 1 {
+					// Before 1.14 this section applied to every renderer
+					log.Warn("The rule of [markup.%s] only applies to the external renderer %s since Gitea 1.14. Rename the section, e.g. to [markup.%s.rule-1], to silence this warning.", name, parts[0], name)
+				}
+				ExternalMarkupParsers[i].SanitizerRules = append(ExternalMarkupParsers[i].SanitizerRules, rule)
+				return
+			}
+		}
+	}
+
+	ExternalSanitizerRules = append(ExternalSanitizerRules, rule)
+}
+
+func createMarkupSanitizerRule(name string, sec *ini.Section) (MarkupSanitizerRule, bool) {
 	haveElement := sec.HasKey("ELEMENT")
 	haveAttr := sec.HasKey("ALLOW_ATTR")
 	haveRegexp := sec.HasKey("REGEXP")
 
 	if !haveElement && !haveAttr && !haveRegexp {
 		log.Warn("Skipping empty section: markup.%s.", name)
-		return
+		return MarkupSanitizerRule{}, false
 	}
 
 	if !haveElement || !haveAttr || !haveRegexp {
 		log.Error("Missing required keys from markup.%s. Must have all three of ELEMENT, ALLOW_ATTR, and REGEXP defined!", name)
-		return
+		return MarkupSanitizerRule{}, false
 	}
 
 	elements := sec.Key("ELEMENT").Value()
@@ -72,14 +116,11 @@ func newMarkupSanitizer(name string, sec *ini.Section) {
 	regexpStr := sec.Key("REGEXP").Value()
 
 	if regexpStr == "" {
-		rule := MarkupSanitizerRule{
+		return MarkupSanitizerRule{
 			Element:   elements,
 			AllowAttr: allowAttrs,
 			Regexp:    nil,
-		}
-
-		ExternalSanitizerRules = append(ExternalSanitizerRules, rule)
-		return
+		}, true
 	}
 
 	// Validate when parsing the config that this is a valid regular
@@ -87,16 +128,14 @@ func newMarkupSanitizer(name string, sec *ini.Section) {
 	compiled, err := regexp.Compile(regexpStr)
 	if err != nil {
 		log.Error("In module.%s: REGEXP (%s) at definition %d failed to compile: %v", regexpStr, name, err)
-		return
+		return MarkupSanitizerRule{}, false
 	}
 
-	rule := MarkupSanitizerRule{
+	return MarkupSanitizerRule{
 		Element:   elements,
 		AllowAttr: allowAttrs,
 		Regexp:    compiled,
-	}
-
-	ExternalSanitizerRules = append(ExternalSanitizerRules, rule)
+	}, true
 }
 
 func newMarkupRenderer(name string, sec *ini.Section) {
@@ -123,11 +162,16 @@ func newMarkupRenderer(name string, sec *ini.Section) {
 		return
 	}
 
+	renderContentMode := sec.Key("RENDER_CONTENT_MODE").In(RenderContentModeSanitized,
+		[]string{RenderContentModeSanitized, RenderContentModeIframe})
+
 	ExternalMarkupParsers = append(ExternalMarkupParsers, MarkupParser{
-		Enabled:        sec.Key("ENABLED").MustBool(false),
-		MarkupName:     name,
-		FileExtensions: exts,
-		Command:        command,
-		IsInputFile:    sec.Key("IS_INPUT_FILE").MustBool(false),
+		Enabled:           sec.Key("ENABLED").MustBool(false),
+		MarkupName:        name,
+		FileExtensions:    exts,
+		Command:           command,
+		IsInputFile:       sec.Key("IS_INPUT_FILE").MustBool(false),
+		Timeout:           sec.Key("TIMEOUT").MustDuration(60 * time.Second),
+		RenderContentMode: renderContentMode,
 	})
 }
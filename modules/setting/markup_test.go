@@ -0,0 +1,63 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ini "gopkg.in/ini.v1"
+)
+
+func Test_newMarkup(t *testing.T) {
+	iniStr := `
+[markup.sanitizer.TeX]
+ELEMENT = span
+ALLOW_ATTR = class
+REGEXP = ^math$
+
+[markup.sanitizer.asciidoc.rule-1]
+ELEMENT = div
+ALLOW_ATTR = class
+REGEXP = ^admonitionblock$
+
+[markup.asciidoc]
+ENABLED = true
+FILE_EXTENSIONS = .adoc
+RENDER_COMMAND = asciidoctor -s -
+TIMEOUT = 5s
+RENDER_CONTENT_MODE = iframe
+
+[markup.rst]
+ENABLED = true
+FILE_EXTENSIONS = .rst
+RENDER_COMMAND = rst2html.py
+RENDER_CONTENT_MODE = invalid
+`
+	Cfg, _ = ini.Load([]byte(iniStr))
+	ExternalMarkupParsers = nil
+	ExternalSanitizerRules = nil
+	newMarkup()
+
+	if assert.Len(t, ExternalMarkupParsers, 2) {
+		asciidoc := ExternalMarkupParsers[0]
+		assert.EqualValues(t, "asciidoc", asciidoc.MarkupName)
+		assert.EqualValues(t, 5*time.Second, asciidoc.Timeout)
+		assert.EqualValues(t, RenderContentModeIframe, asciidoc.RenderContentMode)
+		if assert.Len(t, asciidoc.SanitizerRules, 1) {
+			assert.EqualValues(t, "div", asciidoc.SanitizerRules[0].Element)
+		}
+
+		rst := ExternalMarkupParsers[1]
+		assert.EqualValues(t, 60*time.Second, rst.Timeout)
+		assert.EqualValues(t, RenderContentModeSanitized, rst.RenderContentMode)
+		assert.Empty(t, rst.SanitizerRules)
+	}
+
+	if assert.Len(t, ExternalSanitizerRules, 1) {
+		assert.EqualValues(t, "span", ExternalSanitizerRules[0].Element)
+	}
+}
@@ -177,6 +177,12 @@ var (
 	PasswordComplexity                 []string
 	PasswordHashAlgo                   string
 	PasswordCheckPwn                   bool
+	LoginMaxFailedAttempts             int
+	LoginLockoutDuration               time.Duration
+	LoginMaxFailedAttemptsPerIP        int
+	LoginIPLockoutDuration             time.Duration
+	LoginLockoutNotifyUser             bool
+	EnforceTwoFactorAuth               bool
 
 	// UI settings
 	UI = struct {
@@ -191,6 +197,7 @@ var (
 		ReactionMaxUserNum    int
 		ThemeColorMetaTag     string
 		MaxDisplayFileSize    int64
+		MaxRichDiffFileSize   int64
 		ShowUserEmail         bool
 		DefaultShowFullName   bool
 		DefaultTheme          string
@@ -237,6 +244,7 @@ var (
 		ReactionMaxUserNum:  10,
 		ThemeColorMetaTag:   `#6cc644`,
 		MaxDisplayFileSize:  8388608,
+		MaxRichDiffFileSize: 524288,
 		DefaultTheme:        `gitea`,
 		Themes:              []string{`gitea`, `arc-green`},
 		Reactions:           []string{`+1`, `-1`, `laugh`, `hooray`, `confused`, `heart`, `rocket`, `eyes`},
@@ -289,16 +297,21 @@ var (
 		EnableHardLineBreakInDocuments bool
 		CustomURLSchemes               []string `ini:"CUSTOM_URL_SCHEMES"`
 		FileExtensions                 []string
+		EnableMath                     bool
+		PlantUMLServerURL              string `ini:"PLANTUML_SERVER_URL"`
 	}{
 		EnableHardLineBreakInComments:  true,
 		EnableHardLineBreakInDocuments: false,
 		FileExtensions:                 strings.Split(".md,.markdown,.mdown,.mkd", ","),
+		EnableMath:                     false,
 	}
 
 	// Admin settings
 	Admin struct {
 		DisableRegularOrgCreation bool
 		DefaultEmailNotification  string
+		EnableImpersonation       bool
+		ImpersonationDuration     time.Duration
 	}
 
 	// Log settings
@@ -352,13 +365,21 @@ var (
 		InvalidateRefreshTokens    bool
 		JWTSecretBytes             []byte `ini:"-"`
 		JWTSecretBase64            string `ini:"JWT_SECRET"`
+		JWTSigningAlgorithm        string `ini:"JWT_SIGNING_ALGORITHM"`
+		JWTSigningPrivateKeyFile   string `ini:"JWT_SIGNING_PRIVATE_KEY_FILE"`
 		MaxTokenLength             int
+		DeviceCodeExpirationTime   int64
+		DeviceCodePollingInterval  int64
 	}{
 		Enable:                     true,
 		AccessTokenExpirationTime:  3600,
 		RefreshTokenExpirationTime: 730,
 		InvalidateRefreshTokens:    false,
+		JWTSigningAlgorithm:        "RS256",
+		JWTSigningPrivateKeyFile:   "jwt/private.pem",
 		MaxTokenLength:             math.MaxInt16,
+		DeviceCodeExpirationTime:   600,
+		DeviceCodePollingInterval:  5,
 	}
 
 	U2F = struct {
@@ -776,6 +797,10 @@ func NewContext() {
 		return
 	}
 
+	if !filepath.IsAbs(OAuth2.JWTSigningPrivateKeyFile) {
+		OAuth2.JWTSigningPrivateKeyFile = filepath.Join(AppDataPath, OAuth2.JWTSigningPrivateKeyFile)
+	}
+
 	if OAuth2.Enable {
 		OAuth2.JWTSecretBytes = make([]byte, 32)
 		n, err := base64.RawURLEncoding.Decode(OAuth2.JWTSecretBytes, []byte(OAuth2.JWTSecretBase64))
@@ -812,6 +837,8 @@ func NewContext() {
 
 	sec = Cfg.Section("admin")
 	Admin.DefaultEmailNotification = sec.Key("DEFAULT_EMAIL_NOTIFICATIONS").MustString("enabled")
+	Admin.EnableImpersonation = sec.Key("ENABLE_IMPERSONATION").MustBool(true)
+	Admin.ImpersonationDuration = sec.Key("IMPERSONATION_DURATION").MustDuration(30 * time.Minute)
 
 	sec = Cfg.Section("security")
 	InstallLock = sec.Key("INSTALL_LOCK").MustBool(false)
@@ -829,6 +856,12 @@ func NewContext() {
 	PasswordHashAlgo = sec.Key("PASSWORD_HASH_ALGO").MustString("pbkdf2")
 	CSRFCookieHTTPOnly = sec.Key("CSRF_COOKIE_HTTP_ONLY").MustBool(true)
 	PasswordCheckPwn = sec.Key("PASSWORD_CHECK_PWN").MustBool(false)
+	LoginMaxFailedAttempts = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS").MustInt(10)
+	LoginLockoutDuration = sec.Key("LOGIN_LOCKOUT_DURATION").MustDuration(15 * time.Minute)
+	LoginMaxFailedAttemptsPerIP = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP").MustInt(50)
+	LoginIPLockoutDuration = sec.Key("LOGIN_IP_LOCKOUT_DURATION").MustDuration(15 * time.Minute)
+	LoginLockoutNotifyUser = sec.Key("LOGIN_LOCKOUT_NOTIFY_USER").MustBool(true)
+	EnforceTwoFactorAuth = sec.Key("ENFORCE_TWO_FACTOR_AUTH").MustBool(false)
 
 	InternalToken = loadInternalToken(sec)
 
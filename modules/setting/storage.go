@@ -17,6 +17,7 @@ type Storage struct {
 	Path        string
 	Section     *ini.Section
 	ServeDirect bool
+	Deduplicate bool
 }
 
 // MapTo implements the Mappable interface
@@ -74,6 +75,7 @@ func getStorage(name, typ string, targetSec *ini.Section) Storage {
 		}
 	}
 	storage.ServeDirect = storage.Section.Key("SERVE_DIRECT").MustBool(false)
+	storage.Deduplicate = storage.Section.Key("DEDUPLICATE").MustBool(false)
 
 	// Specific defaults
 	storage.Path = storage.Section.Key("PATH").MustString(filepath.Join(AppDataPath, name))
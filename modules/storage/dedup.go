@@ -0,0 +1,321 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/sync"
+	"code.gitea.io/gitea/modules/util"
+)
+
+var (
+	_ ObjectStorage = &DedupStorage{}
+
+	// ErrDedupRefNotExist represents a path which has no deduplicated content
+	ErrDedupRefNotExist = errors.New("deduplicated reference does not exist")
+	// ErrDedupRefStoreNotRegistered represents that no DedupRefStore has been registered
+	ErrDedupRefStoreNotRegistered = errors.New("no deduplication reference store registered")
+)
+
+// dedupContentPrefix is the path prefix within the wrapped storage under which the content is stored
+const dedupContentPrefix = "sha256"
+
+// DedupRefStore records which content the paths of a deduplicated storage refer to
+// and how often each content is referenced.
+type DedupRefStore interface {
+	// GetDedupRef returns the content hash the path refers to or ErrDedupRefNotExist
+	GetDedupRef(storage, path string) (string, error)
+	// AddDedupRef lets path refer to the content hash and increases the reference count of the content.
+	// It returns true if the content was not referenced before. If path referred to another content
+	// which is no longer referenced afterwards, release is called with its hash after the change
+	// has been stored.
+	AddDedupRef(storage, path, hash string, size int64, release func(hash string) error) (bool, error)
+	// RemoveDedupRef removes the reference of path and decreases the reference count of its content.
+	// If the content is no longer referenced, release is called with its hash after the change
+	// has been stored.
+	// It returns ErrDedupRefNotExist if path has no reference.
+	RemoveDedupRef(storage, path string, release func(hash string) error) error
+	// IsDedupContentReferenced returns true if any path of the storage refers to the content hash
+	IsDedupContentReferenced(storage, hash string) (bool, error)
+	// IterateDedupRefs iterates over all references of the storage
+	IterateDedupRefs(storage string, fn func(path, hash string) error) error
+}
+
+var (
+	dedupRefStore DedupRefStore
+
+	// dedupContentPool serializes adding references to a content and releasing it
+	dedupContentPool = sync.NewExclusivePool()
+)
+
+// RegisterDedupRefStore registers the store which keeps the references of deduplicated storages
+func RegisterDedupRefStore(store DedupRefStore) {
+	dedupRefStore = store
+}
+
+// DedupContentPath returns the path within the wrapped storage of the content with the provided hash
+func DedupContentPath(hash string) string {
+	if len(hash) < 5 {
+		return path.Join(dedupContentPrefix, hash)
+	}
+	return path.Join(dedupContentPrefix, hash[0:2], hash[2:4], hash)
+}
+
+// IsDedupContentPath returns true if the path of the wrapped storage belongs to deduplicated content
+func IsDedupContentPath(p string) bool {
+	return strings.HasPrefix(filepath.ToSlash(p), dedupContentPrefix+"/")
+}
+
+// DedupStorage wraps an ObjectStorage and stores each distinct content only once,
+// keyed by its SHA-256 hash. Paths which have been stored before deduplication
+// was enabled are still served from the wrapped storage.
+type DedupStorage struct {
+	name    string
+	storage ObjectStorage
+	refs    DedupRefStore
+}
+
+// NewDedupStorage wraps the provided ObjectStorage with a deduplicating layer.
+// The name identifies the storage within the registered DedupRefStore.
+func NewDedupStorage(name string, storage ObjectStorage) (*DedupStorage, error) {
+	if dedupRefStore == nil {
+		return nil, ErrDedupRefStoreNotRegistered
+	}
+	if ds, ok := storage.(*DedupStorage); ok {
+		storage = ds.storage
+	}
+	return &DedupStorage{
+		name:    name,
+		storage: storage,
+		refs:    dedupRefStore,
+	}, nil
+}
+
+// Name returns the name of the deduplicated storage
+func (d *DedupStorage) Name() string {
+	return d.name
+}
+
+// Unwrap returns the wrapped ObjectStorage
+func (d *DedupStorage) Unwrap() ObjectStorage {
+	return d.storage
+}
+
+// Open opens the content the path refers to
+func (d *DedupStorage) Open(p string) (Object, error) {
+	hash, err := d.refs.GetDedupRef(d.name, p)
+	if err == ErrDedupRefNotExist {
+		return d.storage.Open(p)
+	} else if err != nil {
+		return nil, err
+	}
+	return d.storage.Open(DedupContentPath(hash))
+}
+
+// Save stores the content once and lets the path refer to it
+func (d *DedupStorage) Save(p string, r io.Reader) (int64, error) {
+	size, err := d.saveRef(p, r)
+	if err != nil {
+		return 0, err
+	}
+	d.removeUndeduplicated(p)
+	return size, nil
+}
+
+// saveRef stores the content of r if it is not already present and lets the path refer to it
+func (d *DedupStorage) saveRef(p string, r io.Reader) (int64, error) {
+	tmp, err := ioutil.TempFile("", "gitea-dedup-*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = util.Remove(tmp.Name())
+	}()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	if err := d.saveContent(tmp, hash); err != nil {
+		return 0, err
+	}
+
+	// The old content of the path is released once the new content is in place,
+	// so that no two contents are locked at the same time
+	var releaseHash string
+	defer func() {
+		if releaseHash != "" {
+			if err := d.release(releaseHash); err != nil {
+				log.Error("Unable to release content %s of %s storage: %v", releaseHash, d.name, err)
+			}
+		}
+	}()
+
+	dedupContentPool.CheckIn(d.contentKey(hash))
+	defer dedupContentPool.CheckOut(d.contentKey(hash))
+
+	if _, err := d.refs.AddDedupRef(d.name, p, hash, size, func(oldHash string) error {
+		releaseHash = oldHash
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	// The content could have been released between saving and adding the reference.
+	// Releasing checks for new references while holding the same lock, so once the
+	// reference is committed the content can no longer disappear.
+	if err := d.saveContent(tmp, hash); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// removeUndeduplicated removes a copy of the path which may have been stored before deduplication was enabled
+func (d *DedupStorage) removeUndeduplicated(p string) {
+	if err := d.storage.Delete(p); err != nil {
+		log.Warn("Unable to remove undeduplicated object %s from %s storage: %v", p, d.name, err)
+	}
+}
+
+// saveContent stores the content of tmp with the provided hash if it is not already present
+func (d *DedupStorage) saveContent(tmp *os.File, hash string) error {
+	contentPath := DedupContentPath(hash)
+	if _, err := d.storage.Stat(contentPath); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := d.storage.Save(contentPath, tmp)
+	return err
+}
+
+func (d *DedupStorage) contentKey(hash string) string {
+	return d.name + "/" + hash
+}
+
+// release removes the content unless it has been referenced again in the meantime
+func (d *DedupStorage) release(hash string) error {
+	dedupContentPool.CheckIn(d.contentKey(hash))
+	defer dedupContentPool.CheckOut(d.contentKey(hash))
+
+	referenced, err := d.refs.IsDedupContentReferenced(d.name, hash)
+	if err != nil || referenced {
+		return err
+	}
+	return d.storage.Delete(DedupContentPath(hash))
+}
+
+// Stat returns the info of the content the path refers to
+func (d *DedupStorage) Stat(p string) (os.FileInfo, error) {
+	hash, err := d.refs.GetDedupRef(d.name, p)
+	if err == ErrDedupRefNotExist {
+		return d.storage.Stat(p)
+	} else if err != nil {
+		return nil, err
+	}
+	info, err := d.storage.Stat(DedupContentPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	return &dedupFileInfo{info, path.Base(p)}, nil
+}
+
+// Delete removes the reference of the path and the content if it is no longer referenced
+func (d *DedupStorage) Delete(p string) error {
+	err := d.refs.RemoveDedupRef(d.name, p, d.release)
+	if err == ErrDedupRefNotExist {
+		return d.storage.Delete(p)
+	}
+	return err
+}
+
+// URL gets the redirect URL to the content the path refers to
+func (d *DedupStorage) URL(p, name string) (*url.URL, error) {
+	hash, err := d.refs.GetDedupRef(d.name, p)
+	if err == ErrDedupRefNotExist {
+		return d.storage.URL(p, name)
+	} else if err != nil {
+		return nil, err
+	}
+	return d.storage.URL(DedupContentPath(hash), name)
+}
+
+// IterateObjects iterates across the objects which have not been deduplicated yet
+// and then across all deduplicated paths. Paths whose content is missing are
+// logged and skipped.
+func (d *DedupStorage) IterateObjects(fn func(path string, obj Object) error) error {
+	if err := d.storage.IterateObjects(func(p string, obj Object) error {
+		if IsDedupContentPath(p) {
+			return nil
+		}
+		return fn(p, obj)
+	}); err != nil {
+		return err
+	}
+
+	return d.refs.IterateDedupRefs(d.name, func(p, hash string) error {
+		obj, err := d.storage.Open(DedupContentPath(hash))
+		if os.IsNotExist(err) {
+			log.Warn("Content %s of %s is missing from %s storage", hash, p, d.name)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("open content %s of %s: %w", hash, p, err)
+		}
+		defer obj.Close()
+		return fn(p, obj)
+	})
+}
+
+// Deduplicate moves an object which has been stored before deduplication was enabled
+// into the deduplicated content. It returns false if the path has already been deduplicated.
+func (d *DedupStorage) Deduplicate(p string) (bool, error) {
+	if _, err := d.refs.GetDedupRef(d.name, p); err == nil {
+		return false, nil
+	} else if err != ErrDedupRefNotExist {
+		return false, err
+	}
+
+	obj, err := d.storage.Open(p)
+	if err != nil {
+		return false, err
+	}
+	_, err = d.saveRef(p, obj)
+	obj.Close()
+	if err != nil {
+		return false, err
+	}
+	d.removeUndeduplicated(p)
+	return true, nil
+}
+
+type dedupFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (d *dedupFileInfo) Name() string {
+	return d.name
+}
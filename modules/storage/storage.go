@@ -141,26 +141,41 @@ func NewStorage(typStr string, cfg interface{}) (ObjectStorage, error) {
 	return fn(context.Background(), cfg)
 }
 
+// newStorage creates the storage for the provided configuration and
+// wraps it with a deduplicating layer if that is enabled
+func newStorage(name string, cfg *setting.Storage) (ObjectStorage, error) {
+	s, err := NewStorage(cfg.Type, cfg)
+	if err != nil || !cfg.Deduplicate {
+		return s, err
+	}
+	log.Info("Enabling deduplication for %s storage", name)
+	ds, err := NewDedupStorage(name, s)
+	if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
 func initAvatars() (err error) {
 	log.Info("Initialising Avatar storage with type: %s", setting.Avatar.Storage.Type)
-	Avatars, err = NewStorage(setting.Avatar.Storage.Type, &setting.Avatar.Storage)
+	Avatars, err = newStorage("avatars", &setting.Avatar.Storage)
 	return
 }
 
 func initAttachments() (err error) {
 	log.Info("Initialising Attachment storage with type: %s", setting.Attachment.Storage.Type)
-	Attachments, err = NewStorage(setting.Attachment.Storage.Type, &setting.Attachment.Storage)
+	Attachments, err = newStorage("attachments", &setting.Attachment.Storage)
 	return
 }
 
 func initLFS() (err error) {
 	log.Info("Initialising LFS storage with type: %s", setting.LFS.Storage.Type)
-	LFS, err = NewStorage(setting.LFS.Storage.Type, &setting.LFS.Storage)
+	LFS, err = newStorage("lfs", &setting.LFS.Storage)
 	return
 }
 
 func initRepoAvatars() (err error) {
 	log.Info("Initialising Repository Avatar storage with type: %s", setting.RepoAvatar.Storage.Type)
-	RepoAvatars, err = NewStorage(setting.RepoAvatar.Storage.Type, &setting.RepoAvatar.Storage)
+	RepoAvatars, err = newStorage("repo-avatars", &setting.RepoAvatar.Storage)
 	return
 }
@@ -0,0 +1,26 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package structs
+
+import (
+	"time"
+)
+
+// PullMergeQueueEntry represents a pull request in the merge queue of a branch
+type PullMergeQueueEntry struct {
+	// position of the pull request in the queue, starting at 1
+	Position   int    `json:"position"`
+	Number     int64  `json:"number"`
+	Title      string `json:"title"`
+	HTMLURL    string `json:"html_url"`
+	BaseBranch string `json:"base_branch"`
+	MergeStyle string `json:"merge_style"`
+	QueuedBy   *User  `json:"queued_by"`
+	// commit the speculative merge was built on, empty if it was not built yet
+	BaseCommitID        string `json:"base_commit_id"`
+	SpeculativeCommitID string `json:"speculative_commit_id"`
+	// swagger:strfmt date-time
+	Queued time.Time `json:"queued_at"`
+}
@@ -103,3 +103,18 @@ type PullReviewRequestOptions struct {
 	Reviewers     []string `json:"reviewers"`
 	TeamReviewers []string `json:"team_reviewers"`
 }
+
+// PullViewedFile represents a file of a pull request the user has marked as viewed
+type PullViewedFile struct {
+	Path string `json:"path"`
+	// SHA of the blob of the viewed version, empty for deleted files
+	BlobSHA string `json:"blob_sha"`
+	// whether the file was changed since it was viewed
+	Outdated bool `json:"outdated"`
+}
+
+// PullViewedFilesOptions are options to mark files of a pull request as viewed or not viewed
+type PullViewedFilesOptions struct {
+	// required: true
+	Files []string `json:"files" binding:"Required"`
+}
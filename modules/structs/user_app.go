@@ -12,10 +12,19 @@ import (
 // AccessToken represents an API access token.
 // swagger:response AccessToken
 type AccessToken struct {
-	ID             int64  `json:"id"`
-	Name           string `json:"name"`
-	Token          string `json:"sha1"`
-	TokenLastEight string `json:"token_last_eight"`
+	ID             int64    `json:"id"`
+	Name           string   `json:"name"`
+	Token          string   `json:"sha1"`
+	TokenLastEight string   `json:"token_last_eight"`
+	Scopes         []string `json:"scopes"`
+	// full name of the repository the token is restricted to
+	Repository string `json:"repository,omitempty"`
+	// name of the organization the token is restricted to
+	Organization string `json:"organization,omitempty"`
+	// swagger:strfmt date-time
+	Expires *time.Time `json:"expires_at,omitempty"`
+	// swagger:strfmt date-time
+	LastUsed *time.Time `json:"last_used_at,omitempty"`
 }
 
 // AccessTokenList represents a list of API access token.
@@ -23,9 +32,18 @@ type AccessToken struct {
 type AccessTokenList []*AccessToken
 
 // CreateAccessTokenOption options when create access token
-// swagger:parameters userCreateToken
 type CreateAccessTokenOption struct {
+	// required: true
 	Name string `json:"name" binding:"Required"`
+	// scopes granted to the token, e.g. `repo:read` or `issue:write`, defaults to `all`
+	// which grants full access to the account
+	Scopes []string `json:"scopes"`
+	// full name of the repository to restrict the token to
+	Repository string `json:"repository"`
+	// name of the organization to restrict the token to
+	Organization string `json:"organization"`
+	// swagger:strfmt date-time
+	Expires *time.Time `json:"expires_at"`
 }
 
 // CreateOAuth2ApplicationOptions holds options to create an oauth2 application
@@ -350,9 +350,109 @@ func Queue(ctx *context.Context) {
 	ctx.Data["PageIsAdmin"] = true
 	ctx.Data["PageIsAdminMonitor"] = true
 	ctx.Data["Queue"] = mq
+	if mq.IsInspectable() {
+		items, err := mq.PeekPending(queue.DefaultPeekNumber)
+		if err != nil {
+			log.Error("Unable to peek pending items of queue %s: %v", mq.Name, err)
+		}
+		ctx.Data["PendingItems"] = items
+		ctx.Data["PendingItemsLimit"] = queue.DefaultPeekNumber
+	}
 	ctx.HTML(200, tplQueue)
 }
 
+// PauseQueue pauses a queue
+func PauseQueue(ctx *context.Context) {
+	qid := ctx.ParamsInt64("qid")
+	mq := queue.GetManager().GetManagedQueue(qid)
+	if mq == nil {
+		ctx.Status(404)
+		return
+	}
+	if !mq.Pause() {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.pool.none"))
+	} else {
+		ctx.Flash.Success(ctx.Tr("admin.monitor.queue.pause.success", mq.Name))
+	}
+	ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+}
+
+// ResumeQueue resumes a queue
+func ResumeQueue(ctx *context.Context) {
+	qid := ctx.ParamsInt64("qid")
+	mq := queue.GetManager().GetManagedQueue(qid)
+	if mq == nil {
+		ctx.Status(404)
+		return
+	}
+	if !mq.Resume() {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.pool.none"))
+	} else {
+		ctx.Flash.Success(ctx.Tr("admin.monitor.queue.resume.success", mq.Name))
+	}
+	ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+}
+
+// ExportQueue downloads the pending items of a paused queue as a file without removing them
+func ExportQueue(ctx *context.Context) {
+	qid := ctx.ParamsInt64("qid")
+	mq := queue.GetManager().GetManagedQueue(qid)
+	if mq == nil {
+		ctx.Status(404)
+		return
+	}
+	if !mq.IsInspectable() {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.pending.none"))
+		ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+		return
+	}
+	if !mq.IsPaused() {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.export.not_paused"))
+		ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/x-ndjson")
+	ctx.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.jsonl"`, mq.Name))
+	count, err := mq.ExportPending(ctx.Resp)
+	if err != nil {
+		log.Error("Exporting pending items of queue %s failed after %d items: %v", mq.Name, count, err)
+		return
+	}
+	log.Info("Exported %d pending items of queue %s", count, mq.Name)
+}
+
+// ImportQueue pushes the items of an uploaded file to a queue
+func ImportQueue(ctx *context.Context) {
+	qid := ctx.ParamsInt64("qid")
+	mq := queue.GetManager().GetManagedQueue(qid)
+	if mq == nil {
+		ctx.Status(404)
+		return
+	}
+	if !mq.IsInspectable() {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.pending.none"))
+		ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+		return
+	}
+
+	file, _, err := ctx.Req.FormFile("file")
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.import.nofile"))
+		ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+		return
+	}
+	defer file.Close()
+
+	count, err := mq.ImportPending(file)
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("admin.monitor.queue.import.error", count, err.Error()))
+	} else {
+		ctx.Flash.Success(ctx.Tr("admin.monitor.queue.import.success", count))
+	}
+	ctx.Redirect(setting.AppSubURL + "/admin/monitor/queue/" + strconv.FormatInt(qid, 10))
+}
+
 // WorkerCancel cancels a worker group
 func WorkerCancel(ctx *context.Context) {
 	qid := ctx.ParamsInt64("qid")
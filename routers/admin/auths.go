@@ -5,14 +5,17 @@
 package admin
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/auth/ldap"
 	"code.gitea.io/gitea/modules/auth/oauth2"
 	"code.gitea.io/gitea/modules/auth/pam"
+	"code.gitea.io/gitea/modules/auth/saml"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	auth "code.gitea.io/gitea/modules/forms"
@@ -65,6 +68,7 @@ var (
 			{models.LoginNames[models.LoginSMTP], models.LoginSMTP},
 			{models.LoginNames[models.LoginOAuth2], models.LoginOAuth2},
 			{models.LoginNames[models.LoginSSPI], models.LoginSSPI},
+			{models.LoginNames[models.LoginSAML], models.LoginSAML},
 		}
 		if pam.Supported {
 			items = append(items, dropdownItem{models.LoginNames[models.LoginPAM], models.LoginPAM})
@@ -103,6 +107,12 @@ func NewAuthSource(ctx *context.Context) {
 	ctx.Data["SSPISeparatorReplacement"] = "_"
 	ctx.Data["SSPIDefaultLanguage"] = ""
 
+	ctx.Data["saml_want_assertions_signed"] = true
+	ctx.Data["saml_attribute_username"] = "uid"
+	ctx.Data["saml_attribute_email"] = "mail"
+	ctx.Data["saml_attribute_full_name"] = "displayName"
+	ctx.Data["saml_auto_create_users"] = true
+
 	// only the first as default
 	for key := range models.OAuth2Providers {
 		ctx.Data["oauth2_provider"] = key
@@ -112,12 +122,12 @@ func NewAuthSource(ctx *context.Context) {
 	ctx.HTML(200, tplAuthNew)
 }
 
-func parseLDAPConfig(form auth.AuthenticationForm) *models.LDAPConfig {
+func parseLDAPConfig(ctx *context.Context, form auth.AuthenticationForm) (*models.LDAPConfig, error) {
 	var pageSize uint32
 	if form.UsePagedSearch {
 		pageSize = uint32(form.SearchPageSize)
 	}
-	return &models.LDAPConfig{
+	config := &models.LDAPConfig{
 		Source: &ldap.Source{
 			Name:                  form.Name,
 			Host:                  form.Host,
@@ -141,12 +151,19 @@ func parseLDAPConfig(form auth.AuthenticationForm) *models.LDAPConfig {
 			GroupFilter:           form.GroupFilter,
 			GroupMemberUID:        form.GroupMemberUID,
 			UserUID:               form.UserUID,
+			GroupTeamMap:          form.GroupTeamMap,
+			GroupTeamMapRemoval:   form.GroupTeamMapRemoval,
 			AdminFilter:           form.AdminFilter,
 			RestrictedFilter:      form.RestrictedFilter,
 			AllowDeactivateAll:    form.AllowDeactivateAll,
 			Enabled:               true,
 		},
 	}
+	if _, err := config.GroupTeamMapping(); err != nil {
+		ctx.Data["Err_GroupTeamMap"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.group_team_map_invalid", err.Error()))
+	}
+	return config, nil
 }
 
 func parseSMTPConfig(form auth.AuthenticationForm) *models.SMTPConfig {
@@ -160,7 +177,7 @@ func parseSMTPConfig(form auth.AuthenticationForm) *models.SMTPConfig {
 	}
 }
 
-func parseOAuth2Config(form auth.AuthenticationForm) *models.OAuth2Config {
+func parseOAuth2Config(ctx *context.Context, form auth.AuthenticationForm) (*models.OAuth2Config, error) {
 	var customURLMapping *oauth2.CustomURLMapping
 	if form.Oauth2UseCustomURL {
 		customURLMapping = &oauth2.CustomURLMapping{
@@ -172,14 +189,31 @@ func parseOAuth2Config(form auth.AuthenticationForm) *models.OAuth2Config {
 	} else {
 		customURLMapping = nil
 	}
-	return &models.OAuth2Config{
+	config := &models.OAuth2Config{
 		Provider:                      form.Oauth2Provider,
 		ClientID:                      form.Oauth2Key,
 		ClientSecret:                  form.Oauth2Secret,
 		OpenIDConnectAutoDiscoveryURL: form.OpenIDConnectAutoDiscoveryURL,
 		CustomURLMapping:              customURLMapping,
 		IconURL:                       form.Oauth2IconURL,
+		GroupClaimName:                form.Oauth2GroupClaimName,
+		RequiredGroup:                 form.Oauth2RequiredGroup,
+		AdminGroup:                    form.Oauth2AdminGroup,
+		RestrictedGroup:               form.Oauth2RestrictedGroup,
+		GroupTeamMap:                  form.Oauth2GroupTeamMap,
+		GroupTeamMapRemoval:           form.Oauth2GroupTeamMapRemoval,
+	}
+	// without the claim no groups are known, so none of these would be enforced
+	if len(config.GroupClaimName) == 0 && (len(config.RequiredGroup) > 0 || len(config.AdminGroup) > 0 ||
+		len(config.RestrictedGroup) > 0 || len(strings.TrimSpace(config.GroupTeamMap)) > 0) {
+		ctx.Data["Err_Oauth2GroupClaimName"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.oauth2_group_claim_name_required"))
 	}
+	if _, err := config.GroupTeamMapping(); err != nil {
+		ctx.Data["Err_Oauth2GroupTeamMap"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.group_team_map_invalid", err.Error()))
+	}
+	return config, nil
 }
 
 func parseSSPIConfig(ctx *context.Context, form auth.AuthenticationForm) (*models.SSPIConfig, error) {
@@ -206,6 +240,61 @@ func parseSSPIConfig(ctx *context.Context, form auth.AuthenticationForm) (*model
 	}, nil
 }
 
+func parseSAMLConfig(ctx *context.Context, form auth.AuthenticationForm, existing *models.SAMLConfig) (*models.SAMLConfig, error) {
+	metadata := strings.TrimSpace(form.SAMLIdentityProviderMetadata)
+	if len(form.SAMLIdentityProviderMetadataURL) > 0 {
+		data, err := saml.FetchIdentityProviderMetadata(form.SAMLIdentityProviderMetadataURL)
+		if err != nil {
+			ctx.Data["Err_SAMLIdentityProviderMetadataURL"] = true
+			return nil, errors.New(ctx.Tr("admin.auths.saml_metadata_fetch_error", err))
+		}
+		metadata = string(data)
+	}
+	if _, err := saml.ParseIdentityProviderMetadata([]byte(metadata)); err != nil {
+		ctx.Data["Err_SAMLIdentityProviderMetadata"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.saml_metadata_invalid", err))
+	}
+
+	cert := strings.TrimSpace(form.SAMLServiceProviderCertificate)
+	key := strings.TrimSpace(form.SAMLServiceProviderPrivateKey)
+	switch {
+	case len(cert) == 0 && len(key) == 0 && existing != nil:
+		// Keep the key pair the identity provider already knows
+		cert, key = existing.ServiceProviderCertificate, existing.ServiceProviderPrivateKey
+	case len(cert) == 0 && len(key) == 0:
+		var err error
+		if cert, key, err = saml.GenerateServiceProviderKeyPair(setting.Domain); err != nil {
+			return nil, err
+		}
+	default:
+		if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+			ctx.Data["Err_SAMLServiceProviderCertificate"] = true
+			return nil, errors.New(ctx.Tr("admin.auths.saml_key_pair_invalid", err))
+		}
+	}
+
+	return &models.SAMLConfig{
+		Source: &saml.Source{
+			IdentityProviderMetadata:    metadata,
+			IdentityProviderMetadataURL: form.SAMLIdentityProviderMetadataURL,
+			ServiceProviderCertificate:  cert,
+			ServiceProviderPrivateKey:   key,
+			SignRequests:                form.SAMLSignRequests,
+			WantAssertionsSigned:        form.SAMLWantAssertionsSigned,
+			AllowUnsolicited:            form.SAMLAllowUnsolicited,
+			NameIDFormat:                form.SAMLNameIDFormat,
+			AttributeUsername:           form.SAMLAttributeUsername,
+			AttributeEmail:              form.SAMLAttributeEmail,
+			AttributeFullName:           form.SAMLAttributeFullName,
+			AttributeGroups:             form.SAMLAttributeGroups,
+			RequiredGroup:               form.SAMLRequiredGroup,
+			AdminGroup:                  form.SAMLAdminGroup,
+			RestrictedGroup:             form.SAMLRestrictedGroup,
+			AutoCreateUsers:             form.SAMLAutoCreateUsers,
+		},
+	}, nil
+}
+
 // NewAuthSourcePost response for adding an auth source
 func NewAuthSourcePost(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*auth.AuthenticationForm)
@@ -231,7 +320,12 @@ func NewAuthSourcePost(ctx *context.Context) {
 	var config convert.Conversion
 	switch models.LoginType(form.Type) {
 	case models.LoginLDAP, models.LoginDLDAP:
-		config = parseLDAPConfig(form)
+		var err error
+		config, err = parseLDAPConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 		hasTLS = ldap.SecurityProtocol(form.SecurityProtocol) > ldap.SecurityProtocolUnencrypted
 	case models.LoginSMTP:
 		config = parseSMTPConfig(form)
@@ -241,7 +335,12 @@ func NewAuthSourcePost(ctx *context.Context) {
 			ServiceName: form.PAMServiceName,
 		}
 	case models.LoginOAuth2:
-		config = parseOAuth2Config(form)
+		var err error
+		config, err = parseOAuth2Config(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 	case models.LoginSSPI:
 		var err error
 		config, err = parseSSPIConfig(ctx, form)
@@ -255,6 +354,13 @@ func NewAuthSourcePost(ctx *context.Context) {
 			ctx.RenderWithErr(ctx.Tr("admin.auths.login_source_of_type_exist"), tplAuthNew, form)
 			return
 		}
+	case models.LoginSAML:
+		var err error
+		config, err = parseSAMLConfig(ctx, form, nil)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 	default:
 		ctx.Error(400)
 		return
@@ -340,7 +446,11 @@ func EditAuthSourcePost(ctx *context.Context) {
 	var config convert.Conversion
 	switch models.LoginType(form.Type) {
 	case models.LoginLDAP, models.LoginDLDAP:
-		config = parseLDAPConfig(form)
+		config, err = parseLDAPConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	case models.LoginSMTP:
 		config = parseSMTPConfig(form)
 	case models.LoginPAM:
@@ -348,13 +458,27 @@ func EditAuthSourcePost(ctx *context.Context) {
 			ServiceName: form.PAMServiceName,
 		}
 	case models.LoginOAuth2:
-		config = parseOAuth2Config(form)
+		config, err = parseOAuth2Config(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	case models.LoginSSPI:
 		config, err = parseSSPIConfig(ctx, form)
 		if err != nil {
 			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
 			return
 		}
+	case models.LoginSAML:
+		var existing *models.SAMLConfig
+		if source.IsSAML() {
+			existing = source.SAML()
+		}
+		config, err = parseSAMLConfig(ctx, form, existing)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	default:
 		ctx.Error(400)
 		return
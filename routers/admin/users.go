@@ -168,6 +168,8 @@ func prepareUserInfo(ctx *context.Context) *models.User {
 		return nil
 	}
 	ctx.Data["User"] = u
+	ctx.Data["EnableImpersonation"] = setting.Admin.EnableImpersonation && !u.IsAdmin
+	ctx.Data["ImpersonationDuration"] = setting.Admin.ImpersonationDuration.String()
 
 	if u.LoginSource > 0 {
 		ctx.Data["LoginSource"], err = models.GetLoginSourceByID(u.LoginSource)
@@ -298,6 +300,13 @@ func EditUserPost(ctx *context.Context) {
 		}
 	}
 
+	if form.UnlockAccount {
+		if err := models.UnlockUser(u); err != nil {
+			ctx.ServerError("UnlockUser", err)
+			return
+		}
+	}
+
 	u.LoginName = form.LoginName
 	u.FullName = form.FullName
 	u.Email = form.Email
@@ -368,3 +377,30 @@ func DeleteUser(ctx *context.Context) {
 		"redirect": setting.AppSubURL + "/admin/users",
 	})
 }
+
+// ImpersonateUser lets the administrator view the site as the user
+func ImpersonateUser(ctx *context.Context) {
+	if !setting.Admin.EnableImpersonation {
+		ctx.NotFound("ImpersonateUser", nil)
+		return
+	}
+
+	u, err := models.GetUserByID(ctx.ParamsInt64(":userid"))
+	if err != nil {
+		ctx.ServerError("GetUserByID", err)
+		return
+	}
+
+	// viewing the site as another administrator would grant the same access as before
+	if u.IsAdmin || u.IsOrganization() || ctx.IsImpersonating() {
+		ctx.Flash.Error(ctx.Tr("admin.users.impersonate_not_allowed"))
+		ctx.Redirect(setting.AppSubURL + "/admin/users/" + ctx.Params(":userid"))
+		return
+	}
+
+	if err = ctx.StartImpersonation(u, ctx.QueryBool("allow_write")); err != nil {
+		ctx.ServerError("StartImpersonation", err)
+		return
+	}
+	ctx.Redirect(setting.AppSubURL + "/")
+}
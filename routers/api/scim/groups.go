@@ -0,0 +1,442 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/scim"
+)
+
+// groupDisplayName returns the display name of the group of a team
+func groupDisplayName(org *models.User, team *models.Team) string {
+	return org.Name + ":" + team.Name
+}
+
+// parseGroupDisplayName returns the organization and the name of the team of a group
+func parseGroupDisplayName(displayName string) (*models.User, string, error) {
+	parts := strings.SplitN(strings.TrimSpace(displayName), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, "", scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "the displayName of a group must be organization:team")
+	}
+	org, err := models.GetOrgByName(parts[0])
+	if err != nil {
+		if models.IsErrOrgNotExist(err) {
+			return nil, "", scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "organization %s does not exist", parts[0])
+		}
+		return nil, "", err
+	}
+	return org, parts[1], nil
+}
+
+// toSCIMGroup converts a team to a SCIM group resource
+func toSCIMGroup(team *models.Team, withMembers bool) (*scim.Group, error) {
+	org, err := models.GetUserByID(team.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	externalID, err := models.GetSCIMExternalID(models.SCIMResourceTypeGroup, team.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          strconv.FormatInt(team.ID, 10),
+		ExternalID:  externalID,
+		DisplayName: groupDisplayName(org, team),
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeGroup,
+			Location:     resourceLocation("Groups", team.ID),
+		},
+	}
+	if withMembers {
+		members, err := models.GetTeamMembers(team.ID)
+		if err != nil {
+			return nil, err
+		}
+		group.Members = make([]scim.MultiValuedAttribute, 0, len(members))
+		for _, member := range members {
+			group.Members = append(group.Members, scim.MultiValuedAttribute{
+				Value:   strconv.FormatInt(member.ID, 10),
+				Display: member.Name,
+				Ref:     resourceLocation("Users", member.ID),
+			})
+		}
+	}
+	return group, nil
+}
+
+// getTeamByID returns the team with the given id
+func getTeamByID(id int64) (*models.Team, error) {
+	team, err := models.GetTeamByID(id)
+	if err != nil {
+		if models.IsErrTeamNotExist(err) {
+			return nil, scim.NewError(http.StatusNotFound, "", fmt.Sprintf("Group %d does not exist", id))
+		}
+		return nil, err
+	}
+	return team, nil
+}
+
+// findTeams returns the teams matching a filter, which must compare one of the indexed attributes
+func findTeams(filter scim.Filter) ([]*models.Team, error) {
+	var team *models.Team
+	var err error
+	if value, ok := filter.Get("id"); ok {
+		id, _ := strconv.ParseInt(value, 10, 64)
+		team, err = models.GetTeamByID(id)
+	} else if value, ok := filter.Get("externalid"); ok {
+		var id int64
+		if id, err = models.GetSCIMResourceIDByExternalID(models.SCIMResourceTypeGroup, value); err != nil {
+			return nil, err
+		}
+		team, err = models.GetTeamByID(id)
+	} else if value, ok := filter.Get("displayname"); ok {
+		org, teamName, parseErr := parseGroupDisplayName(value)
+		if parseErr != nil {
+			if scim.IsError(parseErr) {
+				return nil, nil
+			}
+			return nil, parseErr
+		}
+		team, err = models.GetTeam(org.ID, teamName)
+	} else {
+		return nil, scim.NewBadRequestError(scim.ErrorTypeInvalidFilter, "groups can only be filtered by id, externalId or displayName")
+	}
+	if err != nil {
+		if models.IsErrTeamNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []*models.Team{team}, nil
+}
+
+// ListGroups lists the groups, optionally filtered. The members are omitted if excludedAttributes is members.
+func ListGroups(ctx *context.APIContext) {
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	startIndex, count := parsePagination(ctx)
+	withMembers := !strings.EqualFold(ctx.Query("excludedAttributes"), "members")
+
+	if filter == nil {
+		teams, total, err := models.ListSCIMTeams(startIndex-1, count)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		resources := make([]interface{}, 0, len(teams))
+		for _, team := range teams {
+			group, err := toSCIMGroup(team, withMembers)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+			resources = append(resources, group)
+		}
+		writeResponse(ctx, http.StatusOK, scim.NewListResponse(total, startIndex, resources))
+		return
+	}
+
+	teams, err := findTeams(filter)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	resources := make([]interface{}, 0, len(teams))
+	for _, team := range teams {
+		group, err := toSCIMGroup(team, true)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if filter.MatchesGroup(group) {
+			if !withMembers {
+				group.Members = nil
+			}
+			resources = append(resources, group)
+		}
+	}
+	writeResponse(ctx, http.StatusOK, scim.NewListResponse(int64(len(resources)), startIndex, paginate(resources, startIndex, count)))
+}
+
+// GetGroup returns a group
+func GetGroup(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeGroup)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	team, err := getTeamByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeGroup(ctx, http.StatusOK, team)
+}
+
+// writeGroup writes the SCIM group resource of a team
+func writeGroup(ctx *context.APIContext, status int, team *models.Team) {
+	group, err := toSCIMGroup(team, !strings.EqualFold(ctx.Query("excludedAttributes"), "members"))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeResponse(ctx, status, group)
+}
+
+// translateTeamError converts the errors of creating or updating a team into SCIM errors
+func translateTeamError(err error) error {
+	switch {
+	case models.IsErrTeamAlreadyExist(err),
+		models.IsErrSCIMExternalIDAlreadyUsed(err):
+		return scim.NewError(http.StatusConflict, scim.ErrorTypeUniqueness, err.Error())
+	case models.IsErrLastOrgOwner(err):
+		return scim.NewBadRequestError(scim.ErrorTypeMutability, "%v", err)
+	}
+	return err
+}
+
+// parseMemberIDs returns the ids of the members of a group, which must all be individual users
+func parseMemberIDs(group *scim.Group) (map[int64]bool, error) {
+	ids := make(map[int64]bool, len(group.Members))
+	for _, member := range group.Members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return nil, scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "invalid member: %s", member.Value)
+		}
+		if _, err := getUserByID(id); err != nil {
+			if scim.IsError(err) {
+				return nil, scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "member %d does not exist", id)
+			}
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// syncTeamMembers adds and removes members of a team to match the members of the group
+func syncTeamMembers(team *models.Team, group *scim.Group) error {
+	ids, err := parseMemberIDs(group)
+	if err != nil {
+		return err
+	}
+
+	members, err := models.GetTeamMembers(team.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if ids[member.ID] {
+			delete(ids, member.ID)
+			continue
+		}
+		if err := models.RemoveTeamMember(team, member.ID); err != nil {
+			return translateTeamError(err)
+		}
+	}
+	for id := range ids {
+		if err := models.AddTeamMember(team, id); err != nil {
+			return translateTeamError(err)
+		}
+	}
+	return nil
+}
+
+// provisionTeam sets the external ID and the members of a newly created team
+func provisionTeam(team *models.Team, group *scim.Group) error {
+	if err := models.SetSCIMExternalID(models.SCIMResourceTypeGroup, team.ID, group.ExternalID); err != nil {
+		return translateTeamError(err)
+	}
+	return syncTeamMembers(team, group)
+}
+
+// CreateGroup provisions a team in an existing organization, it grants read access to all units
+// of the repositories added to it.
+func CreateGroup(ctx *context.APIContext) {
+	group := &scim.Group{}
+	if err := decodeBody(ctx, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	org, teamName, err := parseGroupDisplayName(group.DisplayName)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := checkExternalIDAvailable(models.SCIMResourceTypeGroup, 0, group.ExternalID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if _, err := parseMemberIDs(group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	team := &models.Team{
+		OrgID:     org.ID,
+		Name:      teamName,
+		Authorize: models.AccessModeRead,
+	}
+	for _, tp := range models.AllRepoUnitTypes {
+		team.Units = append(team.Units, &models.TeamUnit{
+			OrgID: org.ID,
+			Type:  tp,
+		})
+	}
+	if err := models.NewTeam(team); err != nil {
+		writeError(ctx, translateTeamError(err))
+		return
+	}
+
+	// A partially provisioned team is removed again, otherwise the retry of the client
+	// would fail because of the existing team
+	if err := provisionTeam(team, group); err != nil {
+		if delErr := models.DeleteTeam(team); delErr != nil {
+			log.Error("Unable to delete partially provisioned team %d: %v", team.ID, delErr)
+		}
+		writeError(ctx, err)
+		return
+	}
+	log.Trace("Team provisioned through SCIM by %s: %s", ctx.User.Name, groupDisplayName(org, team))
+
+	ctx.Resp.Header().Set("Location", resourceLocation("Groups", team.ID))
+	writeGroup(ctx, http.StatusCreated, team)
+}
+
+// updateTeam updates a team to match the SCIM group resource, a team can be renamed
+// but not moved to another organization
+func updateTeam(ctx *context.APIContext, team *models.Team, group *scim.Group) error {
+	org, teamName, err := parseGroupDisplayName(group.DisplayName)
+	if err != nil {
+		return err
+	}
+	if org.ID != team.OrgID {
+		return scim.NewBadRequestError(scim.ErrorTypeMutability, "a group can not be moved to another organization")
+	}
+	if err := checkExternalIDAvailable(models.SCIMResourceTypeGroup, team.ID, group.ExternalID); err != nil {
+		return err
+	}
+
+	if teamName != team.Name {
+		if team.IsOwnerTeam() {
+			return scim.NewBadRequestError(scim.ErrorTypeMutability, "the owners team can not be renamed")
+		}
+		team.Name = teamName
+		if err := models.UpdateTeam(team, false, false); err != nil {
+			return translateTeamError(err)
+		}
+	}
+
+	if err := models.SetSCIMExternalID(models.SCIMResourceTypeGroup, team.ID, group.ExternalID); err != nil {
+		return translateTeamError(err)
+	}
+	if err := syncTeamMembers(team, group); err != nil {
+		return err
+	}
+	log.Trace("Team updated through SCIM by %s: %s", ctx.User.Name, groupDisplayName(org, team))
+	return nil
+}
+
+// ReplaceGroup replaces the name and the members of a group
+func ReplaceGroup(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeGroup)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	team, err := getTeamByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	group := &scim.Group{}
+	if err := decodeBody(ctx, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := updateTeam(ctx, team, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeGroup(ctx, http.StatusOK, team)
+}
+
+// PatchGroup modifies the name or the members of a group
+func PatchGroup(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeGroup)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	team, err := getTeamByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	req := &scim.PatchRequest{}
+	if err := decodeBody(ctx, req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	group, err := toSCIMGroup(team, true)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := scim.ApplyGroupPatch(group, req.Operations); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := updateTeam(ctx, team, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeGroup(ctx, http.StatusOK, team)
+}
+
+// DeleteGroup deletes a team, the owners team can not be deleted
+func DeleteGroup(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeGroup)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	team, err := getTeamByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if team.IsOwnerTeam() {
+		writeError(ctx, scim.NewBadRequestError(scim.ErrorTypeMutability, "the owners team can not be deleted"))
+		return
+	}
+
+	if err := models.DeleteTeam(team); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	log.Trace("Team deleted through SCIM by %s: %d", ctx.User.Name, team.ID)
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,229 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package scim implements the System for Cross-domain Identity Management (SCIM) 2.0
+// endpoints an identity provider uses to provision users and teams.
+//
+// Users are mapped to individual users and groups to organization teams, the display
+// name of a group is the name of the organization and the team joined by a colon.
+// The endpoints require a personal access token of a site administrator which has
+// explicitly been granted the scim scope.
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/web"
+
+	"gitea.com/go-chi/session"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Routes registers the SCIM endpoints, they are mounted at /api/scim/v2
+func Routes() *web.Route {
+	var m = web.NewRoute()
+
+	m.Use(session.Sessioner(session.Options{
+		Provider:       setting.SessionConfig.Provider,
+		ProviderConfig: setting.SessionConfig.ProviderConfig,
+		CookieName:     setting.SessionConfig.CookieName,
+		CookiePath:     setting.SessionConfig.CookiePath,
+		Gclifetime:     setting.SessionConfig.Gclifetime,
+		Maxlifetime:    setting.SessionConfig.Maxlifetime,
+		Secure:         setting.SessionConfig.Secure,
+		Domain:         setting.SessionConfig.Domain,
+	}))
+	m.Use(context.APIContexter())
+	if setting.EnableAccessLog {
+		m.Use(context.AccessLogger())
+	}
+	m.Use(reqSCIMToken)
+
+	m.Get("/ServiceProviderConfig", ServiceProviderConfig)
+	m.Get("/ResourceTypes", ResourceTypes)
+	m.Combo("/Users").Get(ListUsers).Post(CreateUser)
+	m.Combo("/Users/{id}").Get(GetUser).Put(ReplaceUser).Patch(PatchUser).Delete(DeleteUser)
+	m.Combo("/Groups").Get(ListGroups).Post(CreateGroup)
+	m.Combo("/Groups/{id}").Get(GetGroup).Put(ReplaceGroup).Patch(PatchGroup).Delete(DeleteGroup)
+
+	return m
+}
+
+// reqSCIMToken requires a personal access token of a site administrator with the scim scope,
+// a token with full access to the account is not sufficient.
+func reqSCIMToken(ctx *context.APIContext) {
+	token := ctx.AccessToken()
+	if token == nil || ctx.User == nil {
+		ctx.Resp.Header().Set("WWW-Authenticate", `Bearer realm="SCIM"`)
+		writeError(ctx, scim.NewError(http.StatusUnauthorized, "", "a personal access token is required"))
+		return
+	}
+
+	// the router has no ToggleAPI, which rejects these users on the other API routes
+	if !ctx.User.IsActive || ctx.User.ProhibitLogin {
+		writeError(ctx, scim.NewError(http.StatusForbidden, "", "the user of the token is not allowed to sign in"))
+		return
+	}
+
+	write := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
+	if !ctx.User.IsAdmin || token.IsRestricted() || token.Scope == models.AccessTokenScopeAll ||
+		!token.Scope.HasScope(models.AccessTokenScopeCategorySCIM, write) {
+		writeError(ctx, scim.NewError(http.StatusForbidden, "",
+			fmt.Sprintf("the token of a site administrator with the %s scope is required", models.AccessTokenScopeCategorySCIM)))
+		return
+	}
+}
+
+// writeResponse writes a SCIM message
+func writeResponse(ctx *context.APIContext, status int, content interface{}) {
+	ctx.Resp.Header().Set("Content-Type", scim.ContentType+";charset=utf-8")
+	ctx.Resp.WriteHeader(status)
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewEncoder(ctx.Resp).Encode(content); err != nil {
+		log.Error("Render SCIM response failed: %v", err)
+	}
+}
+
+// writeError writes a SCIM error response, errors which are not a *scim.Error are internal server errors
+func writeError(ctx *context.APIContext, err error) {
+	scimErr, ok := err.(*scim.Error)
+	if !ok {
+		log.Error("SCIM %s %s: %v", ctx.Req.Method, ctx.Req.URL.Path, err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+	}
+	status, convErr := strconv.Atoi(scimErr.Status)
+	if convErr != nil {
+		status = http.StatusInternalServerError
+	}
+	writeResponse(ctx, status, scimErr)
+}
+
+// decodeBody decodes the JSON body of the request
+func decodeBody(ctx *context.APIContext, v interface{}) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewDecoder(ctx.Req.Body).Decode(v); err != nil {
+		return scim.NewBadRequestError(scim.ErrorTypeInvalidSyntax, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// resourceLocation returns the URL of a resource
+func resourceLocation(endpoint string, id int64) string {
+	return fmt.Sprintf("%sapi/scim/v2/%s/%d", setting.AppURL, endpoint, id)
+}
+
+// parseResourceID parses the id of the resource in the path, a malformed id does not match any resource
+func parseResourceID(ctx *context.APIContext, resourceType string) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, scim.NewError(http.StatusNotFound, "", fmt.Sprintf("%s %s does not exist", resourceType, ctx.Params("id")))
+	}
+	return id, nil
+}
+
+// parsePagination returns the one-based start index and the number of resources requested
+func parsePagination(ctx *context.APIContext) (startIndex, count int) {
+	startIndex = ctx.QueryInt("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count = setting.API.MaxResponseItems
+	if ctx.Query("count") != "" {
+		count = ctx.QueryInt("count")
+	}
+	if count < 0 {
+		count = 0
+	} else if count > setting.API.MaxResponseItems {
+		count = setting.API.MaxResponseItems
+	}
+	return startIndex, count
+}
+
+// paginate returns the requested page of resources which have all been loaded
+func paginate(resources []interface{}, startIndex, count int) []interface{} {
+	start := startIndex - 1
+	if start > len(resources) {
+		start = len(resources)
+	}
+	end := start + count
+	if end > len(resources) {
+		end = len(resources)
+	}
+	return resources[start:end]
+}
+
+// parseFilter parses the filter of a query, it returns a nil filter if there is none
+func parseFilter(ctx *context.APIContext) (scim.Filter, error) {
+	if ctx.Query("filter") == "" {
+		return nil, nil
+	}
+	return scim.ParseFilter(ctx.Query("filter"))
+}
+
+// ServiceProviderConfig returns the features supported by the service provider
+func ServiceProviderConfig(ctx *context.APIContext) {
+	writeResponse(ctx, http.StatusOK, &scim.ServiceProviderConfig{
+		Schemas:          []string{scim.SchemaServiceProviderConfig},
+		DocumentationURI: "https://docs.gitea.io/en-us/scim/",
+		Patch:            scim.Supported{Supported: true},
+		Bulk:             scim.BulkSupport{Supported: false},
+		Filter: scim.FilterSupport{
+			Supported:  true,
+			MaxResults: setting.API.MaxResponseItems,
+		},
+		ChangePassword: scim.Supported{Supported: true},
+		Sort:           scim.Supported{Supported: false},
+		ETag:           scim.Supported{Supported: false},
+		AuthenticationSchemes: []scim.AuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication with a personal access token of a site administrator granted the scim scope",
+				Primary:     true,
+			},
+		},
+		Meta: &scim.Meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     setting.AppURL + "api/scim/v2/ServiceProviderConfig",
+		},
+	})
+}
+
+// ResourceTypes returns the supported resource types
+func ResourceTypes(ctx *context.APIContext) {
+	resources := []interface{}{
+		&scim.ResourceType{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          scim.ResourceTypeUser,
+			Name:        scim.ResourceTypeUser,
+			Endpoint:    "/Users",
+			Description: "Individual user accounts",
+			Schema:      scim.SchemaUser,
+			Meta: &scim.Meta{
+				ResourceType: "ResourceType",
+				Location:     setting.AppURL + "api/scim/v2/ResourceTypes/" + scim.ResourceTypeUser,
+			},
+		},
+		&scim.ResourceType{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          scim.ResourceTypeGroup,
+			Name:        scim.ResourceTypeGroup,
+			Endpoint:    "/Groups",
+			Description: "Teams of organizations, named organization:team",
+			Schema:      scim.SchemaGroup,
+			Meta: &scim.Meta{
+				ResourceType: "ResourceType",
+				Location:     setting.AppURL + "api/scim/v2/ResourceTypes/" + scim.ResourceTypeGroup,
+			},
+		},
+	}
+	writeResponse(ctx, http.StatusOK, scim.NewListResponse(int64(len(resources)), 1, resources))
+}
@@ -0,0 +1,450 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/password"
+	"code.gitea.io/gitea/modules/scim"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// generatedPasswordLength is the length of the random password of users provisioned without one
+const generatedPasswordLength = 32
+
+// userNameToLogin returns the name of the account of a SCIM user name. Identity providers
+// often use email addresses as user names, their local part is used as name in that case.
+func userNameToLogin(userName string) string {
+	userName = strings.TrimSpace(userName)
+	if i := strings.LastIndex(userName, "@"); i > 0 {
+		return userName[:i]
+	}
+	return userName
+}
+
+// toSCIMUser converts a user to a SCIM user resource
+func toSCIMUser(u *models.User) (*scim.User, error) {
+	externalID, err := models.GetSCIMExternalID(models.SCIMResourceTypeUser, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := u.IsActive && !u.ProhibitLogin
+	created := u.CreatedUnix.AsTime()
+	updated := u.UpdatedUnix.AsTime()
+	user := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          strconv.FormatInt(u.ID, 10),
+		ExternalID:  externalID,
+		UserName:    u.Name,
+		DisplayName: u.FullName,
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeUser,
+			Created:      &created,
+			LastModified: &updated,
+			Location:     resourceLocation("Users", u.ID),
+		},
+	}
+	if u.FullName != "" {
+		user.Name = &scim.Name{Formatted: u.FullName}
+	}
+	if u.Email != "" {
+		user.Emails = []scim.MultiValuedAttribute{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return user, nil
+}
+
+// getUserByID returns the individual user with the given id
+func getUserByID(id int64) (*models.User, error) {
+	u, err := models.GetUserByID(id)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			return nil, scim.NewError(http.StatusNotFound, "", fmt.Sprintf("User %d does not exist", id))
+		}
+		return nil, err
+	}
+	if u.IsOrganization() {
+		return nil, scim.NewError(http.StatusNotFound, "", fmt.Sprintf("User %d does not exist", id))
+	}
+	return u, nil
+}
+
+// findUsers returns the users matching a filter, which must compare one of the indexed attributes
+func findUsers(filter scim.Filter) ([]*models.User, error) {
+	var u *models.User
+	var err error
+	if value, ok := filter.Get("id"); ok {
+		id, _ := strconv.ParseInt(value, 10, 64)
+		u, err = models.GetUserByID(id)
+	} else if value, ok := filter.Get("externalid"); ok {
+		var id int64
+		if id, err = models.GetSCIMResourceIDByExternalID(models.SCIMResourceTypeUser, value); err != nil {
+			return nil, err
+		}
+		u, err = models.GetUserByID(id)
+	} else if value, ok := filter.Get("username"); ok {
+		u, err = models.GetUserByName(userNameToLogin(value))
+	} else if value, ok := filter.Get("emails"); ok {
+		u, err = models.GetUserByEmail(value)
+	} else {
+		return nil, scim.NewBadRequestError(scim.ErrorTypeInvalidFilter, "users can only be filtered by id, externalId, userName or emails")
+	}
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if u.IsOrganization() {
+		return nil, nil
+	}
+	return []*models.User{u}, nil
+}
+
+// ListUsers lists the users, optionally filtered
+func ListUsers(ctx *context.APIContext) {
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	startIndex, count := parsePagination(ctx)
+
+	if filter == nil {
+		users, total, err := models.ListSCIMUsers(startIndex-1, count)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		resources := make([]interface{}, 0, len(users))
+		for _, u := range users {
+			user, err := toSCIMUser(u)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+			resources = append(resources, user)
+		}
+		writeResponse(ctx, http.StatusOK, scim.NewListResponse(total, startIndex, resources))
+		return
+	}
+
+	users, err := findUsers(filter)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	resources := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		user, err := toSCIMUser(u)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if filter.MatchesUser(user) {
+			resources = append(resources, user)
+		}
+	}
+	writeResponse(ctx, http.StatusOK, scim.NewListResponse(int64(len(resources)), startIndex, paginate(resources, startIndex, count)))
+}
+
+// GetUser returns a user
+func GetUser(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeUser)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	u, err := getUserByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	user, err := toSCIMUser(u)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeResponse(ctx, http.StatusOK, user)
+}
+
+// checkPassword checks that a password provided by the identity provider can be used
+func checkPassword(ctx *context.APIContext, passwd string) error {
+	if !password.IsComplexEnough(passwd) || len(passwd) < setting.MinPasswordLength {
+		return scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "the password does not meet the password requirements")
+	}
+	pwned, err := password.IsPwned(ctx.Req.Context(), passwd)
+	if err != nil {
+		log.Error("IsPwned: %v", err)
+	}
+	if pwned {
+		return scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "the password has been exposed in a data breach")
+	}
+	return nil
+}
+
+// translateUserError converts the errors of creating or updating a user into SCIM errors
+func translateUserError(err error) error {
+	switch {
+	case models.IsErrUserAlreadyExist(err),
+		models.IsErrEmailAlreadyUsed(err),
+		models.IsErrSCIMExternalIDAlreadyUsed(err):
+		return scim.NewError(http.StatusConflict, scim.ErrorTypeUniqueness, err.Error())
+	case models.IsErrNameReserved(err),
+		models.IsErrNamePatternNotAllowed(err),
+		models.IsErrNameCharsNotAllowed(err),
+		models.IsErrEmailInvalid(err):
+		return scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "%v", err)
+	}
+	return err
+}
+
+// checkExternalIDAvailable checks that the external id is not assigned to another resource
+func checkExternalIDAvailable(resourceType models.SCIMResourceType, resourceID int64, externalID string) error {
+	if externalID == "" {
+		return nil
+	}
+	id, err := models.GetSCIMResourceIDByExternalID(resourceType, externalID)
+	if err != nil {
+		return err
+	}
+	if id != 0 && id != resourceID {
+		return translateUserError(models.ErrSCIMExternalIDAlreadyUsed{ResourceType: resourceType, ExternalID: externalID})
+	}
+	return nil
+}
+
+// CreateUser provisions a user
+func CreateUser(ctx *context.APIContext) {
+	user := &scim.User{}
+	if err := decodeBody(ctx, user); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if userNameToLogin(user.UserName) == "" {
+		writeError(ctx, scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "userName is required"))
+		return
+	}
+	if user.PrimaryEmail() == "" {
+		writeError(ctx, scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "an email address is required"))
+		return
+	}
+	if err := checkExternalIDAvailable(models.SCIMResourceTypeUser, 0, user.ExternalID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	passwd := user.Password
+	if passwd != "" {
+		if err := checkPassword(ctx, passwd); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	} else {
+		length := generatedPasswordLength
+		if setting.MinPasswordLength > length {
+			length = setting.MinPasswordLength
+		}
+		var err error
+		if passwd, err = password.Generate(length); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+
+	u := &models.User{
+		Name:          userNameToLogin(user.UserName),
+		FullName:      user.FullName(),
+		Email:         user.PrimaryEmail(),
+		Passwd:        passwd,
+		IsActive:      true,
+		ProhibitLogin: !user.IsActive(),
+		LoginType:     models.LoginPlain,
+	}
+	if err := models.CreateUser(u); err != nil {
+		writeError(ctx, translateUserError(err))
+		return
+	}
+	log.Trace("Account provisioned through SCIM by %s: %s", ctx.User.Name, u.Name)
+
+	if err := models.SetSCIMExternalID(models.SCIMResourceTypeUser, u.ID, user.ExternalID); err != nil {
+		writeError(ctx, translateUserError(err))
+		return
+	}
+
+	created, err := toSCIMUser(u)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Resp.Header().Set("Location", created.Meta.Location)
+	writeResponse(ctx, http.StatusCreated, created)
+}
+
+// updateUser updates a user to match the SCIM user resource
+func updateUser(ctx *context.APIContext, u *models.User, user *scim.User) error {
+	name := userNameToLogin(user.UserName)
+	if name == "" {
+		return scim.NewBadRequestError(scim.ErrorTypeInvalidValue, "userName is required")
+	}
+	if u.ID == ctx.User.ID && !user.IsActive() {
+		return scim.NewBadRequestError(scim.ErrorTypeMutability, "the owner of the token can not be deactivated")
+	}
+	if err := checkExternalIDAvailable(models.SCIMResourceTypeUser, u.ID, user.ExternalID); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(name, u.Name) {
+		if err := models.ChangeUserName(u, name); err != nil {
+			return translateUserError(err)
+		}
+		log.Trace("Account renamed through SCIM by %s: %s -> %s", ctx.User.Name, u.Name, name)
+	}
+	u.Name = name
+	u.LowerName = strings.ToLower(name)
+
+	u.FullName = user.FullName()
+	if email := user.PrimaryEmail(); email != "" {
+		u.Email = email
+	}
+
+	if user.IsActive() {
+		u.IsActive = true
+		u.ProhibitLogin = false
+	} else {
+		u.ProhibitLogin = true
+	}
+
+	if user.Password != "" {
+		if err := checkPassword(ctx, user.Password); err != nil {
+			return err
+		}
+		if err := u.SetPassword(user.Password); err != nil {
+			return err
+		}
+	}
+
+	if err := models.UpdateUserSetting(u); err != nil {
+		return translateUserError(err)
+	}
+	if err := models.SetSCIMExternalID(models.SCIMResourceTypeUser, u.ID, user.ExternalID); err != nil {
+		return translateUserError(err)
+	}
+	log.Trace("Account updated through SCIM by %s: %s", ctx.User.Name, u.Name)
+	return nil
+}
+
+// writeUser writes the SCIM user resource of a user
+func writeUser(ctx *context.APIContext, u *models.User) {
+	user, err := toSCIMUser(u)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeResponse(ctx, http.StatusOK, user)
+}
+
+// ReplaceUser replaces the attributes of a user
+func ReplaceUser(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeUser)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	u, err := getUserByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	user := &scim.User{}
+	if err := decodeBody(ctx, user); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := updateUser(ctx, u, user); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeUser(ctx, u)
+}
+
+// PatchUser modifies some attributes of a user, this is how identity providers deactivate users
+func PatchUser(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeUser)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	u, err := getUserByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	req := &scim.PatchRequest{}
+	if err := decodeBody(ctx, req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	user, err := toSCIMUser(u)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := scim.ApplyUserPatch(user, req.Operations); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := updateUser(ctx, u, user); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeUser(ctx, u)
+}
+
+// DeleteUser deletes a user, which fails if the user still owns repositories or belongs to organizations.
+// Identity providers usually deactivate users instead.
+func DeleteUser(ctx *context.APIContext) {
+	id, err := parseResourceID(ctx, scim.ResourceTypeUser)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	u, err := getUserByID(id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if u.ID == ctx.User.ID {
+		writeError(ctx, scim.NewBadRequestError(scim.ErrorTypeMutability, "the owner of the token can not be deleted"))
+		return
+	}
+
+	if err := models.DeleteUser(u); err != nil {
+		if models.IsErrUserOwnRepos(err) || models.IsErrUserHasOrgs(err) {
+			writeError(ctx, scim.NewError(http.StatusConflict, scim.ErrorTypeMutability, err.Error()))
+		} else {
+			writeError(ctx, err)
+		}
+		return
+	}
+	log.Trace("Account deleted through SCIM by %s: %s", ctx.User.Name, u.Name)
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -65,6 +65,7 @@
 package v1
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
@@ -98,7 +99,7 @@ func sudo() func(ctx *context.APIContext) {
 		}
 
 		if len(sudo) > 0 {
-			if ctx.IsSigned && ctx.User.IsAdmin {
+			if ctx.IsSigned && ctx.User.IsAdmin && hasTokenScope(ctx, models.AccessTokenScopeCategoryAdmin) {
 				user, err := models.GetUserByName(sudo)
 				if err != nil {
 					if models.IsErrUserNotExist(err) {
@@ -183,6 +184,43 @@ func repoAssignment() func(ctx *context.APIContext) {
 			ctx.NotFound()
 			return
 		}
+
+		if token := ctx.AccessToken(); token != nil && !token.CanAccessRepo(repo) {
+			ctx.Error(http.StatusForbidden, "repoAssignment", "token is restricted to another repository or organization")
+			return
+		}
+	}
+}
+
+// hasTokenScope returns if the request is not authenticated with a personal access token or if the token
+// has the scope of the category needed by the request method
+func hasTokenScope(ctx *context.APIContext, category models.AccessTokenScopeCategory) bool {
+	token := ctx.AccessToken()
+	if token == nil {
+		return true
+	}
+	write := ctx.Req.Method != http.MethodGet && ctx.Req.Method != http.MethodHead
+	return token.Scope.HasScope(category, write)
+}
+
+// reqTokenScope requests authenticated with a personal access token require the read scope
+// of the category, or its write scope for requests which can modify resources
+func reqTokenScope(category models.AccessTokenScopeCategory) func(ctx *context.APIContext) {
+	return func(ctx *context.APIContext) {
+		if !hasTokenScope(ctx, category) {
+			ctx.Error(http.StatusForbidden, "reqTokenScope", fmt.Sprintf("token does not have the required %s scope", category))
+			return
+		}
+	}
+}
+
+// reqTokenFullAccess requests authenticated with a personal access token require a token with full access
+func reqTokenFullAccess() func(ctx *context.APIContext) {
+	return func(ctx *context.APIContext) {
+		if token := ctx.AccessToken(); token != nil && (token.Scope != models.AccessTokenScopeAll || token.IsRestricted()) {
+			ctx.Error(http.StatusForbidden, "reqTokenFullAccess", "token must have full access to the account")
+			return
+		}
 	}
 }
 
@@ -438,6 +476,14 @@ func orgAssignment(args ...bool) func(ctx *context.APIContext) {
 				return
 			}
 		}
+
+		if token := ctx.AccessToken(); token != nil {
+			if ctx.Org.Organization != nil && !token.CanAccessOrg(ctx.Org.Organization.ID) ||
+				ctx.Org.Team != nil && !token.CanAccessOrg(ctx.Org.Team.OrgID) {
+				ctx.Error(http.StatusForbidden, "orgAssignment", "token is restricted to another repository or organization")
+				return
+			}
+		}
 	}
 }
 
@@ -599,7 +645,7 @@ func Routes() *web.Route {
 			m.Combo("/threads/{id}").
 				Get(notify.GetThread).
 				Patch(notify.ReadThread)
-		}, reqToken())
+		}, reqToken(), reqTokenScope(models.AccessTokenScopeCategoryUser))
 
 		// Users
 		m.Group("/users", func() {
@@ -617,9 +663,9 @@ func Routes() *web.Route {
 					m.Combo("").Get(user.ListAccessTokens).
 						Post(bind(api.CreateAccessTokenOption{}), user.CreateAccessToken)
 					m.Combo("/{id}").Delete(user.DeleteAccessToken)
-				}, reqBasicAuth())
+				}, reqBasicAuth(), reqTokenFullAccess())
 			})
-		})
+		}, reqTokenScope(models.AccessTokenScopeCategoryUser))
 
 		m.Group("/users", func() {
 			m.Group("/{username}", func() {
@@ -636,11 +682,11 @@ func Routes() *web.Route {
 
 				m.Get("/subscriptions", user.GetWatchedRepos)
 			})
-		}, reqToken())
+		}, reqToken(), reqTokenScope(models.AccessTokenScopeCategoryUser))
 
 		m.Group("/user", func() {
 			m.Get("", user.GetAuthenticatedUser)
-			m.Combo("/emails").Get(user.ListEmails).
+			m.Combo("/emails", reqTokenFullAccess()).Get(user.ListEmails).
 				Post(bind(api.CreateEmailOption{}), user.AddEmail).
 				Delete(bind(api.DeleteEmailOption{}), user.DeleteEmail)
 
@@ -655,7 +701,7 @@ func Routes() *web.Route {
 					Post(bind(api.CreateKeyOption{}), user.CreatePublicKey)
 				m.Combo("/{id}").Get(user.GetPublicKey).
 					Delete(user.DeletePublicKey)
-			})
+			}, reqTokenFullAccess())
 			m.Group("/applications", func() {
 				m.Combo("/oauth2").
 					Get(user.ListOauth2Applications).
@@ -664,14 +710,14 @@ func Routes() *web.Route {
 					Delete(user.DeleteOauth2Application).
 					Patch(bind(api.CreateOAuth2ApplicationOptions{}), user.UpdateOauth2Application).
 					Get(user.GetOauth2Application)
-			}, reqToken())
+			}, reqToken(), reqTokenFullAccess())
 
 			m.Group("/gpg_keys", func() {
 				m.Combo("").Get(user.ListMyGPGKeys).
 					Post(bind(api.CreateGPGKeyOption{}), user.CreateGPGKey)
 				m.Combo("/{id}").Get(user.GetGPGKey).
 					Delete(user.DeleteGPGKey)
-			})
+			}, reqTokenFullAccess())
 
 			m.Combo("/repos").Get(user.ListMyRepos).
 				Post(bind(api.CreateRepoOption{}), repo.Create)
@@ -691,19 +737,19 @@ func Routes() *web.Route {
 			m.Get("/subscriptions", user.GetMyWatchedRepos)
 
 			m.Get("/teams", org.ListUserTeams)
-		}, reqToken())
+		}, reqToken(), reqTokenScope(models.AccessTokenScopeCategoryUser))
 
 		// Repositories
-		m.Post("/org/{org}/repos", reqToken(), bind(api.CreateRepoOption{}), repo.CreateOrgRepoDeprecated)
+		m.Post("/org/{org}/repos", reqToken(), reqTokenScope(models.AccessTokenScopeCategoryRepo), bind(api.CreateRepoOption{}), repo.CreateOrgRepoDeprecated)
 
-		m.Combo("/repositories/{id}", reqToken()).Get(repo.GetByID)
+		m.Combo("/repositories/{id}", reqToken(), reqTokenScope(models.AccessTokenScopeCategoryRepo)).Get(repo.GetByID)
 
 		m.Group("/repos", func() {
-			m.Get("/search", repo.Search)
+			m.Get("/search", reqTokenScope(models.AccessTokenScopeCategoryRepo), repo.Search)
 
-			m.Get("/issues/search", repo.SearchIssues)
+			m.Get("/issues/search", reqTokenScope(models.AccessTokenScopeCategoryIssue), repo.SearchIssues)
 
-			m.Post("/migrate", reqToken(), bind(api.MigrateRepoOptions{}), repo.Migrate)
+			m.Post("/migrate", reqToken(), reqTokenScope(models.AccessTokenScopeCategoryRepo), bind(api.MigrateRepoOptions{}), repo.Migrate)
 
 			m.Group("/{username}/{reponame}", func() {
 				m.Combo("").Get(reqAnyRepoReader(), repo.Get).
@@ -753,6 +799,7 @@ func Routes() *web.Route {
 					m.Delete("/*", context.ReferencesGitRepo(false), reqRepoWriter(models.UnitTypeCode), repo.DeleteBranch)
 					m.Post("", reqRepoWriter(models.UnitTypeCode), bind(api.CreateBranchRepoOption{}), repo.CreateBranch)
 				}, reqRepoReader(models.UnitTypeCode))
+				m.Get("/merge_queues/*", reqRepoReader(models.UnitTypePullRequests), repo.ListMergeQueue)
 				m.Group("/branch_protections", func() {
 					m.Get("", repo.ListBranchProtections)
 					m.Post("", bind(api.CreateBranchProtectionOption{}), repo.CreateBranchProtection)
@@ -772,6 +819,97 @@ func Routes() *web.Route {
 					m.Combo("/{id}").Get(repo.GetDeployKey).
 						Delete(repo.DeleteDeploykey)
 				}, reqToken(), reqAdmin())
+				m.Post("/markdown", bind(api.MarkdownOption{}), misc.Markdown)
+				m.Post("/markdown/raw", misc.MarkdownRaw)
+				m.Get("/stargazers", repo.ListStargazers)
+				m.Get("/subscribers", repo.ListSubscribers)
+				m.Group("/subscription", func() {
+					m.Get("", user.IsWatching)
+					m.Put("", reqToken(), user.Watch)
+					m.Delete("", reqToken(), user.Unwatch)
+				})
+				m.Post("/mirror-sync", reqToken(), reqRepoWriter(models.UnitTypeCode), repo.MirrorSync)
+				m.Get("/editorconfig/{filename}", context.RepoRefForAPI, reqRepoReader(models.UnitTypeCode), repo.GetEditorconfig)
+				m.Group("/pulls", func() {
+					m.Combo("").Get(repo.ListPullRequests).
+						Post(reqToken(), mustNotBeArchived, bind(api.CreatePullRequestOption{}), repo.CreatePullRequest)
+					m.Group("/{index}", func() {
+						m.Combo("").Get(repo.GetPullRequest).
+							Patch(reqToken(), reqRepoWriter(models.UnitTypePullRequests), bind(api.EditPullRequestOption{}), repo.EditPullRequest)
+						m.Get(".diff", repo.DownloadPullDiff)
+						m.Get(".patch", repo.DownloadPullPatch)
+						m.Post("/update", reqToken(), repo.UpdatePullRequest)
+						m.Combo("/merge").Get(repo.IsPullRequestMerged).
+							Post(reqToken(), mustNotBeArchived, bind(auth.MergePullRequestForm{}), repo.MergePullRequest)
+						m.Delete("/merge_queue", reqToken(), mustNotBeArchived, repo.RemovePullFromMergeQueue)
+						m.Group("/reviews", func() {
+							m.Combo("").
+								Get(repo.ListPullReviews).
+								Post(reqToken(), bind(api.CreatePullReviewOptions{}), repo.CreatePullReview)
+							m.Group("/{id}", func() {
+								m.Combo("").
+									Get(repo.GetPullReview).
+									Delete(reqToken(), repo.DeletePullReview).
+									Post(reqToken(), bind(api.SubmitPullReviewOptions{}), repo.SubmitPullReview)
+								m.Combo("/comments").
+									Get(repo.GetPullReviewComments)
+								m.Post("/dismissals", reqToken(), bind(api.DismissPullReviewOptions{}), repo.DismissPullReview)
+								m.Post("/undismissals", reqToken(), repo.UnDismissPullReview)
+							})
+						})
+						m.Combo("/requested_reviewers").
+							Delete(reqToken(), bind(api.PullReviewRequestOptions{}), repo.DeleteReviewRequests).
+							Post(reqToken(), bind(api.PullReviewRequestOptions{}), repo.CreateReviewRequests)
+						m.Combo("/viewed_files").
+							Get(reqToken(), repo.ListPullViewedFiles).
+							Delete(reqToken(), bind(api.PullViewedFilesOptions{}), repo.UnmarkPullFilesViewed).
+							Post(reqToken(), bind(api.PullViewedFilesOptions{}), repo.MarkPullFilesViewed)
+					})
+				}, mustAllowPulls, reqRepoReader(models.UnitTypeCode), context.ReferencesGitRepo(false))
+				m.Group("/statuses", func() {
+					m.Combo("/{sha}").Get(repo.GetCommitStatuses).
+						Post(reqToken(), bind(api.CreateStatusOption{}), repo.NewCommitStatus)
+				}, reqRepoReader(models.UnitTypeCode))
+				m.Group("/commits", func() {
+					m.Get("", repo.GetAllCommits)
+					m.Group("/{ref}", func() {
+						m.Get("/status", repo.GetCombinedCommitStatusByRef)
+						m.Get("/statuses", repo.GetCommitStatusesByRef)
+					})
+				}, reqRepoReader(models.UnitTypeCode))
+				m.Group("/git", func() {
+					m.Group("/commits", func() {
+						m.Get("/{sha}", repo.GetSingleCommit)
+					})
+					m.Get("/refs", repo.GetGitAllRefs)
+					m.Get("/refs/*", repo.GetGitRefs)
+					m.Get("/trees/{sha}", context.RepoRefForAPI, repo.GetTree)
+					m.Get("/blobs/{sha}", context.RepoRefForAPI, repo.GetBlob)
+					m.Get("/tags/{sha}", context.RepoRefForAPI, repo.GetTag)
+				}, reqRepoReader(models.UnitTypeCode))
+				m.Group("/contents", func() {
+					m.Get("", repo.GetContentsList)
+					m.Get("/*", repo.GetContents)
+					m.Group("/*", func() {
+						m.Post("", bind(api.CreateFileOptions{}), repo.CreateFile)
+						m.Put("", bind(api.UpdateFileOptions{}), repo.UpdateFile)
+						m.Delete("", bind(api.DeleteFileOptions{}), repo.DeleteFile)
+					}, reqRepoWriter(models.UnitTypeCode), reqToken())
+				}, reqRepoReader(models.UnitTypeCode))
+				m.Get("/signing-key.gpg", misc.SigningKey)
+				m.Group("/topics", func() {
+					m.Combo("").Get(repo.ListTopics).
+						Put(reqToken(), reqAdmin(), bind(api.RepoTopicOptions{}), repo.UpdateTopics)
+					m.Group("/{topic}", func() {
+						m.Combo("").Put(reqToken(), repo.AddTopic).
+							Delete(reqToken(), repo.DeleteTopic)
+					}, reqAdmin())
+				}, reqAnyRepoReader())
+				m.Get("/issue_templates", context.ReferencesGitRepo(false), repo.GetIssueTemplates)
+				m.Get("/languages", reqRepoReader(models.UnitTypeCode), repo.GetLanguages)
+			}, repoAssignment(), reqTokenScope(models.AccessTokenScopeCategoryRepo))
+
+			m.Group("/{username}/{reponame}", func() {
 				m.Group("/times", func() {
 					m.Combo("").Get(repo.ListTrackedTimesByRepository)
 					m.Combo("/{timetrackingusername}").Get(repo.ListTrackedTimesByUser)
@@ -840,8 +978,6 @@ func Routes() *web.Route {
 						Patch(reqToken(), reqRepoWriter(models.UnitTypeIssues, models.UnitTypePullRequests), bind(api.EditLabelOption{}), repo.EditLabel).
 						Delete(reqToken(), reqRepoWriter(models.UnitTypeIssues, models.UnitTypePullRequests), repo.DeleteLabel)
 				})
-				m.Post("/markdown", bind(api.MarkdownOption{}), misc.Markdown)
-				m.Post("/markdown/raw", misc.MarkdownRaw)
 				m.Group("/milestones", func() {
 					m.Combo("").Get(repo.ListMilestones).
 						Post(reqToken(), reqRepoWriter(models.UnitTypeIssues, models.UnitTypePullRequests), bind(api.CreateMilestoneOption{}), repo.CreateMilestone)
@@ -849,13 +985,9 @@ func Routes() *web.Route {
 						Patch(reqToken(), reqRepoWriter(models.UnitTypeIssues, models.UnitTypePullRequests), bind(api.EditMilestoneOption{}), repo.EditMilestone).
 						Delete(reqToken(), reqRepoWriter(models.UnitTypeIssues, models.UnitTypePullRequests), repo.DeleteMilestone)
 				})
-				m.Get("/stargazers", repo.ListStargazers)
-				m.Get("/subscribers", repo.ListSubscribers)
-				m.Group("/subscription", func() {
-					m.Get("", user.IsWatching)
-					m.Put("", reqToken(), user.Watch)
-					m.Delete("", reqToken(), user.Unwatch)
-				})
+			}, repoAssignment(), reqTokenScope(models.AccessTokenScopeCategoryIssue))
+
+			m.Group("/{username}/{reponame}", func() {
 				m.Group("/releases", func() {
 					m.Combo("").Get(repo.ListReleases).
 						Post(reqToken(), reqRepoWriter(models.UnitTypeReleases), context.ReferencesGitRepo(false), bind(api.CreateReleaseOption{}), repo.CreateRelease)
@@ -877,88 +1009,14 @@ func Routes() *web.Route {
 							Delete(reqToken(), reqRepoWriter(models.UnitTypeReleases), repo.DeleteReleaseByTag)
 					})
 				}, reqRepoReader(models.UnitTypeReleases))
-				m.Post("/mirror-sync", reqToken(), reqRepoWriter(models.UnitTypeCode), repo.MirrorSync)
-				m.Get("/editorconfig/{filename}", context.RepoRefForAPI, reqRepoReader(models.UnitTypeCode), repo.GetEditorconfig)
-				m.Group("/pulls", func() {
-					m.Combo("").Get(repo.ListPullRequests).
-						Post(reqToken(), mustNotBeArchived, bind(api.CreatePullRequestOption{}), repo.CreatePullRequest)
-					m.Group("/{index}", func() {
-						m.Combo("").Get(repo.GetPullRequest).
-							Patch(reqToken(), reqRepoWriter(models.UnitTypePullRequests), bind(api.EditPullRequestOption{}), repo.EditPullRequest)
-						m.Get(".diff", repo.DownloadPullDiff)
-						m.Get(".patch", repo.DownloadPullPatch)
-						m.Post("/update", reqToken(), repo.UpdatePullRequest)
-						m.Combo("/merge").Get(repo.IsPullRequestMerged).
-							Post(reqToken(), mustNotBeArchived, bind(auth.MergePullRequestForm{}), repo.MergePullRequest)
-						m.Group("/reviews", func() {
-							m.Combo("").
-								Get(repo.ListPullReviews).
-								Post(reqToken(), bind(api.CreatePullReviewOptions{}), repo.CreatePullReview)
-							m.Group("/{id}", func() {
-								m.Combo("").
-									Get(repo.GetPullReview).
-									Delete(reqToken(), repo.DeletePullReview).
-									Post(reqToken(), bind(api.SubmitPullReviewOptions{}), repo.SubmitPullReview)
-								m.Combo("/comments").
-									Get(repo.GetPullReviewComments)
-								m.Post("/dismissals", reqToken(), bind(api.DismissPullReviewOptions{}), repo.DismissPullReview)
-								m.Post("/undismissals", reqToken(), repo.UnDismissPullReview)
-							})
-						})
-						m.Combo("/requested_reviewers").
-							Delete(reqToken(), bind(api.PullReviewRequestOptions{}), repo.DeleteReviewRequests).
-							Post(reqToken(), bind(api.PullReviewRequestOptions{}), repo.CreateReviewRequests)
-					})
-				}, mustAllowPulls, reqRepoReader(models.UnitTypeCode), context.ReferencesGitRepo(false))
-				m.Group("/statuses", func() {
-					m.Combo("/{sha}").Get(repo.GetCommitStatuses).
-						Post(reqToken(), bind(api.CreateStatusOption{}), repo.NewCommitStatus)
-				}, reqRepoReader(models.UnitTypeCode))
-				m.Group("/commits", func() {
-					m.Get("", repo.GetAllCommits)
-					m.Group("/{ref}", func() {
-						m.Get("/status", repo.GetCombinedCommitStatusByRef)
-						m.Get("/statuses", repo.GetCommitStatusesByRef)
-					})
-				}, reqRepoReader(models.UnitTypeCode))
-				m.Group("/git", func() {
-					m.Group("/commits", func() {
-						m.Get("/{sha}", repo.GetSingleCommit)
-					})
-					m.Get("/refs", repo.GetGitAllRefs)
-					m.Get("/refs/*", repo.GetGitRefs)
-					m.Get("/trees/{sha}", context.RepoRefForAPI, repo.GetTree)
-					m.Get("/blobs/{sha}", context.RepoRefForAPI, repo.GetBlob)
-					m.Get("/tags/{sha}", context.RepoRefForAPI, repo.GetTag)
-				}, reqRepoReader(models.UnitTypeCode))
-				m.Group("/contents", func() {
-					m.Get("", repo.GetContentsList)
-					m.Get("/*", repo.GetContents)
-					m.Group("/*", func() {
-						m.Post("", bind(api.CreateFileOptions{}), repo.CreateFile)
-						m.Put("", bind(api.UpdateFileOptions{}), repo.UpdateFile)
-						m.Delete("", bind(api.DeleteFileOptions{}), repo.DeleteFile)
-					}, reqRepoWriter(models.UnitTypeCode), reqToken())
-				}, reqRepoReader(models.UnitTypeCode))
-				m.Get("/signing-key.gpg", misc.SigningKey)
-				m.Group("/topics", func() {
-					m.Combo("").Get(repo.ListTopics).
-						Put(reqToken(), reqAdmin(), bind(api.RepoTopicOptions{}), repo.UpdateTopics)
-					m.Group("/{topic}", func() {
-						m.Combo("").Put(reqToken(), repo.AddTopic).
-							Delete(reqToken(), repo.DeleteTopic)
-					}, reqAdmin())
-				}, reqAnyRepoReader())
-				m.Get("/issue_templates", context.ReferencesGitRepo(false), repo.GetIssueTemplates)
-				m.Get("/languages", reqRepoReader(models.UnitTypeCode), repo.GetLanguages)
-			}, repoAssignment())
+			}, repoAssignment(), reqTokenScope(models.AccessTokenScopeCategoryPackage))
 		})
 
 		// Organizations
-		m.Get("/user/orgs", reqToken(), org.ListMyOrgs)
-		m.Get("/users/{username}/orgs", org.ListUserOrgs)
-		m.Post("/orgs", reqToken(), bind(api.CreateOrgOption{}), org.Create)
-		m.Get("/orgs", org.GetAll)
+		m.Get("/user/orgs", reqToken(), reqTokenScope(models.AccessTokenScopeCategoryOrg), org.ListMyOrgs)
+		m.Get("/users/{username}/orgs", reqTokenScope(models.AccessTokenScopeCategoryOrg), org.ListUserOrgs)
+		m.Post("/orgs", reqToken(), reqTokenScope(models.AccessTokenScopeCategoryOrg), bind(api.CreateOrgOption{}), org.Create)
+		m.Get("/orgs", reqTokenScope(models.AccessTokenScopeCategoryOrg), org.GetAll)
 		m.Group("/orgs/{org}", func() {
 			m.Combo("").Get(org.Get).
 				Patch(reqToken(), reqOrgOwnership(), bind(api.EditOrgOption{}), org.Edit).
@@ -995,7 +1053,7 @@ func Routes() *web.Route {
 					Patch(bind(api.EditHookOption{}), org.EditHook).
 					Delete(org.DeleteHook)
 			}, reqToken(), reqOrgOwnership(), reqWebhooksEnabled())
-		}, orgAssignment(true))
+		}, orgAssignment(true), reqTokenScope(models.AccessTokenScopeCategoryOrg))
 		m.Group("/teams/{teamid}", func() {
 			m.Combo("").Get(org.GetTeam).
 				Patch(reqOrgOwnership(), bind(api.EditTeamOption{}), org.EditTeam).
@@ -1013,7 +1071,7 @@ func Routes() *web.Route {
 					Put(org.AddTeamRepository).
 					Delete(org.RemoveTeamRepository)
 			})
-		}, orgAssignment(false, true), reqToken(), reqTeamMembership())
+		}, orgAssignment(false, true), reqToken(), reqTokenScope(models.AccessTokenScopeCategoryOrg), reqTeamMembership())
 
 		m.Group("/admin", func() {
 			m.Group("/cron", func() {
@@ -1041,11 +1099,11 @@ func Routes() *web.Route {
 				m.Post("/{username}/{reponame}", admin.AdoptRepository)
 				m.Delete("/{username}/{reponame}", admin.DeleteUnadoptedRepository)
 			})
-		}, reqToken(), reqSiteAdmin())
+		}, reqToken(), reqTokenScope(models.AccessTokenScopeCategoryAdmin), reqSiteAdmin())
 
 		m.Group("/topics", func() {
 			m.Get("/search", repo.TopicSearch)
-		})
+		}, reqTokenScope(models.AccessTokenScopeCategoryRepo))
 	}, sudo())
 
 	return m
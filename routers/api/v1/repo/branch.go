@@ -542,6 +542,7 @@ func CreateBranchProtection(ctx *context.APIContext) {
 		RequireSignedCommits:          form.RequireSignedCommits,
 		ProtectedFilePatterns:         form.ProtectedFilePatterns,
 		BlockOnOutdatedBranch:         form.BlockOnOutdatedBranch,
+		EnableMergeQueue:              form.EnableMergeQueue,
 	}
 
 	err = models.UpdateProtectBranch(ctx.Repo.Repository, protectBranch, models.WhitelistOptions{
@@ -690,6 +691,10 @@ func EditBranchProtection(ctx *context.APIContext) {
 		protectBranch.BlockOnOutdatedBranch = *form.BlockOnOutdatedBranch
 	}
 
+	if form.EnableMergeQueue != nil {
+		protectBranch.EnableMergeQueue = *form.EnableMergeQueue
+	}
+
 	var whitelistUsers []int64
 	if form.PushWhitelistUsernames != nil {
 		whitelistUsers, err = models.GetUserIDsByNames(form.PushWhitelistUsernames, false)
@@ -853,6 +858,8 @@ func DeleteBranchProtection(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "DeleteProtectedBranch", err)
 		return
 	}
+	// the pull requests in the merge queue of the branch have to be removed
+	pull_service.AddToMergeQueueCheck(ctx.Repo.Repository.ID, bp.BranchName)
 
 	ctx.Status(http.StatusNoContent)
 }
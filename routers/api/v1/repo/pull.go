@@ -195,6 +195,10 @@ func DownloadPullDiff(ctx *context.APIContext) {
 	//   type: integer
 	//   format: int64
 	//   required: true
+	// - name: word_diff
+	//   in: query
+	//   description: mark the changed words instead of lines like git diff --word-diff
+	//   type: boolean
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/string"
@@ -247,7 +251,7 @@ func DownloadPullDiffOrPatch(ctx *context.APIContext, patch bool) {
 		return
 	}
 
-	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch); err != nil {
+	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch, ctx.QueryBool("word_diff")); err != nil {
 		ctx.InternalServerError(err)
 		return
 	}
@@ -602,6 +606,14 @@ func EditPullRequest(ctx *context.APIContext) {
 		notification.NotifyIssueChangeStatus(ctx.User, issue, statusChangeComment, issue.IsClosed)
 	}
 
+	// only the poster of a pull request may allow edits by maintainers
+	if form.AllowMaintainerEdit != nil && issue.IsPoster(ctx.User.ID) {
+		if err := pr.SetAllowMaintainerEdit(*form.AllowMaintainerEdit); err != nil {
+			ctx.Error(http.StatusInternalServerError, "SetAllowMaintainerEdit", err)
+			return
+		}
+	}
+
 	// change pull target branch
 	if len(form.Base) != 0 && form.Base != pr.BaseBranch {
 		if !ctx.Repo.GitRepo.IsBranchExist(form.Base) {
@@ -718,6 +730,8 @@ func MergePullRequest(ctx *context.APIContext) {
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/empty"
+	//   "202":
+	//     description: the pull request was added to the merge queue of its base branch
 	//   "405":
 	//     "$ref": "#/responses/empty"
 	//   "409":
@@ -848,6 +862,27 @@ func MergePullRequest(ctx *context.APIContext) {
 		message += "\n\n" + form.MergeMessageField
 	}
 
+	if mergeQueueEnabled, err := pull_service.IsMergeQueueEnabled(pr); err != nil {
+		ctx.Error(http.StatusInternalServerError, "IsMergeQueueEnabled", err)
+		return
+	} else if mergeQueueEnabled {
+		if err := pull_service.AddToMergeQueue(pr, ctx.User, models.MergeStyle(form.Do), message); err != nil {
+			if models.IsErrInvalidMergeStyle(err) {
+				ctx.Error(http.StatusMethodNotAllowed, "Invalid merge style", fmt.Errorf("%s is not allowed an allowed merge style for this repository", models.MergeStyle(form.Do)))
+				return
+			} else if models.IsErrPullAlreadyInMergeQueue(err) {
+				ctx.Error(http.StatusConflict, "AddToMergeQueue", err)
+				return
+			}
+			ctx.Error(http.StatusInternalServerError, "AddToMergeQueue", err)
+			return
+		}
+
+		log.Trace("Pull request added to the merge queue: %d", pr.ID)
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+
 	if err := pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
 		if models.IsErrInvalidMergeStyle(err) {
 			ctx.Error(http.StatusMethodNotAllowed, "Invalid merge style", fmt.Errorf("%s is not allowed an allowed merge style for this repository", models.MergeStyle(form.Do)))
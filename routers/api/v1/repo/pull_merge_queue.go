@@ -0,0 +1,136 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/convert"
+	api "code.gitea.io/gitea/modules/structs"
+	pull_service "code.gitea.io/gitea/services/pull"
+)
+
+// ListMergeQueue lists the pull requests in the merge queue of a branch
+func ListMergeQueue(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/merge_queues/{branch} repository repoListMergeQueue
+	// ---
+	// summary: List the pull requests in the merge queue of a branch
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: branch
+	//   in: path
+	//   description: base branch of the merge queue
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullMergeQueueEntryList"
+
+	entries, err := models.GetMergeQueue(ctx.Repo.Repository.ID, ctx.Params("*"))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetMergeQueue", err)
+		return
+	}
+
+	apiEntries := make([]*api.PullMergeQueueEntry, 0, len(entries))
+	for i, entry := range entries {
+		apiEntry, err := convert.ToPullMergeQueueEntry(entry, i+1, ctx.User)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "ToPullMergeQueueEntry", err)
+			return
+		}
+		apiEntries = append(apiEntries, apiEntry)
+	}
+
+	ctx.JSON(http.StatusOK, &apiEntries)
+}
+
+// RemovePullFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemovePullFromMergeQueue(ctx *context.APIContext) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/pulls/{index}/merge_queue repository repoRemovePullFromMergeQueue
+	// ---
+	// summary: Remove a pull request from the merge queue of its base branch
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	if _, err := models.GetMergeQueueEntryByPullID(pr.ID); err != nil {
+		if models.IsErrMergeQueueEntryNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetMergeQueueEntryByPullID", err)
+		}
+		return
+	}
+
+	if err := pr.LoadIssue(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadIssue", err)
+		return
+	}
+	if pr.Issue.PosterID != ctx.User.ID {
+		allowedMerge, err := pull_service.IsUserAllowedToMerge(pr, ctx.Repo.Permission, ctx.User)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "IsUserAllowedToMerge", err)
+			return
+		}
+		if !allowedMerge {
+			ctx.Error(http.StatusForbidden, "RemoveFromMergeQueue", "User not allowed to remove the pull request from the merge queue")
+			return
+		}
+	}
+
+	if err := pull_service.RemoveFromMergeQueue(pr, ctx.User); err != nil {
+		ctx.Error(http.StatusInternalServerError, "RemoveFromMergeQueue", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
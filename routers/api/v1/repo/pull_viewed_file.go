@@ -0,0 +1,196 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"net/http"
+	"sort"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/services/gitdiff"
+	pull_service "code.gitea.io/gitea/services/pull"
+)
+
+// ListPullViewedFiles lists the files of a pull request the authenticated user has marked as viewed
+func ListPullViewedFiles(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/viewed_files repository repoListPullViewedFiles
+	// ---
+	// summary: List the files of a pull request the authenticated user has marked as viewed
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullViewedFileList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr := getPullRequestForViewedFiles(ctx)
+	if ctx.Written() {
+		return
+	}
+	listPullViewedFiles(ctx, pr, http.StatusOK)
+}
+
+// MarkPullFilesViewed marks files of a pull request as viewed
+func MarkPullFilesViewed(ctx *context.APIContext) {
+	// swagger:operation POST /repos/{owner}/{repo}/pulls/{index}/viewed_files repository repoMarkPullFilesViewed
+	// ---
+	// summary: Mark files of a pull request as viewed in their versions at the head of the pull request
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/PullViewedFilesOptions"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/PullViewedFileList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.PullViewedFilesOptions)
+	pr := getPullRequestForViewedFiles(ctx)
+	if ctx.Written() {
+		return
+	}
+	if err := pull_service.MarkFilesViewed(ctx.Repo.GitRepo, pr, ctx.User, opts.Files); err != nil {
+		ctx.Error(http.StatusInternalServerError, "MarkFilesViewed", err)
+		return
+	}
+	listPullViewedFiles(ctx, pr, http.StatusCreated)
+}
+
+// UnmarkPullFilesViewed removes the viewed marks of files of a pull request
+func UnmarkPullFilesViewed(ctx *context.APIContext) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/pulls/{index}/viewed_files repository repoUnmarkPullFilesViewed
+	// ---
+	// summary: Mark files of a pull request as not viewed
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/PullViewedFilesOptions"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.PullViewedFilesOptions)
+	pr := getPullRequestForViewedFiles(ctx)
+	if ctx.Written() {
+		return
+	}
+	if err := models.UnsetPullFilesViewed(ctx.User.ID, pr.ID, opts.Files); err != nil {
+		ctx.Error(http.StatusInternalServerError, "UnsetPullFilesViewed", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func getPullRequestForViewedFiles(ctx *context.APIContext) *models.PullRequest {
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound("GetPullRequestByIndex", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return nil
+	}
+	return pr
+}
+
+func listPullViewedFiles(ctx *context.APIContext, pr *models.PullRequest, status int) {
+	viewed, err := models.GetPullViewedFiles(ctx.User.ID, pr.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetPullViewedFiles", err)
+		return
+	}
+	headCommit, err := ctx.Repo.GitRepo.GetCommit(pr.GetGitRefName())
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetCommit", err)
+		return
+	}
+
+	files := make([]*api.PullViewedFile, 0, len(viewed))
+	for treePath, blobSHA := range viewed {
+		headBlobSHA, err := gitdiff.GetBlobSHA(headCommit, treePath)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "GetBlobSHA", err)
+			return
+		}
+		files = append(files, &api.PullViewedFile{
+			Path:     treePath,
+			BlobSHA:  blobSHA,
+			Outdated: headBlobSHA != blobSHA,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	ctx.JSON(status, files)
+}
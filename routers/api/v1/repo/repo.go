@@ -454,6 +454,10 @@ func GetByID(ctx *context.APIContext) {
 		ctx.NotFound()
 		return
 	}
+	if token := ctx.AccessToken(); token != nil && !token.CanAccessRepo(repo) {
+		ctx.NotFound()
+		return
+	}
 	ctx.JSON(http.StatusOK, convert.ToRepo(repo, perm.AccessMode))
 }
 
@@ -18,6 +18,9 @@ type swaggerParameterBodies struct {
 	// in:body
 	AddCollaboratorOption api.AddCollaboratorOption
 
+	// in:body
+	CreateAccessTokenOption api.CreateAccessTokenOption
+
 	// in:body
 	CreateEmailOption api.CreateEmailOption
 	// in:body
@@ -158,4 +161,7 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	PullReviewRequestOptions api.PullReviewRequestOptions
+
+	// in:body
+	PullViewedFilesOptions api.PullViewedFilesOptions
 }
@@ -155,6 +155,20 @@ type swaggerResponsePullReviewList struct {
 	Body []api.PullReview `json:"body"`
 }
 
+// PullMergeQueueEntryList
+// swagger:response PullMergeQueueEntryList
+type swaggerResponsePullMergeQueueEntryList struct {
+	// in:body
+	Body []api.PullMergeQueueEntry `json:"body"`
+}
+
+// PullViewedFileList
+// swagger:response PullViewedFileList
+type swaggerResponsePullViewedFileList struct {
+	// in:body
+	Body []api.PullViewedFile `json:"body"`
+}
+
 // PullComment
 // swagger:response PullReviewComment
 type swaggerPullReviewComment struct {
@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/convert"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
 )
@@ -52,10 +55,9 @@ func ListAccessTokens(ctx *context.APIContext) {
 
 	apiTokens := make([]*api.AccessToken, len(tokens))
 	for i := range tokens {
-		apiTokens[i] = &api.AccessToken{
-			ID:             tokens[i].ID,
-			Name:           tokens[i].Name,
-			TokenLastEight: tokens[i].TokenLastEight,
+		if apiTokens[i], err = convert.ToAccessToken(tokens[i]); err != nil {
+			ctx.Error(http.StatusInternalServerError, "ToAccessToken", err)
+			return
 		}
 	}
 	ctx.JSON(http.StatusOK, &apiTokens)
@@ -76,24 +78,71 @@ func CreateAccessToken(ctx *context.APIContext) {
 	//   description: username of user
 	//   type: string
 	//   required: true
-	// - name: accessToken
+	// - name: body
 	//   in: body
 	//   schema:
-	//     type: object
-	//     required:
-	//       - name
-	//     properties:
-	//       name:
-	//         type: string
+	//     "$ref": "#/definitions/CreateAccessTokenOption"
 	// responses:
 	//   "201":
 	//     "$ref": "#/responses/AccessToken"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	form := web.GetForm(ctx).(*api.CreateAccessTokenOption)
 
+	scope, err := models.ParseAccessTokenScope(form.Scopes)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "ParseAccessTokenScope", err)
+		return
+	}
+
 	t := &models.AccessToken{
-		UID:  ctx.User.ID,
-		Name: form.Name,
+		UID:   ctx.User.ID,
+		Name:  form.Name,
+		Scope: scope,
+	}
+
+	if len(form.Repository) > 0 && len(form.Organization) > 0 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "token can not be restricted to both a repository and an organization")
+		return
+	}
+	if len(form.Repository) > 0 {
+		parts := strings.SplitN(form.Repository, "/", 2)
+		if len(parts) != 2 {
+			ctx.Error(http.StatusUnprocessableEntity, "", "repository must be given as owner/name")
+			return
+		}
+		repo, err := models.GetRepositoryByOwnerAndName(parts[0], parts[1])
+		if err != nil {
+			if models.IsErrRepoNotExist(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "GetRepositoryByOwnerAndName", err)
+			} else {
+				ctx.Error(http.StatusInternalServerError, "GetRepositoryByOwnerAndName", err)
+			}
+			return
+		}
+		t.RepoID = repo.ID
+	} else if len(form.Organization) > 0 {
+		org, err := models.GetOrgByName(form.Organization)
+		if err != nil {
+			if models.IsErrOrgNotExist(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "GetOrgByName", err)
+			} else {
+				ctx.Error(http.StatusInternalServerError, "GetOrgByName", err)
+			}
+			return
+		}
+		t.OrgID = org.ID
+	}
+
+	if form.Expires != nil {
+		if form.Expires.Before(time.Now()) {
+			ctx.Error(http.StatusUnprocessableEntity, "", "expiration date must be in the future")
+			return
+		}
+		t.ExpiresUnix = timeutil.TimeStamp(form.Expires.Unix())
 	}
 
 	exist, err := models.AccessTokenByNameExists(t)
@@ -110,12 +159,12 @@ func CreateAccessToken(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "NewAccessToken", err)
 		return
 	}
-	ctx.JSON(http.StatusCreated, &api.AccessToken{
-		Name:           t.Name,
-		Token:          t.Token,
-		ID:             t.ID,
-		TokenLastEight: t.TokenLastEight,
-	})
+	apiToken, err := convert.ToAccessToken(t)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "ToAccessToken", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, apiToken)
 }
 
 // DeleteAccessToken delete access tokens
@@ -110,6 +110,15 @@ func Home(ctx *context.Context) {
 			return
 		}
 		opts.PublicOnly = !isMember && !ctx.User.IsAdmin
+
+		if isMember && org.RequireTwoFactor {
+			hasTwoFactor, err := models.HasTwoFactorByUID(ctx.User.ID)
+			if err != nil {
+				ctx.ServerError("HasTwoFactorByUID", err)
+				return
+			}
+			ctx.Data["TwoFactorRequired"] = !hasTwoFactor
+		}
 	}
 
 	members, _, err := models.FindOrgMembers(&opts)
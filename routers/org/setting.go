@@ -35,6 +35,7 @@ func Settings(ctx *context.Context) {
 	ctx.Data["PageIsSettingsOptions"] = true
 	ctx.Data["CurrentVisibility"] = ctx.Org.Organization.Visibility
 	ctx.Data["RepoAdminChangeTeamAccess"] = ctx.Org.Organization.RepoAdminChangeTeamAccess
+	ctx.Data["RequireTwoFactor"] = ctx.Org.Organization.RequireTwoFactor
 	ctx.HTML(200, tplSettingsOptions)
 }
 
@@ -52,6 +53,19 @@ func SettingsPost(ctx *context.Context) {
 
 	org := ctx.Org.Organization
 
+	// Make sure the owner does not lock themselves out of the repositories
+	if form.RequireTwoFactor && !org.RequireTwoFactor {
+		hasTwoFactor, err := models.HasTwoFactorByUID(ctx.User.ID)
+		if err != nil {
+			ctx.ServerError("HasTwoFactorByUID", err)
+			return
+		} else if !hasTwoFactor {
+			ctx.Data["RequireTwoFactor"] = org.RequireTwoFactor
+			ctx.RenderWithErr(ctx.Tr("org.settings.require_two_factor_not_enrolled"), tplSettingsOptions, &form)
+			return
+		}
+	}
+
 	// Check if organization name has been changed.
 	if org.LowerName != strings.ToLower(form.Name) {
 		isExist, err := models.IsUserExist(org.ID, form.Name)
@@ -88,6 +102,7 @@ func SettingsPost(ctx *context.Context) {
 	org.Website = form.Website
 	org.Location = form.Location
 	org.RepoAdminChangeTeamAccess = form.RepoAdminChangeTeamAccess
+	org.RequireTwoFactor = form.RequireTwoFactor
 
 	visibilityChanged := form.Visibility != org.Visibility
 	org.Visibility = form.Visibility
@@ -63,6 +63,14 @@ func Routes() *web.Route {
 	r.Post("/manager/shutdown", Shutdown)
 	r.Post("/manager/restart", Restart)
 	r.Post("/manager/flush-queues", bind(private.FlushOptions{}), FlushQueues)
+	r.Get("/manager/queues", ListQueues)
+	r.Post("/manager/queues/{name}/pause", PauseQueue)
+	r.Post("/manager/queues/{name}/resume", ResumeQueue)
+	r.Post("/manager/queues/{name}/flush", bind(private.FlushOptions{}), FlushQueue)
+	r.Get("/manager/queues/{name}/peek", PeekQueue)
+	r.Post("/manager/queues/{name}/export", ExportQueue)
+	r.Post("/manager/queues/{name}/remove", RemovePendingQueueItems)
+	r.Post("/manager/queues/{name}/import", ImportQueue)
 	r.Post("/manager/pause-logging", PauseLogging)
 	r.Post("/manager/resume-logging", ResumeLogging)
 	r.Post("/manager/release-and-reopen-logging", ReleaseReopenLogging)
@@ -44,6 +44,172 @@ func FlushQueues(ctx *context.PrivateContext) {
 	ctx.PlainText(http.StatusOK, []byte("success"))
 }
 
+// ListQueues lists the managed queues
+func ListQueues(ctx *context.PrivateContext) {
+	mqs := queue.GetManager().ManagedQueues()
+	infos := make([]private.QueueInfo, 0, len(mqs))
+	for _, mq := range mqs {
+		infos = append(infos, private.QueueInfo{
+			QID:                  mq.QID,
+			Name:                 mq.Name,
+			Type:                 string(mq.Type),
+			NumberOfWorkers:      mq.NumberOfWorkers(),
+			NumberOfPendingItems: mq.NumberOfPendingItems(),
+			IsPaused:             mq.IsPaused(),
+		})
+	}
+	ctx.JSON(http.StatusOK, infos)
+}
+
+func getManagedQueue(ctx *context.PrivateContext) *queue.ManagedQueue {
+	name := ctx.Params("name")
+	mq := queue.GetManager().GetManagedQueueByName(name)
+	if mq == nil {
+		ctx.JSON(http.StatusNotFound, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s not found", name),
+		})
+	}
+	return mq
+}
+
+// PauseQueue pauses a queue
+func PauseQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	if !mq.Pause() {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s cannot be paused", mq.Name),
+		})
+		return
+	}
+	ctx.PlainText(http.StatusOK, []byte("success"))
+}
+
+// ResumeQueue resumes a queue
+func ResumeQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	if !mq.Resume() {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s cannot be resumed", mq.Name),
+		})
+		return
+	}
+	ctx.PlainText(http.StatusOK, []byte("success"))
+}
+
+// FlushQueue flushes a queue
+func FlushQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	opts := web.GetForm(ctx).(*private.FlushOptions)
+	if opts.NonBlocking {
+		go func() {
+			if err := mq.Flush(opts.Timeout); err != nil {
+				log.Error("Flushing queue %s failed with error: %v", mq.Name, err)
+			}
+		}()
+		ctx.JSON(http.StatusAccepted, map[string]interface{}{
+			"err": "Flushing",
+		})
+		return
+	}
+	if err := mq.Flush(opts.Timeout); err != nil {
+		ctx.JSON(http.StatusRequestTimeout, map[string]interface{}{
+			"err": fmt.Sprintf("%v", err),
+		})
+		return
+	}
+	ctx.PlainText(http.StatusOK, []byte("success"))
+}
+
+// PeekQueue returns the first pending items of a queue
+func PeekQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	items, err := mq.PeekPending(ctx.QueryInt("count"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"err": fmt.Sprintf("Unable to peek queue %s: %v", mq.Name, err),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, items)
+}
+
+// ExportQueue writes the pending items of a paused queue to the response without removing them
+func ExportQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	if !mq.IsInspectable() {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s does not keep pending items", mq.Name),
+		})
+		return
+	}
+	if !mq.IsPaused() {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s must be paused first", mq.Name),
+		})
+		return
+	}
+	ctx.Resp.Header().Set("Content-Type", "application/x-ndjson")
+	count, err := mq.ExportPending(ctx.Resp)
+	if err != nil {
+		log.Error("Exporting pending items of queue %s failed after %d items: %v", mq.Name, count, err)
+		return
+	}
+	log.Info("Exported %d pending items of queue %s", count, mq.Name)
+}
+
+// RemovePendingQueueItems removes the first pending items of a paused queue once they have been exported
+func RemovePendingQueueItems(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	count, err := mq.RemovePending(ctx.QueryInt64("count"))
+	if err == queue.ErrQueueNotPaused {
+		ctx.JSON(http.StatusBadRequest, map[string]interface{}{
+			"err": fmt.Sprintf("Queue %s must be paused first", mq.Name),
+		})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"err": fmt.Sprintf("Removing pending items of queue %s failed after %d items: %v", mq.Name, count, err),
+		})
+		return
+	}
+	log.Info("Removed %d pending items of queue %s", count, mq.Name)
+	ctx.PlainText(http.StatusOK, []byte(fmt.Sprintf("Removed %d items", count)))
+}
+
+// ImportQueue pushes the items of the request body to a queue
+func ImportQueue(ctx *context.PrivateContext) {
+	mq := getManagedQueue(ctx)
+	if mq == nil {
+		return
+	}
+	count, err := mq.ImportPending(ctx.Req.Body)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"err": fmt.Sprintf("Import to queue %s failed after %d items: %v", mq.Name, count, err),
+		})
+		return
+	}
+	ctx.PlainText(http.StatusOK, []byte(fmt.Sprintf("Imported %d items", count)))
+}
+
 // PauseLogging pauses logging
 func PauseLogging(ctx *context.PrivateContext) {
 	log.Pause()
@@ -339,6 +339,7 @@ func Diff(ctx *context.Context) {
 	setImageCompareContext(ctx, parentCommit, commit)
 	headTarget := path.Join(userName, repoName)
 	setPathsCompareContext(ctx, parentCommit, commit, headTarget)
+	setRichDiffCompareContext(ctx, parentCommit, commit)
 	ctx.Data["Title"] = commit.Summary() + " · " + base.ShortSha(commitID)
 	ctx.Data["Commit"] = commit
 	verification := models.ParseCommitWithSignature(commit)
@@ -383,10 +384,14 @@ func RawDiff(ctx *context.Context) {
 	} else {
 		repoPath = models.RepoPath(ctx.Repo.Owner.Name, ctx.Repo.Repository.Name)
 	}
+	diffType := git.RawDiffType(ctx.Params(":ext"))
+	if diffType == git.RawDiffNormal && ctx.QueryBool("word_diff") {
+		diffType = git.RawDiffWord
+	}
 	if err := git.GetRawDiff(
 		repoPath,
 		ctx.Params(":sha"),
-		git.RawDiffType(ctx.Params(":ext")),
+		diffType,
 		ctx.Resp,
 	); err != nil {
 		ctx.ServerError("GetRawDiff", err)
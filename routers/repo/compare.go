@@ -6,8 +6,11 @@ package repo
 
 import (
 	"bufio"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"html"
+	"io/ioutil"
 	"path"
 	"strings"
 
@@ -16,6 +19,8 @@ import (
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/markup"
+	csv_module "code.gitea.io/gitea/modules/markup/csv"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/upload"
 	"code.gitea.io/gitea/services/gitdiff"
@@ -65,6 +70,119 @@ func setImageCompareContext(ctx *context.Context, base *git.Commit, head *git.Co
 	}
 }
 
+var errRichDiffTooLarge = errors.New("file is too large for a rendered diff")
+
+// getRichDiffBlob returns the content of a file for a rendered diff, which is nil if the file does not exist
+func getRichDiffBlob(commit *git.Commit, treePath string) ([]byte, error) {
+	if commit == nil {
+		return nil, nil
+	}
+	entry, err := commit.GetTreeEntryByPath(treePath)
+	if err != nil {
+		if git.IsErrNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	blob := entry.Blob()
+	if blob.Size() > setting.UI.MaxRichDiffFileSize {
+		return nil, errRichDiffTooLarge
+	}
+	dataRc, err := blob.DataAsync()
+	if err != nil {
+		return nil, err
+	}
+	defer dataRc.Close()
+	return ioutil.ReadAll(dataRc)
+}
+
+// setRichDiffCompareContext sets context data that is required by the rendered
+// diffs of markup and CSV files, it must be called after setPathsCompareContext
+func setRichDiffCompareContext(ctx *context.Context, base *git.Commit, head *git.Commit) {
+	hasRichDiff := func(diffFile *gitdiff.DiffFile) bool {
+		return !diffFile.IsBin && !diffFile.IsLFSFile && !diffFile.IsSubmodule
+	}
+	ctx.Data["IsCsvFile"] = func(diffFile *gitdiff.DiffFile) bool {
+		return hasRichDiff(diffFile) && markup.IsMarkupFile(diffFile.Name, "csv")
+	}
+	ctx.Data["IsRenderedFile"] = func(diffFile *gitdiff.DiffFile) bool {
+		return hasRichDiff(diffFile) && markup.Type(diffFile.Name) != "" &&
+			!markup.IsMarkupFile(diffFile.Name, "csv") && !markup.IsIFrameRenderedFile(diffFile.Name)
+	}
+
+	getBlobs := func(diffFile *gitdiff.DiffFile) (baseName string, baseContent, headContent []byte, err error) {
+		baseName = diffFile.OldName
+		if len(baseName) == 0 {
+			baseName = diffFile.Name
+		}
+		if !diffFile.IsCreated {
+			if baseContent, err = getRichDiffBlob(base, baseName); err != nil {
+				return
+			}
+		}
+		if !diffFile.IsDeleted {
+			headContent, err = getRichDiffBlob(head, diffFile.Name)
+		}
+		return
+	}
+	errorMessage := func(diffFile *gitdiff.DiffFile, err error) string {
+		if err == errRichDiffTooLarge {
+			return ctx.Tr("repo.diff.rich_diff_too_large")
+		}
+		log.Error("Unable to create rendered diff of %s: %v", diffFile.Name, err)
+		return ctx.Tr("repo.diff.rich_diff_failed")
+	}
+
+	type CsvDiffResult struct {
+		Sections []*gitdiff.TableDiffSection
+		Error    string
+	}
+	ctx.Data["CreateCsvDiff"] = func(diffFile *gitdiff.DiffFile) CsvDiffResult {
+		_, baseContent, headContent, err := getBlobs(diffFile)
+		if err != nil {
+			return CsvDiffResult{Error: errorMessage(diffFile, err)}
+		}
+		var baseReader, headReader *csv.Reader
+		if baseContent != nil {
+			baseReader = csv_module.NewReader(baseContent)
+		}
+		if headContent != nil {
+			headReader = csv_module.NewReader(headContent)
+		}
+		sections, err := gitdiff.CreateCsvDiff(baseReader, headReader)
+		if err != nil {
+			return CsvDiffResult{Error: errorMessage(diffFile, err)}
+		}
+		return CsvDiffResult{Sections: sections}
+	}
+
+	sourcePath, _ := ctx.Data["SourcePath"].(string)
+	beforeSourcePath, _ := ctx.Data["BeforeSourcePath"].(string)
+	metas := ctx.Repo.Repository.ComposeDocumentMetas()
+	type RenderedDiffResult struct {
+		Blocks []*gitdiff.RenderedDiffBlock
+		Error  string
+	}
+	ctx.Data["CreateRenderedDiff"] = func(diffFile *gitdiff.DiffFile) RenderedDiffResult {
+		baseName, baseContent, headContent, err := getBlobs(diffFile)
+		if err != nil {
+			return RenderedDiffResult{Error: errorMessage(diffFile, err)}
+		}
+		var baseRendered, headRendered []byte
+		if baseContent != nil {
+			baseRendered = markup.Render(baseName, baseContent, path.Dir(beforeSourcePath+"/"+baseName), metas)
+		}
+		if headContent != nil {
+			headRendered = markup.Render(diffFile.Name, headContent, path.Dir(sourcePath+"/"+diffFile.Name), metas)
+		}
+		blocks, err := gitdiff.CreateRenderedDiff(baseRendered, headRendered)
+		if err != nil {
+			return RenderedDiffResult{Error: errorMessage(diffFile, err)}
+		}
+		return RenderedDiffResult{Blocks: blocks}
+	}
+}
+
 // ParseCompareInfo parse compare info between two commit for preparing comparing references
 func ParseCompareInfo(ctx *context.Context) (*models.User, *models.Repository, *git.Repository, *git.CompareInfo, string, string) {
 	baseRepo := ctx.Repo.Repository
@@ -489,6 +607,7 @@ func PrepareCompareDiff(
 	setImageCompareContext(ctx, baseCommit, headCommit)
 	headTarget := path.Join(headUser.Name, repo.Name)
 	setPathsCompareContext(ctx, baseCommit, headCommit, headTarget)
+	setRichDiffCompareContext(ctx, baseCommit, headCommit)
 
 	return false
 }
@@ -30,8 +30,8 @@ import (
 	"code.gitea.io/gitea/modules/process"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
-	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/lockout"
 	repo_service "code.gitea.io/gitea/services/repository"
 )
 
@@ -153,6 +153,7 @@ func httpBase(ctx *context.Context) (h *serviceHandler) {
 	var (
 		askAuth      = !isPublicPull || setting.Service.RequireSignInView
 		authUser     *models.User
+		accessToken  *models.AccessToken
 		authUsername string
 		authPasswd   string
 		environ      []string
@@ -227,21 +228,25 @@ func httpBase(ctx *context.Context) (h *serviceHandler) {
 					return
 				}
 
-				token.UpdatedUnix = timeutil.TimeStampNow()
-				if err = models.UpdateAccessToken(token); err != nil {
-					ctx.ServerError("UpdateAccessToken", err)
+				if err = models.UpdateAccessTokenLastUsed(token); err != nil {
+					ctx.ServerError("UpdateAccessTokenLastUsed", err)
+					return
 				}
+				accessToken = token
 			} else if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) {
 				log.Error("GetAccessTokenBySha: %v", err)
 			}
 
 			if authUser == nil {
 				// Check username and password
-				authUser, err = models.UserSignIn(authUsername, authPasswd)
+				authUser, err = lockout.UserSignIn(authUsername, authPasswd, ctx.RemoteAddr())
 				if err != nil {
 					if models.IsErrUserProhibitLogin(err) {
 						ctx.HandleText(http.StatusForbidden, "User is not permitted to login")
 						return
+					} else if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+						ctx.HandleText(http.StatusTooManyRequests, "Too many failed sign-in attempts, please try again later")
+						return
 					} else if !models.IsErrUserNotExist(err) {
 						ctx.ServerError("UserSignIn error: %v", err)
 						return
@@ -270,6 +275,26 @@ func httpBase(ctx *context.Context) (h *serviceHandler) {
 			return
 		}
 
+		mustEnroll, err := authUser.MustEnrollTwoFactor()
+		if err != nil {
+			ctx.ServerError("MustEnrollTwoFactor", err)
+			return
+		} else if mustEnroll {
+			ctx.HandleText(http.StatusUnauthorized, "Two-factor authentication must be enrolled on the user settings page before accessing repositories")
+			return
+		}
+
+		if accessToken != nil {
+			if !accessToken.Scope.HasScope(models.AccessTokenScopeCategoryRepo, !isPull) {
+				ctx.HandleText(http.StatusForbidden, "The access token does not have the required scope")
+				return
+			}
+			if repoExist && !accessToken.CanAccessRepo(repo) || !repoExist && !accessToken.CanAccessOrg(owner.ID) {
+				ctx.HandleText(http.StatusForbidden, "The access token is restricted to another repository or organization")
+				return
+			}
+		}
+
 		if repoExist {
 			perm, err := models.GetUserRepoPermission(repo, authUser)
 			if err != nil {
@@ -1471,6 +1471,11 @@ func ViewIssue(ctx *context.Context) {
 				ctx.ServerError("CanMarkConversation", err)
 				return
 			}
+
+			if ctx.Data["CanApplySuggestions"], err = canApplySuggestions(ctx, issue); err != nil {
+				ctx.ServerError("canApplySuggestions", err)
+				return
+			}
 		}
 
 		prUnit, err := repo.GetUnit(models.UnitTypePullRequests)
@@ -1512,6 +1517,36 @@ func ViewIssue(ctx *context.Context) {
 			ctx.Data["ChangedProtectedFiles"] = pull.ChangedProtectedFiles
 			ctx.Data["IsBlockedByChangedProtectedFiles"] = len(pull.ChangedProtectedFiles) != 0
 			ctx.Data["ChangedProtectedFilesNum"] = len(pull.ChangedProtectedFiles)
+			ctx.Data["EnableMergeQueue"] = pull.ProtectedBranch.EnableMergeQueue
+			if pull.ProtectedBranch.EnableMergeQueue {
+				mergeQueue, err := models.GetMergeQueue(pull.BaseRepoID, pull.BaseBranch)
+				if err != nil {
+					ctx.ServerError("GetMergeQueue", err)
+					return
+				}
+				ctx.Data["MergeQueueLength"] = len(mergeQueue)
+				for i, entry := range mergeQueue {
+					if entry.PullID != pull.ID {
+						continue
+					}
+					entry.PullRequest = pull
+					if err = entry.LoadAttributes(); err != nil {
+						ctx.ServerError("LoadAttributes", err)
+						return
+					}
+					ctx.Data["MergeQueueEntry"] = entry
+					ctx.Data["MergeQueuePosition"] = i + 1
+					if entry.IsTesting() {
+						commitStatuses, err := models.GetLatestCommitStatus(pull.BaseRepoID, entry.SpeculativeCommitID, models.ListOptions{})
+						if err != nil {
+							ctx.ServerError("GetLatestCommitStatus", err)
+							return
+						}
+						ctx.Data["MergeQueueCommitStatus"] = models.CalcCommitStatus(commitStatuses)
+					}
+					break
+				}
+			}
 		}
 		ctx.Data["WillSign"] = false
 		if ctx.User != nil {
@@ -32,12 +32,25 @@ func SetEditorconfigIfExists(ctx *context.Context) {
 	ctx.Data["Editorconfig"] = ec
 }
 
+func isDiffViewStyle(style string) bool {
+	return style == "unified" || style == "split" || style == "word"
+}
+
+func setDiffViewStyleData(ctx *context.Context, style string) {
+	ctx.Data["DiffViewStyle"] = style
+	ctx.Data["IsSplitStyle"] = style == "split"
+	ctx.Data["IsWordDiffStyle"] = style == "word"
+}
+
 // SetDiffViewStyle set diff style as render variable
 func SetDiffViewStyle(ctx *context.Context) {
 	queryStyle := ctx.Query("style")
 
 	if !ctx.IsSigned {
-		ctx.Data["IsSplitStyle"] = queryStyle == "split"
+		if !isDiffViewStyle(queryStyle) {
+			queryStyle = "unified"
+		}
+		setDiffViewStyleData(ctx, queryStyle)
 		return
 	}
 
@@ -46,15 +59,15 @@ func SetDiffViewStyle(ctx *context.Context) {
 		style     string
 	)
 
-	if queryStyle == "unified" || queryStyle == "split" {
+	if isDiffViewStyle(queryStyle) {
 		style = queryStyle
-	} else if userStyle == "unified" || userStyle == "split" {
+	} else if isDiffViewStyle(userStyle) {
 		style = userStyle
 	} else {
 		style = "unified"
 	}
 
-	ctx.Data["IsSplitStyle"] = style == "split"
+	setDiffViewStyleData(ctx, style)
 	if err := ctx.User.UpdateDiffViewStyle(style); err != nil {
 		ctx.ServerError("ErrUpdateDiffViewStyle", err)
 	}
@@ -10,8 +10,11 @@ import (
 	"container/list"
 	"crypto/subtle"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -573,6 +576,74 @@ func ViewPullCommits(ctx *context.Context) {
 	ctx.HTML(200, tplPullCommits)
 }
 
+// preparePullDiffRange returns the range of commits whose changes are shown on the
+// files page of a pull request. The range is selected by the from and to query
+// parameters, from is the merge base or a commit of the pull request and the range
+// starts after it, to is a later commit of the pull request the range ends with.
+func preparePullDiffRange(ctx *context.Context, issue *models.Issue, prInfo *git.CompareInfo, headCommitID string) (startCommitID, endCommitID string) {
+	// the commits are sorted from the merge base to the newest commit
+	commits := make([]*git.Commit, prInfo.Commits.Len())
+	commitIDs := make([]string, len(commits)+1)
+	commitIDs[0] = prInfo.MergeBase
+	i := len(commits)
+	for e := prInfo.Commits.Front(); e != nil; e = e.Next() {
+		i--
+		commits[i] = e.Value.(*git.Commit)
+		commitIDs[i+1] = commits[i].ID.String()
+	}
+	position := func(commitID string) int {
+		for i, id := range commitIDs {
+			if id == commitID {
+				return i
+			}
+		}
+		return -1
+	}
+
+	startCommitID, endCommitID = prInfo.MergeBase, headCommitID
+	startPos, endPos := 0, len(commitIDs)-1
+	rangeQuery := make(url.Values)
+	if from := ctx.Query("from"); len(from) > 0 {
+		startCommitID, startPos = from, position(from)
+		rangeQuery.Set("from", from)
+	}
+	if to := ctx.Query("to"); len(to) > 0 {
+		endCommitID, endPos = to, position(to)
+		rangeQuery.Set("to", to)
+	}
+	if len(rangeQuery) > 0 && (startPos < 0 || endPos < 0 || startPos >= endPos) {
+		ctx.Flash.Error(ctx.Tr("repo.pulls.commit_range_invalid"))
+		ctx.Redirect(issue.HTMLURL() + "/files")
+		return
+	}
+
+	ctx.Data["PullCommits"] = commits
+	ctx.Data["PullMergeBase"] = prInfo.MergeBase
+	ctx.Data["DiffRangeFrom"] = startCommitID
+	ctx.Data["DiffRangeTo"] = endCommitID
+	ctx.Data["IsDiffRange"] = startCommitID != prInfo.MergeBase || endCommitID != headCommitID
+	if len(rangeQuery) > 0 {
+		ctx.Data["DiffRangeQuery"] = template.URL("&" + rangeQuery.Encode())
+	}
+
+	if ctx.IsSigned {
+		review, err := models.GetLatestSubmittedReview(issue.ID, ctx.User.ID)
+		if err != nil && !models.IsErrReviewNotExist(err) {
+			ctx.ServerError("GetLatestSubmittedReview", err)
+			return
+		}
+		// there is nothing new if the head was reviewed, and the reviewed
+		// commit is gone if the branch was force-pushed
+		if review != nil {
+			if pos := position(review.CommitID); pos > 0 && pos < len(commitIDs)-1 {
+				ctx.Data["LastReviewCommitID"] = review.CommitID
+				ctx.Data["IsSinceLastReview"] = startCommitID == review.CommitID && endCommitID == headCommitID
+			}
+		}
+	}
+	return startCommitID, endCommitID
+}
+
 // ViewPullFiles render pull request changed files list page
 func ViewPullFiles(ctx *context.Context) {
 	ctx.Data["PageIsPullList"] = true
@@ -615,8 +686,10 @@ func ViewPullFiles(ctx *context.Context) {
 		return
 	}
 
-	startCommitID = prInfo.MergeBase
-	endCommitID = headCommitID
+	startCommitID, endCommitID = preparePullDiffRange(ctx, issue, prInfo, headCommitID)
+	if ctx.Written() {
+		return
+	}
 
 	headTarget = path.Join(ctx.Repo.Owner.Name, ctx.Repo.Repository.Name)
 	ctx.Data["Username"] = ctx.Repo.Owner.Name
@@ -632,8 +705,19 @@ func ViewPullFiles(ctx *context.Context) {
 		return
 	}
 
-	if err = diff.LoadComments(issue, ctx.User); err != nil {
-		ctx.ServerError("LoadComments", err)
+	// comments are anchored to the merge base and the head of the pull request
+	baseLines, err := gitdiff.GetLineMap(diffRepoPath, prInfo.MergeBase, startCommitID)
+	if err != nil {
+		ctx.ServerError("GetLineMap", err)
+		return
+	}
+	headLines, err := gitdiff.GetLineMap(diffRepoPath, endCommitID, headCommitID)
+	if err != nil {
+		ctx.ServerError("GetLineMap", err)
+		return
+	}
+	if err = diff.LoadCommentsInRange(issue, ctx.User, baseLines, headLines); err != nil {
+		ctx.ServerError("LoadCommentsInRange", err)
 		return
 	}
 
@@ -665,15 +749,27 @@ func ViewPullFiles(ctx *context.Context) {
 		return
 	}
 
+	if ctx.IsSigned {
+		if err = diff.LoadViewedFiles(pull, ctx.User, commit); err != nil {
+			ctx.ServerError("LoadViewedFiles", err)
+			return
+		}
+	}
+
 	if ctx.IsSigned && ctx.User != nil {
 		if ctx.Data["CanMarkConversation"], err = models.CanMarkConversation(issue, ctx.User); err != nil {
 			ctx.ServerError("CanMarkConversation", err)
 			return
 		}
+		if ctx.Data["CanApplySuggestions"], err = canApplySuggestions(ctx, issue); err != nil {
+			ctx.ServerError("canApplySuggestions", err)
+			return
+		}
 	}
 
 	setImageCompareContext(ctx, baseCommit, commit)
 	setPathsCompareContext(ctx, baseCommit, commit, headTarget)
+	setRichDiffCompareContext(ctx, baseCommit, commit)
 
 	ctx.Data["RequireHighlightJS"] = true
 	ctx.Data["RequireSimpleMDE"] = true
@@ -697,6 +793,60 @@ func ViewPullFiles(ctx *context.Context) {
 	ctx.HTML(200, tplPullFiles)
 }
 
+// SetAllowMaintainerEdit allows or disallows users who can write to the base repository
+// to push to the head branch of a pull request
+func SetAllowMaintainerEdit(ctx *context.Context) {
+	issue := checkPullInfo(ctx)
+	if ctx.Written() {
+		return
+	}
+	if issue.PosterID != ctx.User.ID {
+		ctx.Error(http.StatusForbidden)
+		return
+	}
+
+	allow, err := strconv.ParseBool(ctx.Req.PostForm.Get("allow_maintainer_edit"))
+	if err != nil {
+		ctx.Error(http.StatusBadRequest)
+		return
+	}
+	if err := issue.PullRequest.SetAllowMaintainerEdit(allow); err != nil {
+		ctx.ServerError("SetAllowMaintainerEdit", err)
+		return
+	}
+
+	ctx.Redirect(ctx.Repo.RepoLink+"/pulls/"+fmt.Sprint(issue.Index), http.StatusSeeOther)
+}
+
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemoveFromMergeQueue(ctx *context.Context) {
+	issue := checkPullInfo(ctx)
+	if ctx.Written() {
+		return
+	}
+	pr := issue.PullRequest
+
+	if issue.PosterID != ctx.User.ID {
+		allowedMerge, err := pull_service.IsUserAllowedToMerge(pr, ctx.Repo.Permission, ctx.User)
+		if err != nil {
+			ctx.ServerError("IsUserAllowedToMerge", err)
+			return
+		}
+		if !allowedMerge {
+			ctx.Error(http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := pull_service.RemoveFromMergeQueue(pr, ctx.User); err != nil {
+		ctx.ServerError("RemoveFromMergeQueue", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.pulls.merge_queue.removed"))
+	ctx.Redirect(ctx.Repo.RepoLink+"/pulls/"+fmt.Sprint(issue.Index), http.StatusSeeOther)
+}
+
 // UpdatePullRequest merge PR's baseBranch into headBranch
 func UpdatePullRequest(ctx *context.Context) {
 	issue := checkPullInfo(ctx)
@@ -887,6 +1037,30 @@ func MergePullRequest(ctx *context.Context) {
 		return
 	}
 
+	if mergeQueueEnabled, err := pull_service.IsMergeQueueEnabled(pr); err != nil {
+		ctx.ServerError("IsMergeQueueEnabled", err)
+		return
+	} else if mergeQueueEnabled {
+		if err := pull_service.AddToMergeQueue(pr, ctx.User, models.MergeStyle(form.Do), message); err != nil {
+			if models.IsErrInvalidMergeStyle(err) {
+				ctx.Flash.Error(ctx.Tr("repo.pulls.invalid_merge_option"))
+				ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + fmt.Sprint(pr.Index))
+				return
+			} else if models.IsErrPullAlreadyInMergeQueue(err) {
+				ctx.Flash.Error(ctx.Tr("repo.pulls.merge_queue.already_queued"))
+				ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + fmt.Sprint(pr.Index))
+				return
+			}
+			ctx.ServerError("AddToMergeQueue", err)
+			return
+		}
+
+		log.Trace("Pull request added to the merge queue: %d", pr.ID)
+		ctx.Flash.Success(ctx.Tr("repo.pulls.merge_queue.added"))
+		ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + fmt.Sprint(pr.Index))
+		return
+	}
+
 	if err = pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
 		if models.IsErrInvalidMergeStyle(err) {
 			ctx.Flash.Error(ctx.Tr("repo.pulls.invalid_merge_option"))
@@ -1283,7 +1457,7 @@ func DownloadPullDiffOrPatch(ctx *context.Context, patch bool) {
 
 	pr := issue.PullRequest
 
-	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch); err != nil {
+	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch, ctx.QueryBool("word_diff")); err != nil {
 		ctx.ServerError("DownloadDiffOrPatch", err)
 		return
 	}
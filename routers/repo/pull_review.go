@@ -5,14 +5,19 @@
 package repo
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	auth "code.gitea.io/gitea/modules/forms"
+	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/services/gitdiff"
 	pull_service "code.gitea.io/gitea/services/pull"
 )
 
@@ -66,6 +71,26 @@ func CreateCodeComment(ctx *context.Context) {
 		signedLine *= -1
 	}
 
+	latestCommitID := form.LatestCommitID
+	if form.Reply == 0 && (len(form.DiffStartCommitID) > 0 || len(form.DiffEndCommitID) > 0) {
+		// the comment was made on a diff of a range of the commits of the pull request
+		var err error
+		signedLine, latestCommitID, err = anchorCodeCommentLine(ctx.Repo.GitRepo, issue, form.TreePath, signedLine, form.DiffStartCommitID, form.DiffEndCommitID)
+		if err == errInvalidDiffRange {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.commit_range_invalid"))
+			ctx.Redirect(fmt.Sprintf("%s/pulls/%d/files", ctx.Repo.RepoLink, issue.Index))
+			return
+		} else if err != nil {
+			ctx.ServerError("anchorCodeCommentLine", err)
+			return
+		}
+		if signedLine == 0 {
+			ctx.Flash.Error(ctx.Tr("repo.diff.comment.line_changed_outside_range"))
+			ctx.Redirect(fmt.Sprintf("%s/pulls/%d/files", ctx.Repo.RepoLink, issue.Index))
+			return
+		}
+	}
+
 	comment, err := pull_service.CreateCodeComment(
 		ctx.User,
 		ctx.Repo.GitRepo,
@@ -75,7 +100,7 @@ func CreateCodeComment(ctx *context.Context) {
 		form.TreePath,
 		form.IsReview,
 		form.Reply,
-		form.LatestCommitID,
+		latestCommitID,
 	)
 	if err != nil {
 		ctx.ServerError("CreateCodeComment", err)
@@ -97,6 +122,81 @@ func CreateCodeComment(ctx *context.Context) {
 	ctx.Redirect(comment.HTMLURL())
 }
 
+// UpdateViewedFile marks a file of a pull request as viewed or not viewed
+func UpdateViewedFile(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.ViewedFileForm)
+	issue := GetActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+	if !issue.IsPull || ctx.HasError() {
+		ctx.Error(http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if form.Viewed {
+		err = models.SetPullFilesViewed(ctx.User.ID, issue.PullRequest.ID, map[string]string{form.TreePath: form.BlobSHA})
+	} else {
+		err = models.UnsetPullFilesViewed(ctx.User.ID, issue.PullRequest.ID, []string{form.TreePath})
+	}
+	if err != nil {
+		ctx.ServerError("UpdateViewedFile", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"ok": true,
+	})
+}
+
+// errInvalidDiffRange is returned for diff ranges which are not made of commits of the pull request
+var errInvalidDiffRange = errors.New("invalid diff range")
+
+// anchorCodeCommentLine translates a line of a diff between two commits of a pull
+// request to the merge base for the previous side or to the head of the pull request
+// for the proposed side, which code comments are anchored to. It returns 0 if the
+// line was changed outside of the diff and the head commit of the pull request.
+func anchorCodeCommentLine(gitRepo *git.Repository, issue *models.Issue, treePath string, line int64, startCommitID, endCommitID string) (int64, string, error) {
+	if err := issue.LoadPullRequest(); err != nil {
+		return 0, "", err
+	}
+	pr := issue.PullRequest
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return 0, "", err
+	}
+
+	// the commit IDs are passed to git, only accept the commits of the pull request
+	commits, err := gitRepo.CommitsBetweenIDs(headCommitID, pr.MergeBase)
+	if err != nil {
+		return 0, "", err
+	}
+	commitIDs := map[string]bool{pr.MergeBase: true}
+	for e := commits.Front(); e != nil; e = e.Next() {
+		commitIDs[e.Value.(*git.Commit).ID.String()] = true
+	}
+	for _, commitID := range []string{startCommitID, endCommitID} {
+		if len(commitID) > 0 && (len(commitID) != 40 || !git.SHAPattern.MatchString(commitID) || !commitIDs[commitID]) {
+			return 0, "", errInvalidDiffRange
+		}
+	}
+
+	if line < 0 && len(startCommitID) > 0 && startCommitID != pr.MergeBase {
+		baseLines, err := gitdiff.GetLineMap(gitRepo.Path, pr.MergeBase, startCommitID)
+		if err != nil {
+			return 0, "", err
+		}
+		line = -int64(baseLines.ToOld(treePath, int(-line)))
+	} else if line > 0 && len(endCommitID) > 0 && endCommitID != headCommitID {
+		headLines, err := gitdiff.GetLineMap(gitRepo.Path, endCommitID, headCommitID)
+		if err != nil {
+			return 0, "", err
+		}
+		line = int64(headLines.ToNew(treePath, int(line)))
+	}
+	return line, headCommitID, nil
+}
+
 // UpdateResolveConversation add or remove an Conversation resolved mark
 func UpdateResolveConversation(ctx *context.Context) {
 	origin := ctx.Query("origin")
@@ -169,9 +269,93 @@ func renderConversation(ctx *context.Context, comment *models.Comment) {
 		return
 	}
 	ctx.Data["AfterCommitID"] = pullHeadCommitID
+	if ctx.Data["CanApplySuggestions"], err = canApplySuggestions(ctx, comment.Issue); err != nil {
+		ctx.ServerError("canApplySuggestions", err)
+		return
+	}
 	ctx.HTML(200, tplConversation)
 }
 
+// canApplySuggestions returns whether the signed in user can commit suggestions to the head branch of a pull request
+func canApplySuggestions(ctx *context.Context, issue *models.Issue) (bool, error) {
+	if !ctx.IsSigned || issue.IsClosed || ctx.Repo.Repository.IsArchived {
+		return false, nil
+	}
+	if err := issue.LoadPullRequest(); err != nil {
+		return false, err
+	}
+	if issue.PullRequest.HasMerged {
+		return false, nil
+	}
+	return pull_service.IsUserAllowedToEditHeadBranch(issue.PullRequest, ctx.User)
+}
+
+// ApplySuggestions commits the suggestions of one or more code comments to the head branch of a pull request
+func ApplySuggestions(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.ApplySuggestionsForm)
+	issue := GetActionIssue(ctx)
+	if ctx.Written() {
+		return
+	}
+	if !issue.IsPull || ctx.HasError() {
+		ctx.Error(http.StatusBadRequest)
+		return
+	}
+	if issue.IsClosed || issue.PullRequest.HasMerged {
+		ctx.NotFound("ApplySuggestions", nil)
+		return
+	}
+
+	commentIDs, err := base.StringsToInt64s(strings.Split(form.CommentIDs, ","))
+	if err != nil {
+		ctx.Error(http.StatusBadRequest)
+		return
+	}
+	comments := make([]*models.Comment, 0, len(commentIDs))
+	for _, commentID := range commentIDs {
+		comment, err := models.GetCommentByID(commentID)
+		if err != nil {
+			if models.IsErrCommentNotExist(err) {
+				ctx.NotFound("GetCommentByID", err)
+			} else {
+				ctx.ServerError("GetCommentByID", err)
+			}
+			return
+		}
+		if comment.IssueID != issue.ID || !comment.HasSuggestion() {
+			ctx.NotFound("ApplySuggestions", nil)
+			return
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := pull_service.ApplySuggestions(issue.PullRequest, ctx.User, comments); err != nil {
+		switch {
+		case models.IsErrUserCannotCommit(err):
+			ctx.Flash.Error(ctx.Tr("repo.pulls.suggestion.cannot_commit"))
+		case models.IsErrFilePathProtected(err):
+			ctx.Flash.Error(ctx.Tr("repo.pulls.suggestion.file_protected", err.(models.ErrFilePathProtected).Path))
+		case models.IsErrLFSFileLocked(err):
+			ctx.Flash.Error(ctx.Tr("repo.editor.upload_file_is_locked", err.(models.ErrLFSFileLocked).Path, err.(models.ErrLFSFileLocked).UserName))
+		case models.IsErrSuggestionOutdated(err), models.IsErrCommitIDDoesNotMatch(err), git.IsErrPushOutOfDate(err):
+			ctx.Flash.Error(ctx.Tr("repo.pulls.suggestion.outdated"))
+		case models.IsErrSuggestionConflict(err):
+			ctx.Flash.Error(ctx.Tr("repo.pulls.suggestion.conflict", err.(models.ErrSuggestionConflict).Path, err.(models.ErrSuggestionConflict).Line))
+		case git.IsErrPushRejected(err):
+			ctx.Flash.Error(ctx.Tr("repo.pulls.suggestion.push_rejected"))
+		default:
+			ctx.ServerError("ApplySuggestions", err)
+			return
+		}
+	} else {
+		ctx.Flash.Success(ctx.Tr("repo.pulls.suggestion.applied", len(comments)))
+	}
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": fmt.Sprintf("%s/pulls/%d/files", ctx.Repo.RepoLink, issue.Index),
+	})
+}
+
 // SubmitReview creates a review out of the existing pending review or creates a new one if no pending review exist
 func SubmitReview(ctx *context.Context) {
 	form := web.GetForm(ctx).(*auth.SubmitReviewForm)
@@ -0,0 +1,102 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/charset"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// renderContentSecurityPolicy is sent with unsanitized renderer output. The
+// sandbox directive gives the document an opaque origin, so even scripts
+// allowed to run inside the iframe have no access to the user's session.
+const renderContentSecurityPolicy = "frame-ancestors 'self'; sandbox allow-scripts; default-src 'none'; " +
+	"script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src * data:; font-src data:"
+
+// RenderFile renders a file with a renderer which has to be displayed in a
+// sandboxed iframe and serves its unsanitized output
+func RenderFile(ctx *context.Context) {
+	if !markup.IsIFrameRenderedFile(ctx.Repo.TreePath) {
+		ctx.NotFound("IsIFrameRenderedFile", nil)
+		return
+	}
+
+	blob, err := ctx.Repo.Commit.GetBlobByPath(ctx.Repo.TreePath)
+	if err != nil {
+		if git.IsErrNotExist(err) {
+			ctx.NotFound("GetBlobByPath", nil)
+		} else {
+			ctx.ServerError("GetBlobByPath", err)
+		}
+		return
+	}
+
+	dataRc, err := blob.DataAsync()
+	if err != nil {
+		ctx.ServerError("DataAsync", err)
+		return
+	}
+	defer func() {
+		if err = dataRc.Close(); err != nil {
+			log.Error("RenderFile: Close: %v", err)
+		}
+	}()
+
+	fileSize := blob.Size()
+	var reader io.Reader = dataRc
+	meta, head := lfs.ReadPointerFile(dataRc)
+	if head != nil {
+		reader = io.MultiReader(bytes.NewReader(*head), dataRc)
+	}
+	if meta != nil {
+		meta, _ = ctx.Repo.Repository.GetLFSMetaObjectByOid(meta.Oid)
+	}
+	if meta != nil {
+		lfsDataRc, err := lfs.ReadMetaObject(meta)
+		if err != nil {
+			ctx.ServerError("ReadMetaObject", err)
+			return
+		}
+		defer lfsDataRc.Close()
+		reader = lfsDataRc
+		fileSize = meta.Size
+	}
+
+	if fileSize >= setting.UI.MaxDisplayFileSize {
+		ctx.Error(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(reader)
+	if err != nil {
+		ctx.ServerError("ReadAll", err)
+		return
+	}
+	if base.IsTextFile(buf) {
+		buf = charset.ToUTF8WithFallback(buf)
+	}
+
+	treeLink := ctx.Repo.RepoLink + "/src/" + ctx.Repo.BranchNameSubURL() + "/" + ctx.Repo.TreePath
+
+	ctx.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx.Resp.Header().Set("Content-Security-Policy", renderContentSecurityPolicy)
+	ctx.Resp.Header().Set("X-Content-Type-Options", "nosniff")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	if _, err = ctx.Resp.Write(markup.RenderUnsanitized(blob.Name(), buf, path.Dir(treeLink), ctx.Repo.Repository.ComposeDocumentMetas())); err != nil {
+		log.Error("RenderFile: Write: %v", err)
+	}
+}
@@ -253,6 +253,7 @@ func SettingsProtectedBranchPost(ctx *context.Context) {
 		protectBranch.RequireSignedCommits = f.RequireSignedCommits
 		protectBranch.ProtectedFilePatterns = f.ProtectedFilePatterns
 		protectBranch.BlockOnOutdatedBranch = f.BlockOnOutdatedBranch
+		protectBranch.EnableMergeQueue = f.EnableMergeQueue
 
 		err = models.UpdateProtectBranch(ctx.Repo.Repository, protectBranch, models.WhitelistOptions{
 			UserIDs:          whitelistUsers,
@@ -278,6 +279,8 @@ func SettingsProtectedBranchPost(ctx *context.Context) {
 				ctx.ServerError("DeleteProtectedBranch", err)
 				return
 			}
+			// the pull requests in the merge queue of the branch have to be removed
+			pull_service.AddToMergeQueueCheck(ctx.Repo.Repository.ID, protectBranch.BranchName)
 		}
 		ctx.Flash.Success(ctx.Tr("repo.settings.remove_protected_branch_success", branch))
 		ctx.Redirect(fmt.Sprintf("%s/settings/branches", ctx.Repo.RepoLink))
@@ -330,7 +330,12 @@ func renderDirectory(ctx *context.Context, treeLink string) {
 				if markupType := markup.Type(readmeFile.name); markupType != "" {
 					ctx.Data["IsMarkup"] = true
 					ctx.Data["MarkupType"] = string(markupType)
-					ctx.Data["FileContent"] = string(markup.Render(readmeFile.name, buf, readmeTreelink, ctx.Repo.Repository.ComposeDocumentMetas()))
+					if markup.IsIFrameRenderedFile(readmeFile.name) {
+						ctx.Data["RenderAsIFrame"] = true
+						ctx.Data["RenderFileLink"] = ctx.Repo.RepoLink + "/render/" + ctx.Repo.BranchNameSubURL() + "/" + path.Join(ctx.Repo.TreePath, readmeFile.name)
+					} else {
+						ctx.Data["FileContent"] = string(markup.Render(readmeFile.name, buf, readmeTreelink, ctx.Repo.Repository.ComposeDocumentMetas()))
+					}
 				} else {
 					ctx.Data["IsRenderedHTML"] = true
 					ctx.Data["FileContent"] = strings.ReplaceAll(
@@ -488,7 +493,12 @@ func renderFile(ctx *context.Context, entry *git.TreeEntry, treeLink, rawLink st
 		if markupType := markup.Type(blob.Name()); markupType != "" {
 			ctx.Data["IsMarkup"] = true
 			ctx.Data["MarkupType"] = markupType
-			ctx.Data["FileContent"] = string(markup.Render(blob.Name(), buf, path.Dir(treeLink), ctx.Repo.Repository.ComposeDocumentMetas()))
+			if markup.IsIFrameRenderedFile(blob.Name()) {
+				ctx.Data["RenderAsIFrame"] = true
+				ctx.Data["RenderFileLink"] = ctx.Repo.RepoLink + "/render/" + ctx.Repo.BranchNameSubURL() + "/" + ctx.Repo.TreePath
+			} else {
+				ctx.Data["FileContent"] = string(markup.Render(blob.Name(), buf, path.Dir(treeLink), ctx.Repo.Repository.ComposeDocumentMetas()))
+			}
 		} else if readmeExist {
 			ctx.Data["IsRenderedHTML"] = true
 			ctx.Data["FileContent"] = strings.ReplaceAll(
@@ -536,7 +546,12 @@ func renderFile(ctx *context.Context, entry *git.TreeEntry, treeLink, rawLink st
 			buf = append(buf, d...)
 			ctx.Data["IsMarkup"] = true
 			ctx.Data["MarkupType"] = markupType
-			ctx.Data["FileContent"] = string(markup.Render(blob.Name(), buf, path.Dir(treeLink), ctx.Repo.Repository.ComposeDocumentMetas()))
+			if markup.IsIFrameRenderedFile(blob.Name()) {
+				ctx.Data["RenderAsIFrame"] = true
+				ctx.Data["RenderFileLink"] = ctx.Repo.RepoLink + "/render/" + ctx.Repo.BranchNameSubURL() + "/" + ctx.Repo.TreePath
+			} else {
+				ctx.Data["FileContent"] = string(markup.Render(blob.Name(), buf, path.Dir(treeLink), ctx.Repo.Repository.ComposeDocumentMetas()))
+			}
 		}
 	}
 
@@ -29,6 +29,7 @@ import (
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers"
 	"code.gitea.io/gitea/routers/admin"
+	"code.gitea.io/gitea/routers/api/scim"
 	apiv1 "code.gitea.io/gitea/routers/api/v1"
 	"code.gitea.io/gitea/routers/api/v1/misc"
 	"code.gitea.io/gitea/routers/dev"
@@ -106,6 +107,7 @@ func NormalRoutes() *web.Route {
 	r.Mount("/", WebRoutes())
 	r.Mount("/api/v1", apiv1.Routes())
 	r.Mount("/api/internal", private.Routes())
+	r.Mount("/api/scim/v2", scim.Routes())
 	return r
 }
 
@@ -365,6 +367,10 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/{provider}", user.SignInOAuth)
 			m.Get("/{provider}/callback", user.SignInOAuthCallback)
 		})
+		m.Group("/saml", func() {
+			m.Get("/{provider}", user.SignInSAML)
+			m.Post("/{provider}/acs", user.SignInSAMLCallback)
+		})
 		m.Get("/link_account", user.LinkAccount)
 		m.Post("/link_account_signin", bindIgnErr(auth.SignInForm{}), user.LinkAccountPostSignIn)
 		m.Post("/link_account_signup", bindIgnErr(auth.RegisterForm{}), user.LinkAccountPostRegister)
@@ -383,6 +389,7 @@ func RegisterRoutes(m *web.Route) {
 	}, reqSignOut)
 
 	m.Any("/user/events", reqSignIn, events.Events)
+	m.Get("/user/saml/{provider}/metadata", user.SAMLMetadata)
 
 	m.Group("/login/oauth", func() {
 		m.Get("/authorize", bindIgnErr(auth.AuthorizationForm{}), user.AuthorizeOAuth)
@@ -402,6 +409,17 @@ func RegisterRoutes(m *web.Route) {
 	} else {
 		m.Post("/login/oauth/access_token", bindIgnErr(auth.AccessTokenForm{}), ignSignInAndCsrf, user.AccessTokenOAuth)
 	}
+	m.Group("/login/oauth", func() {
+		m.Get("/keys", user.OIDCKeys)
+		m.Combo("/userinfo").Get(user.InfoOAuth).Post(user.InfoOAuth)
+		m.Post("/introspect", bindIgnErr(auth.OAuth2TokenForm{}), user.IntrospectOAuth)
+		m.Post("/revoke", bindIgnErr(auth.OAuth2TokenForm{}), user.RevokeOAuth)
+		m.Post("/device_authorization", bindIgnErr(auth.DeviceAuthorizationForm{}), user.DeviceAuthorizationOAuth)
+	}, ignSignInAndCsrf)
+	m.Combo("/login/device", reqSignIn).
+		Get(user.DeviceVerification).
+		Post(bindIgnErr(auth.DeviceVerificationForm{}), user.DeviceVerificationPost)
+	m.Get("/.well-known/openid-configuration", user.OIDCWellKnown)
 
 	m.Group("/user/settings", func() {
 		m.Get("", userSetting.Profile)
@@ -470,6 +488,7 @@ func RegisterRoutes(m *web.Route) {
 		m.Get("/forgot_password", user.ForgotPasswd)
 		m.Post("/forgot_password", user.ForgotPasswdPost)
 		m.Post("/logout", user.SignOut)
+		m.Post("/impersonation/stop", reqSignIn, user.StopImpersonation)
 		m.Get("/task/{task}", user.TaskStatus)
 	})
 	// ***** END: User *****
@@ -493,6 +512,10 @@ func RegisterRoutes(m *web.Route) {
 				m.Post("/add", admin.AddWorkers)
 				m.Post("/cancel/{pid}", admin.WorkerCancel)
 				m.Post("/flush", admin.Flush)
+				m.Post("/pause", admin.PauseQueue)
+				m.Post("/resume", admin.ResumeQueue)
+				m.Post("/export", admin.ExportQueue)
+				m.Post("/import", admin.ImportQueue)
 			})
 		})
 
@@ -501,6 +524,7 @@ func RegisterRoutes(m *web.Route) {
 			m.Combo("/new").Get(admin.NewUser).Post(bindIgnErr(auth.AdminCreateUserForm{}), admin.NewUserPost)
 			m.Combo("/{userid}").Get(admin.EditUser).Post(bindIgnErr(auth.AdminEditUserForm{}), admin.EditUserPost)
 			m.Post("/{userid}/delete", admin.DeleteUser)
+			m.Post("/{userid}/impersonate", admin.ImpersonateUser)
 		})
 
 		m.Group("/emails", func() {
@@ -1009,14 +1033,18 @@ func RegisterRoutes(m *web.Route) {
 			m.Get(".patch", repo.DownloadPullPatch)
 			m.Get("/commits", context.RepoRef(), repo.ViewPullCommits)
 			m.Post("/merge", context.RepoMustNotBeArchived(), bindIgnErr(auth.MergePullRequestForm{}), repo.MergePullRequest)
+			m.Post("/merge_queue/remove", reqSignIn, context.RepoMustNotBeArchived(), repo.RemoveFromMergeQueue)
 			m.Post("/update", repo.UpdatePullRequest)
 			m.Post("/cleanup", context.RepoMustNotBeArchived(), context.RepoRef(), repo.CleanUpPullRequest)
+			m.Post("/allow_maintainer_edit", reqSignIn, context.RepoMustNotBeArchived(), repo.SetAllowMaintainerEdit)
 			m.Group("/files", func() {
 				m.Get("", context.RepoRef(), repo.SetEditorconfigIfExists, repo.SetDiffViewStyle, repo.SetWhitespaceBehavior, repo.ViewPullFiles)
+				m.Post("/viewed", reqSignIn, bindIgnErr(auth.ViewedFileForm{}), repo.UpdateViewedFile)
 				m.Group("/reviews", func() {
 					m.Get("/new_comment", repo.RenderNewCodeCommentForm)
 					m.Post("/comments", bindIgnErr(auth.CodeCommentForm{}), repo.CreateCodeComment)
 					m.Post("/submit", bindIgnErr(auth.SubmitReviewForm{}), repo.SubmitReview)
+					m.Post("/suggestions", reqSignIn, bindIgnErr(auth.ApplySuggestionsForm{}), repo.ApplySuggestions)
 				}, context.RepoMustNotBeArchived())
 			})
 		}, repo.MustAllowPulls)
@@ -1039,6 +1067,12 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/*", context.RepoRefByType(context.RepoRefLegacy), repo.SingleDownload)
 		}, repo.MustBeNotEmpty, reqRepoCodeReader)
 
+		m.Group("/render", func() {
+			m.Get("/branch/*", context.RepoRefByType(context.RepoRefBranch), repo.RenderFile)
+			m.Get("/tag/*", context.RepoRefByType(context.RepoRefTag), repo.RenderFile)
+			m.Get("/commit/*", context.RepoRefByType(context.RepoRefCommit), repo.RenderFile)
+		}, repo.MustBeNotEmpty, reqRepoCodeReader)
+
 		m.Group("/commits", func() {
 			m.Get("/branch/*", context.RepoRefByType(context.RepoRefBranch), repo.RefCommits)
 			m.Get("/tag/*", context.RepoRefByType(context.RepoRefTag), repo.RefCommits)
@@ -27,6 +27,7 @@ import (
 	"code.gitea.io/gitea/modules/web/middleware"
 	"code.gitea.io/gitea/routers/utils"
 	"code.gitea.io/gitea/services/externalaccount"
+	"code.gitea.io/gitea/services/lockout"
 	"code.gitea.io/gitea/services/mailer"
 
 	"github.com/markbates/goth"
@@ -140,6 +141,11 @@ func SignIn(ctx *context.Context) {
 	}
 	ctx.Data["OrderedOAuth2Names"] = orderedOAuth2Names
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	ctx.Data["SAMLProviders"], err = models.ActiveLoginSources(models.LoginSAML)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -160,6 +166,11 @@ func SignInPost(ctx *context.Context) {
 	}
 	ctx.Data["OrderedOAuth2Names"] = orderedOAuth2Names
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	ctx.Data["SAMLProviders"], err = models.ActiveLoginSources(models.LoginSAML)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -172,11 +183,14 @@ func SignInPost(ctx *context.Context) {
 	}
 
 	form := web.GetForm(ctx).(*auth.SignInForm)
-	u, err := models.UserSignIn(form.UserName, form.Password)
+	u, err := lockout.UserSignIn(form.UserName, form.Password, ctx.RemoteAddr())
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplSignIn, &form)
 			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
+		} else if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+			ctx.RenderWithErr(ctx.Tr("auth.login_too_many_failed_attempts"), tplSignIn, &form)
+			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
 		} else if models.IsErrEmailAlreadyUsed(err) {
 			ctx.RenderWithErr(ctx.Tr("form.email_been_used"), tplSignIn, &form)
 			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
@@ -614,6 +628,12 @@ func SignInOAuthCallback(ctx *context.Context) {
 
 func handleOAuth2SignIn(u *models.User, gothUser goth.User, ctx *context.Context, err error) {
 	if err != nil {
+		if externalaccount.IsErrRequiredGroupMissing(err) {
+			log.Info("Failed authentication attempt for %s from %s: %v", gothUser.UserID, ctx.RemoteAddr(), err)
+			ctx.Flash.Error(ctx.Tr("auth.oauth_signin_group_required"))
+			ctx.Redirect(setting.AppSubURL + "/user/login")
+			return
+		}
 		ctx.ServerError("UserSignIn", err)
 		return
 	}
@@ -708,6 +728,10 @@ func oAuth2UserLoginCallback(loginSource *models.LoginSource, request *http.Requ
 		return nil, goth.User{}, err
 	}
 
+	if err := externalaccount.CheckRequiredGroup(loginSource, gothUser); err != nil {
+		return nil, gothUser, err
+	}
+
 	user := &models.User{
 		LoginName:   gothUser.UserID,
 		LoginType:   models.LoginOAuth2,
@@ -720,6 +744,9 @@ func oAuth2UserLoginCallback(loginSource *models.LoginSource, request *http.Requ
 	}
 
 	if hasUser {
+		if err := externalaccount.SyncGroups(user, loginSource, gothUser); err != nil {
+			log.Error("SyncGroups failed for user %s: %v", user.Name, err)
+		}
 		return user, gothUser, nil
 	}
 
@@ -734,7 +761,13 @@ func oAuth2UserLoginCallback(loginSource *models.LoginSource, request *http.Requ
 	}
 	if hasUser {
 		user, err = models.GetUserByID(externalLoginUser.UserID)
-		return user, gothUser, err
+		if err != nil {
+			return nil, goth.User{}, err
+		}
+		if err := externalaccount.SyncGroups(user, loginSource, gothUser); err != nil {
+			log.Error("SyncGroups failed for user %s: %v", user.Name, err)
+		}
+		return user, gothUser, nil
 	}
 
 	// no user found to login
@@ -825,11 +858,14 @@ func LinkAccountPostSignIn(ctx *context.Context) {
 		return
 	}
 
-	u, err := models.UserSignIn(signInForm.UserName, signInForm.Password)
+	u, err := lockout.UserSignIn(signInForm.UserName, signInForm.Password, ctx.RemoteAddr())
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
 			ctx.Data["user_exists"] = true
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplLinkAccount, &signInForm)
+		} else if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+			ctx.Data["user_exists"] = true
+			ctx.RenderWithErr(ctx.Tr("auth.login_too_many_failed_attempts"), tplLinkAccount, &signInForm)
 		} else {
 			ctx.ServerError("UserLinkAccount", err)
 		}
@@ -1005,6 +1041,10 @@ func LinkAccountPostRegister(ctx *context.Context) {
 	}
 	log.Trace("Account created: %s", u.Name)
 
+	if err := externalaccount.SyncGroups(u, loginSource, gothUser.(goth.User)); err != nil {
+		log.Error("SyncGroups failed for user %s: %v", u.Name, err)
+	}
+
 	// Auto-set admin for the only user.
 	if models.CountUsers() == 1 {
 		u.IsAdmin = true
@@ -1052,6 +1092,12 @@ func HandleSignOut(ctx *context.Context) {
 
 // SignOut sign out from login status
 func SignOut(ctx *context.Context) {
+	// signing out while viewing the site as another user must not sign out the sessions of the user
+	if ctx.IsImpersonating() {
+		StopImpersonation(ctx)
+		return
+	}
+
 	if ctx.User != nil {
 		eventsource.GetManager().SendMessageBlocking(ctx.User.ID, &eventsource.Event{
 			Name: "logout",
@@ -1062,6 +1108,23 @@ func SignOut(ctx *context.Context) {
 	ctx.Redirect(setting.AppSubURL + "/")
 }
 
+// StopImpersonation signs the administrator back in after viewing the site as another user
+func StopImpersonation(ctx *context.Context) {
+	if !ctx.IsImpersonating() {
+		ctx.Redirect(setting.AppSubURL + "/")
+		return
+	}
+
+	uid := ctx.User.ID
+	if err := ctx.StopImpersonation(); err != nil {
+		ctx.ServerError("StopImpersonation", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+	ctx.Redirect(fmt.Sprintf("%s/admin/users/%d", setting.AppSubURL, uid))
+}
+
 // SignUp render the register page
 func SignUp(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("sign_up")
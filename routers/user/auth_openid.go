@@ -21,6 +21,7 @@ import (
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/modules/web/middleware"
+	"code.gitea.io/gitea/services/lockout"
 	"code.gitea.io/gitea/services/mailer"
 )
 
@@ -290,10 +291,12 @@ func ConnectOpenIDPost(ctx *context.Context) {
 	ctx.Data["EnableOpenIDSignUp"] = setting.Service.EnableOpenIDSignUp
 	ctx.Data["OpenID"] = oid
 
-	u, err := models.UserSignIn(form.UserName, form.Password)
+	u, err := lockout.UserSignIn(form.UserName, form.Password, ctx.RemoteAddr())
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplConnectOID, &form)
+		} else if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+			ctx.RenderWithErr(ctx.Tr("auth.login_too_many_failed_attempts"), tplConnectOID, &form)
 		} else {
 			ctx.ServerError("ConnectOpenIDPost", err)
 		}
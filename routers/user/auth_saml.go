@@ -0,0 +1,183 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/auth/saml"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/web/middleware"
+)
+
+// samlRequestIDSessionKey is the session key of the ID of the pending authentication request
+const samlRequestIDSessionKey = "saml_request_id"
+
+func getSAMLLoginSource(ctx *context.Context) *models.LoginSource {
+	source, err := models.GetActiveSAMLLoginSourceByName(ctx.Params(":provider"))
+	if err != nil {
+		if models.IsErrLoginSourceNotExist(err) {
+			ctx.NotFound("GetActiveSAMLLoginSourceByName", err)
+		} else {
+			ctx.ServerError("GetActiveSAMLLoginSourceByName", err)
+		}
+		return nil
+	}
+	return source
+}
+
+// SignInSAML sends the user to the identity provider of a SAML login source
+func SignInSAML(ctx *context.Context) {
+	source := getSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	requestID, redirectURL, postBody, err := source.SAML().AuthRequest(source.SAMLEntityID(), source.SAMLAssertionConsumerServiceURL())
+	if err != nil {
+		ctx.ServerError("AuthRequest", err)
+		return
+	}
+	if err := ctx.Session.Set(samlRequestIDSessionKey, requestID); err != nil {
+		ctx.ServerError("Session.Set", err)
+		return
+	}
+
+	if postBody != nil {
+		ctx.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ctx.Resp.WriteHeader(http.StatusOK)
+		if _, err := ctx.Resp.Write(postBody); err != nil {
+			log.Error("Unable to write SAML request: %v", err)
+		}
+		return
+	}
+	ctx.Redirect(redirectURL)
+}
+
+// SignInSAMLCallback consumes the response the identity provider posts after authentication
+func SignInSAMLCallback(ctx *context.Context) {
+	source := getSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	// The session cookie is not sent along with the cross-site POST of the identity provider
+	// unless SameSite is none, so the response is posted once more from this site
+	if setting.SessionConfig.SameSite != http.SameSiteNoneMode && ctx.Req.PostFormValue("same_site") == "" {
+		body, err := saml.RepostBody(source.SAMLAssertionConsumerServiceURL(), ctx.Req.PostFormValue("SAMLResponse"))
+		if err != nil {
+			ctx.ServerError("RepostBody", err)
+			return
+		}
+		ctx.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ctx.Resp.WriteHeader(http.StatusOK)
+		if _, err := ctx.Resp.Write(body); err != nil {
+			log.Error("Unable to write SAML response: %v", err)
+		}
+		return
+	}
+
+	requestID, _ := ctx.Session.Get(samlRequestIDSessionKey).(string)
+	if err := ctx.Session.Delete(samlRequestIDSessionKey); err != nil {
+		log.Error("Unable to delete SAML request ID from session: %v", err)
+	}
+
+	assertion, err := source.SAML().Authenticate(source.SAMLEntityID(), source.SAMLAssertionConsumerServiceURL(), ctx.Req.PostFormValue("SAMLResponse"), requestID)
+	if err != nil {
+		log.Warn("Failed to validate SAML response of %s: %v", source.Name, err)
+		if err == saml.ErrNotInRequiredGroup {
+			ctx.Flash.Error(ctx.Tr("auth.saml_not_in_required_group", source.Name))
+		} else {
+			ctx.Flash.Error(ctx.Tr("auth.saml_invalid_response", source.Name))
+		}
+		ctx.Redirect(setting.AppSubURL + "/user/login")
+		return
+	}
+
+	u, err := models.LoginViaSAML(source, assertion)
+	if err != nil {
+		switch {
+		case models.IsErrUserNotExist(err):
+			ctx.Flash.Error(ctx.Tr("auth.saml_no_account", source.Name))
+		case models.IsErrUserAlreadyExist(err):
+			ctx.Flash.Error(ctx.Tr("form.username_been_taken"))
+		case models.IsErrEmailAlreadyUsed(err):
+			ctx.Flash.Error(ctx.Tr("form.email_been_used"))
+		case models.IsErrNameReserved(err), models.IsErrNamePatternNotAllowed(err), models.IsErrNameCharsNotAllowed(err):
+			ctx.Flash.Error(ctx.Tr("auth.saml_invalid_username", assertion.Username))
+		case models.IsErrUserProhibitLogin(err):
+			ctx.Data["Title"] = ctx.Tr("auth.prohibit_login")
+			ctx.HTML(200, "user/auth/prohibit_login")
+			return
+		default:
+			ctx.ServerError("LoginViaSAML", err)
+			return
+		}
+		ctx.Redirect(setting.AppSubURL + "/user/login")
+		return
+	}
+
+	handleSAMLSignIn(ctx, u)
+}
+
+func handleSAMLSignIn(ctx *context.Context, u *models.User) {
+	// If this user is enrolled in 2FA, we can't sign the user in just yet.
+	// Instead, redirect them to the 2FA authentication page.
+	_, err := models.GetTwoFactorByUID(u.ID)
+	if err != nil {
+		if !models.IsErrTwoFactorNotEnrolled(err) {
+			ctx.ServerError("UserSignIn", err)
+			return
+		}
+		handleSignIn(ctx, u, false)
+		return
+	}
+
+	// User needs to use 2FA, save data and redirect to 2FA page.
+	if err := ctx.Session.Set("twofaUid", u.ID); err != nil {
+		log.Error("Error setting twofaUid in session: %v", err)
+	}
+	if err := ctx.Session.Set("twofaRemember", false); err != nil {
+		log.Error("Error setting twofaRemember in session: %v", err)
+	}
+	if err := ctx.Session.Release(); err != nil {
+		log.Error("Error storing session: %v", err)
+	}
+
+	// Clear whatever CSRF has right now, force to generate a new one
+	middleware.DeleteCSRFCookie(ctx.Resp)
+
+	// If U2F is enrolled -> Redirect to U2F instead
+	regs, err := models.GetU2FRegistrationsByUID(u.ID)
+	if err == nil && len(regs) > 0 {
+		ctx.Redirect(setting.AppSubURL + "/user/u2f")
+		return
+	}
+
+	ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+}
+
+// SAMLMetadata serves the service provider metadata of a SAML login source
+func SAMLMetadata(ctx *context.Context) {
+	source := getSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	metadata, err := source.SAML().Metadata(source.SAMLEntityID(), source.SAMLAssertionConsumerServiceURL())
+	if err != nil {
+		ctx.ServerError("Metadata", err)
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/samlmetadata+xml")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	if _, err := ctx.Resp.Write(metadata); err != nil {
+		log.Error("Unable to write SAML metadata: %v", err)
+	}
+}
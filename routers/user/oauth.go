@@ -8,10 +8,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"html"
+	"net/http"
 	"net/url"
 	"strings"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/auth/oauth2"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	auth "code.gitea.io/gitea/modules/forms"
@@ -25,8 +27,12 @@ import (
 )
 
 const (
-	tplGrantAccess base.TplName = "user/auth/grant"
-	tplGrantError  base.TplName = "user/auth/grant_error"
+	tplGrantAccess        base.TplName = "user/auth/grant"
+	tplGrantError         base.TplName = "user/auth/grant_error"
+	tplDeviceVerification base.TplName = "user/auth/device"
+
+	// grantTypeDeviceCode is the grant type of the device authorization grant (RFC 8628)
+	grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
 )
 
 // TODO move error and responses to SDK or models
@@ -79,6 +85,14 @@ const (
 	AccessTokenErrorCodeUnsupportedGrantType = "unsupported_grant_type"
 	// AccessTokenErrorCodeInvalidScope represents an error code specified in RFC 6749
 	AccessTokenErrorCodeInvalidScope = "invalid_scope"
+	// AccessTokenErrorCodeAuthorizationPending represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAuthorizationPending = "authorization_pending"
+	// AccessTokenErrorCodeSlowDown represents an error code specified in RFC 8628
+	AccessTokenErrorCodeSlowDown = "slow_down"
+	// AccessTokenErrorCodeAccessDenied represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAccessDenied = "access_denied"
+	// AccessTokenErrorCodeExpiredToken represents an error code specified in RFC 8628
+	AccessTokenErrorCodeExpiredToken = "expired_token"
 )
 
 // AccessTokenError represents an error response specified in RFC 6749
@@ -165,6 +179,21 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 				ErrorDescription: "cannot find application",
 			}
 		}
+		user, err := models.GetUserByID(grant.UserID)
+		if err != nil {
+			return nil, &AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot find user",
+			}
+		}
+		claims, err := getOIDCClaims(user, grant)
+		if err != nil {
+			log.Error("Error loading OpenID Connect claims of user %s: %v", user.Name, err)
+			return nil, &AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot load user claims",
+			}
+		}
 		idToken := &models.OIDCToken{
 			StandardClaims: jwt.StandardClaims{
 				ExpiresAt: expirationDate.AsTime().Unix(),
@@ -172,9 +201,22 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 				Audience:  app.ClientID,
 				Subject:   fmt.Sprint(grant.UserID),
 			},
-			Nonce: grant.Nonce,
+			Nonce:      grant.Nonce,
+			OIDCClaims: claims,
+		}
+
+		// symmetric keys are shared with the server, so id_tokens are signed with the client secret instead
+		signingKey := oauth2.DefaultSigningKey
+		if signingKey.IsSymmetric() {
+			signingKey, err = oauth2.CreateJWTSigningKey(signingKey.SigningMethod().Alg(), []byte(clientSecret))
+			if err != nil {
+				return nil, &AccessTokenError{
+					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+					ErrorDescription: "cannot create signing key",
+				}
+			}
 		}
-		signedIDToken, err = idToken.SignToken(clientSecret)
+		signedIDToken, err = idToken.SignToken(signingKey)
 		if err != nil {
 			return nil, &AccessTokenError{
 				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
@@ -192,6 +234,57 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 	}, nil
 }
 
+// getOIDCClaims returns the claims about the user the grant gives access to
+func getOIDCClaims(user *models.User, grant *models.OAuth2Grant) (models.OIDCClaims, error) {
+	var claims models.OIDCClaims
+	if grant.ScopeContains("profile") {
+		claims.Name = user.FullName
+		claims.PreferredUsername = user.Name
+		claims.Profile = user.HTMLURL()
+		claims.Picture = user.AvatarLink()
+		claims.Website = user.Website
+		claims.Locale = user.Language
+		claims.UpdatedAt = user.UpdatedUnix
+	}
+	if grant.ScopeContains("email") {
+		claims.Email = user.Email
+		claims.EmailVerified = user.IsActive
+	}
+	if grant.ScopeContains("groups") {
+		groups, err := getOAuthGroupsForUser(user)
+		if err != nil {
+			return claims, err
+		}
+		claims.Groups = groups
+	}
+	return claims, nil
+}
+
+// getOAuthGroupsForUser returns the names of the organizations and, as "org:team", the teams the user is a member of
+func getOAuthGroupsForUser(user *models.User) ([]string, error) {
+	orgs, err := models.GetOrgsByUserID(user.ID, true)
+	if err != nil {
+		return nil, fmt.Errorf("GetOrgsByUserID: %v", err)
+	}
+	teams, err := models.GetUserTeams(user.ID, models.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTeams: %v", err)
+	}
+
+	orgNames := make(map[int64]string, len(orgs))
+	groups := make([]string, 0, len(orgs)+len(teams))
+	for _, org := range orgs {
+		orgNames[org.ID] = org.Name
+		groups = append(groups, org.Name)
+	}
+	for _, team := range teams {
+		if orgName, ok := orgNames[team.OrgID]; ok {
+			groups = append(groups, orgName+":"+team.Name)
+		}
+	}
+	return groups, nil
+}
+
 // AuthorizeOAuth manages authorize requests
 func AuthorizeOAuth(ctx *context.Context) {
 	form := web.GetForm(ctx).(*auth.AuthorizationForm)
@@ -392,28 +485,16 @@ func GrantApplicationOAuth(ctx *context.Context) {
 func AccessTokenOAuth(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*auth.AccessTokenForm)
 	if form.ClientID == "" {
-		authHeader := ctx.Req.Header.Get("Authorization")
-		authContent := strings.SplitN(authHeader, " ", 2)
-		if len(authContent) == 2 && authContent[0] == "Basic" {
-			payload, err := base64.StdEncoding.DecodeString(authContent[1])
-			if err != nil {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "cannot parse basic auth header",
-				})
-				return
-			}
-			pair := strings.SplitN(string(payload), ":", 2)
-			if len(pair) != 2 {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "cannot parse basic auth header",
-				})
-				return
-			}
-			form.ClientID = pair[0]
-			form.ClientSecret = pair[1]
+		clientID, clientSecret, err := parseBasicAuth(ctx)
+		if err != nil {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: err.Error(),
+			})
+			return
 		}
+		form.ClientID = clientID
+		form.ClientSecret = clientSecret
 	}
 	switch form.GrantType {
 	case "refresh_token":
@@ -422,10 +503,13 @@ func AccessTokenOAuth(ctx *context.Context) {
 	case "authorization_code":
 		handleAuthorizationCode(ctx, form)
 		return
+	case grantTypeDeviceCode:
+		handleDeviceCode(ctx, form)
+		return
 	default:
 		handleAccessTokenError(ctx, AccessTokenError{
 			ErrorCode:        AccessTokenErrorCodeUnsupportedGrantType,
-			ErrorDescription: "Only refresh_token or authorization_code grant type is supported",
+			ErrorDescription: "Only refresh_token, authorization_code or device_code grant type is supported",
 		})
 	}
 }
@@ -529,6 +613,99 @@ func handleAuthorizationCode(ctx *context.Context, form auth.AccessTokenForm) {
 	ctx.JSON(200, resp)
 }
 
+func handleDeviceCode(ctx *context.Context, form auth.AccessTokenForm) {
+	app, err := models.GetOAuth2ApplicationByClientID(form.ClientID)
+	if err != nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidClient,
+			ErrorDescription: fmt.Sprintf("cannot load client with client id: '%s'", form.ClientID),
+		})
+		return
+	}
+	if !app.ValidateClientSecret([]byte(form.ClientSecret)) {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeUnauthorizedClient,
+			ErrorDescription: "client is not authorized",
+		})
+		return
+	}
+	deviceCode, err := models.GetOAuth2DeviceCodeByDeviceCode(form.DeviceCode)
+	if err != nil || deviceCode == nil || deviceCode.ApplicationID != app.ID {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "invalid device code",
+		})
+		return
+	}
+	if deviceCode.IsExpired() {
+		if err := deviceCode.Invalidate(); err != nil {
+			log.Error("Unable to delete expired device code: %v", err)
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeExpiredToken,
+			ErrorDescription: "device code has expired",
+		})
+		return
+	}
+
+	switch deviceCode.Status {
+	case models.OAuth2DeviceCodePending:
+		slowDown, err := deviceCode.Poll()
+		if err != nil {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot proceed your request",
+			})
+			return
+		}
+		if slowDown {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeSlowDown,
+				ErrorDescription: fmt.Sprintf("polling too fast, wait at least %d seconds between requests", deviceCode.PollInterval),
+			})
+			return
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAuthorizationPending,
+			ErrorDescription: "user has not yet approved the request",
+		})
+		return
+	case models.OAuth2DeviceCodeDenied:
+		if err := deviceCode.Invalidate(); err != nil {
+			log.Error("Unable to delete denied device code: %v", err)
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAccessDenied,
+			ErrorDescription: "user denied the request",
+		})
+		return
+	}
+
+	// remove device code from database to deny duplicate usage
+	if err := deviceCode.Invalidate(); err != nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "invalid device code",
+		})
+		return
+	}
+	grant, err := app.GetGrantByUserID(deviceCode.UserID)
+	if err != nil || grant == nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "grant does not exist",
+		})
+		return
+	}
+	resp, tokenErr := newAccessTokenResponse(grant, form.ClientSecret)
+	if tokenErr != nil {
+		handleAccessTokenError(ctx, *tokenErr)
+		return
+	}
+	// send successful response
+	ctx.JSON(200, resp)
+}
+
 func handleAccessTokenError(ctx *context.Context, acErr AccessTokenError) {
 	ctx.JSON(400, acErr)
 }
@@ -560,3 +737,393 @@ func handleAuthorizeError(ctx *context.Context, authErr AuthorizeError, redirect
 	redirect.RawQuery = q.Encode()
 	ctx.Redirect(redirect.String(), 302)
 }
+
+// parseBasicAuth returns the client credentials of a "Basic" Authorization header, if present
+func parseBasicAuth(ctx *context.Context) (clientID, clientSecret string, err error) {
+	authContent := strings.SplitN(ctx.Req.Header.Get("Authorization"), " ", 2)
+	if len(authContent) != 2 || authContent[0] != "Basic" {
+		return "", "", nil
+	}
+	payload, err := base64.StdEncoding.DecodeString(authContent[1])
+	if err != nil {
+		return "", "", fmt.Errorf("cannot parse basic auth header")
+	}
+	pair := strings.SplitN(string(payload), ":", 2)
+	if len(pair) != 2 {
+		return "", "", fmt.Errorf("cannot parse basic auth header")
+	}
+	return pair[0], pair[1], nil
+}
+
+// authenticateClient returns the application of the client authenticated by the request,
+// responding with an invalid_client error if the credentials are missing or wrong
+func authenticateClient(ctx *context.Context, form *auth.OAuth2TokenForm) *models.OAuth2Application {
+	clientID, clientSecret, err := parseBasicAuth(ctx)
+	if err == nil && clientID == "" {
+		clientID, clientSecret = form.ClientID, form.ClientSecret
+	}
+	if err == nil && clientID != "" {
+		app, err := models.GetOAuth2ApplicationByClientID(clientID)
+		if err == nil && app.ValidateClientSecret([]byte(clientSecret)) {
+			return app
+		}
+		if err != nil && !models.IsErrOauthClientIDInvalid(err) {
+			ctx.ServerError("GetOAuth2ApplicationByClientID", err)
+			return nil
+		}
+	}
+
+	ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="`+setting.AppName+`"`)
+	ctx.JSON(http.StatusUnauthorized, AccessTokenError{
+		ErrorCode:        AccessTokenErrorCodeInvalidClient,
+		ErrorDescription: "client authentication failed",
+	})
+	return nil
+}
+
+// OIDCWellKnownResponse represents the OpenID Connect discovery document
+type OIDCWellKnownResponse struct {
+	Issuer                                    string   `json:"issuer"`
+	AuthorizationEndpoint                     string   `json:"authorization_endpoint"`
+	TokenEndpoint                             string   `json:"token_endpoint"`
+	UserinfoEndpoint                          string   `json:"userinfo_endpoint"`
+	JWKSURI                                   string   `json:"jwks_uri,omitempty"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	RevocationEndpoint                        string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint               string   `json:"device_authorization_endpoint"`
+	ScopesSupported                           []string `json:"scopes_supported"`
+	ResponseTypesSupported                    []string `json:"response_types_supported"`
+	GrantTypesSupported                       []string `json:"grant_types_supported"`
+	SubjectTypesSupported                     []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported          []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported         []string `json:"token_endpoint_auth_methods_supported"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethodsSupported    []string `json:"revocation_endpoint_auth_methods_supported"`
+	ClaimsSupported                           []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported             []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCWellKnown serves the OpenID Connect discovery document
+func OIDCWellKnown(ctx *context.Context) {
+	if oauth2.DefaultSigningKey == nil {
+		ctx.NotFound("OIDCWellKnown", nil)
+		return
+	}
+
+	authMethods := []string{"client_secret_basic", "client_secret_post"}
+	resp := &OIDCWellKnownResponse{
+		Issuer:                      setting.AppURL,
+		AuthorizationEndpoint:       setting.AppURL + "login/oauth/authorize",
+		TokenEndpoint:               setting.AppURL + "login/oauth/access_token",
+		UserinfoEndpoint:            setting.AppURL + "login/oauth/userinfo",
+		IntrospectionEndpoint:       setting.AppURL + "login/oauth/introspect",
+		RevocationEndpoint:          setting.AppURL + "login/oauth/revoke",
+		DeviceAuthorizationEndpoint: setting.AppURL + "login/oauth/device_authorization",
+		ScopesSupported:             []string{"openid", "profile", "email", "groups"},
+		ResponseTypesSupported:      []string{"code"},
+		GrantTypesSupported:         []string{"authorization_code", "refresh_token", grantTypeDeviceCode},
+		SubjectTypesSupported:       []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{
+			oauth2.DefaultSigningKey.SigningMethod().Alg(),
+		},
+		TokenEndpointAuthMethodsSupported:         authMethods,
+		IntrospectionEndpointAuthMethodsSupported: authMethods,
+		RevocationEndpointAuthMethodsSupported:    authMethods,
+		ClaimsSupported: []string{
+			"aud", "exp", "iat", "iss", "sub", "nonce",
+			"name", "preferred_username", "profile", "picture", "website", "locale", "updated_at",
+			"email", "email_verified",
+			"groups",
+		},
+		CodeChallengeMethodsSupported: []string{"plain", "S256"},
+	}
+	if !oauth2.DefaultSigningKey.IsSymmetric() {
+		resp.JWKSURI = setting.AppURL + "login/oauth/keys"
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// OIDCKeys serves the public signing keys as JSON Web Key Set
+func OIDCKeys(ctx *context.Context) {
+	if oauth2.DefaultSigningKey == nil || oauth2.DefaultSigningKey.IsSymmetric() {
+		ctx.NotFound("OIDCKeys", nil)
+		return
+	}
+
+	jwks, err := oauth2.NewJSONWebKeySet(oauth2.DefaultSigningKey)
+	if err != nil {
+		ctx.ServerError("NewJSONWebKeySet", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, jwks)
+}
+
+// userInfoResponse represents a successful userinfo response
+type userInfoResponse struct {
+	Subject string `json:"sub"`
+	models.OIDCClaims
+}
+
+// InfoOAuth responds with the claims about the user of an access token
+func InfoOAuth(ctx *context.Context) {
+	var accessToken string
+	if fields := strings.Fields(ctx.Req.Header.Get("Authorization")); len(fields) == 2 && strings.EqualFold(fields[0], "bearer") {
+		accessToken = fields[1]
+	} else {
+		accessToken = ctx.Req.FormValue("access_token")
+	}
+
+	grant := getGrantOfAccessToken(accessToken)
+	if grant == nil || !grant.ScopeContains("openid") {
+		ctx.Resp.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.Error(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := models.GetUserByID(grant.UserID)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Resp.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			ctx.Error(http.StatusUnauthorized)
+			return
+		}
+		ctx.ServerError("GetUserByID", err)
+		return
+	}
+
+	claims, err := getOIDCClaims(user, grant)
+	if err != nil {
+		ctx.ServerError("getOIDCClaims", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, &userInfoResponse{
+		Subject:    fmt.Sprint(user.ID),
+		OIDCClaims: claims,
+	})
+}
+
+// getGrantOfAccessToken returns the grant of a valid access token or nil
+func getGrantOfAccessToken(accessToken string) *models.OAuth2Grant {
+	if accessToken == "" {
+		return nil
+	}
+	token, err := models.ParseOAuth2Token(accessToken)
+	if err != nil || token.Type != models.TypeAccessToken {
+		return nil
+	}
+	grant, err := models.GetOAuth2GrantByID(token.GrantID)
+	if err != nil {
+		log.Error("GetOAuth2GrantByID: %v", err)
+		return nil
+	}
+	return grant
+}
+
+// IntrospectTokenResponse represents a token introspection response specified in RFC 7662
+type IntrospectTokenResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// IntrospectOAuth responds with the state of an access or refresh token (RFC 7662)
+func IntrospectOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.OAuth2TokenForm)
+	app := authenticateClient(ctx, form)
+	if app == nil {
+		return
+	}
+
+	// tokens issued to other clients are reported as inactive
+	resp := &IntrospectTokenResponse{}
+	token, err := models.ParseOAuth2Token(form.Token)
+	if err == nil {
+		grant, err := models.GetOAuth2GrantByID(token.GrantID)
+		if err != nil {
+			ctx.ServerError("GetOAuth2GrantByID", err)
+			return
+		}
+		// refresh tokens of grants which invalidate them are only valid once
+		if grant != nil && grant.ApplicationID == app.ID &&
+			(token.Type == models.TypeAccessToken || !setting.OAuth2.InvalidateRefreshTokens || grant.Counter == token.Counter) {
+			user, err := models.GetUserByID(grant.UserID)
+			if err != nil {
+				ctx.ServerError("GetUserByID", err)
+				return
+			}
+
+			resp.Active = true
+			resp.Scope = grant.Scope
+			resp.ClientID = app.ClientID
+			resp.Username = user.Name
+			resp.TokenType = string(TokenTypeBearer)
+			if token.Type == models.TypeRefreshToken {
+				resp.TokenType = "refresh_token"
+			}
+			resp.ExpiresAt = token.ExpiresAt
+			resp.IssuedAt = token.IssuedAt
+			resp.Subject = fmt.Sprint(grant.UserID)
+			resp.Audience = app.ClientID
+			resp.Issuer = setting.AppURL
+		}
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// RevokeOAuth revokes the grant of an access or refresh token issued to the client (RFC 7009)
+func RevokeOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.OAuth2TokenForm)
+	app := authenticateClient(ctx, form)
+	if app == nil {
+		return
+	}
+
+	// invalid tokens do not cause an error response as the client cannot do anything about them
+	token, err := models.ParseOAuth2Token(form.Token)
+	if err == nil {
+		grant, err := models.GetOAuth2GrantByID(token.GrantID)
+		if err != nil {
+			ctx.ServerError("GetOAuth2GrantByID", err)
+			return
+		}
+		if grant != nil && grant.ApplicationID == app.ID {
+			if err := models.RevokeOAuth2Grant(grant.ID, grant.UserID); err != nil {
+				ctx.ServerError("RevokeOAuth2Grant", err)
+				return
+			}
+		}
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// DeviceAuthorizationResponse represents a successful device authorization response (RFC 8628)
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceAuthorizationOAuth issues a device code and a user code to a client on a device without
+// a browser, the user enters the user code on the verification page to grant access (RFC 8628)
+func DeviceAuthorizationOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.DeviceAuthorizationForm)
+	app := authenticateClient(ctx, &auth.OAuth2TokenForm{
+		ClientID:     form.ClientID,
+		ClientSecret: form.ClientSecret,
+	})
+	if app == nil {
+		return
+	}
+
+	deviceCode, err := models.CreateOAuth2DeviceCode(app, form.Scope)
+	if err != nil {
+		ctx.ServerError("CreateOAuth2DeviceCode", err)
+		return
+	}
+
+	verificationURI := setting.AppURL + "login/device"
+	ctx.JSON(http.StatusOK, &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode.DeviceCode,
+		UserCode:                deviceCode.FormattedUserCode(),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(deviceCode.FormattedUserCode()),
+		ExpiresIn:               int64(deviceCode.ValidUntil - timeutil.TimeStampNow()),
+		Interval:                deviceCode.PollInterval,
+	})
+}
+
+// loadPendingDeviceCode returns the pending device authorization request of the user code and its
+// application, rendering the verification page with an error if there is none
+func loadPendingDeviceCode(ctx *context.Context, userCode string) (*models.OAuth2DeviceCode, *models.OAuth2Application) {
+	deviceCode, err := models.GetOAuth2DeviceCodeByUserCode(userCode)
+	if err != nil {
+		ctx.ServerError("GetOAuth2DeviceCodeByUserCode", err)
+		return nil, nil
+	}
+	if deviceCode == nil || deviceCode.IsExpired() || deviceCode.Status != models.OAuth2DeviceCodePending {
+		ctx.Data["Err_UserCode"] = true
+		ctx.Data["user_code"] = userCode
+		ctx.RenderWithErr(ctx.Tr("auth.device_user_code_invalid"), tplDeviceVerification, nil)
+		return nil, nil
+	}
+
+	app, err := models.GetOAuth2ApplicationByID(deviceCode.ApplicationID)
+	if err != nil {
+		ctx.ServerError("GetOAuth2ApplicationByID", err)
+		return nil, nil
+	}
+	if err := app.LoadUser(); err != nil {
+		ctx.ServerError("LoadUser", err)
+		return nil, nil
+	}
+	return deviceCode, app
+}
+
+// DeviceVerification shows the page to enter a user code and to grant access to the device it belongs to
+func DeviceVerification(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("auth.device_verification_title")
+
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		ctx.HTML(http.StatusOK, tplDeviceVerification)
+		return
+	}
+
+	deviceCode, app := loadPendingDeviceCode(ctx, userCode)
+	if deviceCode == nil {
+		return
+	}
+	ctx.Data["DeviceCode"] = deviceCode
+	ctx.Data["Application"] = app
+	ctx.Data["ApplicationUserLink"] = "<a href=\"" + html.EscapeString(setting.AppURL) + html.EscapeString(url.PathEscape(app.User.LowerName)) + "\">@" + html.EscapeString(app.User.Name) + "</a>"
+	ctx.HTML(http.StatusOK, tplDeviceVerification)
+}
+
+// DeviceVerificationPost manages the post request submitted when a user grants or denies access to a device
+func DeviceVerificationPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*auth.DeviceVerificationForm)
+	ctx.Data["Title"] = ctx.Tr("auth.device_verification_title")
+
+	deviceCode, app := loadPendingDeviceCode(ctx, form.UserCode)
+	if deviceCode == nil {
+		return
+	}
+
+	if !form.Granted {
+		if err := deviceCode.Deny(ctx.User.ID); err != nil {
+			ctx.ServerError("Deny", err)
+			return
+		}
+		ctx.Data["DeviceDenied"] = true
+		ctx.HTML(http.StatusOK, tplDeviceVerification)
+		return
+	}
+
+	grant, err := app.GetGrantByUserID(ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("GetGrantByUserID", err)
+		return
+	}
+	if grant == nil {
+		if _, err := app.CreateGrant(ctx.User.ID, deviceCode.Scope); err != nil {
+			ctx.ServerError("CreateGrant", err)
+			return
+		}
+	}
+	if err := deviceCode.Approve(ctx.User.ID); err != nil {
+		ctx.ServerError("Approve", err)
+		return
+	}
+	ctx.Data["DeviceGranted"] = true
+	ctx.HTML(http.StatusOK, tplDeviceVerification)
+}
@@ -18,6 +18,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/services/lockout"
 	"code.gitea.io/gitea/services/mailer"
 )
 
@@ -226,11 +227,15 @@ func DeleteAccount(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("settings")
 	ctx.Data["PageIsSettingsAccount"] = true
 
-	if _, err := models.UserSignIn(ctx.User.Name, ctx.Query("password")); err != nil {
+	if _, err := lockout.UserSignIn(ctx.User.Name, ctx.Query("password"), ctx.RemoteAddr()); err != nil {
 		if models.IsErrUserNotExist(err) {
 			loadAccountData(ctx)
 
 			ctx.RenderWithErr(ctx.Tr("form.enterred_invalid_password"), tplSettingsAccount, nil)
+		} else if models.IsErrUserLockedOut(err) || models.IsErrLoginRateLimited(err) {
+			loadAccountData(ctx)
+
+			ctx.RenderWithErr(ctx.Tr("auth.login_too_many_failed_attempts"), tplSettingsAccount, nil)
 		} else {
 			ctx.ServerError("UserSignIn", err)
 		}
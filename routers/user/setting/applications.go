@@ -6,11 +6,15 @@
 package setting
 
 import (
+	"strings"
+	"time"
+
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	auth "code.gitea.io/gitea/modules/forms"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/web"
 )
 
@@ -41,9 +45,66 @@ func ApplicationsPost(ctx *context.Context) {
 		return
 	}
 
+	scope, err := models.ParseAccessTokenScope(form.Scope)
+	if err != nil {
+		loadApplicationsData(ctx)
+		ctx.RenderWithErr(ctx.Tr("settings.token_scope_invalid"), tplSettingsApplications, form)
+		return
+	}
+
 	t := &models.AccessToken{
-		UID:  ctx.User.ID,
-		Name: form.Name,
+		UID:   ctx.User.ID,
+		Name:  form.Name,
+		Scope: scope,
+	}
+
+	if len(form.Repository) > 0 && len(form.Organization) > 0 {
+		loadApplicationsData(ctx)
+		ctx.RenderWithErr(ctx.Tr("settings.token_restriction_conflict"), tplSettingsApplications, form)
+		return
+	}
+	if len(form.Repository) > 0 {
+		var repo *models.Repository
+		if parts := strings.SplitN(form.Repository, "/", 2); len(parts) == 2 {
+			repo, err = models.GetRepositoryByOwnerAndName(parts[0], parts[1])
+			if err != nil && !models.IsErrRepoNotExist(err) {
+				ctx.ServerError("GetRepositoryByOwnerAndName", err)
+				return
+			}
+		}
+		if repo == nil {
+			loadApplicationsData(ctx)
+			ctx.Data["Err_Repository"] = true
+			ctx.RenderWithErr(ctx.Tr("settings.token_repository_not_exist", form.Repository), tplSettingsApplications, form)
+			return
+		}
+		t.RepoID = repo.ID
+	} else if len(form.Organization) > 0 {
+		org, err := models.GetOrgByName(form.Organization)
+		if models.IsErrOrgNotExist(err) {
+			loadApplicationsData(ctx)
+			ctx.Data["Err_Organization"] = true
+			ctx.RenderWithErr(ctx.Tr("settings.token_organization_not_exist", form.Organization), tplSettingsApplications, form)
+			return
+		} else if err != nil {
+			ctx.ServerError("GetOrgByName", err)
+			return
+		}
+		t.OrgID = org.ID
+	}
+
+	if len(form.Expires) > 0 {
+		expires, err := time.ParseInLocation("2006-01-02", form.Expires, time.Local)
+		if err == nil {
+			expires = time.Date(expires.Year(), expires.Month(), expires.Day(), 23, 59, 59, 0, expires.Location())
+		}
+		if err != nil || expires.Before(time.Now()) {
+			loadApplicationsData(ctx)
+			ctx.Data["Err_Expires"] = true
+			ctx.RenderWithErr(ctx.Tr("settings.token_expires_invalid"), tplSettingsApplications, form)
+			return
+		}
+		t.ExpiresUnix = timeutil.TimeStamp(expires.Unix())
 	}
 
 	exist, err := models.AccessTokenByNameExists(t)
@@ -87,7 +148,14 @@ func loadApplicationsData(ctx *context.Context) {
 		ctx.ServerError("ListAccessTokens", err)
 		return
 	}
+	for _, token := range tokens {
+		if err := token.LoadAttributes(); err != nil {
+			ctx.ServerError("LoadAttributes", err)
+			return
+		}
+	}
 	ctx.Data["Tokens"] = tokens
+	ctx.Data["AccessTokenScopeCategories"] = models.AccessTokenScopeCategories
 	ctx.Data["EnableOAuth2"] = setting.OAuth2.Enable
 	if setting.OAuth2.Enable {
 		ctx.Data["Applications"], err = models.GetOAuth2ApplicationsByUserID(ctx.User.ID)
@@ -0,0 +1,131 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externalaccount
+
+import (
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
+
+	"github.com/markbates/goth"
+)
+
+// ErrRequiredGroupMissing represents a user signing in through an OAuth2 login source
+// without being a member of the group it requires
+type ErrRequiredGroupMissing struct {
+	LoginSource string
+	Group       string
+}
+
+// IsErrRequiredGroupMissing checks if an error is a ErrRequiredGroupMissing.
+func IsErrRequiredGroupMissing(err error) bool {
+	_, ok := err.(ErrRequiredGroupMissing)
+	return ok
+}
+
+func (err ErrRequiredGroupMissing) Error() string {
+	return fmt.Sprintf("user is not a member of the required group [login_source: %s, group: %s]", err.LoginSource, err.Group)
+}
+
+// claimValueToStringSlice converts the value of a group claim, which is usually a list
+// but may also be a comma separated string, to a list of group names
+func claimValueToStringSlice(claimValue interface{}) []string {
+	var groups []string
+	switch v := claimValue.(type) {
+	case nil:
+	case []string:
+		groups = v
+	case []interface{}:
+		groups = make([]string, 0, len(v))
+		for _, group := range v {
+			groups = append(groups, fmt.Sprint(group))
+		}
+	case string:
+		groups = strings.Split(v, ",")
+	default:
+		groups = []string{fmt.Sprint(v)}
+	}
+
+	result := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group = strings.TrimSpace(group); group != "" {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// GetGroups returns the groups of the user listed in the group claim of the login source
+func GetGroups(source *models.LoginSource, gothUser goth.User) []string {
+	cfg := source.OAuth2()
+	if cfg == nil || cfg.GroupClaimName == "" {
+		return nil
+	}
+	return claimValueToStringSlice(gothUser.RawData[cfg.GroupClaimName])
+}
+
+func containsGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRequiredGroup returns an ErrRequiredGroupMissing if the login source requires
+// a group the user is not a member of
+func CheckRequiredGroup(source *models.LoginSource, gothUser goth.User) error {
+	cfg := source.OAuth2()
+	if cfg == nil || cfg.GroupClaimName == "" || cfg.RequiredGroup == "" {
+		return nil
+	}
+	if !containsGroup(GetGroups(source, gothUser), cfg.RequiredGroup) {
+		return ErrRequiredGroupMissing{LoginSource: source.Name, Group: cfg.RequiredGroup}
+	}
+	return nil
+}
+
+// SyncGroups grants or revokes the site administrator and restricted status of a user and
+// synchronizes its team memberships according to its groups. It is applied on each sign-in,
+// settings which are not configured for the login source are left alone.
+func SyncGroups(user *models.User, source *models.LoginSource, gothUser goth.User) error {
+	cfg := source.OAuth2()
+	if cfg == nil || cfg.GroupClaimName == "" {
+		return nil
+	}
+	groups := GetGroups(source, gothUser)
+
+	var cols []string
+	if cfg.AdminGroup != "" {
+		isAdmin := containsGroup(groups, cfg.AdminGroup)
+		if user.IsAdmin != isAdmin {
+			log.Trace("SyncGroups[%s]: Setting site administrator status of user %s to %t", source.Name, user.Name, isAdmin)
+			user.IsAdmin = isAdmin
+			cols = append(cols, "is_admin")
+		}
+	}
+	if cfg.RestrictedGroup != "" {
+		isRestricted := !user.IsAdmin && containsGroup(groups, cfg.RestrictedGroup)
+		if user.IsRestricted != isRestricted {
+			log.Trace("SyncGroups[%s]: Setting restricted status of user %s to %t", source.Name, user.Name, isRestricted)
+			user.IsRestricted = isRestricted
+			cols = append(cols, "is_restricted")
+		}
+	}
+	if len(cols) > 0 {
+		if err := models.UpdateUserCols(user, cols...); err != nil {
+			return err
+		}
+	}
+
+	mapping, err := cfg.GroupTeamMapping()
+	if err != nil {
+		return err
+	}
+	return models.SyncGroupTeamMapping(user, source, mapping, groups, cfg.GroupTeamMapRemoval)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externalaccount
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/markbates/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimValueToStringSlice(t *testing.T) {
+	assert.Empty(t, claimValueToStringSlice(nil))
+	assert.Equal(t, []string{"a", "b"}, claimValueToStringSlice([]interface{}{"a", "b"}))
+	assert.Equal(t, []string{"a", "b"}, claimValueToStringSlice([]string{"a", " ", "b"}))
+	assert.Equal(t, []string{"a", "b"}, claimValueToStringSlice("a, b,"))
+	assert.Equal(t, []string{"42"}, claimValueToStringSlice(42))
+}
+
+func TestCheckRequiredGroup(t *testing.T) {
+	source := &models.LoginSource{
+		Name: "oidc",
+		Type: models.LoginOAuth2,
+		Cfg: &models.OAuth2Config{
+			GroupClaimName: "groups",
+			RequiredGroup:  "Gitea-Users",
+		},
+	}
+
+	gothUser := goth.User{RawData: map[string]interface{}{"groups": []interface{}{"gitea-users", "admins"}}}
+	assert.Equal(t, []string{"gitea-users", "admins"}, GetGroups(source, gothUser))
+	assert.NoError(t, CheckRequiredGroup(source, gothUser))
+
+	gothUser = goth.User{RawData: map[string]interface{}{"groups": []interface{}{"admins"}}}
+	err := CheckRequiredGroup(source, gothUser)
+	assert.True(t, IsErrRequiredGroupMissing(err))
+
+	gothUser = goth.User{RawData: map[string]interface{}{}}
+	assert.True(t, IsErrRequiredGroupMissing(CheckRequiredGroup(source, gothUser)))
+
+	// without a group claim the required group is not enforced
+	source.Cfg = &models.OAuth2Config{RequiredGroup: "Gitea-Users"}
+	assert.NoError(t, CheckRequiredGroup(source, gothUser))
+}
+
+func TestSyncGroups(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	source := &models.LoginSource{
+		Name: "oidc",
+		Type: models.LoginOAuth2,
+		Cfg: &models.OAuth2Config{
+			GroupClaimName:  "groups",
+			AdminGroup:      "Gitea-Admins",
+			RestrictedGroup: "Contractors",
+		},
+	}
+	sync := func(groups ...interface{}) *models.User {
+		user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+		assert.NoError(t, SyncGroups(user, source, goth.User{RawData: map[string]interface{}{"groups": groups}}))
+		return models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	}
+
+	user := sync("gitea-admins")
+	assert.True(t, user.IsAdmin)
+	assert.False(t, user.IsRestricted)
+
+	// administrators are never restricted
+	user = sync("gitea-admins", "contractors")
+	assert.True(t, user.IsAdmin)
+	assert.False(t, user.IsRestricted)
+
+	user = sync("contractors")
+	assert.False(t, user.IsAdmin)
+	assert.True(t, user.IsRestricted)
+
+	user = sync()
+	assert.False(t, user.IsAdmin)
+	assert.False(t, user.IsRestricted)
+
+	// statuses without a configured group are left alone
+	source.Cfg = &models.OAuth2Config{GroupClaimName: "groups", AdminGroup: "Gitea-Admins"}
+	user = models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	user.IsRestricted = true
+	assert.NoError(t, models.UpdateUserCols(user, "is_restricted"))
+	user = sync("contractors")
+	assert.False(t, user.IsAdmin)
+	assert.True(t, user.IsRestricted)
+
+	// without a group claim nothing is synchronized
+	source.Cfg = &models.OAuth2Config{AdminGroup: "Gitea-Admins"}
+	user = sync("gitea-admins")
+	assert.False(t, user.IsAdmin)
+}
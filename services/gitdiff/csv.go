@@ -0,0 +1,168 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// csvDiffContextRows is the number of unchanged rows shown around changed rows
+const csvDiffContextRows = 2
+
+// TableDiffCell represents a cell of a TableDiffRow.
+type TableDiffCell struct {
+	LeftCell  string
+	RightCell string
+	Type      RichDiffType
+}
+
+// GetType returns the type of a TableDiffCell.
+func (c *TableDiffCell) GetType() int {
+	return int(c.Type)
+}
+
+// TableDiffRow represents a row of a TableDiffSection, the one-based row
+// numbers are 0 if the row does not exist in that version.
+type TableDiffRow struct {
+	LeftIdx  int
+	RightIdx int
+	Cells    []*TableDiffCell
+}
+
+// TableDiffSection represents consecutive rows of a CSV diff.
+type TableDiffSection struct {
+	Rows []*TableDiffRow
+}
+
+// CreateCsvDiff creates a cell level diff of two CSV files. Columns are
+// aligned by their header, rows by the cells of the columns both versions
+// have. Unchanged rows apart from the changes are left out, so the diff is
+// split in sections. A nil reader stands for a file which does not exist.
+func CreateCsvDiff(baseReader, headReader *csv.Reader) ([]*TableDiffSection, error) {
+	baseRecords, err := readCsvRecords(baseReader)
+	if err != nil {
+		return nil, err
+	}
+	headRecords, err := readCsvRecords(headReader)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := align(csvHeader(baseRecords), csvHeader(headRecords))
+	rowKeys := func(records [][]string, left bool) []string {
+		keys := make([]string, len(records))
+		for i, record := range records {
+			var sb strings.Builder
+			for _, column := range columns {
+				if column.left < 0 || column.right < 0 {
+					continue
+				}
+				idx := column.right
+				if left {
+					idx = column.left
+				}
+				sb.WriteString(csvCell(record, idx))
+				sb.WriteByte(0)
+			}
+			keys[i] = sb.String()
+		}
+		return keys
+	}
+
+	rows := align(rowKeys(baseRecords, true), rowKeys(headRecords, false))
+	diffRows := make([]*TableDiffRow, len(rows))
+	changed := make([]bool, len(rows))
+	for i, row := range rows {
+		diffRow := &TableDiffRow{
+			LeftIdx:  row.left + 1,
+			RightIdx: row.right + 1,
+			Cells:    make([]*TableDiffCell, len(columns)),
+		}
+		for j, column := range columns {
+			cell := &TableDiffCell{}
+			if row.left >= 0 {
+				cell.LeftCell = csvCell(baseRecords[row.left], column.left)
+			}
+			if row.right >= 0 {
+				cell.RightCell = csvCell(headRecords[row.right], column.right)
+			}
+			switch {
+			case row.left < 0 || column.left < 0:
+				cell.Type = RichDiffAdd
+			case row.right < 0 || column.right < 0:
+				cell.Type = RichDiffDel
+			case cell.LeftCell != cell.RightCell:
+				cell.Type = RichDiffChanged
+			default:
+				cell.Type = RichDiffEqual
+			}
+			changed[i] = changed[i] || cell.Type != RichDiffEqual
+			diffRow.Cells[j] = cell
+		}
+		diffRows[i] = diffRow
+	}
+
+	// keep the header, changed rows and the rows around them
+	visible := make([]bool, len(rows))
+	for i := range rows {
+		if !changed[i] {
+			continue
+		}
+		for j := i - csvDiffContextRows; j <= i+csvDiffContextRows; j++ {
+			if j >= 0 && j < len(rows) {
+				visible[j] = true
+			}
+		}
+	}
+	if len(rows) > 0 {
+		visible[0] = true
+	}
+
+	var sections []*TableDiffSection
+	var section *TableDiffSection
+	for i, row := range diffRows {
+		if !visible[i] {
+			section = nil
+			continue
+		}
+		if section == nil {
+			section = &TableDiffSection{}
+			sections = append(sections, section)
+		}
+		section.Rows = append(section.Rows, row)
+	}
+	return sections, nil
+}
+
+func readCsvRecords(rd *csv.Reader) ([][]string, error) {
+	if rd == nil {
+		return nil, nil
+	}
+	rd.FieldsPerRecord = -1
+	return rd.ReadAll()
+}
+
+// csvHeader returns the first record padded to the number of columns of the widest record
+func csvHeader(records [][]string) []string {
+	width := 0
+	for _, record := range records {
+		if len(record) > width {
+			width = len(record)
+		}
+	}
+	header := make([]string, width)
+	if len(records) > 0 {
+		copy(header, records[0])
+	}
+	return header
+}
+
+func csvCell(record []string, idx int) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return record[idx]
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCsvDiff(t *testing.T) {
+	newReader := func(content string) *csv.Reader {
+		return csv.NewReader(strings.NewReader(content))
+	}
+	cellTypes := func(row *TableDiffRow) []RichDiffType {
+		types := make([]RichDiffType, len(row.Cells))
+		for i, cell := range row.Cells {
+			types[i] = cell.Type
+		}
+		return types
+	}
+
+	// changed cell
+	sections, err := CreateCsvDiff(newReader("a,b\n1,2\n3,4\n"), newReader("a,b\n1,2\n3,5\n"))
+	assert.NoError(t, err)
+	assert.Len(t, sections, 1)
+	assert.Len(t, sections[0].Rows, 3)
+	row := sections[0].Rows[2]
+	assert.Equal(t, 3, row.LeftIdx)
+	assert.Equal(t, 3, row.RightIdx)
+	assert.Equal(t, []RichDiffType{RichDiffEqual, RichDiffChanged}, cellTypes(row))
+	assert.Equal(t, "4", row.Cells[1].LeftCell)
+	assert.Equal(t, "5", row.Cells[1].RightCell)
+
+	// added row and column
+	sections, err = CreateCsvDiff(newReader("a,b\n1,2\n"), newReader("a,c,b\n1,x,2\n3,y,4\n"))
+	assert.NoError(t, err)
+	assert.Len(t, sections, 1)
+	assert.Len(t, sections[0].Rows, 3)
+	assert.Equal(t, []RichDiffType{RichDiffEqual, RichDiffAdd, RichDiffEqual}, cellTypes(sections[0].Rows[1]))
+	assert.Equal(t, 0, sections[0].Rows[2].LeftIdx)
+	assert.Equal(t, 3, sections[0].Rows[2].RightIdx)
+	assert.Equal(t, []RichDiffType{RichDiffAdd, RichDiffAdd, RichDiffAdd}, cellTypes(sections[0].Rows[2]))
+
+	// deleted file
+	sections, err = CreateCsvDiff(newReader("a\n1\n"), nil)
+	assert.NoError(t, err)
+	assert.Len(t, sections, 1)
+	assert.Equal(t, []RichDiffType{RichDiffDel}, cellTypes(sections[0].Rows[1]))
+
+	// unchanged rows apart from the changes are left out
+	var base, head strings.Builder
+	base.WriteString("id,value\n")
+	head.WriteString("id,value\n")
+	for i := 0; i < 20; i++ {
+		base.WriteString(strings.Repeat("x", i) + ",a\n")
+		if i == 10 {
+			head.WriteString(strings.Repeat("x", i) + ",b\n")
+		} else {
+			head.WriteString(strings.Repeat("x", i) + ",a\n")
+		}
+	}
+	sections, err = CreateCsvDiff(newReader(base.String()), newReader(head.String()))
+	assert.NoError(t, err)
+	assert.Len(t, sections, 2)
+	assert.Len(t, sections[0].Rows, 1)
+	assert.Len(t, sections[1].Rows, 2*csvDiffContextRows+1)
+	assert.Equal(t, 12, sections[1].Rows[csvDiffContextRows].LeftIdx)
+
+	// invalid CSV
+	_, err = CreateCsvDiff(newReader("a,\"b\n"), newReader("a,b\n"))
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/charset"
@@ -29,6 +30,7 @@ import (
 	"code.gitea.io/gitea/modules/process"
 	"code.gitea.io/gitea/modules/setting"
 
+	"github.com/go-enry/go-enry/v2"
 	"github.com/sergi/go-diff/diffmatchpatch"
 	stdcharset "golang.org/x/net/html/charset"
 	"golang.org/x/text/transform"
@@ -77,6 +79,14 @@ type DiffLine struct {
 	Content     string
 	Comments    []*models.Comment
 	SectionInfo *DiffLineSectionInfo
+	Move        *DiffLineMove
+
+	// wordDiffDeleted is the deleted line which is combined with this line in word diff mode
+	wordDiffDeleted *DiffLine
+	// leftUnanchored and rightUnanchored are set if comments on that side of the
+	// line can not be anchored to the merge base or the head of the pull request
+	leftUnanchored  bool
+	rightUnanchored bool
 }
 
 // DiffLineSectionInfo represents diff line section meta data
@@ -103,6 +113,16 @@ func (d *DiffLine) CanComment() bool {
 	return len(d.Comments) == 0 && d.Type != DiffLineSection
 }
 
+// CanCommentLeft returns whether the previous version of a line can get commented
+func (d *DiffLine) CanCommentLeft() bool {
+	return !d.leftUnanchored
+}
+
+// CanCommentRight returns whether the proposed version of a line can get commented
+func (d *DiffLine) CanCommentRight() bool {
+	return !d.rightUnanchored
+}
+
 // GetCommentSide returns the comment side of the first comment, if not set returns empty string
 func (d *DiffLine) GetCommentSide() string {
 	if len(d.Comments) == 0 {
@@ -187,12 +207,13 @@ var unfinishedtagRegex = regexp.MustCompile(`<[^>]*$`)
 var trailingSpanRegex = regexp.MustCompile(`<span\s*[[:alpha:]="]*?[>]?$`)
 var entityRegex = regexp.MustCompile(`&[#]*?[0-9[:alpha:]]*$`)
 
-// shouldWriteInline represents combinations where we manually write inline changes
+// shouldWriteInline represents combinations where we manually write inline changes,
+// a DiffLinePlain lineType writes both the deleted and inserted text as in word diff mode
 func shouldWriteInline(diff diffmatchpatch.Diff, lineType DiffLineType) bool {
 	if true &&
 		diff.Type == diffmatchpatch.DiffEqual ||
-		diff.Type == diffmatchpatch.DiffInsert && lineType == DiffLineAdd ||
-		diff.Type == diffmatchpatch.DiffDelete && lineType == DiffLineDel {
+		diff.Type == diffmatchpatch.DiffInsert && (lineType == DiffLineAdd || lineType == DiffLinePlain) ||
+		diff.Type == diffmatchpatch.DiffDelete && (lineType == DiffLineDel || lineType == DiffLinePlain) {
 		return true
 	}
 	return false
@@ -466,11 +487,11 @@ func diffToHTML(fileName string, diffs []diffmatchpatch.Diff, lineType DiffLineT
 		switch {
 		case diff.Type == diffmatchpatch.DiffEqual:
 			buf.WriteString(diff.Text)
-		case diff.Type == diffmatchpatch.DiffInsert && lineType == DiffLineAdd:
+		case diff.Type == diffmatchpatch.DiffInsert && (lineType == DiffLineAdd || lineType == DiffLinePlain):
 			buf.Write(addedCodePrefix)
 			buf.WriteString(diff.Text)
 			buf.Write(codeTagSuffix)
-		case diff.Type == diffmatchpatch.DiffDelete && lineType == DiffLineDel:
+		case diff.Type == diffmatchpatch.DiffDelete && (lineType == DiffLineDel || lineType == DiffLinePlain):
 			buf.Write(removedCodePrefix)
 			buf.WriteString(diff.Text)
 			buf.Write(codeTagSuffix)
@@ -541,17 +562,20 @@ func (diffSection *DiffSection) GetComputedInlineDiffFor(diffLine *DiffLine) tem
 	)
 
 	// try to find equivalent diff line. ignore, otherwise
-	switch diffLine.Type {
-	case DiffLineSection:
+	switch {
+	case diffLine.wordDiffDeleted != nil:
+		diff1 = diffLine.wordDiffDeleted.Content
+		diff2 = diffLine.Content
+	case diffLine.Type == DiffLineSection:
 		return template.HTML(getLineContent(diffLine.Content[1:]))
-	case DiffLineAdd:
+	case diffLine.Type == DiffLineAdd:
 		compareDiffLine = diffSection.GetLine(DiffLineDel, diffLine.RightIdx)
 		if compareDiffLine == nil {
 			return template.HTML(highlight.Code(diffSection.FileName, diffLine.Content[1:]))
 		}
 		diff1 = compareDiffLine.Content
 		diff2 = diffLine.Content
-	case DiffLineDel:
+	case diffLine.Type == DiffLineDel:
 		compareDiffLine = diffSection.GetLine(DiffLineAdd, diffLine.LeftIdx)
 		if compareDiffLine == nil {
 			return template.HTML(highlight.Code(diffSection.FileName, diffLine.Content[1:]))
@@ -565,12 +589,200 @@ func (diffSection *DiffSection) GetComputedInlineDiffFor(diffLine *DiffLine) tem
 		return template.HTML(highlight.Code(diffSection.FileName, diffLine.Content))
 	}
 
+	if diffLine.wordDiffDeleted != nil {
+		return wordDiffToHTML(diffSection.FileName, diff1[1:], diff2[1:])
+	}
+
 	diffRecord := diffMatchPatch.DiffMain(highlight.Code(diffSection.FileName, diff1[1:]), highlight.Code(diffSection.FileName, diff2[1:]), true)
 	diffRecord = diffMatchPatch.DiffCleanupEfficiency(diffRecord)
 
 	return diffToHTML(diffSection.FileName, diffRecord, diffLine.Type)
 }
 
+// wordDiffTokenRegex splits code into words, whitespace and single characters
+var wordDiffTokenRegex = regexp.MustCompile(`\w+|\s+|.`)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// tokenRunes maps every distinct token to a rune, so sequences of tokens can be compared by diffmatchpatch
+type tokenRunes struct {
+	ids    map[string]rune
+	tokens []string
+}
+
+func newTokenRunes() *tokenRunes {
+	return &tokenRunes{ids: make(map[string]rune)}
+}
+
+func (t *tokenRunes) toRunes(tokens []string) []rune {
+	runes := make([]rune, len(tokens))
+	for i, token := range tokens {
+		id, ok := t.ids[token]
+		if !ok {
+			t.tokens = append(t.tokens, token)
+			id = rune(len(t.tokens))
+			if id >= 0xD800 {
+				// skip the surrogate halves, they are no valid runes
+				id += 0x800
+			}
+			t.ids[token] = id
+		}
+		runes[i] = id
+	}
+	return runes
+}
+
+func (t *tokenRunes) token(id rune) string {
+	if id >= 0xD800 {
+		id -= 0x800
+	}
+	return t.tokens[id-1]
+}
+
+// diffWords computes the differences of two lines word by word
+func diffWords(text1, text2 string) []diffmatchpatch.Diff {
+	tokens := newTokenRunes()
+	runes1 := tokens.toRunes(wordDiffTokenRegex.FindAllString(text1, -1))
+	runes2 := tokens.toRunes(wordDiffTokenRegex.FindAllString(text2, -1))
+
+	diffs := diffMatchPatch.DiffMainRunes(runes1, runes2, false)
+	for i := range diffs {
+		var sb strings.Builder
+		for _, id := range diffs[i].Text {
+			sb.WriteString(tokens.token(id))
+		}
+		diffs[i].Text = sb.String()
+	}
+	return diffs
+}
+
+// highlightedLine cuts the highlighted HTML of a line at offsets of its source, so that
+// added and removed words can be marked without breaking the spans of the highlighting
+type highlightedLine struct {
+	html string
+	open []string
+}
+
+func newHighlightedLine(fileName, code string) *highlightedLine {
+	highlighted := highlight.Code(fileName, code)
+	if htmlTagRegex.ReplaceAllString(highlighted, "") != html.EscapeString(code) {
+		// the highlighting does not match the source, e.g. if the line is too long
+		highlighted = html.EscapeString(code)
+	}
+	return &highlightedLine{html: highlighted}
+}
+
+// next returns the HTML of the next n bytes of the source. The spans which are still open
+// at the end are closed and opened again by the following call.
+func (l *highlightedLine) next(n int) string {
+	var buf strings.Builder
+	for _, tag := range l.open {
+		buf.WriteString(tag)
+	}
+	for len(l.html) > 0 && (n > 0 || strings.HasPrefix(l.html, "</")) {
+		var size int
+		switch l.html[0] {
+		case '<':
+			size = strings.IndexByte(l.html, '>') + 1
+			if l.html[1] == '/' {
+				if len(l.open) > 0 {
+					l.open = l.open[:len(l.open)-1]
+				}
+			} else {
+				l.open = append(l.open, l.html[:size])
+			}
+		case '&':
+			size = strings.IndexByte(l.html, ';') + 1
+			n -= len(html.UnescapeString(l.html[:size]))
+		default:
+			_, size = utf8.DecodeRuneInString(l.html)
+			n -= size
+		}
+		buf.WriteString(l.html[:size])
+		l.html = l.html[size:]
+	}
+	for range l.open {
+		buf.WriteString("</span>")
+	}
+	return buf.String()
+}
+
+// wordDiffToHTML highlights a deleted and an added line and combines them into one line
+// marking the removed and added words. The words are compared on the source of the lines,
+// so the differences of the highlighting do not produce unbalanced HTML.
+func wordDiffToHTML(fileName, text1, text2 string) template.HTML {
+	line1 := newHighlightedLine(fileName, text1)
+	line2 := newHighlightedLine(fileName, text2)
+
+	var buf bytes.Buffer
+	for _, diff := range diffWords(text1, text2) {
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			line1.next(len(diff.Text))
+			buf.WriteString(line2.next(len(diff.Text)))
+		case diffmatchpatch.DiffDelete:
+			buf.Write(removedCodePrefix)
+			buf.WriteString(line1.next(len(diff.Text)))
+			buf.Write(codeTagSuffix)
+		case diffmatchpatch.DiffInsert:
+			buf.Write(addedCodePrefix)
+			buf.WriteString(line2.next(len(diff.Text)))
+			buf.Write(codeTagSuffix)
+		}
+	}
+	return template.HTML(buf.Bytes())
+}
+
+// GetWordDiffLines returns the lines of the section as shown in word diff mode, in which
+// every added line replacing a deleted line is combined with it into one line showing
+// both the removed and the added words. Commented and moved lines are not combined.
+func (diffSection *DiffSection) GetWordDiffLines() []*DiffLine {
+	if setting.Git.DisableDiffHighlight {
+		return diffSection.Lines
+	}
+
+	canCombine := func(diffLine *DiffLine) bool {
+		return len(diffLine.Comments) == 0 && diffLine.Move == nil
+	}
+	deletedLines := make(map[*DiffLine]*DiffLine)
+	for _, diffLine := range diffSection.Lines {
+		if diffLine.Type != DiffLineAdd || diffLine.Move != nil {
+			continue
+		}
+		if deleted := diffSection.GetLine(DiffLineDel, diffLine.RightIdx); deleted != nil && canCombine(deleted) {
+			deletedLines[diffLine] = deleted
+		}
+	}
+	if len(deletedLines) == 0 {
+		return diffSection.Lines
+	}
+
+	combined := make(map[*DiffLine]bool, len(deletedLines))
+	for _, deleted := range deletedLines {
+		combined[deleted] = true
+	}
+	lines := make([]*DiffLine, 0, len(diffSection.Lines)-len(deletedLines))
+	for _, diffLine := range diffSection.Lines {
+		if combined[diffLine] {
+			continue
+		}
+		if deleted, ok := deletedLines[diffLine]; ok {
+			diffLine = &DiffLine{
+				LeftIdx:         deleted.LeftIdx,
+				RightIdx:        diffLine.RightIdx,
+				Type:            DiffLinePlain,
+				Content:         " " + diffLine.Content[1:],
+				Comments:        diffLine.Comments,
+				wordDiffDeleted: deleted,
+				leftUnanchored:  deleted.leftUnanchored,
+				rightUnanchored: diffLine.rightUnanchored,
+			}
+		}
+		lines = append(lines, diffLine)
+	}
+	return lines
+}
+
 // DiffFile represents a file diff.
 type DiffFile struct {
 	Name               string
@@ -588,6 +800,11 @@ type DiffFile struct {
 	Sections           []*DiffSection
 	IsIncomplete       bool
 	IsProtected        bool
+	IsGenerated        bool
+	IsVendored         bool
+	IsDocumentation    bool
+	BlobSHA            string
+	IsViewed           bool
 }
 
 // GetType returns type of diff file.
@@ -595,6 +812,11 @@ func (diffFile *DiffFile) GetType() int {
 	return int(diffFile.Type)
 }
 
+// IsCollapsedByDefault returns true if the file diff should be folded when the diff is displayed
+func (diffFile *DiffFile) IsCollapsedByDefault() bool {
+	return diffFile.IsGenerated || diffFile.IsVendored || diffFile.IsDocumentation || diffFile.IsViewed
+}
+
 // GetTailSection creates a fake DiffLineSection if the last section is not the end of the file
 func (diffFile *DiffFile) GetTailSection(gitRepo *git.Repository, leftCommitID, rightCommitID string) *DiffSection {
 	if len(diffFile.Sections) == 0 || diffFile.Type != DiffFileChange || diffFile.IsBin || diffFile.IsLFSFile {
@@ -647,34 +869,72 @@ type Diff struct {
 	NumFiles, TotalAddition, TotalDeletion int
 	Files                                  []*DiffFile
 	IsIncomplete                           bool
+	NumViewedFiles                         int
 }
 
 // LoadComments loads comments into each line
 func (diff *Diff) LoadComments(issue *models.Issue, currentUser *models.User) error {
+	return diff.LoadCommentsInRange(issue, currentUser, nil, nil)
+}
+
+// LoadCommentsInRange loads comments into a diff between two commits of a pull
+// request. Comments are anchored to lines of the merge base and the head of the
+// pull request, baseLines translates the former to the start of the diff and
+// headLines the end of the diff to the latter. Comments on lines which were
+// changed outside of the diff are left out and such lines can not be commented.
+func (diff *Diff) LoadCommentsInRange(issue *models.Issue, currentUser *models.User, baseLines, headLines LineMap) error {
 	allComments, err := models.FetchCodeComments(issue, currentUser)
 	if err != nil {
 		return err
 	}
 	for _, file := range diff.Files {
-		if lineCommits, ok := allComments[file.Name]; ok {
-			for _, section := range file.Sections {
-				for _, line := range section.Lines {
-					if comments, ok := lineCommits[int64(line.LeftIdx*-1)]; ok {
-						line.Comments = append(line.Comments, comments...)
-					}
-					if comments, ok := lineCommits[int64(line.RightIdx)]; ok {
-						line.Comments = append(line.Comments, comments...)
-					}
-					sort.SliceStable(line.Comments, func(i, j int) bool {
-						return line.Comments[i].CreatedUnix < line.Comments[j].CreatedUnix
-					})
+		lineCommits, hasComments := allComments[file.Name]
+		if hasComments && (baseLines != nil || headLines != nil) {
+			lineCommits = translateCommentLines(file.Name, lineCommits, baseLines, headLines)
+		}
+		for _, section := range file.Sections {
+			for _, line := range section.Lines {
+				if baseLines != nil && line.LeftIdx > 0 {
+					line.leftUnanchored = baseLines.ToOld(file.Name, line.LeftIdx) == 0
 				}
+				if headLines != nil && line.RightIdx > 0 {
+					line.rightUnanchored = headLines.ToNew(file.Name, line.RightIdx) == 0
+				}
+				if !hasComments {
+					continue
+				}
+				if comments, ok := lineCommits[int64(line.LeftIdx*-1)]; ok {
+					line.Comments = append(line.Comments, comments...)
+				}
+				if comments, ok := lineCommits[int64(line.RightIdx)]; ok {
+					line.Comments = append(line.Comments, comments...)
+				}
+				sort.SliceStable(line.Comments, func(i, j int) bool {
+					return line.Comments[i].CreatedUnix < line.Comments[j].CreatedUnix
+				})
 			}
 		}
 	}
 	return nil
 }
 
+// translateCommentLines moves comments from their anchors to the lines of a diff
+func translateCommentLines(treePath string, lineComments map[int64][]*models.Comment, baseLines, headLines LineMap) map[int64][]*models.Comment {
+	translated := make(map[int64][]*models.Comment, len(lineComments))
+	for line, comments := range lineComments {
+		var diffLine int64
+		if line < 0 {
+			diffLine = -int64(baseLines.ToNew(treePath, int(-line)))
+		} else {
+			diffLine = int64(headLines.ToOld(treePath, int(line)))
+		}
+		if diffLine != 0 {
+			translated[diffLine] = append(translated[diffLine], comments...)
+		}
+	}
+	return translated
+}
+
 const cmdDiffHead = "diff --git "
 
 // ParsePatch builds a Diff object from a io.Reader and some parameters.
@@ -1234,6 +1494,8 @@ func GetDiffRangeWithWhitespaceBehavior(repoPath, beforeCommitID, afterCommitID
 			diffFile.Sections = append(diffFile.Sections, tailSection)
 		}
 	}
+	setLinguistAttributes(gitRepo, afterCommitID, diff.Files)
+	diff.detectMovedLines()
 
 	if err = cmd.Wait(); err != nil {
 		return nil, fmt.Errorf("Wait: %v", err)
@@ -1257,6 +1519,29 @@ func GetDiffRangeWithWhitespaceBehavior(repoPath, beforeCommitID, afterCommitID
 	return diff, nil
 }
 
+// setLinguistAttributes flags generated, vendored and documentation files as
+// set by the .gitattributes of the given commit, falling back to the detection
+// by filename for generated and vendored files
+func setLinguistAttributes(gitRepo *git.Repository, commitID string, files []*DiffFile) {
+	filenames := make([]string, 0, len(files))
+	for _, diffFile := range files {
+		filenames = append(filenames, diffFile.Name)
+	}
+	name2attributes, err := gitRepo.GetLinguistAttributes(commitID, filenames)
+	if err != nil {
+		log.Error("GetLinguistAttributes(%s): %v", commitID, err)
+	}
+
+	for _, diffFile := range files {
+		attributes := name2attributes[diffFile.Name]
+		diffFile.IsGenerated = attributes.Generated.IsTrue() ||
+			(attributes.Generated.IsNone() && enry.IsGenerated(diffFile.Name, nil))
+		diffFile.IsVendored = attributes.Vendored.IsTrue() ||
+			(attributes.Vendored.IsNone() && enry.IsVendor(diffFile.Name))
+		diffFile.IsDocumentation = attributes.Documentation.IsTrue()
+	}
+}
+
 // GetDiffCommit builds a Diff representing the given commitID.
 func GetDiffCommit(repoPath, commitID string, maxLines, maxLineCharacters, maxFiles int) (*Diff, error) {
 	return GetDiffRangeWithWhitespaceBehavior(repoPath, "", commitID, maxLines, maxLineCharacters, maxFiles, "")
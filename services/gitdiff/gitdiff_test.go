@@ -535,3 +535,55 @@ func TestDiffToHTML_14231(t *testing.T) {
 
 	assertEqual(t, expected, output)
 }
+
+func TestDiffSection_GetWordDiffLines(t *testing.T) {
+	setting.Cfg = ini.Empty()
+	assertEqual(t, "foo <span class=\"removed-code\">bar</span><span class=\"added-code\">baz</span> biz", diffToHTML("", []dmp.Diff{
+		{Type: dmp.DiffEqual, Text: "foo "},
+		{Type: dmp.DiffDelete, Text: "bar"},
+		{Type: dmp.DiffInsert, Text: "baz"},
+		{Type: dmp.DiffEqual, Text: " biz"},
+	}, DiffLinePlain))
+
+	section := &DiffSection{
+		FileName: "README.txt",
+		Lines: []*DiffLine{
+			{LeftIdx: 1, RightIdx: 1, Type: DiffLinePlain, Content: " first"},
+			{LeftIdx: 2, Type: DiffLineDel, Content: "-foo bar"},
+			{LeftIdx: 3, Type: DiffLineDel, Content: "-second"},
+			{RightIdx: 2, Type: DiffLineAdd, Content: "+foo baz"},
+			{RightIdx: 3, Type: DiffLineAdd, Content: "+second line", Comments: []*models.Comment{{Content: "bla"}}},
+			{LeftIdx: 4, RightIdx: 4, Type: DiffLinePlain, Content: " last"},
+			{RightIdx: 5, Type: DiffLineAdd, Content: "+added"},
+		},
+	}
+	lines := section.GetWordDiffLines()
+	if assert.Len(t, lines, 5) {
+		assert.Equal(t, section.Lines[0], lines[0])
+		assert.Equal(t, 2, lines[1].LeftIdx)
+		assert.Equal(t, 2, lines[1].RightIdx)
+		assert.Equal(t, DiffLinePlain, lines[1].Type)
+		assert.Equal(t, template.HTML("foo <span class=\"removed-code\">bar</span><span class=\"added-code\">baz</span>"), section.GetComputedInlineDiffFor(lines[1]))
+		assert.Equal(t, 3, lines[2].LeftIdx)
+		assert.Len(t, lines[2].Comments, 1)
+		assert.Equal(t, section.Lines[6], lines[4])
+	}
+
+	// commented deleted lines are shown on their own
+	section.Lines[1].Comments = []*models.Comment{{Content: "bla"}}
+	section.Lines[2].Comments = []*models.Comment{{Content: "bla"}}
+	assert.Equal(t, section.Lines, section.GetWordDiffLines())
+}
+
+func TestWordDiffToHTML(t *testing.T) {
+	setting.Cfg = ini.Empty()
+
+	// the words are compared on the source, the spans of the highlighting stay balanced
+	expected := `<span class="removed-code"><span class="nx">fmt</span></span><span class="added-code"><span class="nx">log</span></span>` +
+		`<span class="p">.</span><span class="nf">Println</span><span class="p">(</span>` +
+		`<span class="s">&#34;foo </span><span class="removed-code"><span class="s">bar</span></span><span class="added-code"><span class="s">baz</span></span><span class="s">&#34;</span>` +
+		`<span class="p">,</span> <span class="nx">a</span> <span class="o">&lt;</span><span class="added-code"><span class="o">=</span></span> <span class="nx">b</span><span class="p">)</span>`
+	assertEqual(t, expected, wordDiffToHTML("main.go", `fmt.Println("foo bar", a < b)`, `log.Println("foo baz", a <= b)`))
+
+	assertEqual(t, `<span class="removed-code">a &lt; b</span>`, wordDiffToHTML("README.txt", "a < b", ""))
+}
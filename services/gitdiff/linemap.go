@@ -0,0 +1,158 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/modules/git"
+)
+
+var lineMapHunkRegexp = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type lineMapHunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+}
+
+// LineMap translates the line numbers of the files of one commit to the line
+// numbers of another commit. Lines which were changed in between can not be
+// translated. Files are identified by their name in the newer commit, a nil
+// LineMap translates every line to itself.
+type LineMap map[string][]lineMapHunk
+
+// GetLineMap returns the LineMap between two commits of a repository
+func GetLineMap(repoPath, oldCommitID, newCommitID string) (LineMap, error) {
+	if oldCommitID == newCommitID {
+		return nil, nil
+	}
+	if strings.HasPrefix(oldCommitID, "-") || strings.HasPrefix(newCommitID, "-") {
+		return nil, fmt.Errorf("GetLineMap[%s, %s]: invalid commit ID", oldCommitID, newCommitID)
+	}
+	cmd := git.NewCommand("diff", "-U0", "--no-color", "--no-ext-diff", "-M", "--src-prefix=a/", "--dst-prefix=b/")
+	if git.CheckGitVersionAtLeast("2.24") == nil {
+		cmd.AddArguments("--end-of-options")
+	}
+	stdout, err := cmd.AddArguments(oldCommitID, newCommitID).RunInDirBytes(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("GetLineMap[%s, %s]: %v", oldCommitID, newCommitID, err)
+	}
+	return parseLineMap(bytes.NewReader(stdout))
+}
+
+// parseLineMap reads the hunk headers of a patch created with zero lines of context
+func parseLineMap(reader io.Reader) (LineMap, error) {
+	lineMap := make(LineMap)
+	var oldName, name string
+	// the lines of a hunk may look like headers, so they are skipped by counting them
+	remaining := 0
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), 1024*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if remaining > 0 {
+			if len(line) > 0 && line[0] != '\\' {
+				remaining--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldName = unquoteDiffName(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			name = unquoteDiffName(line[4:], "b/")
+			if len(name) == 0 {
+				// the file was deleted
+				name = oldName
+			}
+		case strings.HasPrefix(line, "@@ "):
+			m := lineMapHunkRegexp.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header: %s", line)
+			}
+			hunk := lineMapHunk{
+				oldStart: atoiOr(m[1], 0),
+				oldLines: atoiOr(m[2], 1),
+				newStart: atoiOr(m[3], 0),
+				newLines: atoiOr(m[4], 1),
+			}
+			lineMap[name] = append(lineMap[name], hunk)
+			remaining = hunk.oldLines + hunk.newLines
+		}
+	}
+	return lineMap, scanner.Err()
+}
+
+// unquoteDiffName returns the name of a file in a patch header without its
+// prefix, or an empty string for /dev/null
+func unquoteDiffName(name, prefix string) string {
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+	}
+	if !strings.HasPrefix(name, prefix) {
+		return ""
+	}
+	return name[len(prefix):]
+}
+
+func atoiOr(s string, defaultValue int) int {
+	if len(s) == 0 {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+// ToNew translates a line of the older commit, it returns 0 if the line was changed.
+func (m LineMap) ToNew(treePath string, line int) int {
+	return translateLine(m[treePath], line, true)
+}
+
+// ToOld translates a line of the newer commit, it returns 0 if the line was changed.
+func (m LineMap) ToOld(treePath string, line int) int {
+	return translateLine(m[treePath], line, false)
+}
+
+func translateLine(hunks []lineMapHunk, line int, toNew bool) int {
+	if line <= 0 {
+		return 0
+	}
+	delta := 0
+	for _, hunk := range hunks {
+		start, count, otherCount := hunk.oldStart, hunk.oldLines, hunk.newLines
+		if !toNew {
+			start, count, otherCount = hunk.newStart, hunk.newLines, hunk.oldLines
+		}
+		if count == 0 {
+			// the lines of the other side were inserted after line start
+			if start >= line {
+				break
+			}
+			delta += otherCount
+			continue
+		}
+		if line < start {
+			break
+		}
+		if line < start+count {
+			return 0
+		}
+		delta += otherCount - count
+	}
+	return line + delta
+}
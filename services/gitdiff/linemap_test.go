@@ -0,0 +1,92 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const lineMapPatch = `diff --git a/README.md b/README.md
+index 3cd8a4d..d7a8b1d 100644
+--- a/README.md
++++ b/README.md
+@@ -2,0 +3,2 @@ line 2
++inserted
++--- looks like a header
+@@ -5 +7 @@ line 4
+-changed
++changed again
+@@ -8,2 +9,0 @@ line 7
+-removed
+-removed
+diff --git a/deleted.txt b/deleted.txt
+deleted file mode 100644
+index 4e3e1a8..0000000
+--- a/deleted.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-first
+-second
+`
+
+func TestParseLineMap(t *testing.T) {
+	lineMap, err := parseLineMap(strings.NewReader(lineMapPatch))
+	assert.NoError(t, err)
+	assert.Equal(t, LineMap{
+		"README.md": {
+			{oldStart: 2, oldLines: 0, newStart: 3, newLines: 2},
+			{oldStart: 5, oldLines: 1, newStart: 7, newLines: 1},
+			{oldStart: 8, oldLines: 2, newStart: 9, newLines: 0},
+		},
+		"deleted.txt": {
+			{oldStart: 1, oldLines: 2, newStart: 0, newLines: 0},
+		},
+	}, lineMap)
+}
+
+func TestLineMap_Translate(t *testing.T) {
+	lineMap, err := parseLineMap(strings.NewReader(lineMapPatch))
+	assert.NoError(t, err)
+
+	for old, new := range map[int]int{1: 1, 2: 2, 3: 5, 4: 6, 5: 0, 6: 8, 7: 9, 8: 0, 9: 0, 10: 10} {
+		assert.Equal(t, new, lineMap.ToNew("README.md", old), "old line %d", old)
+	}
+	for new, old := range map[int]int{2: 2, 3: 0, 4: 0, 5: 3, 7: 0, 9: 7, 10: 10} {
+		assert.Equal(t, old, lineMap.ToOld("README.md", new), "new line %d", new)
+	}
+	assert.Equal(t, 0, lineMap.ToNew("deleted.txt", 1))
+	assert.Equal(t, 4, lineMap.ToNew("unchanged.txt", 4))
+
+	var identity LineMap
+	assert.Equal(t, 5, identity.ToOld("README.md", 5))
+}
+
+func TestTranslateCommentLines(t *testing.T) {
+	baseLines, err := parseLineMap(strings.NewReader(lineMapPatch))
+	assert.NoError(t, err)
+
+	left, right, changed := &models.Comment{Line: -3}, &models.Comment{Line: 7}, &models.Comment{Line: -5}
+	translated := translateCommentLines("README.md", map[int64][]*models.Comment{
+		-3: {left},
+		-5: {changed},
+		7:  {right},
+	}, baseLines, nil)
+	assert.Equal(t, map[int64][]*models.Comment{
+		-5: {left},
+		7:  {right},
+	}, translated)
+}
+
+func TestGetLineMap_RejectsOptions(t *testing.T) {
+	_, err := GetLineMap(".", "--output=/tmp/gitea-linemap", "HEAD")
+	assert.Error(t, err)
+	_, err = GetLineMap(".", "HEAD", "--output=/tmp/gitea-linemap")
+	assert.Error(t, err)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// movedBlockMinAlnum is the number of alphanumeric characters a block of
+// lines must have to be detected as moved, like git diff --color-moved does
+const movedBlockMinAlnum = 20
+
+// DiffLineMove links a moved line to its other end, which is the line of the
+// old file it was moved from for added lines and the line of the new file it
+// was moved to for deleted lines.
+type DiffLineMove struct {
+	FileName string
+	LineIdx  int
+}
+
+type movedLineCandidate struct {
+	file    *DiffFile
+	section *DiffSection
+	pos     int
+	line    *DiffLine
+	key     string
+}
+
+// follows reports whether c is the line following prev in the old or new file
+func (c *movedLineCandidate) follows(prev *movedLineCandidate) bool {
+	if c.file != prev.file {
+		return false
+	}
+	if c.line.Type == DiffLineDel {
+		return c.line.LeftIdx == prev.line.LeftIdx+1
+	}
+	return c.line.RightIdx == prev.line.RightIdx+1
+}
+
+// isInPlace reports whether a deleted and an added line belong to the same
+// change, like the lines of a block which was only indented differently
+func isInPlace(deleted, added *movedLineCandidate) bool {
+	if deleted.section != added.section {
+		return false
+	}
+	from, to := deleted.pos, added.pos
+	if from > to {
+		from, to = to, from
+	}
+	for _, line := range deleted.section.Lines[from:to] {
+		if line.Type != DiffLineDel && line.Type != DiffLineAdd {
+			return false
+		}
+	}
+	return true
+}
+
+// detectMovedLines finds blocks of deleted lines which were added elsewhere
+// in the diff and links both ends. The indentation of lines is ignored, so
+// moving code into another scope is detected as well.
+func (diff *Diff) detectMovedLines() {
+	var deleted, added []*movedLineCandidate
+	for _, file := range diff.Files {
+		for _, section := range file.Sections {
+			for pos, line := range section.Lines {
+				if line.Type != DiffLineDel && line.Type != DiffLineAdd {
+					continue
+				}
+				candidate := &movedLineCandidate{
+					file:    file,
+					section: section,
+					pos:     pos,
+					line:    line,
+					key:     strings.TrimSpace(line.Content[1:]),
+				}
+				if line.Type == DiffLineDel {
+					deleted = append(deleted, candidate)
+				} else {
+					added = append(added, candidate)
+				}
+			}
+		}
+	}
+
+	index := make(map[string][]int)
+	for i, candidate := range deleted {
+		// a blank line may be part of a block but does not start one
+		if len(candidate.key) > 0 {
+			index[candidate.key] = append(index[candidate.key], i)
+		}
+	}
+
+	for i := 0; i < len(added); {
+		bestStart, bestLen := -1, 0
+		for _, j := range index[added[i].key] {
+			if isInPlace(deleted[j], added[i]) {
+				continue
+			}
+			n := 1
+			for i+n < len(added) && j+n < len(deleted) &&
+				added[i+n].key == deleted[j+n].key &&
+				added[i+n].follows(added[i+n-1]) && deleted[j+n].follows(deleted[j+n-1]) {
+				n++
+			}
+			if n > bestLen {
+				bestStart, bestLen = j, n
+			}
+		}
+		if bestLen == 0 || countAlnum(added[i:i+bestLen]) < movedBlockMinAlnum {
+			i++
+			continue
+		}
+
+		for k := 0; k < bestLen; k++ {
+			from, to := deleted[bestStart+k], added[i+k]
+			fromName := from.file.OldName
+			if len(fromName) == 0 {
+				fromName = from.file.Name
+			}
+			to.line.Move = &DiffLineMove{FileName: fromName, LineIdx: from.line.LeftIdx}
+			from.line.Move = &DiffLineMove{FileName: to.file.Name, LineIdx: to.line.RightIdx}
+		}
+		i += bestLen
+	}
+}
+
+func countAlnum(candidates []*movedLineCandidate) int {
+	count := 0
+	for _, candidate := range candidates {
+		for _, r := range candidate.key {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				count++
+			}
+		}
+	}
+	return count
+}
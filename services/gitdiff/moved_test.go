@@ -0,0 +1,79 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/setting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMovedLines(t *testing.T) {
+	const patch = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,6 +1,3 @@
+ package a
+-
+-func helper() {
+-	return computeSomething(42)
+-}
+ var x = 1
+diff --git a/b.go b/b.go
+index 3333333..4444444 100644
+--- a/b.go
++++ b/b.go
+@@ -1,3 +1,8 @@
+ package a
++
++type T struct{}
++
++func (T) helper() {
++		return computeSomething(42)
++}
+-var y = 2
++var y = 3
+`
+	diff, err := ParsePatch(setting.Git.MaxGitDiffLines, setting.Git.MaxGitDiffLineCharacters, setting.Git.MaxGitDiffFiles, strings.NewReader(patch))
+	assert.NoError(t, err)
+	diff.detectMovedLines()
+
+	deleted := diff.Files[0].Sections[0].Lines
+	// the blank line cannot start a block and the signature changed
+	assert.Nil(t, deleted[2].Move)
+	assert.Nil(t, deleted[3].Move)
+	assert.Equal(t, &DiffLineMove{FileName: "b.go", LineIdx: 6}, deleted[4].Move)
+	assert.Equal(t, &DiffLineMove{FileName: "b.go", LineIdx: 7}, deleted[5].Move)
+
+	added := diff.Files[1].Sections[0].Lines
+	assert.Equal(t, &DiffLineMove{FileName: "a.go", LineIdx: 4}, added[6].Move)
+	assert.Equal(t, &DiffLineMove{FileName: "a.go", LineIdx: 5}, added[7].Move)
+	assert.Nil(t, added[9].Move)
+}
+
+func TestDetectMovedLines_InPlace(t *testing.T) {
+	// re-indenting a block is no move
+	const patch = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,5 @@
+ func f() {
+-	doSomethingWithALongName(argument)
++	if condition {
++		doSomethingWithALongName(argument)
++	}
+ }
+`
+	diff, err := ParsePatch(setting.Git.MaxGitDiffLines, setting.Git.MaxGitDiffLineCharacters, setting.Git.MaxGitDiffFiles, strings.NewReader(patch))
+	assert.NoError(t, err)
+	diff.detectMovedLines()
+	for _, line := range diff.Files[0].Sections[0].Lines {
+		assert.Nil(t, line.Move)
+	}
+}
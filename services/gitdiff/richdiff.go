@@ -0,0 +1,156 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RichDiffType represents the type of a cell of a CSV diff or a block of a rendered diff.
+type RichDiffType uint8
+
+// RichDiffType possible values.
+const (
+	RichDiffEqual RichDiffType = iota + 1
+	RichDiffChanged
+	RichDiffAdd
+	RichDiffDel
+)
+
+// RenderedDiffBlock represents a top-level block of a rendered document in a rendered diff.
+type RenderedDiffBlock struct {
+	Left  template.HTML
+	Right template.HTML
+	Type  RichDiffType
+}
+
+// GetType returns the type of a RenderedDiffBlock.
+func (b *RenderedDiffBlock) GetType() int {
+	return int(b.Type)
+}
+
+// CreateRenderedDiff compares the top-level blocks of two rendered and
+// sanitized documents, blocks which differ are paired up in order.
+func CreateRenderedDiff(base, head []byte) ([]*RenderedDiffBlock, error) {
+	baseBlocks, err := splitRenderedBlocks(base)
+	if err != nil {
+		return nil, err
+	}
+	headBlocks, err := splitRenderedBlocks(head)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*RenderedDiffBlock, 0, len(headBlocks))
+	for _, pair := range align(baseBlocks, headBlocks) {
+		block := &RenderedDiffBlock{}
+		if pair.left >= 0 {
+			block.Left = template.HTML(baseBlocks[pair.left])
+		}
+		if pair.right >= 0 {
+			block.Right = template.HTML(headBlocks[pair.right])
+		}
+		switch {
+		case pair.left < 0:
+			block.Type = RichDiffAdd
+		case pair.right < 0:
+			block.Type = RichDiffDel
+		case block.Left != block.Right:
+			block.Type = RichDiffChanged
+		default:
+			block.Type = RichDiffEqual
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// splitRenderedBlocks returns the HTML of each top-level node of a rendered document
+func splitRenderedBlocks(rendered []byte) ([]string, error) {
+	nodes, err := html.ParseFragment(bytes.NewReader(rendered), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Type == html.TextNode && strings.TrimSpace(node.Data) == "" {
+			continue
+		}
+		var buf strings.Builder
+		if err := html.Render(&buf, node); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, buf.String())
+	}
+	return blocks, nil
+}
+
+// alignment is a pair of indexes of two aligned sequences, an index is -1
+// if the element has no counterpart on that side.
+type alignment struct {
+	left, right int
+}
+
+// align computes the longest common subsequence of two sequences of keys.
+// The elements removed and added between two common ones are paired in order,
+// so changed elements line up with their previous version.
+func align(left, right []string) []alignment {
+	tokens := newTokenRunes()
+	diffs := diffmatchpatch.New().DiffMainRunes(tokens.toRunes(left), tokens.toRunes(right), false)
+
+	result := make([]alignment, 0, len(right))
+	var removed, added []int
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			pair := alignment{left: -1, right: -1}
+			if i < len(removed) {
+				pair.left = removed[i]
+			}
+			if i < len(added) {
+				pair.right = added[i]
+			}
+			result = append(result, pair)
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	leftIdx, rightIdx := 0, 0
+	for _, diff := range diffs {
+		n := utf8.RuneCountInString(diff.Text)
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			flush()
+			for i := 0; i < n; i++ {
+				result = append(result, alignment{left: leftIdx, right: rightIdx})
+				leftIdx++
+				rightIdx++
+			}
+		case diffmatchpatch.DiffDelete:
+			for i := 0; i < n; i++ {
+				removed = append(removed, leftIdx)
+				leftIdx++
+			}
+		case diffmatchpatch.DiffInsert:
+			for i := 0; i < n; i++ {
+				added = append(added, rightIdx)
+				rightIdx++
+			}
+		}
+	}
+	flush()
+	return result
+}
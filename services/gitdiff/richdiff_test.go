@@ -0,0 +1,47 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRenderedDiff(t *testing.T) {
+	base := "<h1 id=\"user-content-title\">Title</h1>\n<p>First</p>\n<p>Second</p>\n<ul>\n<li>item</li>\n</ul>\n"
+	head := "<h1 id=\"user-content-title\">Title</h1>\n<p>First, changed</p>\n<p>Second</p>\n<p>Added</p>\n<ul>\n<li>item</li>\n</ul>\n"
+
+	blocks, err := CreateRenderedDiff([]byte(base), []byte(head))
+	assert.NoError(t, err)
+	if assert.Len(t, blocks, 5) {
+		assert.Equal(t, RichDiffEqual, blocks[0].Type)
+		assert.Equal(t, template.HTML(`<h1 id="user-content-title">Title</h1>`), blocks[0].Left)
+		assert.Equal(t, RichDiffChanged, blocks[1].Type)
+		assert.Equal(t, template.HTML("<p>First</p>"), blocks[1].Left)
+		assert.Equal(t, template.HTML("<p>First, changed</p>"), blocks[1].Right)
+		assert.Equal(t, RichDiffEqual, blocks[2].Type)
+		assert.Equal(t, RichDiffAdd, blocks[3].Type)
+		assert.Empty(t, blocks[3].Left)
+		assert.Equal(t, RichDiffEqual, blocks[4].Type)
+		assert.Equal(t, template.HTML("<ul>\n<li>item</li>\n</ul>"), blocks[4].Right)
+	}
+
+	blocks, err = CreateRenderedDiff([]byte(base), nil)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 4)
+	for _, block := range blocks {
+		assert.Equal(t, RichDiffDel, block.Type)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	assert.Equal(t, []alignment{{0, 0}, {1, 1}, {2, -1}, {3, 2}},
+		align([]string{"a", "b", "c", "d"}, []string{"a", "x", "d"}))
+	assert.Equal(t, []alignment{{-1, 0}, {0, 1}},
+		align([]string{"a"}, []string{"b", "a"}))
+	assert.Empty(t, align(nil, nil))
+}
@@ -0,0 +1,46 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package gitdiff
+
+import (
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+)
+
+// GetBlobSHA returns the SHA of the blob of a file in a commit, which is empty if
+// the file does not exist. The viewed state of pull request files is tied to it.
+func GetBlobSHA(commit *git.Commit, treePath string) (string, error) {
+	entry, err := commit.GetTreeEntryByPath(treePath)
+	if err != nil {
+		if git.IsErrNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return entry.ID.String(), nil
+}
+
+// LoadViewedFiles sets the blob SHAs of the files of a diff ending with the commit
+// and marks the files a user has viewed in these versions. Viewed files are
+// collapsed, files changed since are shown again.
+func (diff *Diff) LoadViewedFiles(pull *models.PullRequest, user *models.User, commit *git.Commit) error {
+	viewed, err := models.GetPullViewedFiles(user.ID, pull.ID)
+	if err != nil {
+		return err
+	}
+	diff.NumViewedFiles = 0
+	for _, file := range diff.Files {
+		if file.IsDeleted {
+			file.BlobSHA = ""
+		} else if file.BlobSHA, err = GetBlobSHA(commit, file.Name); err != nil {
+			return err
+		}
+		if blobSHA, ok := viewed[file.Name]; ok && blobSHA == file.BlobSHA {
+			file.IsViewed = true
+			diff.NumViewedFiles++
+		}
+	}
+	return nil
+}
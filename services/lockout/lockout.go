@@ -0,0 +1,50 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lockout
+
+import (
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/translation"
+	"code.gitea.io/gitea/services/mailer"
+)
+
+// UserSignIn validates the user name and password like models.UserSignIn, additionally rejecting
+// sign-in attempts from remote addresses with too many failed attempts. Failed attempts are counted
+// per address, and the user is notified by mail when the account gets locked.
+func UserSignIn(username, password, remoteAddr string) (*models.User, error) {
+	if models.IsLoginRateLimited(remoteAddr) {
+		return nil, models.ErrLoginRateLimited{RemoteAddr: remoteAddr}
+	}
+
+	u, err := models.UserSignIn(username, password)
+	if err == nil {
+		return u, nil
+	}
+
+	if models.IsErrUserNotExist(err) || models.IsErrUserLockedOut(err) {
+		models.RecordFailedLoginFromAddr(remoteAddr)
+	}
+
+	if lockedErr, ok := err.(models.ErrUserLockedOut); ok && lockedErr.JustLocked && setting.LoginLockoutNotifyUser {
+		notifyLockedUser(lockedErr.UID, remoteAddr)
+	}
+	return nil, err
+}
+
+func notifyLockedUser(uid int64, remoteAddr string) {
+	u, err := models.GetUserByID(uid)
+	if err != nil {
+		log.Error("GetUserByID(%d): %v", uid, err)
+		return
+	}
+
+	lang := u.Language
+	if lang == "" {
+		lang = setting.Langs[0]
+	}
+	mailer.SendAccountLockedMail(translation.NewLocale(lang), u, remoteAddr)
+}
@@ -31,6 +31,7 @@ const (
 	mailAuthActivateEmail  base.TplName = "auth/activate_email"
 	mailAuthResetPassword  base.TplName = "auth/reset_passwd"
 	mailAuthRegisterNotify base.TplName = "auth/register_notify"
+	mailAuthAccountLocked  base.TplName = "auth/account_locked"
 
 	mailNotifyCollaborator base.TplName = "notify/collaborator"
 
@@ -142,6 +143,33 @@ func SendRegisterNotifyMail(locale Locale, u *models.User) {
 	SendAsync(msg)
 }
 
+// SendAccountLockedMail notifies the user that the account has been locked after too many
+// failed sign-in attempts
+func SendAccountLockedMail(locale Locale, u *models.User, remoteAddr string) {
+	if setting.MailService == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"DisplayName": u.DisplayName(),
+		"Username":    u.Name,
+		"LockedUntil": u.LockedUntilUnix.FormatLong(),
+		"RemoteAddr":  remoteAddr,
+	}
+
+	var content bytes.Buffer
+
+	if err := bodyTemplates.ExecuteTemplate(&content, string(mailAuthAccountLocked), data); err != nil {
+		log.Error("Template: %v", err)
+		return
+	}
+
+	msg := NewMessage([]string{u.Email}, locale.Tr("mail.account_locked"), content.String())
+	msg.Info = fmt.Sprintf("UID: %d, account locked", u.ID)
+
+	SendAsync(msg)
+}
+
 // SendCollaboratorMail sends mail notification to new collaborator.
 func SendCollaboratorMail(u, doer *models.User, repo *models.Repository) {
 	repoName := repo.FullName()
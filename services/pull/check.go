@@ -251,6 +251,9 @@ func CheckPrsForBaseBranch(baseRepo *models.Repository, baseBranchName string) e
 		AddToTaskQueue(pr)
 	}
 
+	// the merge queue may have been enabled or disabled
+	AddToMergeQueueCheck(baseRepo.ID, baseBranchName)
+
 	return nil
 }
 
@@ -264,5 +267,14 @@ func Init() error {
 
 	go graceful.GetManager().RunWithShutdownFns(prQueue.Run)
 	go graceful.GetManager().RunWithShutdownContext(InitializePullRequests)
+
+	mergeQueue = queue.CreateUniqueQueue("pr_merge_queue", handleMergeQueue, "").(queue.UniqueQueue)
+
+	if mergeQueue == nil {
+		return fmt.Errorf("Unable to create pr_merge_queue Queue")
+	}
+
+	go graceful.GetManager().RunWithShutdownFns(mergeQueue.Run)
+	go graceful.GetManager().RunWithShutdownContext(initializeMergeQueues)
 	return nil
 }
@@ -60,6 +60,11 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		return err
 	}
 
+	return setMerged(pr, doer)
+}
+
+// setMerged marks the pull request as merged with its merged commit and resolves its cross references
+func setMerged(pr *models.PullRequest, doer *models.User) (err error) {
 	pr.MergedUnix = timeutil.TimeStampNow()
 	pr.Merger = doer
 	pr.MergerID = doer.ID
@@ -111,6 +116,13 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 
 // rawMerge perform the merge operation without changing any pull information in database
 func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.MergeStyle, message string) (string, error) {
+	return rawMergeOnto(pr, doer, mergeStyle, message, "", git.BranchPrefix+pr.BaseBranch)
+}
+
+// rawMergeOnto merges the pull request into the given base commit, or the head of the base branch
+// if it is empty, and pushes the result to the target ref. The target ref is overwritten if it is
+// not the base branch of the pull request.
+func rawMergeOnto(pr *models.PullRequest, doer *models.User, mergeStyle models.MergeStyle, message, baseCommitID, targetRef string) (string, error) {
 	err := git.LoadGitVersion()
 	if err != nil {
 		log.Error("git.LoadGitVersion: %v", err)
@@ -135,6 +147,17 @@ func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.Merge
 
 	var outbuf, errbuf strings.Builder
 
+	if baseCommitID != "" {
+		for _, branch := range []string{baseBranch, "original_" + baseBranch} {
+			if err := git.NewCommand("update-ref", git.BranchPrefix+branch, baseCommitID).RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
+				log.Error("git update-ref [%s -> %s]: %v\n%s\n%s", branch, baseCommitID, err, outbuf.String(), errbuf.String())
+				return "", fmt.Errorf("Unable to set %s to %s: %v\n%s\n%s", branch, baseCommitID, err, outbuf.String(), errbuf.String())
+			}
+			outbuf.Reset()
+			errbuf.Reset()
+		}
+	}
+
 	// Enable sparse-checkout
 	sparseCheckoutList, err := getDiffTree(tmpBasePath, baseBranch, trackingBranch)
 	if err != nil {
@@ -410,8 +433,13 @@ func rawMerge(pr *models.PullRequest, doer *models.User, mergeStyle models.Merge
 		pr.ID,
 	)
 
+	refspec := baseBranch + ":" + targetRef
+	if targetRef != git.BranchPrefix+pr.BaseBranch {
+		refspec = "+" + refspec
+	}
+
 	// Push back to upstream.
-	if err := git.NewCommand("push", "origin", baseBranch+":refs/heads/"+pr.BaseBranch).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+	if err := git.NewCommand("push", "origin", refspec).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 		if strings.Contains(errbuf.String(), "non-fast-forward") {
 			return "", &git.ErrPushOutOfDate{
 				StdOut: outbuf.String(),
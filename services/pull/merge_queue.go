@@ -0,0 +1,375 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/convert"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/queue"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/sync"
+	"code.gitea.io/gitea/modules/timeutil"
+	webhook_service "code.gitea.io/gitea/services/webhook"
+)
+
+// mergeQueue represents a queue of the branches whose merge queues have to be processed
+var mergeQueue queue.UniqueQueue
+
+var mergeQueueWorkingPool = sync.NewExclusivePool()
+
+// mergeQueueRefPrefix is the prefix of the hidden refs the speculative merges are pushed to
+const mergeQueueRefPrefix = "refs/merge-queue/"
+
+// Reasons for removing a pull request from the merge queue, they are stored in the timeline comment
+const (
+	mergeQueueReasonClosed        = "The pull request was closed."
+	mergeQueueReasonDisabled      = "The merge queue of the base branch was disabled."
+	mergeQueueReasonConflicts     = "The pull request could not be merged with the pull requests ahead of it in the queue."
+	mergeQueueReasonChecksFailed  = "The required status checks failed for the speculative merge."
+	mergeQueueReasonTimeout       = "The required status checks did not finish within %s."
+	mergeQueueReasonNewCommits    = "New commits were pushed to the head branch."
+	mergeQueueReasonTargetChanged = "The target branch of the pull request was changed."
+	mergeQueueReasonMergeFailed   = "The speculative merge failed."
+	mergeQueueReasonPushRejected  = "The base branch could not be updated: %s"
+)
+
+// MergeQueueRef returns the hidden ref the speculative merge of a queued pull request is pushed to.
+// Unlike a branch it is not listed in the repository, but a push webhook is sent for it so CI
+// systems can fetch and test it and report the result as a commit status of the merge commit.
+func MergeQueueRef(pr *models.PullRequest) string {
+	return fmt.Sprintf("%s%d", mergeQueueRefPrefix, pr.Index)
+}
+
+// IsMergeQueueEnabled returns if pull requests are merged through the merge queue of their base branch
+func IsMergeQueueEnabled(pr *models.PullRequest) (bool, error) {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return false, err
+	}
+	return pr.ProtectedBranch != nil && pr.ProtectedBranch.EnableMergeQueue, nil
+}
+
+// AddToMergeQueue adds a pull request which is ready to be merged to the merge queue of its base branch.
+// Caller should check PR is ready to be merged (review and status checks)
+func AddToMergeQueue(pr *models.PullRequest, doer *models.User, mergeStyle models.MergeStyle, message string) error {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return err
+	}
+
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		return err
+	}
+	if !prUnit.PullRequestsConfig().IsMergeStyleAllowed(mergeStyle) || mergeStyle == models.MergeStyleManuallyMerged {
+		return models.ErrInvalidMergeStyle{ID: pr.BaseRepo.ID, Style: mergeStyle}
+	}
+
+	if _, err := models.AddToMergeQueue(pr, doer, mergeStyle, message); err != nil {
+		return err
+	}
+	AddToMergeQueueCheck(pr.BaseRepoID, pr.BaseBranch)
+	return nil
+}
+
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemoveFromMergeQueue(pr *models.PullRequest, doer *models.User) error {
+	return removeFromMergeQueue(pr, doer, "")
+}
+
+// removeFromMergeQueue removes a pull request from the merge queue of its base branch if it is queued
+func removeFromMergeQueue(pr *models.PullRequest, doer *models.User, reason string) error {
+	if _, err := models.GetMergeQueueEntryByPullID(pr.ID); err != nil {
+		if models.IsErrMergeQueueEntryNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := models.RemoveFromMergeQueue(pr, doer, reason); err != nil {
+		return err
+	}
+	if err := deleteMergeQueueRef(pr); err != nil {
+		log.Error("deleteMergeQueueRef[%d]: %v", pr.ID, err)
+	}
+	AddToMergeQueueCheck(pr.BaseRepoID, pr.BaseBranch)
+	return nil
+}
+
+// AddToMergeQueueCheck adds the merge queue of a branch to the queue of merge queues to process
+func AddToMergeQueueCheck(repoID int64, branch string) {
+	go func() {
+		key := strconv.FormatInt(repoID, 10) + ":" + branch
+		if err := mergeQueue.Push(key); err != nil && err != queue.ErrAlreadyInQueue {
+			log.Error("Error adding %s to the merge queue check queue: %v", key, err)
+		}
+	}()
+}
+
+// AddMergeQueueCheckByCommitID processes the merge queues a speculative merge commit was built for,
+// it is called when the status of a commit changes.
+func AddMergeQueueCheckByCommitID(repoID int64, sha string) {
+	entries, err := models.GetMergeQueueEntriesBySpeculativeCommitID(repoID, sha)
+	if err != nil {
+		log.Error("GetMergeQueueEntriesBySpeculativeCommitID[%d, %s]: %v", repoID, sha, err)
+		return
+	}
+	for _, entry := range entries {
+		AddToMergeQueueCheck(entry.RepoID, entry.BaseBranch)
+	}
+}
+
+// CheckMergeQueues processes all merge queues with pull requests, which removes the pull requests
+// whose status checks did not finish within MERGE_QUEUE_TIMEOUT
+func CheckMergeQueues(ctx context.Context) error {
+	branches, err := models.GetMergeQueueBranches()
+	if err != nil {
+		return fmt.Errorf("GetMergeQueueBranches: %v", err)
+	}
+	for _, branch := range branches {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Aborted")
+		default:
+			AddToMergeQueueCheck(branch.RepoID, branch.BaseBranch)
+		}
+	}
+	return nil
+}
+
+// initializeMergeQueues processes all merge queues with pull requests
+func initializeMergeQueues(ctx context.Context) {
+	if err := CheckMergeQueues(ctx); err != nil {
+		log.Error("CheckMergeQueues: %v", err)
+	}
+}
+
+// handleMergeQueue processes the merge queues of the passed branches
+func handleMergeQueue(data ...queue.Data) {
+	for _, datum := range data {
+		key := datum.(string)
+		fields := strings.SplitN(key, ":", 2)
+		if len(fields) != 2 {
+			log.Error("Invalid merge queue key: %s", key)
+			continue
+		}
+		repoID, _ := strconv.ParseInt(fields[0], 10, 64)
+
+		mergeQueueWorkingPool.CheckIn(key)
+		if err := processMergeQueue(repoID, fields[1]); err != nil {
+			log.Error("processMergeQueue[%s]: %v", key, err)
+		}
+		mergeQueueWorkingPool.CheckOut(key)
+	}
+}
+
+// processMergeQueue builds the speculative merges of the queued pull requests, each on top of the
+// one before it, and fast-forwards the base branch to the first one once its status checks passed.
+func processMergeQueue(repoID int64, branch string) error {
+	entries, err := models.GetMergeQueue(repoID, branch)
+	if err != nil {
+		return fmt.Errorf("GetMergeQueue: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		return fmt.Errorf("GetRepositoryByID: %v", err)
+	}
+	protectedBranch, err := repo.GetBranchProtection(branch)
+	if err != nil {
+		return fmt.Errorf("GetBranchProtection: %v", err)
+	}
+
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+	baseCommitID, err := gitRepo.GetBranchCommitID(branch)
+	if err != nil {
+		return fmt.Errorf("GetBranchCommitID: %v", err)
+	}
+
+	queued := make([]*models.PullMergeQueueEntry, 0, len(entries))
+	previousCommitID := baseCommitID
+	for _, entry := range entries {
+		if err := entry.LoadAttributes(); err != nil {
+			return fmt.Errorf("LoadAttributes: %v", err)
+		}
+		pr := entry.PullRequest
+		if err := pr.LoadIssue(); err != nil {
+			return fmt.Errorf("LoadIssue: %v", err)
+		}
+
+		reason := ""
+		switch {
+		case pr.HasMerged:
+			if err := models.DeleteMergeQueueEntry(pr.ID); err != nil {
+				return fmt.Errorf("DeleteMergeQueueEntry: %v", err)
+			}
+			if err := deleteMergeQueueRef(pr); err != nil {
+				log.Error("deleteMergeQueueRef[%d]: %v", pr.ID, err)
+			}
+			continue
+		case pr.Issue.IsClosed:
+			reason = mergeQueueReasonClosed
+		case protectedBranch == nil || !protectedBranch.EnableMergeQueue:
+			reason = mergeQueueReasonDisabled
+		case entry.SpeculativeCommitID == "" || entry.BaseCommitID != previousCommitID:
+			// the pull requests ahead of it or the base branch changed
+			commitID, err := rawMergeOnto(pr, entry.Doer, entry.MergeStyle, entry.Message, previousCommitID, MergeQueueRef(pr))
+			if err != nil {
+				log.Info("Unable to build the speculative merge of %s#%d: %v", repo.FullName(), pr.Index, err)
+				reason = mergeQueueReasonMergeFailed
+				if models.IsErrMergeConflicts(err) || models.IsErrRebaseConflicts(err) || models.IsErrMergeUnrelatedHistories(err) {
+					reason = mergeQueueReasonConflicts
+				}
+				break
+			}
+			oldCommitID := entry.SpeculativeCommitID
+			if err := entry.SetSpeculativeCommit(previousCommitID, commitID); err != nil {
+				return fmt.Errorf("SetSpeculativeCommit: %v", err)
+			}
+			notifyMergeQueueRef(entry, repo, gitRepo, oldCommitID)
+		}
+
+		if reason != "" {
+			if err := models.RemoveFromMergeQueue(pr, entry.Doer, reason); err != nil {
+				return fmt.Errorf("RemoveFromMergeQueue: %v", err)
+			}
+			if err := deleteMergeQueueRef(pr); err != nil {
+				log.Error("deleteMergeQueueRef[%d]: %v", pr.ID, err)
+			}
+			continue
+		}
+
+		queued = append(queued, entry)
+		previousCommitID = entry.SpeculativeCommitID
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+
+	head := queued[0]
+	state := structs.CommitStatusSuccess
+	if protectedBranch.EnableStatusCheck {
+		commitStatuses, err := models.GetLatestCommitStatus(repo.ID, head.SpeculativeCommitID, models.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("GetLatestCommitStatus: %v", err)
+		}
+		state = MergeRequiredContextsCommitStatus(commitStatuses, protectedBranch.StatusCheckContexts)
+	}
+
+	// like for merging a pull request directly, only successful required status checks pass,
+	// so a warning is a final result which removes the pull request from the queue
+	timeout := setting.Repository.PullRequest.MergeQueueTimeout
+	switch {
+	case state.IsSuccess():
+		return fastForwardMergeQueue(head)
+	case state.IsPending() && timeout > 0 && head.UpdatedUnix.AddDuration(timeout) <= timeutil.TimeStampNow():
+		return removeFromMergeQueue(head.PullRequest, head.Doer, fmt.Sprintf(mergeQueueReasonTimeout, timeout))
+	case state.IsPending():
+		// wait for the status checks of the speculative merge
+		return nil
+	default:
+		return removeFromMergeQueue(head.PullRequest, head.Doer, mergeQueueReasonChecksFailed)
+	}
+}
+
+// notifyMergeQueueRef sends a push webhook for the speculative merge of a queued pull request
+func notifyMergeQueueRef(entry *models.PullMergeQueueEntry, repo *models.Repository, gitRepo *git.Repository, oldCommitID string) {
+	commit, err := gitRepo.GetCommit(entry.SpeculativeCommitID)
+	if err != nil {
+		log.Error("GetCommit[%s]: %v", entry.SpeculativeCommitID, err)
+		return
+	}
+	if oldCommitID == "" {
+		oldCommitID = git.EmptySHA
+	}
+
+	apiDoer := convert.ToUser(entry.Doer, false, false)
+	apiCommit := convert.ToPayloadCommit(repo, commit)
+	if err := webhook_service.PrepareWebhooks(repo, models.HookEventPush, &structs.PushPayload{
+		Ref:        MergeQueueRef(entry.PullRequest),
+		Before:     oldCommitID,
+		After:      entry.SpeculativeCommitID,
+		CompareURL: setting.AppURL + repo.ComposeCompareURL(entry.BaseCommitID, entry.SpeculativeCommitID),
+		Commits:    []*structs.PayloadCommit{apiCommit},
+		HeadCommit: apiCommit,
+		Repo:       convert.ToRepo(repo, models.AccessModeOwner),
+		Pusher:     apiDoer,
+		Sender:     apiDoer,
+	}); err != nil {
+		log.Error("PrepareWebhooks: %v", err)
+	}
+}
+
+// fastForwardMergeQueue updates the base branch to the speculative merge of the first pull request of the queue
+func fastForwardMergeQueue(entry *models.PullMergeQueueEntry) error {
+	pr := entry.PullRequest
+	if err := pr.LoadBaseRepo(); err != nil {
+		return fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+	if err := pr.LoadHeadRepo(); err != nil {
+		return fmt.Errorf("LoadHeadRepo: %v", err)
+	}
+
+	headUser := entry.Doer
+	if pr.HeadRepo != nil {
+		if err := pr.HeadRepo.GetOwner(); err != nil {
+			if !models.IsErrUserNotExist(err) {
+				return fmt.Errorf("GetOwner: %v", err)
+			}
+		} else {
+			headUser = pr.HeadRepo.Owner
+		}
+	}
+
+	repoPath := pr.BaseRepo.RepoPath()
+	if err := git.Push(repoPath, git.PushOptions{
+		Remote: repoPath,
+		Branch: entry.SpeculativeCommitID + ":" + git.BranchPrefix + pr.BaseBranch,
+		Env:    models.FullPushingEnvironment(headUser, entry.Doer, pr.BaseRepo, pr.BaseRepo.Name, pr.ID),
+	}); err != nil {
+		if git.IsErrPushOutOfDate(err) {
+			// the base branch was pushed to, the queue is processed again after the push
+			return nil
+		} else if git.IsErrPushRejected(err) {
+			return removeFromMergeQueue(pr, entry.Doer, fmt.Sprintf(mergeQueueReasonPushRejected, err.(*git.ErrPushRejected).Message))
+		}
+		return fmt.Errorf("Push: %v", err)
+	}
+
+	if err := models.DeleteMergeQueueEntry(pr.ID); err != nil {
+		return fmt.Errorf("DeleteMergeQueueEntry: %v", err)
+	}
+	if err := deleteMergeQueueRef(pr); err != nil {
+		log.Error("deleteMergeQueueRef[%d]: %v", pr.ID, err)
+	}
+
+	pr.MergedCommitID = entry.SpeculativeCommitID
+	if err := setMerged(pr, entry.Doer); err != nil {
+		log.Error("setMerged[%d]: %v", pr.ID, err)
+	}
+
+	AddToMergeQueueCheck(pr.BaseRepoID, pr.BaseBranch)
+	return nil
+}
+
+func deleteMergeQueueRef(pr *models.PullRequest) error {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return err
+	}
+	_, err := git.NewCommand("update-ref", "-d", MergeQueueRef(pr)).RunInDir(pr.BaseRepo.RepoPath())
+	return err
+}
@@ -22,8 +22,9 @@ import (
 	"github.com/gobwas/glob"
 )
 
-// DownloadDiffOrPatch will write the patch for the pr to the writer
-func DownloadDiffOrPatch(pr *models.PullRequest, w io.Writer, patch bool) error {
+// DownloadDiffOrPatch will write the patch for the pr to the writer, a diff is
+// written as word diff if wordDiff is set
+func DownloadDiffOrPatch(pr *models.PullRequest, w io.Writer, patch, wordDiff bool) error {
 	if err := pr.LoadBaseRepo(); err != nil {
 		log.Error("Unable to load base repository ID %d for pr #%d [%d]", pr.BaseRepoID, pr.Index, pr.ID)
 		return err
@@ -34,7 +35,12 @@ func DownloadDiffOrPatch(pr *models.PullRequest, w io.Writer, patch bool) error
 		return fmt.Errorf("OpenRepository: %v", err)
 	}
 	defer gitRepo.Close()
-	if err := gitRepo.GetDiffOrPatch(pr.MergeBase, pr.GetGitRefName(), w, patch); err != nil {
+	if !patch && wordDiff {
+		err = gitRepo.GetWordDiff(pr.MergeBase, pr.GetGitRefName(), w)
+	} else {
+		err = gitRepo.GetDiffOrPatch(pr.MergeBase, pr.GetGitRefName(), w, patch)
+	}
+	if err != nil {
 		log.Error("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
 		return fmt.Errorf("Unable to get patch file from %s to %s in %s Error: %v", pr.MergeBase, pr.HeadBranch, pr.BaseRepo.FullName(), err)
 	}
@@ -161,6 +161,11 @@ func ChangeTargetBranch(pr *models.PullRequest, doer *models.User, targetBranch
 		return err
 	}
 
+	// The pull request was tested on top of the old target branch
+	if err := removeFromMergeQueue(pr, doer, mergeQueueReasonTargetChanged); err != nil {
+		return err
+	}
+
 	// Set new target branch
 	oldBranch := pr.BaseBranch
 	pr.BaseBranch = targetBranch
@@ -263,6 +268,9 @@ func AddTestPullRequestTask(doer *models.User, repoID int64, branch string, isSy
 						if err := models.MarkReviewsAsNotStale(pr.IssueID, newCommitID); err != nil {
 							log.Error("MarkReviewsAsNotStale: %v", err)
 						}
+						if err := removeFromMergeQueue(pr, doer, mergeQueueReasonNewCommits); err != nil {
+							log.Error("removeFromMergeQueue: %v", err)
+						}
 						divergence, err := GetDiverging(pr)
 						if err != nil {
 							log.Error("GetDiverging: %v", err)
@@ -312,6 +320,8 @@ func AddTestPullRequestTask(doer *models.User, repoID int64, branch string, isSy
 			}
 			AddToTaskQueue(pr)
 		}
+
+		AddToMergeQueueCheck(repoID, branch)
 	})
 }
 
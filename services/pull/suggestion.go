@@ -0,0 +1,164 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/repofiles"
+	"code.gitea.io/gitea/services/gitdiff"
+)
+
+// IsUserAllowedToEditHeadBranch checks if a user is allowed to push commits to the head branch
+// of a pull request. Users who can write to the base repository are allowed to if the poster
+// of the pull request allows edits by maintainers.
+func IsUserAllowedToEditHeadBranch(pr *models.PullRequest, user *models.User) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	if err := pr.LoadHeadRepo(); err != nil {
+		return false, err
+	}
+	if pr.HeadRepo == nil {
+		return false, nil
+	}
+
+	headRepoPerm, err := models.GetUserRepoPermission(pr.HeadRepo, user)
+	if err != nil {
+		return false, err
+	}
+	canWrite := headRepoPerm.CanWrite(models.UnitTypeCode)
+	if !canWrite && pr.AllowMaintainerEdit && pr.HeadRepoID != pr.BaseRepoID {
+		if err := pr.LoadBaseRepo(); err != nil {
+			return false, err
+		}
+		baseRepoPerm, err := models.GetUserRepoPermission(pr.BaseRepo, user)
+		if err != nil {
+			return false, err
+		}
+		canWrite = baseRepoPerm.CanWrite(models.UnitTypeCode)
+	}
+	if !canWrite {
+		return false, nil
+	}
+
+	protectedBranch, err := pr.HeadRepo.GetBranchProtection(pr.HeadBranch)
+	if err != nil {
+		return false, err
+	}
+	return protectedBranch == nil || protectedBranch.CanUserPush(user.ID), nil
+}
+
+// ApplySuggestions commits the suggestions of code comments to the head branch of a pull request
+// and resolves their conversations
+func ApplySuggestions(pr *models.PullRequest, doer *models.User, comments []*models.Comment) error {
+	if err := pr.LoadIssue(); err != nil {
+		return err
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return err
+	}
+	if err := pr.LoadHeadRepo(); err != nil {
+		return err
+	}
+
+	if allowed, err := IsUserAllowedToEditHeadBranch(pr, doer); err != nil {
+		return err
+	} else if !allowed {
+		return models.ErrUserCannotCommit{UserName: doer.LowerName}
+	}
+
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return err
+	}
+	defer gitRepo.Close()
+	headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err != nil {
+		return err
+	}
+
+	// the lines of code comments refer to the head of the pull request at the time
+	// they were made, they are translated to the current head
+	lineMaps := make(map[string]gitdiff.LineMap)
+	suggestions := make([]*repofiles.Suggestion, 0, len(comments))
+	coAuthors := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		lines, ok := comment.SuggestedLines()
+		if !ok || comment.IssueID != pr.IssueID {
+			return fmt.Errorf("comment %d is not a suggestion of pull request %d", comment.ID, pr.ID)
+		}
+		if comment.Invalidated {
+			return models.ErrSuggestionOutdated{Path: comment.TreePath, Line: comment.Line}
+		}
+
+		line := comment.Line
+		if comment.CommitSHA != "" && comment.CommitSHA != headCommitID {
+			lineMap, ok := lineMaps[comment.CommitSHA]
+			if !ok {
+				if lineMap, err = gitdiff.GetLineMap(pr.BaseRepo.RepoPath(), comment.CommitSHA, headCommitID); err != nil {
+					return err
+				}
+				lineMaps[comment.CommitSHA] = lineMap
+			}
+			if line = int64(lineMap.ToNew(comment.TreePath, int(line))); line == 0 {
+				return models.ErrSuggestionOutdated{Path: comment.TreePath, Line: comment.Line}
+			}
+		}
+
+		suggestions = append(suggestions, &repofiles.Suggestion{
+			TreePath: comment.TreePath,
+			Line:     line,
+			OldLine:  comment.CommentedLine(),
+			NewLines: lines,
+		})
+
+		if err := comment.LoadPoster(); err != nil {
+			return err
+		}
+		if comment.PosterID != doer.ID && comment.Poster.ID > 0 {
+			coAuthor := comment.Poster.NewGitSig().String()
+			found := false
+			for _, existing := range coAuthors {
+				if existing == coAuthor {
+					found = true
+					break
+				}
+			}
+			if !found {
+				coAuthors = append(coAuthors, coAuthor)
+			}
+		}
+	}
+
+	message := "Apply suggestion from code review"
+	if len(suggestions) > 1 {
+		message = "Apply suggestions from code review"
+	}
+	if len(coAuthors) > 0 {
+		message += "\n"
+		for _, coAuthor := range coAuthors {
+			message += "\nCo-authored-by: " + coAuthor
+		}
+	}
+
+	if _, err := repofiles.ApplySuggestions(pr.HeadRepo, doer, &repofiles.ApplySuggestionsOptions{
+		LastCommitID: headCommitID,
+		Branch:       pr.HeadBranch,
+		Message:      message,
+		Suggestions:  suggestions,
+	}); err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		if err := models.MarkConversation(comment, doer, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
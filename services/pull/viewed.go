@@ -0,0 +1,27 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/services/gitdiff"
+)
+
+// MarkFilesViewed marks files of a pull request as viewed by a user in their
+// versions at the head of the pull request
+func MarkFilesViewed(gitRepo *git.Repository, pr *models.PullRequest, user *models.User, treePaths []string) error {
+	headCommit, err := gitRepo.GetCommit(pr.GetGitRefName())
+	if err != nil {
+		return err
+	}
+	files := make(map[string]string, len(treePaths))
+	for _, treePath := range treePaths {
+		if files[treePath], err = gitdiff.GetBlobSHA(headCommit, treePath); err != nil {
+			return err
+		}
+	}
+	return models.SetPullFilesViewed(user.ID, pr.ID, files)
+}
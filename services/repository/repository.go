@@ -10,6 +10,7 @@ import (
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/repofiles"
 	repo_module "code.gitea.io/gitea/modules/repository"
 	cfg "code.gitea.io/gitea/modules/setting"
 	pull_service "code.gitea.io/gitea/services/pull"
@@ -100,3 +101,13 @@ func PushCreateRepo(authUser, owner *models.User, repoName string) (*models.Repo
 func NewContext() error {
 	return initPushQueue()
 }
+
+// CreateCommitStatus creates a new commit status and processes the merge queues waiting for the
+// status checks of the commit. Commit statuses should always be created through this function.
+func CreateCommitStatus(repo *models.Repository, creator *models.User, sha string, status *models.CommitStatus) error {
+	if err := repofiles.CreateCommitStatus(repo, creator, sha, status); err != nil {
+		return err
+	}
+	pull_service.AddMergeQueueCheckByCommitID(repo.ID, sha)
+	return nil
+}
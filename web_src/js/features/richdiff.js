@@ -0,0 +1,12 @@
+export default function initRichDiff() {
+  $('.rich-diff-toggle .button').on('click', function () {
+    const $button = $(this);
+    if ($button.hasClass('active')) return;
+    $button.addClass('active').siblings().removeClass('active');
+
+    const rendered = $button.data('mode') === 'rendered';
+    const $body = $button.closest('.diff-file-box').find('.diff-file-body');
+    $body.children('.source-diff').toggleClass('hide', rendered);
+    $body.children('.rich-diff').toggleClass('hide', !rendered);
+  });
+}
@@ -0,0 +1,36 @@
+const {csrf} = window.config;
+
+// the rendered ```suggestion code block is replaced by the suggestion diff
+function hideSuggestionBlocks() {
+  $('.suggestion').each(function () {
+    $(this).siblings('.render-content').find('code.language-suggestion').first().closest('pre').hide();
+  });
+}
+
+function updateBatch() {
+  const ids = $('.suggestion-batch input:checked').map(function () {
+    return this.value;
+  }).get();
+  const $batch = $('.apply-suggestions-batch');
+  $batch.attr('data-comment-ids', ids.join(','));
+  $batch.find('.suggestion-batch-count').text(ids.length);
+  $batch.toggleClass('hide', ids.length === 0);
+}
+
+export default function initSuggestions() {
+  hideSuggestionBlocks();
+  // conversations are replaced when a comment is added or resolved
+  $(document).on('ajaxComplete', hideSuggestionBlocks);
+
+  $(document).on('change', '.suggestion-batch input', updateBatch);
+  $(document).on('click', '.apply-suggestions', async function (e) {
+    e.preventDefault();
+    const $button = $(this);
+    $button.addClass('loading disabled');
+    const data = await $.post($button.attr('data-url'), {
+      _csrf: csrf,
+      comment_ids: $button.attr('data-comment-ids'),
+    });
+    window.location.href = data.redirect;
+  });
+}
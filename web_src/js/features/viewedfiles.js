@@ -0,0 +1,24 @@
+import {svg} from '../svg.js';
+
+const {csrf} = window.config;
+
+export default function initViewedFiles() {
+  $('.viewed-file-toggle input').on('change', async function () {
+    const $toggle = $(this).closest('.viewed-file-toggle');
+    const viewed = this.checked;
+    await $.post($toggle.attr('data-url'), {
+      _csrf: csrf,
+      path: $toggle.attr('data-path'),
+      sha: $toggle.attr('data-sha'),
+      viewed,
+    });
+
+    // viewed files are collapsed like the fold button does
+    const box = $toggle.closest('.file-content')[0];
+    box.dataset.folded = String(viewed);
+    $(box).find('.fold-file').html(svg(`octicon-chevron-${viewed ? 'right' : 'down'}`, 18));
+
+    const $count = $('.viewed-files-count');
+    $count.text(Number($count.text()) + (viewed ? 1 : -1));
+  });
+}
@@ -21,6 +21,9 @@ import createColorPicker from './features/colorpicker.js';
 import createDropzone from './features/dropzone.js';
 import initTableSort from './features/tablesort.js';
 import initImageDiff from './features/imagediff.js';
+import initRichDiff from './features/richdiff.js';
+import initViewedFiles from './features/viewedfiles.js';
+import initSuggestions from './features/suggestions.js';
 import ActivityTopAuthors from './components/ActivityTopAuthors.vue';
 import {initNotificationsTable, initNotificationCount} from './features/notification.js';
 import {initStopwatch} from './features/stopwatch.js';
@@ -1363,6 +1366,12 @@ function initPullRequestReview() {
       td.find("input[name='line']").val(idx);
       td.find("input[name='side']").val(side === 'left' ? 'previous' : 'proposed');
       td.find("input[name='path']").val(path);
+      const range = $(this).closest('[data-diff-end-cid]');
+      if (range.length) {
+        // the server anchors comments on a range of commits to the whole pull request
+        td.find("input[name='diff_start_cid']").val(range.attr('data-diff-start-cid'));
+        td.find("input[name='diff_end_cid']").val(range.attr('data-diff-end-cid'));
+      }
       const $textarea = commentCloud.find('textarea');
       attachTribute($textarea.get(), {mentions: true, emoji: true});
       const $simplemde = setCommentSimpleMDE($textarea);
@@ -1972,7 +1981,7 @@ function initAdmin() {
   // New authentication
   if ($('.admin.new.authentication').length > 0) {
     $('#auth_type').on('change', function () {
-      $('.ldap, .dldap, .smtp, .pam, .oauth2, .has-tls, .search-page-size, .sspi').hide();
+      $('.ldap, .dldap, .smtp, .pam, .oauth2, .has-tls, .search-page-size, .sspi, .saml').hide();
 
       $('.ldap input[required], .binddnrequired input[required], .dldap input[required], .smtp input[required], .pam input[required], .oauth2 input[required], .has-tls input[required], .sspi input[required]').removeAttr('required');
       $('.binddnrequired').removeClass('required');
@@ -2006,6 +2015,9 @@ function initAdmin() {
           $('.sspi').show();
           $('.sspi div.required input').attr('required', 'required');
           break;
+        case '8': // SAML
+          $('.saml').show();
+          break;
       }
       if (authType === '2' || authType === '5') {
         onSecurityProtocolChange();
@@ -2689,6 +2701,9 @@ $(document).ready(async () => {
   initNotificationsTable();
   initPullRequestMergeInstruction();
   initReleaseEditor();
+  initRichDiff();
+  initViewedFiles();
+  initSuggestions();
 
   const routes = {
     'div.user.settings': initUserSettings,
@@ -3796,7 +3811,9 @@ $(document).on('submit', '.conversation-holder form', async (e) => {
   e.preventDefault();
   const form = $(e.target);
   const newConversationHolder = $(await $.post(form.attr('action'), form.serialize()));
-  const {path, side, idx} = newConversationHolder.data();
+  const {path, side} = newConversationHolder.data();
+  // the line of the comment may differ from the line of the diff when a range of commits is shown
+  const idx = form.find('input[name="line"]').val() || newConversationHolder.data('idx');
 
   form.closest('.conversation-holder').replaceWith(newConversationHolder);
   if (form.closest('tr').data('line-type') === 'same') {
@@ -1,5 +1,7 @@
+import {renderMath} from './math.js';
 import {renderMermaid} from './mermaid.js';
 
 export default async function renderMarkdownContent() {
+  renderMath(document.querySelectorAll('code.language-math'));
   await renderMermaid(document.querySelectorAll('code.language-mermaid'));
 }
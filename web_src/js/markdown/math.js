@@ -0,0 +1,27 @@
+// Math is typeset by KaTeX when the site provides it, e.g. by loading it in
+// custom/templates/custom/header.tmpl, otherwise the TeX source is shown.
+export function renderMath(els) {
+  if (!els || !els.length) return;
+
+  for (const el of els) {
+    const target = el.closest('pre') || el;
+    target.classList.remove('is-loading');
+    if (!window.katex) continue;
+
+    const displayMode = el.classList.contains('display');
+    const container = document.createElement(displayMode ? 'div' : 'span');
+    container.classList.add('math', displayMode ? 'display' : 'inline');
+    try {
+      window.katex.render(el.textContent, container, {
+        displayMode,
+        throwOnError: false,
+        maxSize: 25,
+        maxExpand: 50,
+      });
+    } catch (err) {
+      // leave the source in place
+      continue;
+    }
+    target.replaceWith(container);
+  }
+}